@@ -1,31 +1,71 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/account"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/backup"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/export"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/handlers"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/importer"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/metadata"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
 	"gorm.io/gorm"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
+	// SIGINT/SIGTERM beenden den Server geordnet
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialization
-	utils.InitKeycloak()
+	utils.InitAuth(ctx, config.LoadAuthConfig())
 	db := setupDatabase()
+	metadataService := metadata.NewServiceFromConfig(config.LoadMetadataConfig())
+	store := setupStorage()
+	setupAccounts(ctx, db, store)
+	jobsCfg := config.LoadJobsConfig()
+	manager := setupJobs(ctx, db, jobsCfg, store)
 
 	// Router setup
-	router := createRouter(db)
+	router := createRouter(db, metadataService, store)
 	setupRoutes(router)
 
 	// Start server
-	log.Println("Starting server on :8080")
-	err := router.Run(":8080")
-	if err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		log.Println("Starting server on :8080")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down...")
+
+	// Erst keine neuen Requests annehmen, dann auf die Worker warten
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), jobsCfg.ShutdownWait+10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR shutting down server: %v\n", err)
+	}
+	if err := manager.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR shutting down job workers: %v\n", err)
 	}
+	log.Println("Server stopped")
 }
 
 func setupDatabase() *gorm.DB {
@@ -43,21 +83,66 @@ func setupDatabase() *gorm.DB {
 	return db
 }
 
-func createRouter(db *gorm.DB) *gin.Engine {
+func setupStorage() storage.Store {
+	storageCfg := config.LoadStorageConfig()
+	store, err := storage.NewFromConfig(storageCfg)
+	if err != nil {
+		log.Fatalf("Storage setup failed: %v", err)
+	}
+	handlers.CoverMaxBytes = storageCfg.CoverMaxBytes
+
+	log.Printf("Using %s storage backend", storageCfg.Backend)
+	return store
+}
+
+// setupAccounts setzt die Löschfrist, startet die regelmäßige Ausführung fälliger Löschungen
+// und das gesammelte Speichern von "zuletzt gesehen"
+func setupAccounts(ctx context.Context, db *gorm.DB, store storage.Store) {
+	accountCfg := config.LoadAccountConfig()
+	handlers.LoeschFrist = accountCfg.DeletionGrace
+	go account.RunSweeper(ctx, db, store, accountCfg.DeletionSweepInterval)
+
+	database.Users = database.NewUserSync(accountCfg.UserSyncTTL)
+	go database.Users.RunLastSeenFlusher(ctx, db, accountCfg.LastSeenFlushInterval)
+}
+
+// jobDateien ordnet den Jobtypen, die Dateien im Speicher ablegen, deren Speicherschlüssel zu
+var jobDateien = map[string]jobs.Dateien{
+	export.JobTyp: export.Dateien,
+	backup.JobTyp: backup.Dateien,
+}
+
+// setupJobs registriert die Jobtypen, startet die Worker für Hintergrundjobs und das Löschen
+// abgelaufener Jobs samt Dateien
+func setupJobs(ctx context.Context, db *gorm.DB, jobsCfg *config.JobsConfig, store storage.Store) *jobs.Manager {
+	manager := jobs.NewManager(db, jobsCfg)
+	manager.Register(importer.JobTyp, importer.RunJob)
+	manager.Register(export.JobTyp, export.NewJobHandler(store))
+	manager.Register(backup.JobTyp, backup.NewJobHandler(store, handlers.BackupCoverSaver(store)))
+	manager.Start()
+	go jobs.RunSweeper(ctx, db, store, jobsCfg.Retention, jobsCfg.SweepInterval, jobDateien)
+
+	log.Printf("Started %d job workers", jobsCfg.Workers)
+	return manager
+}
+
+func createRouter(db *gorm.DB, metadataService *metadata.Service, store storage.Store) *gin.Engine {
 	router := gin.Default()
 
 	// CORS configuration (adjust as needed)
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"https://diplodocu.mpech.dev"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", handlers.HaushaltHeader},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
-	// Inject DB into context
+	// Inject DB and services into context
 	router.Use(func(c *gin.Context) {
 		c.Set("db", db)
+		c.Set("metadata", metadataService)
+		c.Set("storage", store)
 		c.Next()
 	})
 
@@ -66,10 +151,29 @@ func createRouter(db *gorm.DB) *gin.Engine {
 
 func setupRoutes(router *gin.Engine) {
 	api := router.Group("/api")
+	// Error messages in the user's language (preference or Accept-Language)
+	api.Use(handlers.SpracheMiddleware())
+
+	// Health check: database and JWKS state
+	api.GET("/health", handlers.Health)
+	// Public routes: cover images are referenced directly from <img> tags. Covers of household
+	// products need a login (Authorization header) or a share token (?freigabe=).
+	api.GET("/produkte/:id/cover/:size", utils.OptionalAuth(utils.AuthMiddleware()),
+		utils.OptionalAuth(handlers.HaushaltMiddleware()), handlers.GetCoverImage)
+	// Public routes: read-only share links for collections
+	api.GET("/public/sammlungen/:token", handlers.GetPublicSammlung)
+
+	// Public routes: local accounts (AUTH_MODE=local)
+	if utils.LocalMode() {
+		api.POST("/auth/register", handlers.Register)
+		api.POST("/auth/login", handlers.Login)
+		api.POST("/auth/refresh", handlers.Refresh)
+		api.POST("/auth/logout", handlers.Logout)
+	}
 
-	// Protected routes group
+	// Protected routes group; catalog deletes additionally require the admin role (ROLE_MAPPING)
 	protected := api.Group("")
-	protected.Use(utils.AuthMiddleware())
+	protected.Use(utils.AuthMiddleware(), handlers.HaushaltMiddleware())
 	{
 		protected.GET("/sync-user", handlers.SyncUser)
 
@@ -78,36 +182,125 @@ func setupRoutes(router *gin.Engine) {
 		protected.GET("/books", handlers.ListBooks)
 		protected.GET("/books/:id", handlers.GetBook)
 		protected.PUT("/books/:id", handlers.UpdateBook)
-		protected.DELETE("/books/:id", handlers.DeleteBook)
+		protected.DELETE("/books/:id", utils.RequireRole(utils.RoleAdmin), handlers.DeleteBook)
 		// Manga routes
 		protected.POST("/mangas", handlers.CreateManga)
 		protected.GET("/mangas", handlers.ListMangas)
 		protected.GET("/mangas/:id", handlers.GetManga)
 		protected.PUT("/mangas/:id", handlers.UpdateManga)
-		protected.DELETE("/mangas/:id", handlers.DeleteManga)
+		protected.DELETE("/mangas/:id", utils.RequireRole(utils.RoleAdmin), handlers.DeleteManga)
 		// Game routes
 		protected.POST("/spiel", handlers.CreateSpiel)
 		protected.GET("/spiel", handlers.ListSpiele)
 		protected.GET("/spiel/:id", handlers.GetSpiel)
 		protected.PUT("/spiel/:id", handlers.UpdateSpiel)
-		protected.DELETE("/spiel/:id", handlers.DeleteSpiel)
+		protected.DELETE("/spiel/:id", utils.RequireRole(utils.RoleAdmin), handlers.DeleteSpiel)
 		// Film/serie routes
 		protected.POST("/filmserie", handlers.CreateFilmserie)
 		protected.GET("/filmserie", handlers.ListFilmserien)
 		protected.GET("/filmserie/:id", handlers.GetFilmserie)
 		protected.PUT("/filmserie/:id", handlers.UpdateFilmserie)
-		protected.DELETE("/filmserie/:id", handlers.DeleteFilmserie)
+		protected.DELETE("/filmserie/:id", utils.RequireRole(utils.RoleAdmin), handlers.DeleteFilmserie)
+		// Season/episode routes
+		protected.GET("/filmserie/:id/staffeln", handlers.ListStaffeln)
+		protected.POST("/filmserie/:id/staffeln", handlers.CreateStaffel)
+		protected.PUT("/filmserie/:id/staffeln/:staffelId", handlers.UpdateStaffel)
+		protected.DELETE("/filmserie/:id/staffeln/:staffelId", utils.RequireRole(utils.RoleAdmin), handlers.DeleteStaffel)
+		protected.POST("/filmserie/:id/staffeln/:staffelId/episoden", handlers.CreateEpisode)
+		protected.PUT("/filmserie/:id/staffeln/:staffelId/gesehen", handlers.MarkStaffelGesehen)
+		protected.GET("/filmserie/:id/fortschritt", handlers.GetSerienFortschritt)
+		protected.PUT("/episoden/:episodeId", handlers.UpdateEpisode)
+		protected.DELETE("/episoden/:episodeId", utils.RequireRole(utils.RoleAdmin), handlers.DeleteEpisode)
+		protected.PUT("/episoden/:episodeId/gesehen", handlers.MarkEpisodeGesehen)
+		protected.DELETE("/episoden/:episodeId/gesehen", handlers.UnmarkEpisodeGesehen)
+		// Edition routes
+		protected.GET("/produkte/:id/editionen", handlers.ListEditionen)
+		protected.POST("/produkte/:id/editionen", handlers.CreateEdition)
+		protected.PUT("/editionen/:editionId", handlers.UpdateEdition)
+		protected.DELETE("/editionen/:editionId", utils.RequireRole(utils.RoleAdmin), handlers.DeleteEdition)
+		// Identifier (ISBN/EAN) routes
+		protected.GET("/produkte/by-code/:code", handlers.GetProduktByCode)
+		protected.GET("/produkte/:id/codes", handlers.ListProduktCodes)
+		protected.POST("/produkte/:id/codes", handlers.CreateProduktCode)
+		protected.DELETE("/produkte/:id/codes/:codeId", utils.RequireRole(utils.RoleAdmin), handlers.DeleteProduktCode)
+		// Cover routes
+		protected.PUT("/produkte/:id/cover", handlers.UploadCover)
+		protected.DELETE("/produkte/:id/cover", utils.RequireRole(utils.RoleAdmin), handlers.DeleteCover)
+		// Metadata routes
+		protected.GET("/metadata/lookup", handlers.LookupMetadata)
+
+		// Import routes
+		protected.POST("/import/goodreads", handlers.ImportGoodreads)
+		protected.POST("/import/calibre", handlers.ImportCalibre)
+
+		// Backup routes
+		protected.GET("/backup", handlers.ExportBackup)
+		protected.POST("/backup/restore", handlers.RestoreBackup)
+
+		// Profile and preferences
+		protected.GET("/me", handlers.GetProfil)
+		protected.PUT("/me", handlers.UpdateProfil)
+		// Account routes: data export and self-service deletion
+		protected.GET("/me/data", handlers.ExportMyData)
+		protected.GET("/me/deletion", handlers.GetDeletion)
+		protected.POST("/me/deletion", handlers.RequestDeletion)
+		protected.DELETE("/me/deletion", handlers.CancelDeletion)
+		// Personal access tokens for scripts; they cannot manage tokens themselves
+		protected.GET("/me/tokens", handlers.ListZugangstokens)
+		protected.POST("/me/tokens", handlers.CreateZugangstoken)
+		protected.DELETE("/me/tokens/:tokenId", handlers.RevokeZugangstoken)
+		// Local account routes: password change, registration invitations
+		if utils.LocalMode() {
+			protected.PUT("/me/passwort", handlers.ChangePassword)
+			protected.POST("/auth/einladungen", utils.RequireRole(utils.RoleAdmin), handlers.CreateRegistrierungseinladung)
+		}
+
+		// Background job routes
+		protected.GET("/jobs", handlers.ListJobs)
+		protected.GET("/jobs/:id", handlers.GetJob)
+		protected.GET("/jobs/:id/download", handlers.DownloadJobExport)
+		protected.POST("/jobs/:id/cancel", handlers.CancelJob)
+		protected.POST("/jobs/:id/retry", handlers.RetryJob)
+
+		// Household routes: members, kid profiles
+		protected.POST("/haushalte", handlers.CreateHaushalt)
+		protected.GET("/haushalte", handlers.ListHaushalte)
+		protected.GET("/haushalte/:id", handlers.GetHaushalt)
+		protected.POST("/haushalte/:id/mitglieder", handlers.AddHaushaltMitglied)
+		protected.DELETE("/haushalte/:id/mitglieder/:webuserId", handlers.RemoveHaushaltMitglied)
+		protected.POST("/haushalte/:id/kinder", handlers.CreateKinderprofil)
+		protected.DELETE("/haushalte/:id/kinder/:kindId", handlers.DeleteKinderprofil)
 
 		// Collection routes
 		protected.POST("/sammlungen", handlers.CreateSammlung)
 		protected.GET("/sammlungen", handlers.ListUserSammlungen)
+		protected.GET("/sammlungen/export", handlers.ExportSammlungen)
 		protected.GET("/sammlungen/:id", handlers.GetSammlungDetail)
 		protected.DELETE("/sammlungen/:id", handlers.DeleteSammlung)
+		protected.GET("/sammlungen/:id/export", handlers.ExportSammlung)
+		protected.GET("/sammlungen/:id/freigaben", handlers.ListFreigaben)
+		protected.POST("/sammlungen/:id/freigaben", handlers.CreateFreigabe)
+		protected.DELETE("/sammlungen/:id/freigaben/:freigabeId", handlers.DeleteFreigabe)
+		// Collection member routes
+		protected.GET("/sammlungen/:id/mitglieder", handlers.ListMitglieder)
+		protected.POST("/sammlungen/:id/mitglieder", handlers.InviteMitglied)
+		protected.PUT("/sammlungen/:id/mitglieder/:webuserId", handlers.UpdateMitglied)
+		protected.DELETE("/sammlungen/:id/mitglieder/:webuserId", handlers.RemoveMitglied)
+		protected.POST("/sammlungen/:id/besitzer", handlers.TransferSammlung)
+		protected.GET("/einladungen", handlers.ListEinladungen)
+		protected.POST("/einladungen/:sammlungId", handlers.AcceptEinladung)
+		protected.DELETE("/einladungen/:sammlungId", handlers.DeclineEinladung)
 
 		sammlungDetail := protected.Group("/sammlung/:sammlungId")
 		{
+			sammlungDetail.GET("/produkte", handlers.ListSammlungItems)
 			sammlungDetail.POST("/produkte", handlers.AddProduktToSammlung)
+			sammlungDetail.PUT("/produkte/:produktId/edition", handlers.SetSammlungItemEdition)
 			sammlungDetail.DELETE("/produkte/:produktId", handlers.RemoveProduktFromSammlung)
+			sammlungDetail.POST("/import", handlers.ImportSammlung)
+			sammlungDetail.POST("/import/myanimelist", handlers.ImportMyAnimeList)
+			sammlungDetail.POST("/import/letterboxd", handlers.ImportLetterboxd)
+			sammlungDetail.POST("/import/nfo", handlers.ImportNFO)
 
 		}
 	}
@@ -0,0 +1,188 @@
+// Package account führt beantragte Kontolöschungen aus.
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/backup"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/export"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Delete löscht einen Benutzer mit seinen Sammlungen (inkl. Einträgen, Freigaben und Mitgliedern),
+// seinen Mitgliedschaften in fremden Sammlungen und Haushalten, gesehenen Episoden, Zugangstokens,
+// dem lokalen Konto samt Sitzungen, den Einstellungen, Jobs samt ihrer Dateien und dem Löschantrag.
+// Vom Benutzer angelegte Produkte bleiben im Katalog: sie werden anonymisiert oder an
+// uebertragenAn übertragen (existiert dieser nicht mehr, wird anonymisiert). Haushaltssammlungen
+// bleiben dem Haushalt erhalten, siehe uebergebeHaushalte.
+func Delete(ctx context.Context, db *gorm.DB, store storage.Store, webuserID string, produkte string, uebertragenAn *string) error {
+	// Die Dateien werden erst nach der Transaktion gelöscht, die Schlüssel stehen nur an den Jobs
+	var dateiJobs []models.Job
+	err := db.Where("webuser_id = ? AND typ IN ?", webuserID, []string{export.JobTyp, backup.JobTyp}).Find(&dateiJobs).Error
+	if err != nil {
+		return fmt.Errorf("load jobs with files: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var neuerErsteller *string
+		if produkte == models.ProdukteUebertragen && uebertragenAn != nil {
+			var count int64
+			if err := tx.Model(&models.Webuser{}).Where("id = ?", *uebertragenAn).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				neuerErsteller = uebertragenAn
+			}
+		}
+		err := tx.Model(&models.Produkt{}).Where("erstellt_von = ?", webuserID).Update("erstellt_von", neuerErsteller).Error
+		if err != nil {
+			return fmt.Errorf("reassign products: %w", err)
+		}
+
+		if err := uebergebeHaushalte(tx, webuserID); err != nil {
+			return err
+		}
+
+		// Explizit löschen, da nicht jede Datenbank die Fremdschlüssel kaskadiert (SQLite)
+		sammlungen := tx.Model(&models.Sammlung{}).Select("id").Where("webuser_id = ?", webuserID)
+		steps := []struct {
+			name  string
+			query *gorm.DB
+			model interface{}
+		}{
+			{"collection items", tx.Where("sammlung_id IN (?)", sammlungen), &models.SammlungProdukt{}},
+			{"share links", tx.Where("sammlung_id IN (?)", sammlungen), &models.Freigabe{}},
+			{"memberships", tx.Where("sammlung_id IN (?) OR webuser_id = ?", sammlungen, webuserID), &models.SammlungMitglied{}},
+			{"household memberships", tx.Where("webuser_id = ?", webuserID), &models.HaushaltMitgliedschaft{}},
+			{"access tokens", tx.Where("webuser_id = ?", webuserID), &models.Zugangstoken{}},
+			{"refresh tokens", tx.Where("webuser_id = ?", webuserID), &models.RefreshToken{}},
+			{"registration invitations", tx.Where("erstellt_von = ?", webuserID), &models.Registrierungseinladung{}},
+			{"local account", tx.Where("webuser_id = ?", webuserID), &models.LokalesKonto{}},
+			{"settings", tx.Where("webuser_id = ?", webuserID), &models.Benutzereinstellungen{}},
+			{"collections", tx.Where("webuser_id = ?", webuserID), &models.Sammlung{}},
+			{"watched episodes", tx.Where("webuser_id = ?", webuserID), &models.EpisodeGesehen{}},
+			{"jobs", tx.Where("webuser_id = ?", webuserID), &models.Job{}},
+			{"deletion request", tx.Where("webuser_id = ?", webuserID), &models.Loeschantrag{}},
+			{"user", tx.Where("id = ?", webuserID), &models.Webuser{}},
+		}
+		for _, step := range steps {
+			if err := step.query.Delete(step.model).Error; err != nil {
+				return fmt.Errorf("delete %s: %w", step.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Ein erneuter Request des Benutzers soll ihn nicht aus dem Cache heraus als bekannt ansehen
+	database.Users.Invalidate(webuserID)
+
+	// Das Konto ist gelöscht; eine verbliebene Datei ist kein Grund, die Löschung zu wiederholen
+	for _, job := range dateiJobs {
+		for _, key := range append(export.Dateien(job), backup.Dateien(job)...) {
+			if err := store.Delete(ctx, key); err != nil {
+				log.Printf("ERROR Delete file %s of deleted user %s: %v\n", key, webuserID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// uebergebeHaushalte regelt die Haushalte des Benutzers vor seiner Löschung: War er der letzte
+// Verwalter, wird ein anderes Mitglied Verwalter. Seine Haushaltssammlungen gehen an einen
+// Verwalter des Haushalts; gibt es kein anderes Mitglied mehr, werden sie mit dem Konto gelöscht.
+func uebergebeHaushalte(tx *gorm.DB, webuserID string) error {
+	var mitgliedschaften []models.HaushaltMitgliedschaft
+	if err := tx.Where("webuser_id = ?", webuserID).Find(&mitgliedschaften).Error; err != nil {
+		return fmt.Errorf("load household memberships: %w", err)
+	}
+	for _, mitgliedschaft := range mitgliedschaften {
+		haushaltID := mitgliedschaft.HaushaltID
+		var andere []models.HaushaltMitgliedschaft
+		err := tx.Where("haushalt_id = ? AND webuser_id <> ?", haushaltID, webuserID).Order("webuser_id").Find(&andere).Error
+		if err != nil {
+			return fmt.Errorf("load members of household %d: %w", haushaltID, err)
+		}
+		if len(andere) == 0 {
+			continue
+		}
+		// Bevorzugt ein Verwalter, sonst das erste Mitglied nach Benutzer-ID
+		nachfolger := andere[0]
+		for _, mitglied := range andere {
+			if mitglied.Rolle == models.HaushaltVerwalter {
+				nachfolger = mitglied
+				break
+			}
+		}
+		if nachfolger.Rolle != models.HaushaltVerwalter && mitgliedschaft.Rolle == models.HaushaltVerwalter {
+			err := tx.Model(&models.HaushaltMitgliedschaft{}).
+				Where("haushalt_id = ? AND webuser_id = ?", haushaltID, nachfolger.WebuserID).
+				Update("rolle", models.HaushaltVerwalter).Error
+			if err != nil {
+				return fmt.Errorf("promote administrator of household %d: %w", haushaltID, err)
+			}
+		}
+
+		sammlungen := tx.Model(&models.Sammlung{}).Select("id").Where("webuser_id = ? AND haushalt_id = ?", webuserID, haushaltID)
+		// Der neue Besitzer braucht keine Mitgliedschaft mehr in seinen Sammlungen
+		err = tx.Where("webuser_id = ? AND sammlung_id IN (?)", nachfolger.WebuserID, sammlungen).Delete(&models.SammlungMitglied{}).Error
+		if err != nil {
+			return fmt.Errorf("transfer collections of household %d: %w", haushaltID, err)
+		}
+		err = tx.Model(&models.Sammlung{}).Where("webuser_id = ? AND haushalt_id = ?", webuserID, haushaltID).
+			Update("webuser_id", nachfolger.WebuserID).Error
+		if err != nil {
+			return fmt.Errorf("transfer collections of household %d: %w", haushaltID, err)
+		}
+	}
+	return nil
+}
+
+// Sweep führt alle Löschanträge aus, deren Frist abgelaufen ist, und liefert deren Anzahl.
+// Ein fehlgeschlagener Antrag hält die übrigen nicht auf.
+func Sweep(ctx context.Context, db *gorm.DB, store storage.Store, now time.Time) (int, error) {
+	var antraege []models.Loeschantrag
+	if err := db.Where("loeschen_ab <= ?", now).Order("loeschen_ab").Find(&antraege).Error; err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	deleted := 0
+	for _, antrag := range antraege {
+		if err := Delete(ctx, db, store, antrag.WebuserID, antrag.Produkte, antrag.UebertragenAn); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", antrag.WebuserID, err))
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// RunSweeper führt Sweep im angegebenen Intervall aus, bis ctx beendet wird
+func RunSweeper(ctx context.Context, db *gorm.DB, store storage.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := Sweep(ctx, db, store, time.Now())
+		if err != nil {
+			log.Printf("ERROR Account deletion sweep: %v\n", err)
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d account(s) after the grace period", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/export"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAccountTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Sammlung{}, &models.SammlungProdukt{},
+		&models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}, &models.Loeschantrag{}, &models.Freigabe{}, &models.SammlungMitglied{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}, &models.Kinderprofil{}, &models.Job{}, &models.Zugangstoken{}, &models.LokalesKonto{}, &models.RefreshToken{}, &models.Registrierungseinladung{}, &models.Benutzereinstellungen{}))
+	return db
+}
+
+func setupAccountTestStore(t *testing.T) storage.Store {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	return store
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}, query string, args ...interface{}) int64 {
+	var n int64
+	require.NoError(t, db.Model(model).Where(query, args...).Count(&n).Error)
+	return n
+}
+
+func TestSweepDeletesDueAccounts(t *testing.T) {
+	db := setupAccountTestDB(t)
+	alice, bob, carol := "alice", "bob", "carol"
+	for _, id := range []string{alice, bob, carol} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id}).Error)
+	}
+
+	eigenes := models.Produkt{Name: "Eigenbau", Art: "Spiel", ErstelltVon: &alice}
+	require.NoError(t, db.Create(&eigenes).Error)
+	bobsProdukt := models.Produkt{Name: "Bobs Buch", Art: "Buch", ErstelltVon: &bob}
+	require.NoError(t, db.Create(&bobsProdukt).Error)
+	sammlung := models.Sammlung{WebuserID: alice}
+	require.NoError(t, db.Create(&sammlung).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: eigenes.ID}).Error)
+	require.NoError(t, db.Create(&models.EpisodeGesehen{WebuserID: alice, EpisodeID: 1, GesehenAm: time.Now()}).Error)
+	require.NoError(t, db.Omit("Webuser").Create(&models.Zugangstoken{WebuserID: alice, Name: "CLI", Anfang: "dpat_a", Hash: "a",
+		Scopes: models.ScopeVoll, ErstelltAm: time.Now()}).Error)
+
+	ctx := context.Background()
+	store := setupAccountTestStore(t)
+	exportJob, err := jobs.Enqueue(db, alice, export.JobTyp, export.JobPayload{WebuserID: alice, Format: "csv", Dateiname: "sammlung"}, 1)
+	require.NoError(t, err)
+	datei := export.JobDatei(exportJob.ID, export.Formats["csv"])
+	require.NoError(t, store.Put(ctx, datei, strings.NewReader("name\n"), 5, "text/csv"))
+
+	now := time.Now()
+	require.NoError(t, db.Create(&models.Loeschantrag{WebuserID: alice, BeantragtAm: now.Add(-15 * 24 * time.Hour),
+		LoeschenAb: now.Add(-time.Hour), Produkte: models.ProdukteUebertragen, UebertragenAn: &carol}).Error)
+	require.NoError(t, db.Create(&models.Loeschantrag{WebuserID: bob, BeantragtAm: now,
+		LoeschenAb: now.Add(time.Hour), Produkte: models.ProdukteAnonymisieren}).Error)
+
+	deleted, err := Sweep(ctx, db, store, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	assert.Zero(t, count(t, db, &models.Webuser{}, "id = ?", alice))
+	assert.Zero(t, count(t, db, &models.Sammlung{}, "webuser_id = ?", alice))
+	assert.Zero(t, count(t, db, &models.SammlungProdukt{}, "sammlung_id = ?", sammlung.ID))
+	assert.Zero(t, count(t, db, &models.EpisodeGesehen{}, "webuser_id = ?", alice))
+	assert.Zero(t, count(t, db, &models.Zugangstoken{}, "webuser_id = ?", alice))
+	assert.Zero(t, count(t, db, &models.Loeschantrag{}, "webuser_id = ?", alice))
+	assert.Zero(t, count(t, db, &models.Job{}, "webuser_id = ?", alice))
+	_, _, err = store.Get(ctx, datei)
+	assert.True(t, errors.Is(err, storage.ErrNotFound), "export files are deleted with the account")
+	assert.Equal(t, int64(1), count(t, db, &models.Produkt{}, "id = ? AND erstellt_von = ?", eigenes.ID, carol),
+		"products are transferred, not deleted")
+
+	// Bob's grace period is still running
+	assert.Equal(t, int64(1), count(t, db, &models.Webuser{}, "id = ?", bob))
+
+	deleted, err = Sweep(ctx, db, store, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, int64(1), count(t, db, &models.Produkt{}, "id = ? AND erstellt_von IS NULL", bobsProdukt.ID))
+}
+
+func TestDeleteFallsBackToAnonymize(t *testing.T) {
+	db := setupAccountTestDB(t)
+	alice, gone := "alice", "gone"
+	require.NoError(t, db.Create(&models.Webuser{ID: alice}).Error)
+	produkt := models.Produkt{Name: "Eigenbau", Art: "Spiel", ErstelltVon: &alice}
+	require.NoError(t, db.Create(&produkt).Error)
+
+	require.NoError(t, Delete(context.Background(), db, setupAccountTestStore(t), alice, models.ProdukteUebertragen, &gone))
+	assert.Equal(t, int64(1), count(t, db, &models.Produkt{}, "id = ? AND erstellt_von IS NULL", produkt.ID))
+}
+
+func TestDeleteKeepsHouseholdCollections(t *testing.T) {
+	db := setupAccountTestDB(t)
+	for _, id := range []string{"anna", "ben", "clara"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id}).Error)
+	}
+	familie := models.Haushalt{Name: "Familie", ErstelltAm: time.Now()}
+	require.NoError(t, db.Create(&familie).Error)
+	allein := models.Haushalt{Name: "Ferienhaus", ErstelltAm: time.Now()}
+	require.NoError(t, db.Create(&allein).Error)
+	require.NoError(t, db.Omit("Haushalt", "Webuser").Create(&[]models.HaushaltMitgliedschaft{
+		{HaushaltID: familie.ID, WebuserID: "anna", Rolle: models.HaushaltVerwalter},
+		{HaushaltID: familie.ID, WebuserID: "ben", Rolle: models.HaushaltMitglied},
+		{HaushaltID: familie.ID, WebuserID: "clara", Rolle: models.HaushaltMitglied},
+		{HaushaltID: allein.ID, WebuserID: "anna", Rolle: models.HaushaltVerwalter},
+	}).Error)
+
+	bibliothek := models.Sammlung{WebuserID: "anna", Name: strPtr("Familienbibliothek"), HaushaltID: &familie.ID}
+	require.NoError(t, db.Create(&bibliothek).Error)
+	now := time.Now()
+	require.NoError(t, db.Omit("Sammlung", "Webuser").Create(&models.SammlungMitglied{
+		SammlungID: bibliothek.ID, WebuserID: "ben", Rolle: models.RolleBetrachter, EingeladenAm: now, AngenommenAm: &now,
+	}).Error)
+	ferien := models.Sammlung{WebuserID: "anna", HaushaltID: &allein.ID}
+	require.NoError(t, db.Create(&ferien).Error)
+	privat := models.Sammlung{WebuserID: "anna"}
+	require.NoError(t, db.Create(&privat).Error)
+
+	require.NoError(t, Delete(context.Background(), db, setupAccountTestStore(t), "anna", models.ProdukteAnonymisieren, nil))
+
+	// The last administrator is replaced and the household keeps its collection
+	assert.Equal(t, int64(1), count(t, db, &models.HaushaltMitgliedschaft{}, "haushalt_id = ? AND webuser_id = ? AND rolle = ?", familie.ID, "ben", models.HaushaltVerwalter))
+	assert.Equal(t, int64(1), count(t, db, &models.HaushaltMitgliedschaft{}, "haushalt_id = ? AND webuser_id = ? AND rolle = ?", familie.ID, "clara", models.HaushaltMitglied))
+	assert.Equal(t, int64(1), count(t, db, &models.Sammlung{}, "id = ? AND webuser_id = ?", bibliothek.ID, "ben"))
+	assert.Zero(t, count(t, db, &models.SammlungMitglied{}, "sammlung_id = ?", bibliothek.ID))
+
+	// Without other members, household and private collections are deleted with the account
+	assert.Zero(t, count(t, db, &models.Sammlung{}, "id IN ?", []uint{ferien.ID, privat.ID}))
+	assert.Zero(t, count(t, db, &models.HaushaltMitgliedschaft{}, "webuser_id = ?", "anna"))
+}
+
+func strPtr(s string) *string { return &s }
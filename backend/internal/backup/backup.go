@@ -0,0 +1,223 @@
+// Package backup erstellt und liest vollständige Sicherungen eines Benutzerkontos.
+// Eine Sicherung ist ein Zip-Archiv mit backup.json (versioniert) und den Cover-Bildern.
+// IDs im Archiv sind die IDs der Quellinstanz und dienen nur als Referenzen innerhalb
+// der Sicherung; beim Wiederherstellen werden sie auf die Zielinstanz abgebildet.
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+const (
+	// Version des Archivformats. Ältere Versionen müssen beim Wiederherstellen lesbar bleiben.
+	Version = 1
+	// MaxBytes begrenzt die Größe einer hochgeladenen Sicherung
+	MaxBytes = 128 << 20
+	// manifestName ist der Name der JSON-Datei im Archiv
+	manifestName = "backup.json"
+)
+
+var (
+	ErrInvalidArchive     = errors.New("not a backup archive (backup.json missing)")
+	ErrUnsupportedVersion = fmt.Errorf("unsupported backup version, this instance reads up to version %d", Version)
+)
+
+// Archive ist der Inhalt von backup.json
+type Archive struct {
+	Version         int              `json:"version"`
+	ErstelltAm      time.Time        `json:"erstelltAm"`
+	Webuser         Webuser          `json:"webuser"`
+	Produkte        []Produkt        `json:"produkte"`
+	Sammlungen      []Sammlung       `json:"sammlungen"`
+	EpisodenGesehen []EpisodeGesehen `json:"episodenGesehen"`
+}
+
+type Webuser struct {
+	ID   string  `json:"id"`
+	Name *string `json:"name,omitempty"`
+}
+
+// Produkt enthält das Basisprodukt mit allen Details, Kennungen, Ausgaben und Staffeln
+type Produkt struct {
+	Ref      uint    `json:"ref"`
+	Art      string  `json:"art"`
+	Name     string  `json:"name"`
+	Nummer   *int    `json:"nummer,omitempty"`
+	Eigenes  bool    `json:"eigenes"`            // Vom Benutzer angelegt
+	Haushalt bool    `json:"haushalt,omitempty"` // Nur im Haushalt sichtbar, nicht im globalen Katalog
+	Autor    *string `json:"autor,omitempty"`
+	Mangaka  *string `json:"mangaka,omitempty"`
+	Baende   *int    `json:"baende,omitempty"`
+	Konsole  *string `json:"konsole,omitempty"`
+	FilmArt  *string `json:"filmArt,omitempty"`
+	Jahr     *int    `json:"jahr,omitempty"`
+	Sprache  *string `json:"sprache,omitempty"`
+	Genre    *string `json:"genre,omitempty"`
+
+	Codes     []Code    `json:"codes,omitempty"`
+	Editionen []Edition `json:"editionen,omitempty"`
+	Staffeln  []Staffel `json:"staffeln,omitempty"`
+	Cover     *Cover    `json:"cover,omitempty"`
+}
+
+type Code struct {
+	Typ  string `json:"typ"`
+	Code string `json:"code"`
+}
+
+type Edition struct {
+	Ref               uint       `json:"ref"`
+	Format            string     `json:"format"`
+	Verlag            *string    `json:"verlag,omitempty"`
+	Erscheinungsdatum *time.Time `json:"erscheinungsdatum,omitempty"`
+	Code              *string    `json:"code,omitempty"`
+	Sprache           *string    `json:"sprache,omitempty"`
+}
+
+type Staffel struct {
+	Nummer   int       `json:"nummer"`
+	Titel    *string   `json:"titel,omitempty"`
+	Episoden []Episode `json:"episoden,omitempty"`
+}
+
+type Episode struct {
+	Nummer       int        `json:"nummer"`
+	Titel        *string    `json:"titel,omitempty"`
+	Laufzeit     *int       `json:"laufzeit,omitempty"`
+	Ausstrahlung *time.Time `json:"ausstrahlung,omitempty"`
+}
+
+// Cover verweist auf das Originalbild im Archiv
+type Cover struct {
+	Datei       string `json:"datei"`
+	ContentType string `json:"contentType"`
+	Hash        string `json:"hash"`
+}
+
+type Sammlung struct {
+	Ref       uint      `json:"ref"`
+	Name      *string   `json:"name,omitempty"`
+	Haushalt  bool      `json:"haushalt,omitempty"` // Dem Haushalt zugeordnet
+	Eintraege []Eintrag `json:"eintraege"`
+}
+
+// Eintrag ist ein Produkt in einer Sammlung mit den persönlichen Angaben
+type Eintrag struct {
+	Produkt           uint       `json:"produkt"`
+	Edition           *uint      `json:"edition,omitempty"`
+	Status            *string    `json:"status,omitempty"`
+	Bewertung         *int       `json:"bewertung,omitempty"`
+	BewertungOriginal *int       `json:"bewertungOriginal,omitempty"`
+	AbgeschlossenAm   *time.Time `json:"abgeschlossenAm,omitempty"`
+}
+
+// EpisodeGesehen referenziert die Episode über Produkt, Staffel- und Episodennummer
+type EpisodeGesehen struct {
+	Produkt   uint      `json:"produkt"`
+	Staffel   int       `json:"staffel"`
+	Episode   int       `json:"episode"`
+	GesehenAm time.Time `json:"gesehenAm"`
+}
+
+// CoverReader öffnet das Originalbild eines Produkts im Storage
+type CoverReader func(ctx context.Context, produktID uint, contentType string) (io.ReadCloser, error)
+
+// Write schreibt das Archiv als Zip. Ist readCover gesetzt, werden die Cover-Bilder mitgesichert;
+// Bilder, die nicht gelesen werden können, fehlen in der Sicherung.
+func Write(ctx context.Context, w io.Writer, archive *Archive, readCover CoverReader) error {
+	zw := zip.NewWriter(w)
+
+	// Cover zuerst, damit backup.json nur tatsächlich enthaltene Bilder referenziert
+	for i := range archive.Produkte {
+		produkt := &archive.Produkte[i]
+		if produkt.Cover == nil {
+			continue
+		}
+		if readCover == nil || !writeCover(ctx, zw, produkt, readCover) {
+			produkt.Cover = nil
+		}
+	}
+
+	part, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(part)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeCover(ctx context.Context, zw *zip.Writer, produkt *Produkt, readCover CoverReader) bool {
+	reader, err := readCover(ctx, produkt.Ref, produkt.Cover.ContentType)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+
+	// Bilder sind bereits komprimiert und werden nur gespeichert
+	part, err := zw.CreateHeader(&zip.FileHeader{Name: produkt.Cover.Datei, Method: zip.Store})
+	if err != nil {
+		return false
+	}
+	_, err = io.Copy(part, reader)
+	return err == nil
+}
+
+// Read liest ein Archiv und liefert backup.json sowie die enthaltenen Cover-Bilder nach Dateiname
+func Read(data []byte) (*Archive, map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, ErrInvalidArchive
+	}
+
+	var archive *Archive
+	files := make(map[string][]byte)
+	var total int64
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid archive entry %s: %w", entry.Name, err)
+		}
+		// Die Größenangaben im Archiv sind nicht vertrauenswürdig
+		content, err := io.ReadAll(io.LimitReader(rc, 2*MaxBytes-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid archive entry %s: %w", entry.Name, err)
+		}
+		total += int64(len(content))
+		if total > 2*MaxBytes {
+			return nil, nil, fmt.Errorf("unpacked backup must not exceed %d bytes", 2*MaxBytes)
+		}
+
+		if entry.Name == manifestName {
+			archive = &Archive{}
+			if err := json.Unmarshal(content, archive); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", manifestName, err)
+			}
+			continue
+		}
+		files[path.Clean(entry.Name)] = content
+	}
+
+	if archive == nil {
+		return nil, nil, ErrInvalidArchive
+	}
+	if archive.Version < 1 || archive.Version > Version {
+		return nil, nil, ErrUnsupportedVersion
+	}
+	return archive, files, nil
+}
@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBackupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{},
+		&models.Spiel{}, &models.Filmserie{}, &models.Sammlung{}, &models.SammlungProdukt{}, &models.Edition{},
+		&models.ProduktCode{}, &models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}, &models.Cover{}))
+	return db
+}
+
+func strPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+// seedSource legt eine Quellinstanz mit einem Buch, einer Serie und einem eigenen Spiel an
+func seedSource(t *testing.T, db *gorm.DB) {
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice", Name: strPtr("Alice")}).Error)
+
+	// Verschiebt die IDs, damit die Zuordnung in der Zielinstanz geprüft wird
+	require.NoError(t, db.Create(&models.Produkt{Name: "Platzhalter", Art: "Buch"}).Error)
+
+	momo := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&momo).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: momo.ID, Autor: strPtr("Michael Ende")}).Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: momo.ID, Typ: "ISBN", Code: "9780306406157"}).Error)
+	hardcover := models.Edition{ProduktID: momo.ID, Format: "Hardcover", Verlag: strPtr("Thienemann")}
+	require.NoError(t, db.Create(&hardcover).Error)
+	require.NoError(t, db.Create(&models.Cover{ProduktID: momo.ID, ContentType: "image/png", Groesse: 3, Breite: 1, Hoehe: 1,
+		Hash: "abc", AktualisiertAm: time.Now()}).Error)
+
+	serie := models.Produkt{Name: "Mushishi", Art: "Filmserie"}
+	require.NoError(t, db.Create(&serie).Error)
+	require.NoError(t, db.Create(&models.Filmserie{ProdukteID: serie.ID, Art: strPtr("Serie")}).Error)
+	staffel := models.Staffel{FilmserieID: serie.ID, Nummer: 1}
+	require.NoError(t, db.Create(&staffel).Error)
+	episode := models.Episode{StaffelID: staffel.ID, Nummer: 2, Titel: strPtr("The Light of the Eyelid")}
+	require.NoError(t, db.Create(&episode).Error)
+	gesehenAm := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create(&models.EpisodeGesehen{WebuserID: "alice", EpisodeID: episode.ID, GesehenAm: gesehenAm}).Error)
+
+	spiel := models.Produkt{Name: "Eigenbau", Art: "Spiel", ErstelltVon: strPtr("alice")}
+	require.NoError(t, db.Create(&spiel).Error)
+	require.NoError(t, db.Create(&models.Spiel{ProdukteID: spiel.ID, Konsole: strPtr("PC")}).Error)
+
+	regal := models.Sammlung{WebuserID: "alice", Name: strPtr("Regal")}
+	require.NoError(t, db.Create(&regal).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: regal.ID, ProduktID: momo.ID, EditionID: &hardcover.ID,
+		Status: strPtr(models.StatusAbgeschlossen), Bewertung: intPtr(5)}).Error)
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	source := setupBackupTestDB(t)
+	seedSource(t, source)
+
+	archive, err := Create(source, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, Version, archive.Version)
+	require.Len(t, archive.Produkte, 3, "collection items, watched series and own products are included")
+	assert.True(t, archive.Produkte[2].Eigenes)
+	require.Len(t, archive.EpisodenGesehen, 1)
+
+	var buf bytes.Buffer
+	readCover := func(ctx context.Context, produktID uint, contentType string) (io.ReadCloser, error) {
+		assert.Equal(t, "image/png", contentType)
+		return io.NopCloser(bytes.NewReader([]byte("png"))), nil
+	}
+	require.NoError(t, Write(context.Background(), &buf, archive, readCover))
+
+	read, files, err := Read(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png"), files[read.Produkte[0].Cover.Datei])
+
+	// Zielinstanz: Momo existiert bereits unter anderer ID, ebenso eine Sammlung "Regal"
+	target := setupBackupTestDB(t)
+	require.NoError(t, target.Create(&models.Webuser{ID: "alice2"}).Error)
+	vorhanden := models.Produkt{Name: "Momo (Taschenbuch)", Art: "Buch"}
+	require.NoError(t, target.Create(&vorhanden).Error)
+	require.NoError(t, target.Create(&models.ProduktCode{ProduktID: vorhanden.ID, Typ: "ISBN", Code: "9780306406157"}).Error)
+	require.NoError(t, target.Create(&models.Sammlung{WebuserID: "alice2", Name: strPtr("Regal")}).Error)
+
+	savedCovers := map[uint][]byte{}
+	opts := RestoreOptions{SaveCover: func(produktID uint, data []byte) error {
+		savedCovers[produktID] = data
+		return nil
+	}}
+
+	dry := opts
+	dry.DryRun = true
+	result, err := Restore(target, "alice2", read, files, dry)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Produkte.Created)
+	var count int64
+	target.Model(&models.Produkt{}).Count(&count)
+	assert.Equal(t, int64(1), count, "dry run must not change anything")
+	assert.Empty(t, savedCovers)
+
+	result, err = Restore(target, "alice2", read, files, opts)
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Created: 2, Matched: 1}, result.Produkte)
+	assert.Equal(t, Counts{Matched: 1}, result.Sammlungen)
+	assert.Equal(t, Counts{Created: 1}, result.Eintraege)
+	assert.Equal(t, Counts{Created: 1}, result.EpisodenGesehen)
+	assert.Equal(t, 1, result.Cover)
+	assert.Equal(t, []byte("png"), savedCovers[vorhanden.ID])
+
+	var eintrag models.SammlungProdukt
+	require.NoError(t, target.Where("produkt_id = ?", vorhanden.ID).First(&eintrag).Error)
+	assert.Equal(t, 5, *eintrag.Bewertung)
+	require.NotNil(t, eintrag.EditionID)
+	var edition models.Edition
+	require.NoError(t, target.First(&edition, *eintrag.EditionID).Error)
+	assert.Equal(t, vorhanden.ID, edition.ProduktID)
+	assert.Equal(t, "Thienemann", *edition.Verlag)
+
+	var spiel models.Produkt
+	require.NoError(t, target.Where("name = ?", "Eigenbau").First(&spiel).Error)
+	assert.Equal(t, "alice2", *spiel.ErstelltVon)
+
+	// Erneutes Einspielen ändert nichts, mit overwrite werden persönliche Angaben übernommen
+	require.NoError(t, target.Model(&models.SammlungProdukt{}).Where("produkt_id = ?", vorhanden.ID).Update("bewertung", 2).Error)
+	result, err = Restore(target, "alice2", read, files, RestoreOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Matched: 3}, result.Produkte)
+	assert.Equal(t, Counts{Skipped: 1}, result.Eintraege)
+	assert.Equal(t, Counts{Skipped: 1}, result.EpisodenGesehen)
+
+	result, err = Restore(target, "alice2", read, files, RestoreOptions{Conflict: ConflictOverwrite})
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Updated: 1}, result.Eintraege)
+	require.NoError(t, target.Where("produkt_id = ?", vorhanden.ID).First(&eintrag).Error)
+	assert.Equal(t, 5, *eintrag.Bewertung)
+
+	target.Model(&models.Episode{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRestoreValidatesEditionsAndFilmArt(t *testing.T) {
+	db := setupBackupTestDB(t)
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+
+	archive := &Archive{Version: Version, Produkte: []Produkt{
+		{Ref: 1, Art: "Buch", Name: "Momo", Editionen: []Edition{
+			{Ref: 1, Format: "hardcover", Code: strPtr("0-306-40615-2")},
+			{Ref: 2, Format: "DVD"},
+			{Ref: 3, Format: "Paperback", Code: strPtr("1234")},
+		}},
+		{Ref: 2, Art: "Filmserie", Name: "Alien", FilmArt: strPtr("Kinofilm")},
+	}}
+	result, err := Restore(db, "alice", archive, nil, RestoreOptions{})
+	require.NoError(t, err)
+	assert.Len(t, result.Warnings, 3)
+
+	var editionen []models.Edition
+	require.NoError(t, db.Order("id").Find(&editionen).Error)
+	require.Len(t, editionen, 2, "the DVD edition of a book is skipped")
+	assert.Equal(t, "Hardcover", editionen[0].Format)
+	assert.Equal(t, "9780306406157", *editionen[0].Code)
+	assert.Equal(t, "Paperback", editionen[1].Format)
+	assert.Nil(t, editionen[1].Code, "invalid codes are dropped")
+
+	var film models.Filmserie
+	require.NoError(t, db.First(&film).Error)
+	assert.Nil(t, film.Art)
+}
+
+func TestReadRejectsInvalidArchives(t *testing.T) {
+	_, _, err := Read([]byte("kein zip"))
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	var buf bytes.Buffer
+	archive := &Archive{Version: Version + 1}
+	require.NoError(t, Write(context.Background(), &buf, archive, nil))
+	_, _, err = Read(buf.Bytes())
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = Restore(setupBackupTestDB(t), "alice", &Archive{Version: Version}, nil, RestoreOptions{Conflict: "merge"})
+	assert.Error(t, err)
+}
+
+func TestRestoreKeepsHouseholdProductsPrivate(t *testing.T) {
+	source := setupBackupTestDB(t)
+	require.NoError(t, source.Create(&models.Webuser{ID: "alice"}).Error)
+	quellHaushalt := uint(7)
+	album := models.Produkt{Name: "Familienalbum", Art: "Buch", HaushaltID: &quellHaushalt}
+	require.NoError(t, source.Create(&album).Error)
+	require.NoError(t, source.Create(&models.Buch{ProdukteID: album.ID}).Error)
+	require.NoError(t, source.Create(&models.ProduktCode{ProduktID: album.ID, HaushaltID: &quellHaushalt, Typ: "ISBN", Code: "9780306406157"}).Error)
+	familie := models.Sammlung{WebuserID: "alice", Name: strPtr("Familie"), HaushaltID: &quellHaushalt}
+	require.NoError(t, source.Create(&familie).Error)
+	require.NoError(t, source.Create(&models.SammlungProdukt{SammlungID: familie.ID, ProduktID: album.ID}).Error)
+
+	archive, err := Create(source, "alice")
+	require.NoError(t, err)
+	require.Len(t, archive.Produkte, 1)
+	assert.True(t, archive.Produkte[0].Haushalt)
+	require.Len(t, archive.Sammlungen, 1)
+	assert.True(t, archive.Sammlungen[0].Haushalt)
+
+	t.Run("without active household", func(t *testing.T) {
+		target := setupBackupTestDB(t)
+		require.NoError(t, target.Create(&models.Webuser{ID: "alice"}).Error)
+
+		result, err := Restore(target, "alice", archive, nil, RestoreOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, Counts{Skipped: 1}, result.Produkte)
+		assert.Equal(t, Counts{Skipped: 1}, result.Eintraege)
+
+		var count int64
+		require.NoError(t, target.Model(&models.Produkt{}).Count(&count).Error)
+		assert.Zero(t, count, "household products are never published to the global catalog")
+		require.NoError(t, target.Model(&models.ProduktCode{}).Count(&count).Error)
+		assert.Zero(t, count)
+
+		var sammlung models.Sammlung
+		require.NoError(t, target.First(&sammlung).Error)
+		assert.Nil(t, sammlung.HaushaltID)
+	})
+
+	t.Run("into active household", func(t *testing.T) {
+		target := setupBackupTestDB(t)
+		require.NoError(t, target.Create(&models.Webuser{ID: "alice"}).Error)
+		zielHaushalt := uint(3)
+
+		result, err := Restore(target, "alice", archive, nil, RestoreOptions{HaushaltID: &zielHaushalt})
+		require.NoError(t, err)
+		assert.Equal(t, Counts{Created: 1}, result.Produkte)
+
+		var produkt models.Produkt
+		require.NoError(t, target.First(&produkt).Error)
+		require.NotNil(t, produkt.HaushaltID)
+		assert.Equal(t, zielHaushalt, *produkt.HaushaltID)
+		var code models.ProduktCode
+		require.NoError(t, target.First(&code).Error)
+		require.NotNil(t, code.HaushaltID)
+		assert.Equal(t, zielHaushalt, *code.HaushaltID)
+
+		var sammlung models.Sammlung
+		require.NoError(t, target.First(&sammlung).Error)
+		require.NotNil(t, sammlung.HaushaltID)
+		assert.Equal(t, zielHaushalt, *sammlung.HaushaltID)
+		var count int64
+		require.NoError(t, target.Model(&models.SammlungProdukt{}).Where("sammlung_id = ? AND produkt_id = ?", sammlung.ID, produkt.ID).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// coverExtensions bestimmt die Dateiendung der Cover-Bilder im Archiv
+var coverExtensions = map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/gif": ".gif"}
+
+// Create sammelt alle Daten eines Benutzers: seine Sammlungen mit Einträgen, die gesehenen
+// Episoden sowie alle darin verwendeten und alle von ihm angelegten Produkte.
+func Create(db *gorm.DB, webuserID string) (*Archive, error) {
+	var webuser models.Webuser
+	if err := db.First(&webuser, "id = ?", webuserID).Error; err != nil {
+		return nil, fmt.Errorf("load webuser: %w", err)
+	}
+
+	archive := &Archive{
+		Version:         Version,
+		ErstelltAm:      time.Now().UTC().Truncate(time.Second),
+		Webuser:         Webuser{ID: webuser.ID, Name: webuser.Name},
+		Produkte:        []Produkt{},
+		Sammlungen:      []Sammlung{},
+		EpisodenGesehen: []EpisodeGesehen{},
+	}
+	produktIDs := make(map[uint]bool)
+
+	var sammlungen []models.Sammlung
+	if err := db.Where("webuser_id = ?", webuserID).Order("id").Find(&sammlungen).Error; err != nil {
+		return nil, fmt.Errorf("load collections: %w", err)
+	}
+	sammlungIDs := make([]uint, len(sammlungen))
+	for i, sammlung := range sammlungen {
+		sammlungIDs[i] = sammlung.ID
+	}
+	var eintraege []models.SammlungProdukt
+	if len(sammlungIDs) > 0 {
+		err := db.Where("sammlung_id IN ?", sammlungIDs).Order("sammlung_id, produkt_id").Find(&eintraege).Error
+		if err != nil {
+			return nil, fmt.Errorf("load collection items: %w", err)
+		}
+	}
+	bySammlung := make(map[uint][]Eintrag)
+	for _, eintrag := range eintraege {
+		produktIDs[eintrag.ProduktID] = true
+		bySammlung[eintrag.SammlungID] = append(bySammlung[eintrag.SammlungID], Eintrag{
+			Produkt:           eintrag.ProduktID,
+			Edition:           eintrag.EditionID,
+			Status:            eintrag.Status,
+			Bewertung:         eintrag.Bewertung,
+			BewertungOriginal: eintrag.BewertungOriginal,
+			AbgeschlossenAm:   eintrag.AbgeschlossenAm,
+		})
+	}
+	for _, sammlung := range sammlungen {
+		items := bySammlung[sammlung.ID]
+		if items == nil {
+			items = []Eintrag{}
+		}
+		archive.Sammlungen = append(archive.Sammlungen, Sammlung{
+			Ref: sammlung.ID, Name: sammlung.Name, Haushalt: sammlung.HaushaltID != nil, Eintraege: items,
+		})
+	}
+
+	var gesehen []struct {
+		ProduktID     uint
+		StaffelNummer int
+		EpisodeNummer int
+		GesehenAm     time.Time
+	}
+	err := db.Table("episode_gesehen").
+		Select("staffel.filmserie_id AS produkt_id, staffel.nummer AS staffel_nummer, episode.nummer AS episode_nummer, episode_gesehen.gesehen_am").
+		Joins("JOIN episode ON episode.id = episode_gesehen.episode_id").
+		Joins("JOIN staffel ON staffel.id = episode.staffel_id").
+		Where("episode_gesehen.webuser_id = ?", webuserID).
+		Order("staffel.filmserie_id, staffel.nummer, episode.nummer").
+		Scan(&gesehen).Error
+	if err != nil {
+		return nil, fmt.Errorf("load watched episodes: %w", err)
+	}
+	for _, g := range gesehen {
+		produktIDs[g.ProduktID] = true
+		archive.EpisodenGesehen = append(archive.EpisodenGesehen, EpisodeGesehen{
+			Produkt: g.ProduktID, Staffel: g.StaffelNummer, Episode: g.EpisodeNummer, GesehenAm: g.GesehenAm,
+		})
+	}
+
+	var eigene []uint
+	if err := db.Model(&models.Produkt{}).Where("erstellt_von = ?", webuserID).Pluck("id", &eigene).Error; err != nil {
+		return nil, fmt.Errorf("load own products: %w", err)
+	}
+	for _, id := range eigene {
+		produktIDs[id] = true
+	}
+
+	ids := make([]uint, 0, len(produktIDs))
+	for id := range produktIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	produkte, err := loadProdukte(db, webuserID, ids)
+	if err != nil {
+		return nil, err
+	}
+	archive.Produkte = produkte
+	return archive, nil
+}
+
+// loadProdukte lädt die Produkte mit allen Details in wenigen Abfragen
+func loadProdukte(db *gorm.DB, webuserID string, ids []uint) ([]Produkt, error) {
+	result := make([]Produkt, 0, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var produkte []models.Produkt
+	if err := db.Where("id IN ?", ids).Order("id").Find(&produkte).Error; err != nil {
+		return nil, fmt.Errorf("load products: %w", err)
+	}
+
+	var buecher []models.Buch
+	var mangas []models.Manga
+	var spiele []models.Spiel
+	var filmserien []models.Filmserie
+	var codes []models.ProduktCode
+	var editionen []models.Edition
+	var staffeln []models.Staffel
+	var covers []models.Cover
+	queries := []struct {
+		name  string
+		query *gorm.DB
+		dest  interface{}
+	}{
+		{"books", db.Where("produkte_id IN ?", ids), &buecher},
+		{"mangas", db.Where("produkte_id IN ?", ids), &mangas},
+		{"games", db.Where("produkte_id IN ?", ids), &spiele},
+		{"films", db.Where("produkte_id IN ?", ids), &filmserien},
+		{"codes", db.Where("produkt_id IN ?", ids).Order("id"), &codes},
+		{"editions", db.Where("produkt_id IN ?", ids).Order("id"), &editionen},
+		{"seasons", db.Preload("Episoden", func(tx *gorm.DB) *gorm.DB { return tx.Order("nummer") }).
+			Where("filmserie_id IN ?", ids).Order("nummer"), &staffeln},
+		{"covers", db.Where("produkt_id IN ?", ids), &covers},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, fmt.Errorf("load %s: %w", q.name, err)
+		}
+	}
+
+	for _, p := range produkte {
+		result = append(result, Produkt{
+			Ref:      p.ID,
+			Art:      p.Art,
+			Name:     p.Name,
+			Nummer:   p.Nummer,
+			Eigenes:  p.ErstelltVon != nil && *p.ErstelltVon == webuserID,
+			Haushalt: p.HaushaltID != nil,
+		})
+	}
+	byID := make(map[uint]*Produkt, len(result))
+	for i := range result {
+		byID[result[i].Ref] = &result[i]
+	}
+
+	for _, b := range buecher {
+		p := byID[b.ProdukteID]
+		p.Autor, p.Sprache, p.Genre = b.Autor, b.Sprache, b.Genre
+	}
+	for _, m := range mangas {
+		p := byID[m.ProdukteID]
+		p.Mangaka, p.Baende, p.Sprache, p.Genre = m.Mangaka, m.Baende, m.Sprache, m.Genre
+	}
+	for _, s := range spiele {
+		p := byID[s.ProdukteID]
+		p.Konsole, p.Genre = s.Konsole, s.Genre
+	}
+	for _, f := range filmserien {
+		p := byID[f.ProdukteID]
+		p.FilmArt, p.Genre, p.Jahr = f.Art, f.Genre, f.Jahr
+	}
+	for _, code := range codes {
+		p := byID[code.ProduktID]
+		p.Codes = append(p.Codes, Code{Typ: code.Typ, Code: code.Code})
+	}
+	for _, e := range editionen {
+		p := byID[e.ProduktID]
+		p.Editionen = append(p.Editionen, Edition{
+			Ref: e.ID, Format: e.Format, Verlag: e.Verlag, Erscheinungsdatum: e.Erscheinungsdatum, Code: e.Code, Sprache: e.Sprache,
+		})
+	}
+	for _, s := range staffeln {
+		p := byID[s.FilmserieID]
+		staffel := Staffel{Nummer: s.Nummer, Titel: s.Titel}
+		for _, e := range s.Episoden {
+			staffel.Episoden = append(staffel.Episoden, Episode{Nummer: e.Nummer, Titel: e.Titel, Laufzeit: e.Laufzeit, Ausstrahlung: e.Ausstrahlung})
+		}
+		p.Staffeln = append(p.Staffeln, staffel)
+	}
+	for _, cover := range covers {
+		p := byID[cover.ProduktID]
+		p.Cover = &Cover{
+			Datei:       fmt.Sprintf("cover/%d%s", cover.ProduktID, coverExtensions[cover.ContentType]),
+			ContentType: cover.ContentType,
+			Hash:        cover.Hash,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// JobTyp ist der Typ der Hintergrundjobs für Wiederherstellungen
+const JobTyp = "backup-restore"
+
+// JobPayload beschreibt die Wiederherstellung. Die hochgeladene Sicherung liegt im Speicher
+// unter JobDatei, da sie für den Payload zu groß sein kann.
+type JobPayload struct {
+	WebuserID  string
+	HaushaltID *uint
+	Conflict   string
+}
+
+// CoverSaver speichert ein Cover-Bild aus der Sicherung, siehe RestoreOptions.SaveCover
+type CoverSaver func(ctx context.Context, db *gorm.DB, produktID uint, data []byte) error
+
+// JobDatei liefert den Speicherschlüssel der hochgeladenen Sicherung eines Jobs
+func JobDatei(jobID uint) string {
+	return fmt.Sprintf("restores/%d.zip", jobID)
+}
+
+// Dateien liefert den Speicherschlüssel der Sicherung eines Jobs; Jobs anderer Typen haben keine
+func Dateien(job models.Job) []string {
+	if job.Typ != JobTyp {
+		return nil
+	}
+	return []string{JobDatei(job.ID)}
+}
+
+// NewJobHandler erstellt den Handler für Wiederherstellungen. Die Sicherung bleibt nach dem
+// Job im Speicher, damit er wiederholt werden kann; sie wird mit dem Job aufgeräumt.
+func NewJobHandler(store storage.Store, saveCover CoverSaver) jobs.Handler {
+	return func(ctx context.Context, run *jobs.Run) (interface{}, error) {
+		var payload JobPayload
+		if err := run.Decode(&payload); err != nil {
+			return nil, err
+		}
+
+		reader, _, err := store.Get(ctx, JobDatei(run.Job.ID))
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, jobs.Permanent(errors.New("uploaded backup is no longer available"))
+			}
+			return nil, err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		archive, files, err := Read(data)
+		if err != nil {
+			return nil, jobs.Permanent(err)
+		}
+
+		db := run.DB.WithContext(ctx)
+		opts := RestoreOptions{Conflict: payload.Conflict, HaushaltID: payload.HaushaltID}
+		if saveCover != nil {
+			opts.SaveCover = func(produktID uint, image []byte) error {
+				return saveCover(ctx, db, produktID, image)
+			}
+		}
+		return Restore(db, payload.WebuserID, archive, files, opts)
+	}
+}
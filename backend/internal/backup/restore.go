@@ -0,0 +1,500 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/importer"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Umgang mit persönlichen Angaben, die in der Zielinstanz bereits existieren
+const (
+	ConflictSkip      = "skip"      // Vorhandene Einträge bleiben unverändert
+	ConflictOverwrite = "overwrite" // Werte aus der Sicherung überschreiben vorhandene Einträge
+)
+
+// RestoreOptions steuern das Wiederherstellen
+type RestoreOptions struct {
+	Conflict string // ConflictSkip (Standard) oder ConflictOverwrite
+	DryRun   bool
+	// HaushaltID ist der aktive Haushalt. Haushaltsprodukte und -sammlungen der Sicherung werden
+	// diesem Haushalt zugeordnet; ohne aktiven Haushalt werden Haushaltsprodukte übersprungen.
+	HaushaltID *uint
+	// SaveCover speichert ein Cover-Bild aus der Sicherung. Wird erst nach erfolgreichem
+	// Abschluss der Transaktion aufgerufen und nur für Produkte ohne vorhandenes Cover.
+	SaveCover func(produktID uint, data []byte) error
+}
+
+// Counts zählt angelegte, zugeordnete bzw. aktualisierte und übersprungene Datensätze
+type Counts struct {
+	Created int `json:"created"`
+	Matched int `json:"matched,omitempty"`
+	Updated int `json:"updated,omitempty"`
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// Result fasst eine Wiederherstellung zusammen
+type Result struct {
+	DryRun          bool     `json:"dryRun"`
+	Produkte        Counts   `json:"products"`
+	Sammlungen      Counts   `json:"collections"`
+	Eintraege       Counts   `json:"items"`
+	EpisodenGesehen Counts   `json:"watchedEpisodes"`
+	Cover           int      `json:"covers"`
+	Warnings        []string `json:"warnings"`
+}
+
+var errDryRun = errors.New("dry run")
+
+// restoreRun hält die Zuordnung der IDs aus der Sicherung zu den IDs der Zielinstanz
+type restoreRun struct {
+	tx        *gorm.DB
+	webuserID string
+	opts      RestoreOptions
+	result    *Result
+	produkte  map[uint]uint // Ref -> Produkt-ID
+	editionen map[uint]uint // Ref -> Edition-ID
+	covers    map[uint][]byte
+}
+
+func (r *restoreRun) warn(format string, args ...interface{}) {
+	r.result.Warnings = append(r.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Restore spielt eine Sicherung für den Benutzer ein. Produkte werden über ihre Kennungen bzw.
+// Art, Name und Nummer vorhandenen Produkten zugeordnet; fehlende Produkte, Ausgaben, Staffeln
+// und Episoden werden angelegt, vorhandene Katalogdaten aber nie verändert. Produkte aus dem
+// Haushalt der Quellinstanz landen nie im globalen Katalog. Sammlungen werden über den Namen
+// zusammengeführt. Alles läuft in einer Transaktion, ein Probelauf rollt zurück.
+func Restore(db *gorm.DB, webuserID string, archive *Archive, files map[string][]byte, opts RestoreOptions) (*Result, error) {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	if opts.Conflict != ConflictSkip && opts.Conflict != ConflictOverwrite {
+		return nil, fmt.Errorf("unknown conflict mode %q", opts.Conflict)
+	}
+
+	result := &Result{DryRun: opts.DryRun, Warnings: []string{}}
+	run := &restoreRun{
+		webuserID: webuserID,
+		opts:      opts,
+		result:    result,
+		produkte:  make(map[uint]uint),
+		editionen: make(map[uint]uint),
+		covers:    make(map[uint][]byte),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		run.tx = tx
+		for _, produkt := range archive.Produkte {
+			if err := run.restoreProdukt(produkt, files); err != nil {
+				return fmt.Errorf("product %d: %w", produkt.Ref, err)
+			}
+		}
+		for _, sammlung := range archive.Sammlungen {
+			if err := run.restoreSammlung(sammlung); err != nil {
+				return fmt.Errorf("collection %d: %w", sammlung.Ref, err)
+			}
+		}
+		for _, gesehen := range archive.EpisodenGesehen {
+			if err := run.restoreGesehen(gesehen); err != nil {
+				return fmt.Errorf("watched episode: %w", err)
+			}
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !opts.DryRun && opts.SaveCover != nil {
+		for produktID, data := range run.covers {
+			if err := opts.SaveCover(produktID, data); err != nil {
+				run.warn("cover of product %d not restored: %v", produktID, err)
+				continue
+			}
+			result.Cover++
+		}
+	}
+	return result, nil
+}
+
+func (r *restoreRun) restoreProdukt(p Produkt, files map[string][]byte) error {
+	tx := r.tx
+	switch p.Art {
+	case "Buch", "Manga", "Spiel", "Filmserie":
+	default:
+		r.warn("product %q with unknown type %q skipped", p.Name, p.Art)
+		return nil
+	}
+	if p.Haushalt && r.opts.HaushaltID == nil {
+		r.warn("household product %q skipped, select a household to restore it", p.Name)
+		r.result.Produkte.Skipped++
+		return nil
+	}
+
+	var codes []string
+	for _, code := range p.Codes {
+		codes = append(codes, code.Code)
+	}
+	for _, edition := range p.Editionen {
+		if edition.Code != nil {
+			codes = append(codes, *edition.Code)
+		}
+	}
+
+	var produkt *models.Produkt
+	for _, code := range codes {
+		found, err := importer.FindByCode(tx, r.opts.HaushaltID, code)
+		if err != nil {
+			return err
+		}
+		if found != nil && found.Art == p.Art {
+			produkt = found
+			break
+		}
+	}
+	if produkt == nil {
+		found, err := importer.FindByName(tx, r.opts.HaushaltID, p.Art, p.Name, p.Nummer, p.Jahr)
+		if err != nil {
+			return err
+		}
+		produkt = found
+	}
+
+	if produkt != nil {
+		r.result.Produkte.Matched++
+	} else {
+		created, err := r.createProdukt(p)
+		if err != nil {
+			return err
+		}
+		produkt = created
+		r.result.Produkte.Created++
+	}
+	r.produkte[p.Ref] = produkt.ID
+
+	if err := r.restoreCodes(produkt, p); err != nil {
+		return err
+	}
+	for _, edition := range p.Editionen {
+		if err := r.restoreEdition(produkt.ID, p, edition); err != nil {
+			return err
+		}
+	}
+	if p.Art == "Filmserie" {
+		for _, staffel := range p.Staffeln {
+			if err := r.restoreStaffel(produkt.ID, staffel); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.Cover != nil {
+		data, ok := files[p.Cover.Datei]
+		if !ok {
+			r.warn("cover file %s of %q missing in archive", p.Cover.Datei, p.Name)
+			return nil
+		}
+		var count int64
+		if err := tx.Model(&models.Cover{}).Where("produkt_id = ?", produkt.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			r.covers[produkt.ID] = data
+		}
+	}
+	return nil
+}
+
+func (r *restoreRun) createProdukt(p Produkt) (*models.Produkt, error) {
+	produkt := models.Produkt{Name: strings.TrimSpace(p.Name), Nummer: p.Nummer, Art: p.Art}
+	if p.Haushalt {
+		produkt.HaushaltID = r.opts.HaushaltID
+	}
+	if p.Eigenes {
+		produkt.ErstelltVon = &r.webuserID
+	}
+	if err := r.tx.Create(&produkt).Error; err != nil {
+		return nil, err
+	}
+
+	var details interface{}
+	switch p.Art {
+	case "Buch":
+		details = &models.Buch{ProdukteID: produkt.ID, Autor: p.Autor, Sprache: p.Sprache, Genre: p.Genre}
+	case "Manga":
+		details = &models.Manga{ProdukteID: produkt.ID, Mangaka: p.Mangaka, Baende: p.Baende, Sprache: p.Sprache, Genre: p.Genre}
+	case "Spiel":
+		details = &models.Spiel{ProdukteID: produkt.ID, Konsole: p.Konsole, Genre: p.Genre}
+	case "Filmserie":
+		filmArt := p.FilmArt
+		if filmArt != nil && !utils.ValidFilmArt(*filmArt) {
+			r.warn("film type %q of %q ignored", *filmArt, p.Name)
+			filmArt = nil
+		}
+		details = &models.Filmserie{ProdukteID: produkt.ID, Art: filmArt, Genre: p.Genre, Jahr: p.Jahr}
+	}
+	if err := r.tx.Create(details).Error; err != nil {
+		return nil, err
+	}
+	return &produkt, nil
+}
+
+// restoreCodes ergänzt fehlende Kennungen. Gehört ein Code im Katalog des Produkts bereits einem
+// anderen Produkt, wird er übersprungen, da Kennungen je Katalog eindeutig sind.
+func (r *restoreRun) restoreCodes(produkt *models.Produkt, p Produkt) error {
+	for _, code := range p.Codes {
+		normalized, typ, err := utils.NormalizeCodeForArt(p.Art, code.Code)
+		if err != nil {
+			r.warn("code %s of %q ignored: %v", code.Code, p.Name, err)
+			continue
+		}
+		query := r.tx.Where("code = ?", normalized)
+		if produkt.HaushaltID != nil {
+			query = query.Where("haushalt_id = ?", *produkt.HaushaltID)
+		} else {
+			query = query.Where("haushalt_id IS NULL")
+		}
+		var existing []models.ProduktCode
+		if err := query.Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			if existing[0].ProduktID != produkt.ID {
+				r.warn("code %s of %q already belongs to product %d", normalized, p.Name, existing[0].ProduktID)
+			}
+			continue
+		}
+		err = r.tx.Create(&models.ProduktCode{ProduktID: produkt.ID, HaushaltID: produkt.HaushaltID, Typ: typ, Code: normalized}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreEdition ordnet Ausgaben über den Code bzw. Format und Verlag zu. Format und Code werden
+// wie beim Anlegen über die API geprüft; ungültige Ausgaben bzw. Codes werden übersprungen.
+func (r *restoreRun) restoreEdition(produktID uint, p Produkt, e Edition) error {
+	format, err := utils.ValidateEditionFormat(p.Art, e.Format)
+	if err != nil {
+		r.warn("edition of %q skipped: %v", p.Name, err)
+		return nil
+	}
+	var code *string
+	if e.Code != nil && *e.Code != "" {
+		normalized, _, err := utils.NormalizeCodeForArt(p.Art, *e.Code)
+		if err != nil {
+			r.warn("code %s of an edition of %q ignored: %v", *e.Code, p.Name, err)
+		} else {
+			code = &normalized
+		}
+	}
+
+	query := r.tx.Where("produkt_id = ?", produktID)
+	if code != nil {
+		query = query.Where("code = ?", *code)
+	} else {
+		query = query.Where("format = ? AND code IS NULL", format)
+		if e.Verlag != nil {
+			query = query.Where("verlag = ?", *e.Verlag)
+		} else {
+			query = query.Where("verlag IS NULL")
+		}
+	}
+	var existing []models.Edition
+	if err := query.Order("id").Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		r.editionen[e.Ref] = existing[0].ID
+		return nil
+	}
+
+	edition := models.Edition{
+		ProduktID:         produktID,
+		Format:            format,
+		Verlag:            e.Verlag,
+		Erscheinungsdatum: e.Erscheinungsdatum,
+		Code:              code,
+		Sprache:           e.Sprache,
+	}
+	if err := r.tx.Create(&edition).Error; err != nil {
+		return err
+	}
+	r.editionen[e.Ref] = edition.ID
+	return nil
+}
+
+func (r *restoreRun) restoreStaffel(produktID uint, s Staffel) error {
+	var staffeln []models.Staffel
+	if err := r.tx.Where("filmserie_id = ? AND nummer = ?", produktID, s.Nummer).Limit(1).Find(&staffeln).Error; err != nil {
+		return err
+	}
+	if len(staffeln) == 0 {
+		staffel := models.Staffel{FilmserieID: produktID, Nummer: s.Nummer, Titel: s.Titel}
+		if err := r.tx.Create(&staffel).Error; err != nil {
+			return err
+		}
+		staffeln = append(staffeln, staffel)
+	}
+	staffelID := staffeln[0].ID
+
+	for _, e := range s.Episoden {
+		var count int64
+		if err := r.tx.Model(&models.Episode{}).Where("staffel_id = ? AND nummer = ?", staffelID, e.Nummer).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		episode := models.Episode{StaffelID: staffelID, Nummer: e.Nummer, Titel: e.Titel, Laufzeit: e.Laufzeit, Ausstrahlung: e.Ausstrahlung}
+		if err := r.tx.Create(&episode).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreSammlung führt benannte Sammlungen mit gleichnamigen zusammen; unbenannte werden neu angelegt.
+// Haushaltssammlungen werden dem aktiven Haushalt zugeordnet, ohne ihn werden sie persönlich.
+func (r *restoreRun) restoreSammlung(s Sammlung) error {
+	var haushaltID *uint
+	if s.Haushalt {
+		if r.opts.HaushaltID == nil {
+			r.warn("household collection %d restored as personal collection", s.Ref)
+		}
+		haushaltID = r.opts.HaushaltID
+	}
+
+	var sammlungen []models.Sammlung
+	if s.Name != nil {
+		query := r.tx.Where("webuser_id = ? AND name = ?", r.webuserID, *s.Name)
+		if haushaltID != nil {
+			query = query.Where("haushalt_id = ?", *haushaltID)
+		} else {
+			query = query.Where("haushalt_id IS NULL")
+		}
+		if err := query.Order("id").Limit(1).Find(&sammlungen).Error; err != nil {
+			return err
+		}
+	}
+	if len(sammlungen) > 0 {
+		r.result.Sammlungen.Matched++
+	} else {
+		sammlung := models.Sammlung{WebuserID: r.webuserID, Name: s.Name, HaushaltID: haushaltID}
+		if err := r.tx.Create(&sammlung).Error; err != nil {
+			return err
+		}
+		sammlungen = append(sammlungen, sammlung)
+		r.result.Sammlungen.Created++
+	}
+	sammlungID := sammlungen[0].ID
+
+	for _, e := range s.Eintraege {
+		produktID, ok := r.produkte[e.Produkt]
+		if !ok {
+			r.warn("collection item references unknown product %d", e.Produkt)
+			r.result.Eintraege.Skipped++
+			continue
+		}
+		var editionID *uint
+		if e.Edition != nil {
+			if id, ok := r.editionen[*e.Edition]; ok {
+				editionID = &id
+			}
+		}
+
+		var existing []models.SammlungProdukt
+		if err := r.tx.Where("sammlung_id = ? AND produkt_id = ?", sammlungID, produktID).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			err := r.tx.Create(&models.SammlungProdukt{
+				SammlungID:        sammlungID,
+				ProduktID:         produktID,
+				EditionID:         editionID,
+				Status:            e.Status,
+				Bewertung:         e.Bewertung,
+				BewertungOriginal: e.BewertungOriginal,
+				AbgeschlossenAm:   e.AbgeschlossenAm,
+			}).Error
+			if err != nil {
+				return err
+			}
+			r.result.Eintraege.Created++
+			continue
+		}
+		if r.opts.Conflict == ConflictSkip {
+			r.result.Eintraege.Skipped++
+			continue
+		}
+		err := r.tx.Model(&models.SammlungProdukt{}).
+			Where("sammlung_id = ? AND produkt_id = ?", sammlungID, produktID).
+			Updates(map[string]interface{}{
+				"edition_id":         editionID,
+				"status":             e.Status,
+				"bewertung":          e.Bewertung,
+				"bewertung_original": e.BewertungOriginal,
+				"abgeschlossen_am":   e.AbgeschlossenAm,
+			}).Error
+		if err != nil {
+			return err
+		}
+		r.result.Eintraege.Updated++
+	}
+	return nil
+}
+
+func (r *restoreRun) restoreGesehen(g EpisodeGesehen) error {
+	produktID, ok := r.produkte[g.Produkt]
+	if !ok {
+		r.warn("watched episode references unknown product %d", g.Produkt)
+		r.result.EpisodenGesehen.Skipped++
+		return nil
+	}
+
+	var episoden []models.Episode
+	err := r.tx.Joins("JOIN staffel ON staffel.id = episode.staffel_id").
+		Where("staffel.filmserie_id = ? AND staffel.nummer = ? AND episode.nummer = ?", produktID, g.Staffel, g.Episode).
+		Limit(1).Find(&episoden).Error
+	if err != nil {
+		return err
+	}
+	if len(episoden) == 0 {
+		r.warn("episode S%02dE%02d of product %d not found", g.Staffel, g.Episode, produktID)
+		r.result.EpisodenGesehen.Skipped++
+		return nil
+	}
+
+	var existing []models.EpisodeGesehen
+	if err := r.tx.Where("webuser_id = ? AND episode_id = ?", r.webuserID, episoden[0].ID).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	switch {
+	case len(existing) == 0:
+		if err := r.tx.Create(&models.EpisodeGesehen{WebuserID: r.webuserID, EpisodeID: episoden[0].ID, GesehenAm: g.GesehenAm}).Error; err != nil {
+			return err
+		}
+		r.result.EpisodenGesehen.Created++
+	case r.opts.Conflict == ConflictOverwrite:
+		err := r.tx.Model(&models.EpisodeGesehen{}).
+			Where("webuser_id = ? AND episode_id = ?", r.webuserID, episoden[0].ID).
+			Update("gesehen_am", g.GesehenAm).Error
+		if err != nil {
+			return err
+		}
+		r.result.EpisodenGesehen.Updated++
+	default:
+		r.result.EpisodenGesehen.Skipped++
+	}
+	return nil
+}
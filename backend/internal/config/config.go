@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type DBConfig struct {
@@ -25,3 +27,172 @@ func LoadDBConfig() *DBConfig {
 		SSLMode:  os.Getenv("DB_SSLMODE"),
 	}
 }
+
+type MetadataConfig struct {
+	Providers       []string // Aktivierte Provider, z.B. openlibrary, anilist, tmdb
+	OpenLibraryURL  string
+	AniListURL      string
+	TMDBURL         string
+	TMDBAPIKey      string        // v3-API-Schlüssel oder v4-Lesezugriffstoken (bevorzugt, wird als Bearer gesendet)
+	Timeout         time.Duration // Timeout pro Provider-Anfrage
+	CacheTTL        time.Duration
+	CacheMaxEntries int
+}
+
+func LoadMetadataConfig() *MetadataConfig {
+	return &MetadataConfig{
+		Providers:       splitList(getEnv("METADATA_PROVIDERS", "openlibrary,anilist,tmdb")),
+		OpenLibraryURL:  getEnv("METADATA_OPENLIBRARY_URL", "https://openlibrary.org"),
+		AniListURL:      getEnv("METADATA_ANILIST_URL", "https://graphql.anilist.co"),
+		TMDBURL:         getEnv("METADATA_TMDB_URL", "https://api.themoviedb.org/3"),
+		TMDBAPIKey:      os.Getenv("METADATA_TMDB_API_KEY"),
+		Timeout:         getDuration("METADATA_TIMEOUT", 5*time.Second),
+		CacheTTL:        getDuration("METADATA_CACHE_TTL", time.Hour),
+		CacheMaxEntries: getInt("METADATA_CACHE_MAX_ENTRIES", 1000),
+	}
+}
+
+type StorageConfig struct {
+	Backend       string // "local" oder "s3"
+	LocalDir      string
+	S3Endpoint    string // z.B. https://s3.eu-central-1.amazonaws.com oder http://minio:9000
+	S3Bucket      string
+	S3Region      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PathStyle   bool  // Pfad-Adressierung (http://host/bucket/key), nötig für MinIO
+	CoverMaxBytes int64 // Maximale Größe eines hochgeladenen Cover-Bildes
+}
+
+func LoadStorageConfig() *StorageConfig {
+	pathStyle, err := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+	if err != nil {
+		pathStyle = true
+	}
+	return &StorageConfig{
+		Backend:       getEnv("STORAGE_BACKEND", "local"),
+		LocalDir:      getEnv("STORAGE_LOCAL_DIR", "./data"),
+		S3Endpoint:    os.Getenv("S3_ENDPOINT"),
+		S3Bucket:      os.Getenv("S3_BUCKET"),
+		S3Region:      getEnv("S3_REGION", "us-east-1"),
+		S3AccessKey:   os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:   os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle:   pathStyle,
+		CoverMaxBytes: int64(getInt("COVER_MAX_BYTES", 5<<20)),
+	}
+}
+
+type AccountConfig struct {
+	DeletionGrace         time.Duration // Frist zwischen Löschantrag und Löschung
+	DeletionSweepInterval time.Duration // Wie oft fällige Löschungen ausgeführt werden
+	UserSyncTTL           time.Duration // Wie lange ein abgeglichener Benutzer ohne Änderung als aktuell gilt
+	LastSeenFlushInterval time.Duration // Wie oft "zuletzt gesehen" gesammelt gespeichert wird
+}
+
+func LoadAccountConfig() *AccountConfig {
+	return &AccountConfig{
+		DeletionGrace:         getDuration("ACCOUNT_DELETION_GRACE", 14*24*time.Hour),
+		DeletionSweepInterval: getDuration("ACCOUNT_DELETION_SWEEP_INTERVAL", time.Hour),
+		UserSyncTTL:           getDuration("USER_SYNC_TTL", 5*time.Minute),
+		LastSeenFlushInterval: getDuration("USER_LAST_SEEN_FLUSH_INTERVAL", time.Minute),
+	}
+}
+
+type JobsConfig struct {
+	Workers      int           // Anzahl paralleler Worker
+	PollInterval time.Duration // Wartezeit, wenn keine Jobs anstehen
+	Lease        time.Duration // Ohne Lebenszeichen des Workers wird ein Job danach neu vergeben
+	BackoffBase  time.Duration // Wartezeit vor dem ersten Wiederholungsversuch, verdoppelt sich je Versuch
+	BackoffMax   time.Duration
+	ShutdownWait time.Duration // Wie lange beim Beenden auf laufende Jobs gewartet wird
+
+	Retention     time.Duration // Wie lange beendete Jobs mit Dateien (Exporte, Wiederherstellungen) erhalten bleiben
+	SweepInterval time.Duration // Wie oft abgelaufene Jobs samt Dateien gelöscht werden
+}
+
+func LoadJobsConfig() *JobsConfig {
+	return &JobsConfig{
+		Workers:      getInt("JOBS_WORKERS", 2),
+		PollInterval: getDuration("JOBS_POLL_INTERVAL", time.Second),
+		Lease:        getDuration("JOBS_LEASE", time.Minute),
+		BackoffBase:  getDuration("JOBS_BACKOFF_BASE", 10*time.Second),
+		BackoffMax:   getDuration("JOBS_BACKOFF_MAX", 10*time.Minute),
+		ShutdownWait: getDuration("JOBS_SHUTDOWN_WAIT", 30*time.Second),
+
+		Retention:     getDuration("JOBS_RETENTION", 7*24*time.Hour),
+		SweepInterval: getDuration("JOBS_SWEEP_INTERVAL", time.Hour),
+	}
+}
+
+// Anmeldeverfahren
+const (
+	AuthModeOIDC  = "oidc"  // Tokens eines externen Identity Providers (Keycloak, Authentik, ...)
+	AuthModeLocal = "local" // Der Server verwaltet Benutzer und Passwörter selbst
+)
+
+// Registrierung im lokalen Modus; das erste Konto kann sich immer registrieren und wird Admin
+const (
+	RegistrationOpen   = "open"   // Jeder kann sich registrieren
+	RegistrationInvite = "invite" // Nur mit Einladungscode eines Admins
+	RegistrationClosed = "closed" // Keine weiteren Konten
+)
+
+type AuthConfig struct {
+	Mode              string        // AuthModeOIDC oder AuthModeLocal
+	LocalSecret       string        // HMAC-Schlüssel für die lokal ausgestellten Tokens (HS256)
+	LocalIssuer       string        // iss der lokal ausgestellten Tokens
+	AccessTokenTTL    time.Duration // Gültigkeit eines Access Tokens
+	RefreshTokenTTL   time.Duration // Gültigkeit eines Refresh Tokens, wird bei jeder Erneuerung neu ausgestellt
+	Registration      string        // RegistrationOpen, RegistrationInvite oder RegistrationClosed
+	InvitationTTL     time.Duration // Gültigkeit eines Einladungscodes
+	PasswordMinLength int
+}
+
+func LoadAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		Mode:              strings.ToLower(getEnv("AUTH_MODE", AuthModeOIDC)),
+		LocalSecret:       os.Getenv("LOCAL_AUTH_SECRET"),
+		LocalIssuer:       getEnv("LOCAL_AUTH_ISSUER", "diplodocu"),
+		AccessTokenTTL:    getDuration("LOCAL_AUTH_ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:   getDuration("LOCAL_AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		Registration:      strings.ToLower(getEnv("LOCAL_AUTH_REGISTRATION", RegistrationInvite)),
+		InvitationTTL:     getDuration("LOCAL_AUTH_INVITATION_TTL", 7*24*time.Hour),
+		PasswordMinLength: getInt("LOCAL_AUTH_PASSWORD_MIN_LENGTH", 10),
+	}
+}
+
+// getEnv liefert den Wert einer Umgebungsvariable oder den Standardwert
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getDuration liest Werte wie "5s" oder "1h"; ungültige Werte ergeben den Standardwert
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// splitList zerlegt eine kommagetrennte Liste und entfernt leere Einträge
+func splitList(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
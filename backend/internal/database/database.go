@@ -23,7 +23,7 @@ func ConnectDB(cfg *config.DBConfig) (*gorm.DB, error) {
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.Buch{},
 		&models.Spiel{},
 		&models.Manga{},
@@ -32,5 +32,40 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.Webuser{},
 		&models.Sammlung{},
 		&models.SammlungProdukt{},
+		&models.Staffel{},
+		&models.Episode{},
+		&models.EpisodeGesehen{},
+		&models.Edition{},
+		&models.ProduktCode{},
+		&models.Cover{},
+		&models.Loeschantrag{},
+		&models.Job{},
+		&models.Freigabe{},
+		&models.SammlungMitglied{},
+		&models.Haushalt{},
+		&models.HaushaltMitgliedschaft{},
+		&models.Kinderprofil{},
+		&models.Zugangstoken{},
+		&models.LokalesKonto{},
+		&models.RefreshToken{},
+		&models.Registrierungseinladung{},
+		&models.Benutzereinstellungen{},
 	)
+	if err != nil {
+		return err
+	}
+	return migrateProduktCodes(db)
+}
+
+// migrateProduktCodes stellt Kennungen von einem global eindeutigen Code auf einen je Katalog
+// eindeutigen um: der alte Index entfällt, bestehende Codes übernehmen den Haushalt ihres Produkts
+func migrateProduktCodes(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&models.ProduktCode{}, "idx_produkt_code_code") {
+		if err := db.Migrator().DropIndex(&models.ProduktCode{}, "idx_produkt_code_code"); err != nil {
+			return err
+		}
+	}
+	return db.Exec(`UPDATE produkt_code SET haushalt_id = (
+			SELECT produkte.haushalt_id FROM produkte WHERE produkte.id = produkt_code.produkt_id)
+		WHERE haushalt_id IS NULL AND produkt_id IN (SELECT id FROM produkte WHERE haushalt_id IS NOT NULL)`).Error
 }
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// alterProduktCode ist das Schema vor der Eindeutigkeit je Katalog
+type alterProduktCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	ProduktID uint   `gorm:"column:produkt_id;not null;index"`
+	Typ       string `gorm:"not null;type:varchar(10)"`
+	Code      string `gorm:"not null;uniqueIndex;type:varchar(13)"`
+}
+
+func (alterProduktCode) TableName() string {
+	return "produkt_code"
+}
+
+func TestAutoMigrateScopesProduktCodes(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Produkt{}, &alterProduktCode{}))
+	haushaltA, haushaltB := uint(1), uint(2)
+	privat := models.Produkt{Name: "Momo", Art: "Buch", HaushaltID: &haushaltA}
+	require.NoError(t, db.Create(&privat).Error)
+	require.NoError(t, db.Create(&alterProduktCode{ProduktID: privat.ID, Typ: "ISBN", Code: "9783522202107"}).Error)
+
+	require.NoError(t, AutoMigrate(db))
+	assert.False(t, db.Migrator().HasIndex(&models.ProduktCode{}, "idx_produkt_code_code"))
+	var code models.ProduktCode
+	require.NoError(t, db.First(&code, "produkt_id = ?", privat.ID).Error)
+	require.NotNil(t, code.HaushaltID)
+	assert.Equal(t, haushaltA, *code.HaushaltID)
+
+	// The same code can exist once globally and once per household
+	andere := models.Produkt{Name: "Momo", Art: "Buch", HaushaltID: &haushaltB}
+	require.NoError(t, db.Create(&andere).Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: andere.ID, HaushaltID: &haushaltB, Typ: "ISBN", Code: "9783522202107"}).Error)
+	global := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&global).Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: global.ID, Typ: "ISBN", Code: "9783522202107"}).Error)
+
+	assert.Error(t, db.Create(&models.ProduktCode{ProduktID: global.ID, Typ: "ISBN", Code: "9783522202107"}).Error)
+	assert.Error(t, db.Create(&models.ProduktCode{ProduktID: andere.ID, HaushaltID: &haushaltB, Typ: "ISBN", Code: "9783522202107"}).Error)
+}
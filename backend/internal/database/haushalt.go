@@ -0,0 +1,86 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NormalizeGruppe vereinheitlicht Keycloak-Gruppennamen: je nach Mapper enthält der
+// groups-Claim den vollen Pfad ("/familie-meier") oder nur den Namen
+func NormalizeGruppe(gruppe string) string {
+	return strings.TrimPrefix(strings.TrimSpace(gruppe), "/")
+}
+
+// KatalogScope beschränkt Abfragen auf der Tabelle produkte auf den globalen Katalog und die
+// Produkte des Haushalts haushaltID (nil = nur globaler Katalog)
+func KatalogScope(haushaltID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if haushaltID == nil {
+			return db.Where("produkte.haushalt_id IS NULL")
+		}
+		return db.Where("(produkte.haushalt_id IS NULL OR produkte.haushalt_id = ?)", *haushaltID)
+	}
+}
+
+// SyncHaushalte gleicht die über Keycloak-Gruppen vergebenen Haushaltsmitgliedschaften ab.
+// Der Benutzer tritt allen Haushalten bei, deren Gruppe im Token steht, und verlässt die, deren
+// Gruppe fehlt, sofern er nur über die Gruppe beigetreten ist. Manuell vergebene
+// Mitgliedschaften bleiben unverändert. Geschrieben werden nur die Unterschiede zu den
+// bestehenden Mitgliedschaften.
+func SyncHaushalte(db *gorm.DB, webuserID string, gruppen []string) error {
+	normalized := make([]string, 0, len(gruppen))
+	for _, gruppe := range gruppen {
+		if gruppe = NormalizeGruppe(gruppe); gruppe != "" {
+			normalized = append(normalized, gruppe)
+		}
+	}
+
+	var haushalte []models.Haushalt
+	if len(normalized) > 0 {
+		if err := db.Where("gruppe IN ?", normalized).Find(&haushalte).Error; err != nil {
+			return err
+		}
+	}
+	var bestehende []models.HaushaltMitgliedschaft
+	if err := db.Where("webuser_id = ?", webuserID).Find(&bestehende).Error; err != nil {
+		return err
+	}
+	mitglied := make(map[uint]bool, len(bestehende))
+	for _, mitgliedschaft := range bestehende {
+		mitglied[mitgliedschaft.HaushaltID] = true
+	}
+
+	ausGruppen := make(map[uint]bool, len(haushalte))
+	for _, haushalt := range haushalte {
+		ausGruppen[haushalt.ID] = true
+		if mitglied[haushalt.ID] {
+			continue
+		}
+		mitgliedschaft := models.HaushaltMitgliedschaft{
+			HaushaltID: haushalt.ID,
+			WebuserID:  webuserID,
+			Rolle:      models.HaushaltMitglied,
+			AusGruppe:  true,
+		}
+		// Ein gleichzeitiger Abgleich kann die Mitgliedschaft bereits angelegt haben
+		err := db.Omit("Haushalt", "Webuser").Clauses(clause.OnConflict{DoNothing: true}).Create(&mitgliedschaft).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	var verlassen []uint
+	for _, mitgliedschaft := range bestehende {
+		if mitgliedschaft.AusGruppe && !ausGruppen[mitgliedschaft.HaushaltID] {
+			verlassen = append(verlassen, mitgliedschaft.HaushaltID)
+		}
+	}
+	if len(verlassen) == 0 {
+		return nil
+	}
+	return db.Where("webuser_id = ? AND aus_gruppe = ? AND haushalt_id IN ?", webuserID, true, verlassen).
+		Delete(&models.HaushaltMitgliedschaft{}).Error
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSyncHaushalte(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Haushalt{}, &models.HaushaltMitgliedschaft{}))
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+
+	meier := models.Haushalt{Name: "Familie Meier", Gruppe: strPtr("familie-meier")}
+	require.NoError(t, db.Create(&meier).Error)
+	wg := models.Haushalt{Name: "WG", Gruppe: strPtr("wg")}
+	require.NoError(t, db.Create(&wg).Error)
+	manuell := models.Haushalt{Name: "Ferienhaus"}
+	require.NoError(t, db.Create(&manuell).Error)
+	require.NoError(t, db.Create(&models.HaushaltMitgliedschaft{HaushaltID: manuell.ID, WebuserID: "alice", Rolle: models.HaushaltVerwalter}).Error)
+
+	mitgliedschaften := func() map[uint]models.HaushaltMitgliedschaft {
+		var found []models.HaushaltMitgliedschaft
+		require.NoError(t, db.Where("webuser_id = ?", "alice").Find(&found).Error)
+		result := make(map[uint]models.HaushaltMitgliedschaft)
+		for _, m := range found {
+			result[m.HaushaltID] = m
+		}
+		return result
+	}
+
+	// Full group paths and plain names are both recognized
+	require.NoError(t, SyncHaushalte(db, "alice", []string{"/familie-meier", "wg", "/unbekannt"}))
+	found := mitgliedschaften()
+	require.Len(t, found, 3)
+	assert.True(t, found[meier.ID].AusGruppe)
+	assert.Equal(t, models.HaushaltMitglied, found[meier.ID].Rolle)
+
+	// Syncing twice is idempotent and writes nothing
+	writes := 0
+	countWrite := func(*gorm.DB) { writes++ }
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:count_create", countWrite))
+	require.NoError(t, db.Callback().Delete().Before("gorm:delete").Register("test:count_delete", countWrite))
+	require.NoError(t, SyncHaushalte(db, "alice", []string{"/familie-meier", "wg"}))
+	assert.Len(t, mitgliedschaften(), 3)
+	assert.Zero(t, writes)
+
+	// Leaving a group removes only the membership granted by that group
+	require.NoError(t, SyncHaushalte(db, "alice", []string{"/wg"}))
+	found = mitgliedschaften()
+	assert.Len(t, found, 2)
+	assert.NotContains(t, found, meier.ID)
+	assert.Equal(t, 1, writes)
+
+	require.NoError(t, SyncHaushalte(db, "alice", nil))
+	found = mitgliedschaften()
+	require.Len(t, found, 1)
+	assert.Equal(t, models.HaushaltVerwalter, found[manuell.ID].Rolle)
+}
+
+func strPtr(s string) *string { return &s }
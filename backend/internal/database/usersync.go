@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserProfile enthält die Benutzerdaten aus einem Token
+type UserProfile struct {
+	ID          string
+	Name        string // Benutzername, z.B. preferred_username
+	Anzeigename string
+	Email       string
+	Gruppen     []string // Haushalte über Gruppen, siehe SyncHaushalte
+}
+
+// fingerprint fasst alle gespeicherten Angaben zusammen, um Änderungen zu erkennen
+func (p UserProfile) fingerprint() string {
+	gruppen := make([]string, 0, len(p.Gruppen))
+	for _, gruppe := range p.Gruppen {
+		if gruppe = NormalizeGruppe(gruppe); gruppe != "" {
+			gruppen = append(gruppen, gruppe)
+		}
+	}
+	sort.Strings(gruppen)
+	return strings.Join([]string{p.Name, p.Anzeigename, p.Email, strings.Join(gruppen, "\x1f")}, "\x1e")
+}
+
+// UserSync legt Benutzer beim ersten Request an und hält ihre Angaben aktuell, ohne bei jedem
+// Request zu schreiben: ein Benutzer gilt nach dem Abgleich für ttl als aktuell, solange sich
+// seine Claims nicht ändern. Geschrieben wird nur, was sich tatsächlich geändert hat.
+// ZuletztGesehenAm wird gesammelt und von RunLastSeenFlusher in einem Update je Intervall
+// gespeichert.
+type UserSync struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	synced   map[string]syncedUser
+	lastSeen map[string]struct{}
+}
+
+type syncedUser struct {
+	fingerprint string
+	until       time.Time
+}
+
+// NewUserSync erzeugt einen leeren Cache; ttl <= 0 gleicht bei jedem Request ab
+func NewUserSync(ttl time.Duration) *UserSync {
+	return &UserSync{
+		ttl:      ttl,
+		now:      time.Now,
+		synced:   make(map[string]syncedUser),
+		lastSeen: make(map[string]struct{}),
+	}
+}
+
+// Users ist der Cache der AuthMiddleware; main setzt die TTL aus der Konfiguration
+var Users = NewUserSync(5 * time.Minute)
+
+// Sync gleicht einen Benutzer ab, falls er nicht im Cache steht, der Eintrag abgelaufen ist
+// oder sich die Claims geändert haben, und vermerkt ihn für ZuletztGesehenAm
+func (s *UserSync) Sync(db *gorm.DB, profile UserProfile) error {
+	fingerprint := profile.fingerprint()
+	now := s.now()
+
+	s.mu.Lock()
+	s.lastSeen[profile.ID] = struct{}{}
+	entry, cached := s.synced[profile.ID]
+	s.mu.Unlock()
+	if cached && entry.fingerprint == fingerprint && now.Before(entry.until) {
+		return nil
+	}
+
+	if err := upsertUser(db, profile); err != nil {
+		return err
+	}
+	if err := SyncHaushalte(db, profile.ID, profile.Gruppen); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.synced[profile.ID] = syncedUser{fingerprint: fingerprint, until: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+// Touch vermerkt einen Request für ZuletztGesehenAm, ohne die Angaben abzugleichen
+// (z.B. bei Zugangstokens, die keine Claims enthalten)
+func (s *UserSync) Touch(webuserID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen[webuserID] = struct{}{}
+}
+
+// Invalidate entfernt einen Benutzer aus dem Cache, z.B. nach dem Löschen seines Kontos
+func (s *UserSync) Invalidate(webuserID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.synced, webuserID)
+	delete(s.lastSeen, webuserID)
+}
+
+// Reset leert den Cache, z.B. wenn ein Haushalt mit Gruppe angelegt wurde und die
+// Mitgliedschaften aller Benutzer neu abgeglichen werden müssen
+func (s *UserSync) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = make(map[string]syncedUser)
+}
+
+// FlushLastSeen speichert ZuletztGesehenAm für alle seit dem letzten Aufruf gesehenen
+// Benutzer in einem Update und entfernt abgelaufene Cache-Einträge. Schlägt das Update fehl,
+// werden die Benutzer beim nächsten Aufruf erneut berücksichtigt.
+func (s *UserSync) FlushLastSeen(db *gorm.DB) error {
+	now := s.now()
+	s.mu.Lock()
+	seen := s.lastSeen
+	s.lastSeen = make(map[string]struct{})
+	for id, entry := range s.synced {
+		if !now.Before(entry.until) {
+			delete(s.synced, id)
+		}
+	}
+	s.mu.Unlock()
+	if len(seen) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	err := db.Model(&models.Webuser{}).Where("id IN ?", ids).UpdateColumn("zuletzt_gesehen_am", now.UTC()).Error
+	if err != nil {
+		s.mu.Lock()
+		for _, id := range ids {
+			s.lastSeen[id] = struct{}{}
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// RunLastSeenFlusher speichert ZuletztGesehenAm alle interval und ein letztes Mal, wenn ctx endet
+func (s *UserSync) RunLastSeenFlusher(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.FlushLastSeen(db); err != nil {
+				log.Printf("ERROR saving last seen: %v\n", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.FlushLastSeen(db); err != nil {
+				log.Printf("ERROR saving last seen: %v\n", err)
+			}
+		}
+	}
+}
+
+// upsertUser legt einen Benutzer an oder aktualisiert geänderte Angaben
+func upsertUser(db *gorm.DB, profile UserProfile) error {
+	name, anzeigename, email := optionalString(profile.Name), optionalString(profile.Anzeigename), optionalString(profile.Email)
+
+	var user models.Webuser
+	err := db.Where("id = ?", profile.ID).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = models.Webuser{ID: profile.ID, Name: name, Anzeigename: anzeigename, Email: email}
+		// Gleichzeitige erste Requests desselben Benutzers legen ihn nur einmal an
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&user).Error; err != nil {
+			return err
+		}
+		log.Printf("Created user %s (%s)", profile.ID, profile.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changes := map[string]interface{}{}
+	if !sameString(user.Name, name) {
+		changes["name"] = name
+	}
+	if !sameString(user.Anzeigename, anzeigename) {
+		changes["anzeigename"] = anzeigename
+	}
+	if !sameString(user.Email, email) {
+		changes["email"] = email
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := db.Model(&models.Webuser{}).Where("id = ?", profile.ID).Updates(changes).Error; err != nil {
+		return err
+	}
+	log.Printf("Updated user %s (%s)", profile.ID, profile.Name)
+	return nil
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Webuser{})
+	require.NoError(t, err)
+
+	return db
+}
+
+// countWrites zählt Inserts und Updates auf der Datenbank
+func countWrites(t *testing.T, db *gorm.DB) *int {
+	writes := 0
+	count := func(*gorm.DB) { writes++ }
+	require.NoError(t, db.Callback().Create().After("gorm:create").Register("test:count_create", count))
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:count_update", count))
+	return &writes
+}
+
+func newTestUserSync(ttl time.Duration) (*UserSync, *time.Time) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewUserSync(ttl)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestUserSyncWritesOnlyChanges(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Haushalt{}, &models.HaushaltMitgliedschaft{}))
+	writes := countWrites(t, db)
+	s, now := newTestUserSync(time.Minute)
+
+	profile := UserProfile{ID: "alice", Name: "alice", Anzeigename: "Alice Meier", Email: "alice@example.org"}
+	require.NoError(t, s.Sync(db, profile))
+	var user models.Webuser
+	require.NoError(t, db.Where("id = ?", "alice").First(&user).Error)
+	assert.Equal(t, "alice", *user.Name)
+	assert.Equal(t, "Alice Meier", *user.Anzeigename)
+	assert.Equal(t, "alice@example.org", *user.Email)
+	assert.Equal(t, 1, *writes)
+
+	// Cached: no database access at all
+	require.NoError(t, db.Model(&models.Webuser{}).Where("id = ?", "alice").UpdateColumn("name", "changed elsewhere").Error)
+	*writes = 0
+	require.NoError(t, s.Sync(db, profile))
+	require.NoError(t, db.Where("id = ?", "alice").First(&user).Error)
+	assert.Equal(t, "changed elsewhere", *user.Name)
+	assert.Zero(t, *writes)
+
+	// After the TTL the user is compared again and only differences are written
+	*now = now.Add(2 * time.Minute)
+	require.NoError(t, s.Sync(db, profile))
+	require.NoError(t, db.Where("id = ?", "alice").First(&user).Error)
+	assert.Equal(t, "alice", *user.Name)
+	assert.Equal(t, 1, *writes)
+	*now = now.Add(2 * time.Minute)
+	*writes = 0
+	require.NoError(t, s.Sync(db, profile))
+	assert.Zero(t, *writes, "unchanged claims are not written")
+
+	// Changed claims are synced before the TTL expires; empty claims clear the field
+	profile.Email = ""
+	profile.Anzeigename = "Alice Schmidt"
+	require.NoError(t, s.Sync(db, profile))
+	require.NoError(t, db.Where("id = ?", "alice").First(&user).Error)
+	assert.Nil(t, user.Email)
+	assert.Equal(t, "Alice Schmidt", *user.Anzeigename)
+}
+
+func TestUserSyncGroups(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Haushalt{}, &models.HaushaltMitgliedschaft{}))
+	wg := models.Haushalt{Name: "WG", Gruppe: strPtr("wg")}
+	require.NoError(t, db.Create(&wg).Error)
+	s, _ := newTestUserSync(time.Hour)
+
+	countMitglied := func() int64 {
+		var n int64
+		require.NoError(t, db.Model(&models.HaushaltMitgliedschaft{}).Where("webuser_id = ?", "alice").Count(&n).Error)
+		return n
+	}
+
+	require.NoError(t, s.Sync(db, UserProfile{ID: "alice", Name: "alice"}))
+	assert.Zero(t, countMitglied())
+	// Group order and leading slashes do not count as a change
+	require.NoError(t, s.Sync(db, UserProfile{ID: "alice", Name: "alice", Gruppen: []string{"/wg", "sport"}}))
+	assert.Equal(t, int64(1), countMitglied())
+	require.NoError(t, db.Where("webuser_id = ?", "alice").Delete(&models.HaushaltMitgliedschaft{}).Error)
+	require.NoError(t, s.Sync(db, UserProfile{ID: "alice", Name: "alice", Gruppen: []string{"sport", "wg"}}))
+	assert.Zero(t, countMitglied(), "same groups are served from the cache")
+
+	s.Reset()
+	require.NoError(t, s.Sync(db, UserProfile{ID: "alice", Name: "alice", Gruppen: []string{"sport", "wg"}}))
+	assert.Equal(t, int64(1), countMitglied())
+}
+
+func TestUserSyncLastSeen(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Haushalt{}, &models.HaushaltMitgliedschaft{}))
+	s, now := newTestUserSync(time.Minute)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id}).Error)
+	}
+
+	require.NoError(t, s.Sync(db, UserProfile{ID: "alice", Name: "alice"}))
+	s.Touch("bob")
+	writes := countWrites(t, db)
+	require.NoError(t, s.FlushLastSeen(db))
+	assert.Equal(t, 1, *writes, "all users in one update")
+
+	lastSeen := func(id string) *time.Time {
+		var user models.Webuser
+		require.NoError(t, db.Where("id = ?", id).First(&user).Error)
+		return user.ZuletztGesehenAm
+	}
+	require.NotNil(t, lastSeen("alice"))
+	assert.True(t, now.Equal(*lastSeen("alice")))
+	require.NotNil(t, lastSeen("bob"))
+	assert.Nil(t, lastSeen("carol"))
+
+	// Nothing seen since the last flush
+	*writes = 0
+	require.NoError(t, s.FlushLastSeen(db))
+	assert.Zero(t, *writes)
+
+	// Expired cache entries are dropped on flush
+	*now = now.Add(2 * time.Minute)
+	require.NoError(t, s.FlushLastSeen(db))
+	assert.Empty(t, s.synced)
+
+	// Invalidated users are neither cached nor flushed
+	require.NoError(t, s.Sync(db, UserProfile{ID: "carol", Name: "carol"}))
+	s.Invalidate("carol")
+	assert.Empty(t, s.synced)
+	require.NoError(t, s.FlushLastSeen(db))
+	assert.Nil(t, lastSeen("carol"))
+}
+
+func TestRunLastSeenFlusherFlushesOnShutdown(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+	s := NewUserSync(time.Minute)
+	s.Touch("alice")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunLastSeenFlusher(ctx, db, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	var user models.Webuser
+	require.NoError(t, db.Where("id = ?", "alice").First(&user).Error)
+	assert.NotNil(t, user.ZuletztGesehenAm)
+}
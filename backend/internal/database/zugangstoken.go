@@ -0,0 +1,48 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrZugangstokenUngueltig steht für unbekannte, widerrufene und abgelaufene Tokens
+var ErrZugangstokenUngueltig = errors.New("access token invalid")
+
+// zuletztVerwendetIntervall begrenzt die Schreibzugriffe für ZuletztVerwendetAm
+const zuletztVerwendetIntervall = time.Minute
+
+// HashToken liefert den gespeicherten Hash eines zufälligen Tokens (Zugangstokens, Refresh
+// Tokens, Einladungen). Die Tokens sind lang genug, dass ein schneller Hash ohne Salt ausreicht.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindZugangstoken sucht ein gültiges Token und vermerkt die Verwendung (höchstens einmal pro Minute)
+func FindZugangstoken(db *gorm.DB, token string, now time.Time) (*models.Zugangstoken, error) {
+	var zugangstoken models.Zugangstoken
+	if err := db.Where("hash = ?", HashToken(token)).First(&zugangstoken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrZugangstokenUngueltig
+		}
+		return nil, err
+	}
+	if !zugangstoken.Gueltig(now) {
+		return nil, ErrZugangstokenUngueltig
+	}
+
+	if zugangstoken.ZuletztVerwendetAm == nil || now.Sub(*zugangstoken.ZuletztVerwendetAm) >= zuletztVerwendetIntervall {
+		err := db.Model(&models.Zugangstoken{}).Where("id = ?", zugangstoken.ID).
+			UpdateColumn("zuletzt_verwendet_am", now).Error
+		if err != nil {
+			return nil, err
+		}
+		zugangstoken.ZuletztVerwendetAm = &now
+	}
+	return &zugangstoken, nil
+}
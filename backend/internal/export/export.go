@@ -0,0 +1,115 @@
+// Package export schreibt Sammlungen als CSV, JSON oder XLSX. Die Zeilen werden direkt
+// aus der Datenbank gestreamt, damit auch große Exporte wenig Speicher benötigen.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Row ist eine exportierte Zeile: ein Produkt in einer Sammlung inkl. typspezifischer Felder.
+// Die Spaltennamen entsprechen den Feldern des Imports, damit Exporte wieder importiert werden können.
+type Row struct {
+	SammlungID uint    `gorm:"column:sammlung_id" json:"sammlungId"`
+	Sammlung   *string `gorm:"column:sammlung" json:"sammlung"`
+	ProduktID  uint    `gorm:"column:produkt_id" json:"produktId"`
+	Art        string  `gorm:"column:art" json:"art"`
+	Name       string  `gorm:"column:name" json:"name"`
+	Nummer     *int    `gorm:"column:nummer" json:"nummer"`
+	Autor      *string `gorm:"column:autor" json:"autor"`
+	Mangaka    *string `gorm:"column:mangaka" json:"mangaka"`
+	Baende     *int    `gorm:"column:baende" json:"baende"`
+	Konsole    *string `gorm:"column:konsole" json:"konsole"`
+	FilmArt    *string `gorm:"column:film_art" json:"filmArt"`
+	Sprache    *string `gorm:"column:sprache" json:"sprache"`
+	Genre      *string `gorm:"column:genre" json:"genre"`
+	Code       *string `gorm:"column:code" json:"code"` // Erste gespeicherte ISBN/EAN
+}
+
+// Columns sind die Spaltenüberschriften für CSV und XLSX
+var Columns = []string{"sammlungId", "sammlung", "produktId", "art", "name", "nummer",
+	"autor", "mangaka", "baende", "konsole", "filmArt", "sprache", "genre", "code"}
+
+// values liefert die Zellen einer Zeile in der Reihenfolge von Columns
+func (r Row) values() []interface{} {
+	return []interface{}{r.SammlungID, r.Sammlung, r.ProduktID, r.Art, r.Name, r.Nummer,
+		r.Autor, r.Mangaka, r.Baende, r.Konsole, r.FilmArt, r.Sprache, r.Genre, r.Code}
+}
+
+// Writer schreibt Zeilen in einem Exportformat. Close schließt das Dokument ab,
+// nicht aber den darunterliegenden io.Writer.
+type Writer interface {
+	Write(row Row) error
+	Close() error
+}
+
+// Format beschreibt ein unterstütztes Exportformat
+type Format struct {
+	Extension   string
+	ContentType string
+	NewWriter   func(w io.Writer) (Writer, error)
+}
+
+var Formats = map[string]Format{
+	"csv":  {Extension: "csv", ContentType: "text/csv; charset=utf-8", NewWriter: NewCSVWriter},
+	"json": {Extension: "json", ContentType: "application/json; charset=utf-8", NewWriter: NewJSONWriter},
+	"xlsx": {Extension: "xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", NewWriter: NewXLSXWriter},
+}
+
+const exportQuery = `SELECT s.id AS sammlung_id, s.name AS sammlung, p.id AS produkt_id, p.art, p.name, p.nummer,
+	b.autor, m.mangaka, m.baende, sp.konsole, f.art AS film_art,
+	COALESCE(b.sprache, m.sprache) AS sprache,
+	COALESCE(b.genre, m.genre, sp.genre, f.genre) AS genre,
+	(SELECT MIN(pc.code) FROM produkt_code pc WHERE pc.produkt_id = p.id) AS code
+FROM sammlung s
+JOIN sammlung_produkte sap ON sap.sammlung_id = s.id
+JOIN produkte p ON p.id = sap.produkt_id
+LEFT JOIN buch b ON b.produkte_id = p.id
+LEFT JOIN manga m ON m.produkte_id = p.id
+LEFT JOIN spiel sp ON sp.produkte_id = p.id
+LEFT JOIN filmserie f ON f.produkte_id = p.id
+WHERE %s
+ORDER BY s.id, p.art, p.name, p.nummer, p.id`
+
+// Stream liest die Zeilen der Sammlungen eines Benutzers (optional nur einer Sammlung)
+// und übergibt sie einzeln an fn
+func Stream(db *gorm.DB, webuserID string, sammlungID *uint, fn func(Row) error) error {
+	conditions := []string{"s.webuser_id = ?"}
+	args := []interface{}{webuserID}
+	if sammlungID != nil {
+		conditions = append(conditions, "s.id = ?")
+		args = append(args, *sammlungID)
+	}
+
+	rows, err := db.Raw(fmt.Sprintf(exportQuery, strings.Join(conditions, " AND ")), args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row Row
+		if err := db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Write streamt alle Zeilen im gewünschten Format nach w
+func Write(db *gorm.DB, w io.Writer, format Format, webuserID string, sammlungID *uint) error {
+	writer, err := format.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := Stream(db, webuserID, sammlungID, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
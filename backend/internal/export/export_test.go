@@ -0,0 +1,193 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/importer"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func strPtr(s string) *string { return &s }
+
+func setupExportTestDB(t *testing.T) (*gorm.DB, uint, uint) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{},
+		&models.Spiel{}, &models.Filmserie{}, &models.Sammlung{}, &models.Edition{}, &models.SammlungProdukt{},
+		&models.ProduktCode{}))
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "user-1"}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "user-2"}).Error)
+	regal := models.Sammlung{WebuserID: "user-1", Name: strPtr("Regal")}
+	require.NoError(t, db.Create(&regal).Error)
+	keller := models.Sammlung{WebuserID: "user-1", Name: strPtr("Keller")}
+	require.NoError(t, db.Create(&keller).Error)
+	fremd := models.Sammlung{WebuserID: "user-2"}
+	require.NoError(t, db.Create(&fremd).Error)
+
+	add := func(sammlung models.Sammlung, produkt models.Produkt, details interface{}) {
+		require.NoError(t, db.Create(&produkt).Error)
+		switch d := details.(type) {
+		case *models.Buch:
+			d.ProdukteID = produkt.ID
+		case *models.Manga:
+			d.ProdukteID = produkt.ID
+		case *models.Spiel:
+			d.ProdukteID = produkt.ID
+		case *models.Filmserie:
+			d.ProdukteID = produkt.ID
+		}
+		require.NoError(t, db.Create(details).Error)
+		require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: produkt.ID}).Error)
+	}
+	nummer := 3
+	add(regal, models.Produkt{Name: "Momo", Art: "Buch"}, &models.Buch{Autor: strPtr("Michael Ende"), Sprache: strPtr("Deutsch"), Genre: strPtr("Fantasy")})
+	add(regal, models.Produkt{Name: "Berserk", Nummer: &nummer, Art: "Manga"}, &models.Manga{Mangaka: strPtr("Kentarou Miura"), Baende: &nummer, Genre: strPtr("Dark <Fantasy> & more")})
+	add(keller, models.Produkt{Name: "Zelda", Art: "Spiel"}, &models.Spiel{Konsole: strPtr("Switch")})
+	add(keller, models.Produkt{Name: "Alien", Art: "Filmserie"}, &models.Filmserie{Art: strPtr("Film"), Genre: strPtr("Horror")})
+	add(fremd, models.Produkt{Name: "Geheim", Art: "Buch"}, &models.Buch{})
+
+	var momo models.Produkt
+	require.NoError(t, db.First(&momo, "name = ?", "Momo").Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: momo.ID, Typ: "ISBN", Code: "9780306406157"}).Error)
+
+	return db, regal.ID, keller.ID
+}
+
+func TestStreamFiltersByUserAndSammlung(t *testing.T) {
+	db, regalID, _ := setupExportTestDB(t)
+
+	var all []Row
+	require.NoError(t, Stream(db, "user-1", nil, func(row Row) error {
+		all = append(all, row)
+		return nil
+	}))
+	require.Len(t, all, 4)
+
+	var regal []Row
+	require.NoError(t, Stream(db, "user-1", &regalID, func(row Row) error {
+		regal = append(regal, row)
+		return nil
+	}))
+	require.Len(t, regal, 2)
+	assert.Equal(t, "Momo", regal[0].Name)
+	assert.Equal(t, "Michael Ende", *regal[0].Autor)
+	assert.Equal(t, "9780306406157", *regal[0].Code)
+	assert.Equal(t, "Kentarou Miura", *regal[1].Mangaka)
+	assert.Equal(t, 3, *regal[1].Nummer)
+	assert.Equal(t, 3, *regal[1].Baende)
+
+	var none []Row
+	require.NoError(t, Stream(db, "user-2", &regalID, func(row Row) error {
+		none = append(none, row)
+		return nil
+	}))
+	assert.Empty(t, none)
+}
+
+func TestWriteCSVCanBeReimported(t *testing.T) {
+	db, _, _ := setupExportTestDB(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(db, &buf, Formats["csv"], "user-1", nil))
+
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, Columns, records[0])
+
+	rows, err := importer.Parse("csv", buf.Bytes(), nil, "")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	for _, row := range rows {
+		assert.Empty(t, row.Errors)
+	}
+	assert.Equal(t, "Filmserie", rows[2].Art)
+	assert.Equal(t, "Film", *rows[2].FilmArt)
+}
+
+func TestWriteJSON(t *testing.T) {
+	db, _, kellerID := setupExportTestDB(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(db, &buf, Formats["json"], "user-1", &kellerID))
+
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rows))
+	require.Len(t, rows, 2)
+	assert.Equal(t, "Film", rows[0]["filmArt"])
+	assert.Equal(t, "Switch", rows[1]["konsole"])
+	assert.Nil(t, rows[1]["autor"])
+
+	// Empty export is still a valid array
+	buf.Reset()
+	require.NoError(t, Write(db, &buf, Formats["json"], "nobody", nil))
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rows))
+	assert.Empty(t, rows)
+}
+
+func TestWriteXLSX(t *testing.T) {
+	db, regalID, _ := setupExportTestDB(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(db, &buf, Formats["xlsx"], "user-1", &regalID))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		files[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		assert.Contains(t, files, name)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			R     string `xml:"r,attr"`
+			Cells []struct {
+				R      string `xml:"r,attr"`
+				T      string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 3)
+	assert.Equal(t, "sammlungId", sheet.Rows[0].Cells[0].Inline)
+
+	berserk := sheet.Rows[2]
+	byRef := map[string]string{}
+	for _, cell := range berserk.Cells {
+		if cell.T == "inlineStr" {
+			byRef[cell.R] = cell.Inline
+		} else {
+			byRef[cell.R] = cell.Value
+		}
+	}
+	assert.Equal(t, "Berserk", byRef["E3"])
+	assert.Equal(t, "3", byRef["F3"])
+	assert.Equal(t, "Dark <Fantasy> & more", byRef["M3"])
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "BA", columnName(52))
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+)
+
+// JobTyp ist der Typ der Hintergrundjobs für asynchrone Exporte
+const JobTyp = "export"
+
+// JobPayload beschreibt den Export. WebuserID ist der Besitzer der Sammlungen, der Zugriff
+// wurde beim Anlegen des Jobs geprüft.
+type JobPayload struct {
+	WebuserID  string
+	SammlungID *uint
+	Format     string // Schlüssel in Formats
+	Dateiname  string // Ohne Endung
+}
+
+// JobErgebnis beschreibt die fertige Datei; sie liegt im Speicher unter JobDatei
+type JobErgebnis struct {
+	Dateiname   string `json:"dateiname"`
+	ContentType string `json:"contentType"`
+	Groesse     int64  `json:"groesse"`
+}
+
+// JobDatei liefert den Speicherschlüssel der Exportdatei eines Jobs
+func JobDatei(jobID uint, format Format) string {
+	return fmt.Sprintf("exports/%d.%s", jobID, format.Extension)
+}
+
+// Dateien liefert den Speicherschlüssel der Exportdatei eines Jobs. Jobs anderer Typen und mit
+// unlesbarem Payload haben keine Datei.
+func Dateien(job models.Job) []string {
+	if job.Typ != JobTyp {
+		return nil
+	}
+	var payload JobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return nil
+	}
+	format, ok := Formats[payload.Format]
+	if !ok {
+		return nil
+	}
+	return []string{JobDatei(job.ID, format)}
+}
+
+// NewJobHandler erstellt den Handler für asynchrone Exporte. Die Datei wird zunächst in eine
+// temporäre Datei geschrieben und dann im Speicher abgelegt.
+func NewJobHandler(store storage.Store) jobs.Handler {
+	return func(ctx context.Context, run *jobs.Run) (interface{}, error) {
+		var payload JobPayload
+		if err := run.Decode(&payload); err != nil {
+			return nil, err
+		}
+		format, ok := Formats[payload.Format]
+		if !ok {
+			return nil, jobs.Permanent(fmt.Errorf("unknown export format %q", payload.Format))
+		}
+
+		file, err := os.CreateTemp("", "export-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		if err := Write(run.DB.WithContext(ctx), file, format, payload.WebuserID, payload.SammlungID); err != nil {
+			return nil, err
+		}
+		size, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		key := JobDatei(run.Job.ID, format)
+		if err := store.Put(ctx, key, file, size, format.ContentType); err != nil {
+			return nil, err
+		}
+		return JobErgebnis{
+			Dateiname:   payload.Dateiname + "." + format.Extension,
+			ContentType: format.ContentType,
+			Groesse:     size,
+		}, nil
+	}
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// cellString formatiert einen Zellwert als Text; nil wird zur leeren Zelle
+func cellString(value interface{}) string {
+	switch v := value.(type) {
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return *v
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func NewCSVWriter(w io.Writer) (Writer, error) {
+	writer := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(Columns))}
+	if err := writer.w.Write(Columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) Write(row Row) error {
+	for i, value := range row.values() {
+		c.record[i] = cellString(value)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter schreibt ein JSON-Array, ein Objekt nach dem anderen
+type jsonWriter struct {
+	w     io.Writer
+	first bool
+}
+
+func NewJSONWriter(w io.Writer) (Writer, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonWriter{w: w, first: true}, nil
+}
+
+func (j *jsonWriter) Write(row Row) error {
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if !j.first {
+		if _, err := io.WriteString(j.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	j.first = false
+	_, err = j.w.Write(encoded)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// Minimales XLSX (Office Open XML) ohne externe Bibliothek. Die Tabelle wird mit
+// Inline-Strings geschrieben, sodass keine Shared-String-Tabelle im Speicher nötig ist.
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const xlsxSheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
+
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func NewXLSXWriter(w io.Writer) (Writer, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// Das Tabellenblatt ist der letzte Eintrag und bleibt bis Close geöffnet
+	entry, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	writer := &xlsxWriter{zip: archive, sheet: bufio.NewWriter(entry)}
+	if _, err := writer.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(Columns))
+	for i, column := range Columns {
+		header[i] = column
+	}
+	if err := writer.writeRow(header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (x *xlsxWriter) Write(row Row) error {
+	return x.writeRow(row.values())
+}
+
+func (x *xlsxWriter) writeRow(values []interface{}) error {
+	x.row++
+	rowNumber := strconv.Itoa(x.row)
+	x.sheet.WriteString(`<row r="` + rowNumber + `">`)
+	for i, value := range values {
+		ref := columnName(i) + rowNumber
+		switch v := value.(type) {
+		case uint:
+			x.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatUint(uint64(v), 10) + `</v></c>`)
+		case *int:
+			if v != nil {
+				x.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.Itoa(*v) + `</v></c>`)
+			}
+		default:
+			text := cellString(v)
+			if text == "" {
+				continue
+			}
+			x.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(x.sheet, []byte(text)); err != nil {
+				return err
+			}
+			x.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// columnName wandelt einen 0-basierten Spaltenindex in die Excel-Schreibweise (A, B, ..., AA) um
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/backup"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// LoeschFrist ist die Zeit zwischen Löschantrag und Löschung (wird in main aus ACCOUNT_DELETION_GRACE gesetzt)
+var LoeschFrist = 14 * 24 * time.Hour
+
+type LoeschantragRequest struct {
+	Produkte      string  `json:"produkte"`      // anonymisieren (Standard) oder uebertragen
+	UebertragenAn *string `json:"uebertragenAn"` // Benutzer-ID, nur bei uebertragen
+}
+
+type LoeschantragResponse struct {
+	BeantragtAm   time.Time `json:"beantragtAm"`
+	LoeschenAb    time.Time `json:"loeschenAb"`
+	Produkte      string    `json:"produkte"`
+	UebertragenAn *string   `json:"uebertragenAn,omitempty"`
+}
+
+func toLoeschantragResponse(antrag models.Loeschantrag) LoeschantragResponse {
+	return LoeschantragResponse{
+		BeantragtAm:   antrag.BeantragtAm,
+		LoeschenAb:    antrag.LoeschenAb,
+		Produkte:      antrag.Produkte,
+		UebertragenAn: antrag.UebertragenAn,
+	}
+}
+
+// MyDataResponse enthält alle personenbezogenen Daten eines Benutzers
+type MyDataResponse struct {
+	*backup.Archive
+	Profil           *ProfilResponse                  `json:"profil"`
+	LokalesKonto     *LokalesKontoResponse            `json:"lokalesKonto"` // Nur im lokalen Anmeldemodus
+	Mitgliedschaften []SammlungMitgliedschaftResponse `json:"mitgliedschaften"`
+	Haushalte        []HaushaltMitgliedschaftResponse `json:"haushalte"`
+	Zugangstokens    []ZugangstokenResponse           `json:"zugangstokens"`
+	Jobs             []MyDataJobResponse              `json:"jobs"`
+	Loeschantrag     *LoeschantragResponse            `json:"loeschantrag"`
+}
+
+// LokalesKontoResponse enthält die Kontodaten ohne Passwort-Hash
+type LokalesKontoResponse struct {
+	Benutzername        string    `json:"benutzername"`
+	Rollen              []string  `json:"rollen"`
+	ErstelltAm          time.Time `json:"erstelltAm"`
+	PasswortGeaendertAm time.Time `json:"passwortGeaendertAm"`
+}
+
+// SammlungMitgliedschaftResponse ist eine Mitgliedschaft in der Sammlung eines anderen Benutzers
+type SammlungMitgliedschaftResponse struct {
+	EinladungResponse
+	AngenommenAm *time.Time `json:"angenommenAm"`
+}
+
+type HaushaltMitgliedschaftResponse struct {
+	HaushaltResponse
+	AusGruppe bool `json:"ausGruppe"`
+}
+
+// MyDataJobResponse enthält zusätzlich den Auftrag, z.B. die Optionen eines Imports
+type MyDataJobResponse struct {
+	JobResponse
+	Payload json.RawMessage `json:"payload"`
+}
+
+// loadMyDataDetails ergänzt Konto, Mitgliedschaften, Zugangstokens und Jobs des Benutzers
+func loadMyDataDetails(db *gorm.DB, userID string, response *MyDataResponse) error {
+	var konten []models.LokalesKonto
+	if err := db.Where("webuser_id = ?", userID).Limit(1).Find(&konten).Error; err != nil {
+		return err
+	}
+	if len(konten) > 0 {
+		response.LokalesKonto = &LokalesKontoResponse{
+			Benutzername:        konten[0].Benutzername,
+			Rollen:              konten[0].RollenListe(),
+			ErstelltAm:          konten[0].ErstelltAm,
+			PasswortGeaendertAm: konten[0].PasswortGeaendertAm,
+		}
+	}
+
+	var mitgliedschaften []models.SammlungMitglied
+	if err := db.Preload("Sammlung").Where("webuser_id = ?", userID).Order("eingeladen_am").Find(&mitgliedschaften).Error; err != nil {
+		return err
+	}
+	response.Mitgliedschaften = make([]SammlungMitgliedschaftResponse, len(mitgliedschaften))
+	for i, mitglied := range mitgliedschaften {
+		response.Mitgliedschaften[i] = SammlungMitgliedschaftResponse{
+			EinladungResponse: EinladungResponse{
+				SammlungID:   mitglied.SammlungID,
+				Name:         mitglied.Sammlung.Name,
+				BesitzerID:   mitglied.Sammlung.WebuserID,
+				Rolle:        mitglied.Rolle,
+				EingeladenAm: mitglied.EingeladenAm,
+			},
+			AngenommenAm: mitglied.AngenommenAm,
+		}
+	}
+
+	var haushalte []models.HaushaltMitgliedschaft
+	if err := db.Preload("Haushalt").Where("webuser_id = ?", userID).Order("haushalt_id").Find(&haushalte).Error; err != nil {
+		return err
+	}
+	response.Haushalte = make([]HaushaltMitgliedschaftResponse, len(haushalte))
+	for i, mitgliedschaft := range haushalte {
+		response.Haushalte[i] = HaushaltMitgliedschaftResponse{
+			HaushaltResponse: toHaushaltResponse(mitgliedschaft.Haushalt, mitgliedschaft.Rolle),
+			AusGruppe:        mitgliedschaft.AusGruppe,
+		}
+	}
+
+	var zugangstokens []models.Zugangstoken
+	if err := db.Where("webuser_id = ?", userID).Order("erstellt_am").Find(&zugangstokens).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	response.Zugangstokens = make([]ZugangstokenResponse, len(zugangstokens))
+	for i, zugangstoken := range zugangstokens {
+		response.Zugangstokens[i] = toZugangstokenResponse(zugangstoken, now)
+	}
+
+	var jobs []models.Job
+	if err := db.Where("webuser_id = ?", userID).Order("erstellt_am").Find(&jobs).Error; err != nil {
+		return err
+	}
+	response.Jobs = make([]MyDataJobResponse, len(jobs))
+	for i, job := range jobs {
+		response.Jobs[i] = MyDataJobResponse{JobResponse: toJobResponse(job)}
+		if job.Payload != "" {
+			response.Jobs[i].Payload = json.RawMessage(job.Payload)
+		}
+	}
+	return nil
+}
+
+// findLoeschantrag lädt den offenen Löschantrag des Benutzers (nil, wenn keiner existiert)
+func findLoeschantrag(db *gorm.DB, userID string) (*models.Loeschantrag, error) {
+	var antraege []models.Loeschantrag
+	if err := db.Where("webuser_id = ?", userID).Limit(1).Find(&antraege).Error; err != nil {
+		return nil, err
+	}
+	if len(antraege) == 0 {
+		return nil, nil
+	}
+	return &antraege[0], nil
+}
+
+// ExportMyData liefert alle gespeicherten Daten des Benutzers als JSON-Datei (Auskunft nach DSGVO)
+func ExportMyData(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	archive, err := backup.Create(db, userID)
+	if err != nil {
+		log.Printf("ERROR ExportMyData for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect user data"})
+		return
+	}
+	profil, err := loadProfil(db, userID)
+	if err != nil {
+		log.Printf("ERROR ExportMyData - Profile of user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect user data"})
+		return
+	}
+	response := MyDataResponse{Archive: archive, Profil: profil}
+	if err := loadMyDataDetails(db, userID, &response); err != nil {
+		log.Printf("ERROR ExportMyData - Memberships, tokens and jobs of user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect user data"})
+		return
+	}
+
+	antrag, err := findLoeschantrag(db, userID)
+	if err != nil {
+		log.Printf("ERROR ExportMyData - Deletion request for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect user data"})
+		return
+	}
+	if antrag != nil {
+		antragResponse := toLoeschantragResponse(*antrag)
+		response.Loeschantrag = &antragResponse
+	}
+
+	filename := fmt.Sprintf("diplodocu-daten-%s.json", archive.ErstelltAm.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.JSON(http.StatusOK, response)
+}
+
+// RequestDeletion beantragt die Löschung des eigenen Kontos. Gelöscht wird nach Ablauf der
+// Frist; bis dahin kann der Antrag mit CancelDeletion zurückgezogen werden.
+func RequestDeletion(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request LoeschantragRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	switch request.Produkte {
+	case "", models.ProdukteAnonymisieren:
+		request.Produkte = models.ProdukteAnonymisieren
+		request.UebertragenAn = nil
+	case models.ProdukteUebertragen:
+		if request.UebertragenAn == nil || *request.UebertragenAn == "" || *request.UebertragenAn == userID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'uebertragenAn' must name another user"})
+			return
+		}
+		var count int64
+		if err := db.Model(&models.Webuser{}).Where("id = ?", *request.UebertragenAn).Count(&count).Error; err != nil {
+			log.Printf("ERROR RequestDeletion - Check transfer target: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request deletion"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User in 'uebertragenAn' not found"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'produkte' must be anonymisieren or uebertragen"})
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	antrag := models.Loeschantrag{
+		WebuserID:     userID,
+		BeantragtAm:   now,
+		LoeschenAb:    now.Add(LoeschFrist),
+		Produkte:      request.Produkte,
+		UebertragenAn: request.UebertragenAn,
+	}
+	if err := db.Create(&antrag).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account deletion has already been requested"})
+			return
+		}
+		log.Printf("ERROR RequestDeletion for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request deletion"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toLoeschantragResponse(antrag))
+}
+
+// GetDeletion liefert den offenen Löschantrag
+func GetDeletion(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	antrag, err := findLoeschantrag(db, userID)
+	if err != nil {
+		log.Printf("ERROR GetDeletion for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load deletion request"})
+		return
+	}
+	if antrag == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No account deletion requested"})
+		return
+	}
+	c.JSON(http.StatusOK, toLoeschantragResponse(*antrag))
+}
+
+// CancelDeletion zieht den Löschantrag zurück
+func CancelDeletion(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result := db.Where("webuser_id = ?", userID).Delete(&models.Loeschantrag{})
+	if result.Error != nil {
+		log.Printf("ERROR CancelDeletion for user %s: %v\n", userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel deletion"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No account deletion requested"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAccountTestRouter(t *testing.T, userID string) (*gin.Engine, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{},
+		&models.Spiel{}, &models.Filmserie{}, &models.Sammlung{}, &models.SammlungMitglied{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}, &models.Kinderprofil{}, &models.SammlungProdukt{}, &models.Edition{},
+		&models.ProduktCode{}, &models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}, &models.Cover{},
+		&models.Loeschantrag{}, &models.Zugangstoken{}, &models.Benutzereinstellungen{}, &models.LokalesKonto{}, &models.Job{}))
+	require.NoError(t, db.Create(&models.Webuser{ID: userID, Name: strPtr("Alice")}).Error)
+
+	router := setupCollectionTestRouter(db, userID)
+	router.GET("/me/data", ExportMyData)
+	router.GET("/me/deletion", GetDeletion)
+	router.POST("/me/deletion", RequestDeletion)
+	router.DELETE("/me/deletion", CancelDeletion)
+	return router, db
+}
+
+func TestExportMyData(t *testing.T) {
+	router, db := setupAccountTestRouter(t, "alice")
+	require.NoError(t, db.Create(&models.Sammlung{WebuserID: "alice", Name: strPtr("Regal")}).Error)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, db.Create(&models.Webuser{ID: "bob"}).Error)
+	fremde := models.Sammlung{WebuserID: "bob", Name: strPtr("Bobs Spiele")}
+	require.NoError(t, db.Create(&fremde).Error)
+	require.NoError(t, db.Create(&models.SammlungMitglied{SammlungID: fremde.ID, WebuserID: "alice", Rolle: models.RolleBearbeiter, EingeladenAm: now, AngenommenAm: &now}).Error)
+	haushalt := models.Haushalt{Name: "Familie", ErstelltAm: now}
+	require.NoError(t, db.Create(&haushalt).Error)
+	require.NoError(t, db.Create(&models.HaushaltMitgliedschaft{HaushaltID: haushalt.ID, WebuserID: "alice", Rolle: models.HaushaltMitglied, AusGruppe: true}).Error)
+	require.NoError(t, db.Create(&models.Zugangstoken{WebuserID: "alice", Name: "Skript", Anfang: "dpat_abc", Hash: "geheim", Scopes: "lesen", ErstelltAm: now, ZuletztVerwendetAm: &now}).Error)
+	require.NoError(t, db.Create(&models.Job{WebuserID: "alice", Typ: "export", Status: models.JobErfolgreich, Payload: `{"Format":"csv"}`, NaechsterVersuch: now, ErstelltAm: now}).Error)
+	require.NoError(t, db.Create(&models.LokalesKonto{WebuserID: "alice", Benutzername: "alice", PasswortHash: "argon2-hash", Rollen: "admin", ErstelltAm: now, PasswortGeaendertAm: now}).Error)
+
+	req, _ := http.NewRequest(http.MethodGet, "/me/data", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	var response struct {
+		Webuser struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"webuser"`
+		Sammlungen       []map[string]interface{}         `json:"sammlungen"`
+		LokalesKonto     *LokalesKontoResponse            `json:"lokalesKonto"`
+		Mitgliedschaften []SammlungMitgliedschaftResponse `json:"mitgliedschaften"`
+		Haushalte        []HaushaltMitgliedschaftResponse `json:"haushalte"`
+		Zugangstokens    []ZugangstokenResponse           `json:"zugangstokens"`
+		Jobs             []MyDataJobResponse              `json:"jobs"`
+		Loeschantrag     *LoeschantragResponse            `json:"loeschantrag"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Alice", response.Webuser.Name)
+	assert.Len(t, response.Sammlungen, 1)
+	assert.Nil(t, response.Loeschantrag)
+
+	require.NotNil(t, response.LokalesKonto)
+	assert.Equal(t, "alice", response.LokalesKonto.Benutzername)
+	assert.Equal(t, []string{"admin"}, response.LokalesKonto.Rollen)
+	require.Len(t, response.Mitgliedschaften, 1)
+	assert.Equal(t, "bob", response.Mitgliedschaften[0].BesitzerID)
+	assert.Equal(t, models.RolleBearbeiter, response.Mitgliedschaften[0].Rolle)
+	assert.NotNil(t, response.Mitgliedschaften[0].AngenommenAm)
+	require.Len(t, response.Haushalte, 1)
+	assert.Equal(t, "Familie", response.Haushalte[0].Name)
+	assert.True(t, response.Haushalte[0].AusGruppe)
+	require.Len(t, response.Zugangstokens, 1)
+	assert.Equal(t, "Skript", response.Zugangstokens[0].Name)
+	assert.Equal(t, []string{"lesen"}, response.Zugangstokens[0].Scopes)
+	assert.NotNil(t, response.Zugangstokens[0].ZuletztVerwendetAm)
+	require.Len(t, response.Jobs, 1)
+	assert.JSONEq(t, `{"Format":"csv"}`, string(response.Jobs[0].Payload))
+	// Hashes are never part of the export
+	assert.NotContains(t, w.Body.String(), "geheim")
+	assert.NotContains(t, w.Body.String(), "argon2-hash")
+}
+
+func TestDeletionRequestLifecycle(t *testing.T) {
+	router, db := setupAccountTestRouter(t, "alice")
+	require.NoError(t, db.Create(&models.Webuser{ID: "bob"}).Error)
+
+	w := doJSON(router, http.MethodGet, "/me/deletion", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodPost, "/me/deletion", map[string]interface{}{"produkte": "uebertragen"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/me/deletion", map[string]interface{}{"produkte": "uebertragen", "uebertragenAn": "nobody"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/me/deletion", map[string]interface{}{"produkte": "verschenken"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodPost, "/me/deletion", map[string]interface{}{"produkte": "uebertragen", "uebertragenAn": "bob"})
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var antrag LoeschantragResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &antrag))
+	assert.Equal(t, LoeschFrist, antrag.LoeschenAb.Sub(antrag.BeantragtAm))
+	assert.Equal(t, "bob", *antrag.UebertragenAn)
+	assert.WithinDuration(t, time.Now().Add(LoeschFrist), antrag.LoeschenAb, time.Minute)
+
+	w = doJSON(router, http.MethodPost, "/me/deletion", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(router, http.MethodGet, "/me/deletion", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(router, http.MethodDelete, "/me/deletion", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJSON(router, http.MethodDelete, "/me/deletion", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
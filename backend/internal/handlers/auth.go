@@ -10,3 +10,28 @@ func SyncUser(c *gin.Context) {
 	// Just return success
 	c.JSON(http.StatusOK, gin.H{"status": "synced"})
 }
+
+// currentUserID liest die von der AuthMiddleware gesetzte UserID aus dem Context.
+// Fehlt sie, wird direkt mit 401 geantwortet und false zurückgegeben.
+func currentUserID(c *gin.Context) (string, bool) {
+	userIDraw, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return "", false
+	}
+	userID, ok := userIDraw.(string)
+	if !ok || userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid User ID format in context"})
+		return "", false
+	}
+	return userID, true
+}
+
+// optionalUserID liefert die UserID aus dem Context oder nil, ohne zu antworten
+func optionalUserID(c *gin.Context) *string {
+	userID, ok := c.Get("userId")
+	if id, isString := userID.(string); ok && isString && id != "" {
+		return &id
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/backup"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/imaging"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// ExportBackup liefert eine vollständige Sicherung des Benutzerkontos als Zip-Archiv.
+// Mit ?covers=false werden die Cover-Bilder nicht mitgesichert.
+func ExportBackup(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	withCovers := true
+	if raw := c.Query("covers"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'covers' must be true or false"})
+			return
+		}
+		withCovers = parsed
+	}
+
+	archive, err := backup.Create(db, userID)
+	if err != nil {
+		log.Printf("ERROR ExportBackup for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+
+	var readCover backup.CoverReader
+	if store, ok := c.Get("storage"); ok && withCovers {
+		readCover = func(ctx context.Context, produktID uint, contentType string) (io.ReadCloser, error) {
+			reader, _, err := store.(storage.Store).Get(ctx, coverKey(produktID, "original", contentType))
+			return reader, err
+		}
+	}
+
+	filename := fmt.Sprintf("diplodocu-backup-%s.zip", archive.ErstelltAm.Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	if err := backup.Write(c.Request.Context(), c.Writer, archive, readCover); err != nil {
+		// Die Header sind bereits gesendet, die Sicherung kann nur noch abgebrochen werden
+		log.Printf("ERROR ExportBackup for user %s: %v\n", userID, err)
+		c.Abort()
+	}
+}
+
+// restoreJobVersuche begrenzt die Versuche einer Wiederherstellung
+const restoreJobVersuche = 3
+
+// RestoreBackup spielt eine Sicherung (multipart, Feld "file") als Hintergrundjob in das Konto des
+// Benutzers ein (Antwort 202, Status und Ergebnis unter /api/jobs/:id). Haushaltsprodukte und
+// -sammlungen der Sicherung werden dem aktiven Haushalt zugeordnet.
+// Optionen (Formularfelder oder Query):
+//   - conflict: skip (Standard) behält vorhandene Sammlungseinträge, overwrite übernimmt die Sicherung
+//   - dryRun: true liefert sofort nur die Zusammenfassung, ohne etwas zu speichern
+func RestoreBackup(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	data, _, _, ok := readImportFileMax(c, backup.MaxBytes)
+	if !ok {
+		return
+	}
+	conflict := importOption(c, "conflict")
+	if conflict != "" && conflict != backup.ConflictSkip && conflict != backup.ConflictOverwrite {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'conflict' must be skip or overwrite"})
+		return
+	}
+	dryRun, ok := importDryRun(c)
+	if !ok {
+		return
+	}
+
+	archive, files, err := backup.Read(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun {
+		opts := backup.RestoreOptions{Conflict: conflict, DryRun: true, HaushaltID: activeHaushaltID(c)}
+		result, err := backup.Restore(db, userID, archive, files, opts)
+		if err != nil {
+			log.Printf("ERROR RestoreBackup preview for user %s: %v\n", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Restore preview failed"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	// Die Sicherung wird in derselben Transaktion abgelegt, in der der Job angelegt wird, damit
+	// kein Worker den Job vor der Datei sieht
+	store := c.MustGet("storage").(storage.Store)
+	payload := backup.JobPayload{WebuserID: userID, HaushaltID: activeHaushaltID(c), Conflict: conflict}
+	var job *models.Job
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		job, err = jobs.Enqueue(tx, userID, backup.JobTyp, payload, restoreJobVersuche)
+		if err != nil {
+			return err
+		}
+		return store.Put(c.Request.Context(), backup.JobDatei(job.ID), bytes.NewReader(data), int64(len(data)), "application/zip")
+	})
+	if err != nil {
+		log.Printf("ERROR RestoreBackup - Enqueue job for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start restore"})
+		return
+	}
+	jobAccepted(c, job)
+}
+
+// BackupCoverSaver speichert Cover-Bilder aus einer Sicherung. Sie werden wie beim Hochladen
+// geprüft und verkleinert.
+func BackupCoverSaver(store storage.Store) backup.CoverSaver {
+	return func(ctx context.Context, db *gorm.DB, produktID uint, image []byte) error {
+		if int64(len(image)) > CoverMaxBytes {
+			return fmt.Errorf("image exceeds %d bytes", CoverMaxBytes)
+		}
+		contentType, err := imaging.Sniff(image)
+		if err != nil {
+			return err
+		}
+		img, err := imaging.Decode(image)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		_, err = saveCover(ctx, db, store, produktID, image, contentType, img)
+		return err
+	}
+}
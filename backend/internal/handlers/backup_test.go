@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/backup"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBackupTestRouter(t *testing.T, userID string) (*gin.Engine, *gorm.DB, storage.Store) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{},
+		&models.Spiel{}, &models.Filmserie{}, &models.Sammlung{}, &models.SammlungMitglied{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}, &models.Kinderprofil{}, &models.SammlungProdukt{}, &models.Edition{},
+		&models.ProduktCode{}, &models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}, &models.Cover{}, &models.Job{}))
+	require.NoError(t, db.Create(&models.Webuser{ID: userID}).Error)
+
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Set("storage", storage.Store(store))
+		c.Set("userId", userID)
+		c.Next()
+	})
+	router.GET("/backup", ExportBackup)
+	router.POST("/backup/restore", RestoreBackup)
+	router.PUT("/produkte/:id/cover", UploadCover)
+	return router, db, store
+}
+
+func TestBackupExportAndRestore(t *testing.T) {
+	source, sourceDB, _ := setupBackupTestRouter(t, "alice")
+	produkt := models.Produkt{Name: "Momo", Art: "Buch", ErstelltVon: strPtr("alice")}
+	require.NoError(t, sourceDB.Create(&produkt).Error)
+	require.NoError(t, sourceDB.Create(&models.Buch{ProdukteID: produkt.ID, Autor: strPtr("Michael Ende")}).Error)
+	sammlung := models.Sammlung{WebuserID: "alice", Name: strPtr("Lieblingsbücher")}
+	require.NoError(t, sourceDB.Create(&sammlung).Error)
+	require.NoError(t, sourceDB.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: produkt.ID, Bewertung: intPtr(4)}).Error)
+	w := uploadCover(source, produkt.ID, "cover.png", testPNG(t, 40, 60))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	req, _ := http.NewRequest(http.MethodGet, "/backup", nil)
+	w = httptest.NewRecorder()
+	source.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	archiveData := w.Body.Bytes()
+
+	archive, files, err := backup.Read(archiveData)
+	require.NoError(t, err)
+	require.Len(t, archive.Produkte, 1)
+	require.NotNil(t, archive.Produkte[0].Cover)
+	assert.Contains(t, files, archive.Produkte[0].Cover.Datei)
+
+	target, targetDB, targetStore := setupBackupTestRouter(t, "bob")
+	w = multipartImport(target, "/backup/restore", "backup.zip", string(archiveData), map[string]string{"dryRun": "true"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result backup.Result
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 1, result.Produkte.Created)
+
+	w = multipartImport(target, "/backup/restore", "backup.zip", string(archiveData), map[string]string{"conflict": "overwrite"})
+	result = backup.Result{}
+	runQueuedJob(t, targetDB, w, backup.NewJobHandler(targetStore, BackupCoverSaver(targetStore)), &result)
+	assert.Equal(t, 1, result.Produkte.Created)
+	assert.Equal(t, 1, result.Cover)
+
+	var restored models.Produkt
+	require.NoError(t, targetDB.Where("name = ?", "Momo").First(&restored).Error)
+	var cover models.Cover
+	require.NoError(t, targetDB.First(&cover, "produkt_id = ?", restored.ID).Error)
+	assert.Equal(t, 40, cover.Breite)
+	var eintrag models.SammlungProdukt
+	require.NoError(t, targetDB.Where("produkt_id = ?", restored.ID).First(&eintrag).Error)
+	assert.Equal(t, 4, *eintrag.Bewertung)
+
+	w = multipartImport(target, "/backup/restore", "backup.zip", string(archiveData), map[string]string{"conflict": "merge"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = multipartImport(target, "/backup/restore", "backup.zip", "kein zip", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/backup?covers=vielleicht", nil)
+	w = httptest.NewRecorder()
+	source.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
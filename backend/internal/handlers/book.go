@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
@@ -19,12 +21,13 @@ type BookRequest struct {
 
 // BookResponse defines the JSON structure for book responses
 type BookResponse struct {
-	ID      uint    `json:"id"`
-	Name    string  `json:"name"`
-	Nummer  *int    `json:"nummer"`
-	Autor   *string `json:"autor"`
-	Sprache *string `json:"sprache"`
-	Genre   *string `json:"genre"`
+	ID      uint           `json:"id"`
+	Name    string         `json:"name"`
+	Nummer  *int           `json:"nummer"`
+	Autor   *string        `json:"autor"`
+	Sprache *string        `json:"sprache"`
+	Genre   *string        `json:"genre"`
+	Cover   *CoverResponse `json:"cover,omitempty"`
 }
 
 // CreateBook creates a new book with its base product
@@ -47,9 +50,11 @@ func CreateBook(c *gin.Context) {
 
 	// Create base product
 	product := models.Produkt{
-		Name:   request.Name,
-		Nummer: request.Nummer,
-		Art:    "Buch",
+		Name:        request.Name,
+		Nummer:      request.Nummer,
+		Art:         "Buch",
+		ErstelltVon: optionalUserID(c),
+		HaushaltID:  activeHaushaltID(c),
 	}
 
 	if err := tx.Create(&product).Error; err != nil {
@@ -97,7 +102,7 @@ func GetBook(c *gin.Context) {
 	id := c.Param("id")
 
 	var book models.Buch
-	if err := db.Preload("Produkt").First(&book, "produkte_id = ?", id).Error; err != nil {
+	if err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).First(&book, "produkte_id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 			return
@@ -113,6 +118,7 @@ func GetBook(c *gin.Context) {
 		Autor:   book.Autor,
 		Sprache: book.Sprache,
 		Genre:   book.Genre,
+		Cover:   loadCoverResponse(db, book.ProdukteID),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -139,7 +145,7 @@ func UpdateBook(c *gin.Context) {
 
 	// Update base product
 	var product models.Produkt
-	if err := tx.First(&product, "id = ? AND art = 'Buch'", id).Error; err != nil {
+	if err := tx.Scopes(katalogScope(c)).First(&product, "id = ? AND art = 'Buch'", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 			return
@@ -206,7 +212,7 @@ func DeleteBook(c *gin.Context) {
 	}()
 
 	// Delete will cascade to the book record due to the OnDelete:CASCADE constraint
-	if err := tx.Where("id = ? AND art = 'Buch'", id).Delete(&models.Produkt{}).Error; err != nil {
+	if err := tx.Scopes(katalogScope(c)).Where("id = ? AND art = 'Buch'", id).Delete(&models.Produkt{}).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
 		return
@@ -224,13 +230,23 @@ func DeleteBook(c *gin.Context) {
 // ListBooks retrieves all books
 func ListBooks(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	sortierung, order, ok := produktSortierung(c, db)
+	if !ok {
+		return
+	}
 
 	var books []models.Buch
-	if err := db.Preload("Produkt").Find(&books).Error; err != nil {
+	if err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).Order(order).Find(&books).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books"})
 		return
 	}
 
+	produktIDs := make([]uint, len(books))
+	for i, item := range books {
+		produktIDs[i] = item.ProdukteID
+	}
+	covers := loadCoverResponses(db, produktIDs)
+
 	response := make([]BookResponse, len(books))
 	for i, book := range books {
 		response[i] = BookResponse{
@@ -240,8 +256,15 @@ func ListBooks(c *gin.Context) {
 			Autor:   book.Autor,
 			Sprache: book.Sprache,
 			Genre:   book.Genre,
+			Cover:   covers[book.ProdukteID],
 		}
 	}
 
+	if sortierung == models.SortierungName {
+		sort.SliceStable(response, func(i, j int) bool {
+			return strings.ToLower(response[i].Name) < strings.ToLower(response[j].Name)
+		})
+	}
+
 	c.JSON(http.StatusOK, response)
 }
@@ -21,7 +21,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	require.NoError(t, err)
 
 	// Migrate the schema
-	err = db.AutoMigrate(&models.Produkt{}, &models.Buch{})
+	err = db.AutoMigrate(&models.Produkt{}, &models.Buch{}, &models.Cover{})
 	require.NoError(t, err)
 
 	return db
@@ -338,6 +338,46 @@ func intPtr(i int) *int {
 	return &i
 }
 
+func TestListBooksSortierung(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+	router := setupCollectionTestRouter(db, "alice")
+	router.GET("/books", ListBooks)
+	for _, name := range []string{"Krabat", "momo", "Die unendliche Geschichte"} {
+		product := models.Produkt{Name: name, Art: "Buch"}
+		require.NoError(t, db.Create(&product).Error)
+		require.NoError(t, db.Create(&models.Buch{ProdukteID: product.ID}).Error)
+	}
+	names := func(path string) []string {
+		w := doJSON(router, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var books []BookResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &books))
+		result := make([]string, len(books))
+		for i, book := range books {
+			result[i] = book.Name
+		}
+		return result
+	}
+
+	alphabetisch := []string{"Die unendliche Geschichte", "Krabat", "momo"}
+	neueste := []string{"Die unendliche Geschichte", "momo", "Krabat"}
+
+	// Default is alphabetical, ignoring case
+	assert.Equal(t, alphabetisch, names("/books"))
+	assert.Equal(t, neueste, names("/books?sort=neueste"))
+
+	// The user's setting applies without ?sort=
+	einstellungen := models.StandardEinstellungen("alice")
+	einstellungen.Sortierung = models.SortierungNeueste
+	require.NoError(t, db.Create(&einstellungen).Error)
+	assert.Equal(t, neueste, names("/books"))
+	assert.Equal(t, alphabetisch, names("/books?sort=name"))
+
+	w := doJSON(router, http.MethodGet, "/books?sort=zufall", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func strPtr(s string) *string {
 	return &s
 }
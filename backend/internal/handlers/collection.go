@@ -4,7 +4,9 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
@@ -14,11 +16,35 @@ import (
 // --- Structs für Requests ---
 
 type CreateSammlungRequest struct {
-	Name *string `json:"name"` // Name ist nullable im Model
+	Name           *string `json:"name"`           // Name ist nullable im Model
+	KinderprofilID *uint   `json:"kinderprofilId"` // Optional: Kinderprofil im aktiven Haushalt
 }
 
 type AddProduktRequest struct {
-	ProduktID uint `json:"produktId" binding:"required"`
+	ProduktID uint  `json:"produktId" binding:"required"`
+	EditionID *uint `json:"editionId"` // Optional: konkrete Ausgabe des Produkts
+}
+
+type SetEditionRequest struct {
+	EditionID *uint `json:"editionId"` // nil entfernt die Zuordnung
+}
+
+// SammlungListItem ist eine Sammlung mit der Rolle des Benutzers darin
+type SammlungListItem struct {
+	models.Sammlung
+	Rolle string `json:"rolle"`
+}
+
+// SammlungItemResponse beschreibt einen Eintrag einer Sammlung inkl. gewählter Edition
+type SammlungItemResponse struct {
+	Produkt models.Produkt   `json:"produkt"`
+	Edition *EditionResponse `json:"edition"`
+	Cover   *CoverResponse   `json:"cover,omitempty"`
+	// Persönliche Angaben
+	Status            *string `json:"status"`
+	Bewertung         *int    `json:"bewertung"`
+	BewertungOriginal *int    `json:"bewertungOriginal,omitempty"` // 1 bis 10, z.B. aus MyAnimeList
+	AbgeschlossenAm   *string `json:"abgeschlossenAm"`             // YYYY-MM-DD
 }
 
 // --- Handler für Sammlungen ---
@@ -52,9 +78,15 @@ func CreateSammlung(c *gin.Context) {
 	//     return
 	// }
 
+	if request.KinderprofilID != nil && !kinderprofilImHaushalt(c, db, *request.KinderprofilID) {
+		return
+	}
+
 	sammlung := models.Sammlung{
-		Name:      request.Name,
-		WebuserID: userID, // Setze die ID des eingeloggten Benutzers
+		Name:           request.Name,
+		WebuserID:      userID, // Setze die ID des eingeloggten Benutzers
+		HaushaltID:     activeHaushaltID(c),
+		KinderprofilID: request.KinderprofilID,
 	}
 
 	result := db.Create(&sammlung)
@@ -68,7 +100,7 @@ func CreateSammlung(c *gin.Context) {
 	c.JSON(http.StatusCreated, sammlung)
 }
 
-// ListUserSammlungen listet alle Sammlungen des eingeloggten Benutzers auf
+// ListUserSammlungen listet alle Sammlungen auf, auf die der eingeloggte Benutzer Zugriff hat
 func ListUserSammlungen(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	userIDraw, exists := c.Get("userId")
@@ -82,15 +114,63 @@ func ListUserSammlungen(c *gin.Context) {
 		return
 	}
 
+	sortierung, ok := listSortierung(c, db, userID)
+	if !ok {
+		return
+	}
+	order := "name asc"
+	if sortierung == models.SortierungNeueste {
+		order = "id desc"
+	}
+
+	// Eigene Sammlungen und solche, in denen der User Mitglied ist; mit aktivem Haushalt
+	// dessen Sammlungen
+	query := db.Order(order)
+	if haushaltID := activeHaushaltID(c); haushaltID != nil {
+		query = query.Where("haushalt_id = ?", *haushaltID)
+	} else {
+		mitgliedschaften := db.Model(&models.SammlungMitglied{}).Select("sammlung_id").
+			Where("webuser_id = ? AND angenommen_am IS NOT NULL", userID)
+		query = query.Where("webuser_id = ? OR id IN (?)", userID, mitgliedschaften)
+	}
 	var sammlungen []models.Sammlung
-	result := db.Where("webuser_id = ?", userID).Order("name asc").Find(&sammlungen) // Nach User filtern!
+	result := query.Find(&sammlungen)
 	if result.Error != nil {
 		log.Printf("ERROR ListUserSammlungen: %v\n", result.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collections"})
 		return
 	}
 
-	c.JSON(http.StatusOK, sammlungen)
+	var rollen []models.SammlungMitglied
+	if err := db.Where("webuser_id = ? AND angenommen_am IS NOT NULL", userID).Find(&rollen).Error; err != nil {
+		log.Printf("ERROR ListUserSammlungen - Roles: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collections"})
+		return
+	}
+	rolleBySammlung := make(map[uint]string, len(rollen))
+	for _, mitglied := range rollen {
+		rolleBySammlung[mitglied.SammlungID] = mitglied.Rolle
+	}
+
+	haushaltRolleByID, err := haushaltRollen(db, userID, sammlungen)
+	if err != nil {
+		log.Printf("ERROR ListUserSammlungen - Household roles: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collections"})
+		return
+	}
+
+	response := make([]SammlungListItem, len(sammlungen))
+	for i, sammlung := range sammlungen {
+		rolle := models.RolleBesitzer
+		if sammlung.WebuserID != userID {
+			rolle = rolleBySammlung[sammlung.ID]
+			if sammlung.HaushaltID != nil {
+				rolle = hoehereRolle(rolle, rolleAusHaushalt(haushaltRolleByID[*sammlung.HaushaltID]))
+			}
+		}
+		response[i] = SammlungListItem{Sammlung: sammlung, Rolle: rolle}
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetSammlungDetail holt eine Sammlung und optional ihre Produkte
@@ -107,31 +187,18 @@ func GetSammlungDetail(c *gin.Context) {
 		return
 	}
 
-	idStr := c.Param("id") // ID der Sammlung aus URL
-	sammlungID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBetrachter)
+	if sammlung == nil {
 		return
 	}
 
-	var sammlung models.Sammlung
-	query := db.Where("id = ? AND webuser_id = ?", uint(sammlungID), userID)
-
 	// Prüfe, ob Produkte mitgeladen werden sollen (z.B. über Query-Parameter ?include=produkte)
 	if c.Query("include") == "produkte" {
-		query = query.Preload("Produkte") // Lädt die Many-to-Many Beziehung
-	}
-
-	err = query.First(&sammlung).Error
-
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found or access denied"})
-		} else {
-			log.Printf("ERROR GetSammlungDetail ID %d: %v\n", sammlungID, err)
+		if err := db.Model(sammlung).Association("Produkte").Find(&sammlung.Produkte); err != nil {
+			log.Printf("ERROR GetSammlungDetail ID %d: %v\n", sammlung.ID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collection"})
+			return
 		}
-		return
 	}
 
 	c.JSON(http.StatusOK, sammlung) // Enthält .Produkte, wenn Preload aktiv war
@@ -151,12 +218,12 @@ func DeleteSammlung(c *gin.Context) {
 		return
 	}
 
-	idStr := c.Param("id")
-	sammlungID, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+	// Nur der Besitzer darf löschen, Mitglieder erhalten 403
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
 		return
 	}
+	sammlungID := sammlung.ID
 
 	// Transaktion für sicheres Löschen
 	tx := db.Begin()
@@ -166,8 +233,9 @@ func DeleteSammlung(c *gin.Context) {
 		}
 	}()
 
-	// Lösche die Sammlung nur, wenn sie dem User gehört
-	result := tx.Where("id = ? AND webuser_id = ?", uint(sammlungID), userID).Delete(&models.Sammlung{})
+	// Die Berechtigung hat findSammlung geprüft; Verwalter eines Haushalts dürfen auch
+	// Haushaltssammlungen löschen, die nicht ihnen gehören
+	result := tx.Where("id = ?", sammlungID).Delete(&models.Sammlung{})
 
 	if result.Error != nil {
 		tx.Rollback()
@@ -223,22 +291,15 @@ func AddProduktToSammlung(c *gin.Context) {
 	}
 	produktID := request.ProduktID // Ist uint
 
-	// 1. Prüfen, ob die Sammlung existiert und dem User gehört
-	var sammlung models.Sammlung
-	err := db.First(&sammlung, "id = ? AND webuser_id = ?", uint(sammlungID), userID).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found or access denied"})
-		} else {
-			log.Printf("ERROR AddProduktToSammlung - Find Sammlung %d: %v\n", sammlungID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find collection"})
-		}
+	// 1. Prüfen, ob die Sammlung existiert und der User sie bearbeiten darf
+	sammlung := loadSammlung(c, db, userID, uint(sammlungID), models.RolleBearbeiter)
+	if sammlung == nil {
 		return
 	}
 
 	// 2. Optional: Prüfen, ob das Produkt überhaupt existiert
 	var produkt models.Produkt
-	err = db.First(&produkt, produktID).Error
+	err := db.Scopes(katalogScope(c)).First(&produkt, produktID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Product to add does not exist"})
@@ -249,9 +310,14 @@ func AddProduktToSammlung(c *gin.Context) {
 		return
 	}
 
+	// 2b. Optional: Prüfen, ob die Edition zum Produkt gehört
+	if request.EditionID != nil && !editionGehoertZuProdukt(c, db, *request.EditionID, produktID) {
+		return
+	}
+
 	// 3. Verknüpfung hinzufügen
 	// GORM ist oft intelligent genug, Duplikate in der Verknüpfungstabelle zu ignorieren
-	err = db.Model(&sammlung).Association("Produkte").Append(&models.Produkt{ID: produktID})
+	err = db.Model(sammlung).Association("Produkte").Append(&models.Produkt{ID: produktID})
 	if err != nil {
 		log.Printf("ERROR AddProduktToSammlung - Append Association S:%d P:%d: %v\n", sammlungID, produktID, err)
 		// Möglicher Fehler: DB-Constraint verletzt (obwohl Append oft stillschweigend fehlschlägt bei Duplikaten)
@@ -259,6 +325,18 @@ func AddProduktToSammlung(c *gin.Context) {
 		return
 	}
 
+	// 4. Edition am Eintrag vermerken
+	if request.EditionID != nil {
+		err = db.Model(&models.SammlungProdukt{}).
+			Where("sammlung_id = ? AND produkt_id = ?", sammlung.ID, produktID).
+			Update("edition_id", *request.EditionID).Error
+		if err != nil {
+			log.Printf("ERROR AddProduktToSammlung - Set Edition S:%d P:%d: %v\n", sammlungID, produktID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set edition"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"status": "Product added to collection"})
 }
 
@@ -289,21 +367,14 @@ func RemoveProduktFromSammlung(c *gin.Context) {
 		return
 	}
 
-	// 1. Prüfen, ob die Sammlung existiert und dem User gehört
-	var sammlung models.Sammlung
-	err := db.First(&sammlung, "id = ? AND webuser_id = ?", uint(sammlungID), userID).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found or access denied"})
-		} else {
-			log.Printf("ERROR RemoveProduktFromSammlung - Find Sammlung %d: %v\n", sammlungID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find collection"})
-		}
+	// 1. Prüfen, ob die Sammlung existiert und der User sie bearbeiten darf
+	sammlung := loadSammlung(c, db, userID, uint(sammlungID), models.RolleBearbeiter)
+	if sammlung == nil {
 		return
 	}
 
 	// 2. Verknüpfung löschen
-	err = db.Model(&sammlung).Association("Produkte").Delete(&models.Produkt{ID: uint(produktID)})
+	err := db.Model(sammlung).Association("Produkte").Delete(&models.Produkt{ID: uint(produktID)})
 	if err != nil {
 		log.Printf("ERROR RemoveProduktFromSammlung - Delete Association S:%d P:%d: %v\n", sammlungID, produktID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove product from collection"})
@@ -315,3 +386,256 @@ func RemoveProduktFromSammlung(c *gin.Context) {
 
 	c.Status(http.StatusNoContent) // Erfolg
 }
+
+// editionGehoertZuProdukt prüft, ob eine Edition existiert und zum Produkt gehört.
+// Andernfalls wird direkt mit 400 geantwortet.
+func editionGehoertZuProdukt(c *gin.Context, db *gorm.DB, editionID uint, produktID uint) bool {
+	var count int64
+	if err := db.Model(&models.Edition{}).Where("id = ? AND produkt_id = ?", editionID, produktID).Count(&count).Error; err != nil {
+		log.Printf("ERROR editionGehoertZuProdukt E:%d P:%d: %v\n", editionID, produktID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check edition"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Edition does not belong to this product"})
+		return false
+	}
+	return true
+}
+
+// sammlungRolle ermittelt die Rolle des Benutzers in einer Sammlung ("" = kein Zugriff).
+// Offene Einladungen gewähren noch keinen Zugriff. Bei Haushaltssammlungen zählt zusätzlich
+// die Rolle im Haushalt; es gilt die höhere.
+func sammlungRolle(db *gorm.DB, sammlung models.Sammlung, userID string) (string, error) {
+	if sammlung.WebuserID == userID {
+		return models.RolleBesitzer, nil
+	}
+	rolle := ""
+	var mitglieder []models.SammlungMitglied
+	err := db.Where("sammlung_id = ? AND webuser_id = ? AND angenommen_am IS NOT NULL", sammlung.ID, userID).
+		Limit(1).Find(&mitglieder).Error
+	if err != nil {
+		return "", err
+	}
+	if len(mitglieder) > 0 {
+		rolle = mitglieder[0].Rolle
+	}
+	if sammlung.HaushaltID != nil {
+		imHaushalt, err := haushaltRolle(db, *sammlung.HaushaltID, userID)
+		if err != nil {
+			return "", err
+		}
+		rolle = hoehereRolle(rolle, rolleAusHaushalt(imHaushalt))
+	}
+	return rolle, nil
+}
+
+// rolleAusHaushalt bildet eine Haushaltsrolle auf die Rolle in dessen Sammlungen ab
+func rolleAusHaushalt(rolle string) string {
+	switch rolle {
+	case models.HaushaltVerwalter:
+		return models.RolleBesitzer
+	case models.HaushaltMitglied:
+		return models.RolleBearbeiter
+	default:
+		return ""
+	}
+}
+
+// hoehereRolle liefert die umfassendere der beiden Sammlungsrollen
+func hoehereRolle(a, b string) string {
+	if b != "" && (a == "" || models.RolleErlaubt(b, a)) {
+		return b
+	}
+	return a
+}
+
+// haushaltRollen lädt die Rollen des Benutzers in den Haushalten der angegebenen Sammlungen
+func haushaltRollen(db *gorm.DB, userID string, sammlungen []models.Sammlung) (map[uint]string, error) {
+	rollen := make(map[uint]string)
+	var haushaltIDs []uint
+	for _, sammlung := range sammlungen {
+		if sammlung.HaushaltID != nil {
+			haushaltIDs = append(haushaltIDs, *sammlung.HaushaltID)
+		}
+	}
+	if len(haushaltIDs) == 0 {
+		return rollen, nil
+	}
+
+	var mitgliedschaften []models.HaushaltMitgliedschaft
+	if err := db.Where("webuser_id = ? AND haushalt_id IN ?", userID, haushaltIDs).Find(&mitgliedschaften).Error; err != nil {
+		return nil, err
+	}
+	for _, mitgliedschaft := range mitgliedschaften {
+		rollen[mitgliedschaft.HaushaltID] = mitgliedschaft.Rolle
+	}
+	return rollen, nil
+}
+
+// findSammlung lädt eine Sammlung anhand des URL-Parameters und prüft, ob der Benutzer mindestens
+// die Rolle minRolle hat. Ohne Zugriff wird mit 404 geantwortet, damit fremde Sammlungen nicht
+// erkennbar sind; reicht nur die Rolle nicht, mit 403. Im Fehlerfall wird nil zurückgegeben.
+func findSammlung(c *gin.Context, db *gorm.DB, userID string, param string, minRolle string) *models.Sammlung {
+	return findSammlungByID(c, db, userID, c.Param(param), minRolle)
+}
+
+// findSammlungByID wie findSammlung, die ID stammt aber z.B. aus einem Formularfeld
+func findSammlungByID(c *gin.Context, db *gorm.DB, userID string, rawID string, minRolle string) *models.Sammlung {
+	sammlungID, err := strconv.ParseUint(rawID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+		return nil
+	}
+	return loadSammlung(c, db, userID, uint(sammlungID), minRolle)
+}
+
+// loadSammlung wie findSammlung für eine bereits eingelesene ID
+func loadSammlung(c *gin.Context, db *gorm.DB, userID string, sammlungID uint, minRolle string) *models.Sammlung {
+	var sammlung models.Sammlung
+	rolle := ""
+	err := db.First(&sammlung, sammlungID).Error
+	if err == nil {
+		rolle, err = sammlungRolle(db, sammlung, userID)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("ERROR findSammlung %d: %v\n", sammlungID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find collection"})
+		return nil
+	}
+	if rolle == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found or access denied"})
+		return nil
+	}
+	if !models.RolleErlaubt(rolle, minRolle) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role in this collection does not allow this action"})
+		return nil
+	}
+	return &sammlung
+}
+
+// ListSammlungItems listet die Produkte einer Sammlung mit der jeweils gewählten Edition
+func ListSammlungItems(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlungID, err := strconv.ParseUint(c.Param("sammlungId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+		return
+	}
+
+	sammlung := loadSammlung(c, db, userID, uint(sammlungID), models.RolleBetrachter)
+	if sammlung == nil {
+		return
+	}
+	sortierung, ok := listSortierung(c, db, userID)
+	if !ok {
+		return
+	}
+	order := "produkt_id asc"
+	if sortierung == models.SortierungNeueste {
+		order = "produkt_id desc"
+	}
+
+	var eintraege []models.SammlungProdukt
+	if err := db.Where("sammlung_id = ?", sammlung.ID).Preload("Edition").Order(order).Find(&eintraege).Error; err != nil {
+		log.Printf("ERROR ListSammlungItems - Find Eintraege %d: %v\n", sammlungID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collection items"})
+		return
+	}
+
+	produktIDs := make([]uint, len(eintraege))
+	for i, eintrag := range eintraege {
+		produktIDs[i] = eintrag.ProduktID
+	}
+	var produkte []models.Produkt
+	if err := db.Where("id IN ?", produktIDs).Find(&produkte).Error; err != nil {
+		log.Printf("ERROR ListSammlungItems - Find Produkte %d: %v\n", sammlungID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collection items"})
+		return
+	}
+	produktByID := make(map[uint]models.Produkt, len(produkte))
+	for _, produkt := range produkte {
+		produktByID[produkt.ID] = produkt
+	}
+
+	covers := loadCoverResponses(db, produktIDs)
+
+	response := make([]SammlungItemResponse, 0, len(eintraege))
+	for _, eintrag := range eintraege {
+		item := SammlungItemResponse{
+			Produkt:           produktByID[eintrag.ProduktID],
+			Cover:             covers[eintrag.ProduktID],
+			Status:            eintrag.Status,
+			Bewertung:         eintrag.Bewertung,
+			BewertungOriginal: eintrag.BewertungOriginal,
+			AbgeschlossenAm:   formatDatum(eintrag.AbgeschlossenAm),
+		}
+		if eintrag.Edition != nil {
+			edition := toEditionResponse(*eintrag.Edition)
+			item.Edition = &edition
+		}
+		response = append(response, item)
+	}
+	if sortierung == models.SortierungName {
+		sort.SliceStable(response, func(i, j int) bool {
+			return strings.ToLower(response[i].Produkt.Name) < strings.ToLower(response[j].Produkt.Name)
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SetSammlungItemEdition ändert die Edition eines Produkts in einer Sammlung
+func SetSammlungItemEdition(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlungID, errS := strconv.ParseUint(c.Param("sammlungId"), 10, 32)
+	if errS != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+		return
+	}
+	produktID, errP := strconv.ParseUint(c.Param("produktId"), 10, 32)
+	if errP != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID format"})
+		return
+	}
+
+	var request SetEditionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	sammlung := loadSammlung(c, db, userID, uint(sammlungID), models.RolleBearbeiter)
+	if sammlung == nil {
+		return
+	}
+
+	if request.EditionID != nil && !editionGehoertZuProdukt(c, db, *request.EditionID, uint(produktID)) {
+		return
+	}
+
+	result := db.Model(&models.SammlungProdukt{}).
+		Where("sammlung_id = ? AND produkt_id = ?", sammlung.ID, uint(produktID)).
+		Update("edition_id", request.EditionID)
+	if result.Error != nil {
+		log.Printf("ERROR SetSammlungItemEdition S:%d P:%d: %v\n", sammlungID, produktID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set edition"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product is not part of this collection"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Edition updated"})
+}
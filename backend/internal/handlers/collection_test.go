@@ -19,7 +19,7 @@ func setupCollectionTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.Webuser{}, &models.Sammlung{}, &models.Produkt{})
+	err = db.AutoMigrate(&models.Webuser{}, &models.Sammlung{}, &models.SammlungMitglied{}, &models.Produkt{}, &models.Benutzereinstellungen{})
 	require.NoError(t, err)
 
 	return db
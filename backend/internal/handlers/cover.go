@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/imaging"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CoverMaxBytes begrenzt die Größe hochgeladener Cover-Bilder (wird in main aus COVER_MAX_BYTES gesetzt)
+var CoverMaxBytes int64 = 5 << 20
+
+// coverVersionLength ist die Länge des Hash-Präfixes, das als Version in den Bild-URLs steht
+const coverVersionLength = 12
+
+// coverExtensions bildet die erlaubten Bildtypen auf die Dateiendung im Storage ab
+var coverExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// CoverResponse enthält die URLs des Originals und der Vorschaubilder.
+// Die URLs sind versioniert und dürfen daher dauerhaft gecached werden.
+type CoverResponse struct {
+	Original string `json:"original"`
+	Small    string `json:"small"`
+	Medium   string `json:"medium"`
+}
+
+func coverURL(cover models.Cover, size string) string {
+	return fmt.Sprintf("/api/produkte/%d/cover/%s?v=%s", cover.ProduktID, size, cover.Hash[:coverVersionLength])
+}
+
+func toCoverResponse(cover models.Cover) *CoverResponse {
+	return &CoverResponse{
+		Original: coverURL(cover, "original"),
+		Small:    coverURL(cover, "small"),
+		Medium:   coverURL(cover, "medium"),
+	}
+}
+
+// coverKey liefert den Storage-Schlüssel einer Bildgröße. Vorschaubilder sind immer JPEGs.
+func coverKey(produktID uint, size string, contentType string) string {
+	if size == "original" {
+		return fmt.Sprintf("covers/%d/original%s", produktID, coverExtensions[contentType])
+	}
+	return fmt.Sprintf("covers/%d/%s.jpg", produktID, size)
+}
+
+// loadCoverResponses lädt die Cover-URLs für mehrere Produkte.
+// Fehler werden nur protokolliert, damit Listen auch ohne Cover ausgeliefert werden können.
+func loadCoverResponses(db *gorm.DB, produktIDs []uint) map[uint]*CoverResponse {
+	result := make(map[uint]*CoverResponse)
+	if len(produktIDs) == 0 {
+		return result
+	}
+	var covers []models.Cover
+	if err := db.Where("produkt_id IN ?", produktIDs).Find(&covers).Error; err != nil {
+		log.Printf("ERROR loadCoverResponses: %v\n", err)
+		return result
+	}
+	for _, cover := range covers {
+		result[cover.ProduktID] = toCoverResponse(cover)
+	}
+	return result
+}
+
+// loadCoverResponse lädt die Cover-URLs eines einzelnen Produkts (nil, wenn kein Cover existiert)
+func loadCoverResponse(db *gorm.DB, produktID uint) *CoverResponse {
+	return loadCoverResponses(db, []uint{produktID})[produktID]
+}
+
+// --- Handler für Cover-Bilder ---
+
+// UploadCover lädt das Cover eines Produkts hoch (multipart, Feld "file") und ersetzt ein vorhandenes
+func UploadCover(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	store := c.MustGet("storage").(storage.Store)
+
+	produkt := findProdukt(c, db, "id")
+	if produkt == nil {
+		return
+	}
+
+	// Zusätzlicher Spielraum für die Multipart-Kopfzeilen
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, CoverMaxBytes+64<<10)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image must not exceed %d bytes", CoverMaxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing image file (form field 'file')"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, CoverMaxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	if int64(len(data)) > CoverMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image must not exceed %d bytes", CoverMaxBytes)})
+		return
+	}
+
+	// Der Typ wird am Inhalt erkannt, der vom Client angegebene Content-Type wird ignoriert
+	contentType, err := imaging.Sniff(data)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cover, err := saveCover(c.Request.Context(), db, store, produkt.ID, data, contentType, img)
+	if err != nil {
+		log.Printf("ERROR UploadCover - Product %d: %v\n", produkt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save cover"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCoverResponse(*cover))
+}
+
+// saveCover erzeugt die Vorschaubilder, legt alle Größen im Storage ab und speichert die
+// Metadaten. Das Bild muss bereits geprüft sein (imaging.Sniff und imaging.Decode).
+func saveCover(ctx context.Context, db *gorm.DB, store storage.Store, produktID uint, data []byte, contentType string, img image.Image) (*models.Cover, error) {
+	thumbnails := make(map[string][]byte, len(imaging.ThumbnailSizes))
+	for size, edge := range imaging.ThumbnailSizes {
+		encoded, err := imaging.EncodeJPEG(imaging.Thumbnail(img, edge))
+		if err != nil {
+			return nil, fmt.Errorf("thumbnail %s: %w", size, err)
+		}
+		thumbnails[size] = encoded
+	}
+
+	if err := store.Put(ctx, coverKey(produktID, "original", contentType), bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("store original: %w", err)
+	}
+	for size, encoded := range thumbnails {
+		if err := store.Put(ctx, coverKey(produktID, size, ""), bytes.NewReader(encoded), int64(len(encoded)), "image/jpeg"); err != nil {
+			return nil, fmt.Errorf("store %s: %w", size, err)
+		}
+	}
+
+	var previous models.Cover
+	hadPrevious := db.Where("produkt_id = ?", produktID).Limit(1).Find(&previous).RowsAffected > 0
+
+	sum := sha256.Sum256(data)
+	bounds := img.Bounds()
+	cover := models.Cover{
+		ProduktID:      produktID,
+		ContentType:    contentType,
+		Groesse:        int64(len(data)),
+		Breite:         bounds.Dx(),
+		Hoehe:          bounds.Dy(),
+		Hash:           hex.EncodeToString(sum[:]),
+		AktualisiertAm: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cover).Error; err != nil {
+		return nil, fmt.Errorf("save cover: %w", err)
+	}
+
+	// Ein Original mit anderem Typ liegt unter anderem Schlüssel und wird entfernt
+	if hadPrevious && previous.ContentType != contentType {
+		if err := store.Delete(ctx, coverKey(produktID, "original", previous.ContentType)); err != nil {
+			log.Printf("WARN saveCover - Delete old original for product %d: %v\n", produktID, err)
+		}
+	}
+	return &cover, nil
+}
+
+// DeleteCover entfernt das Cover eines Produkts
+func DeleteCover(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	store := c.MustGet("storage").(storage.Store)
+
+	produkt := findProdukt(c, db, "id")
+	if produkt == nil {
+		return
+	}
+
+	var cover models.Cover
+	if err := db.First(&cover, "produkt_id = ?", produkt.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cover"})
+		}
+		return
+	}
+	if err := db.Delete(&cover).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cover"})
+		return
+	}
+
+	// Verwaiste Dateien sind unkritisch, Fehler werden daher nur protokolliert
+	keys := []string{coverKey(produkt.ID, "original", cover.ContentType)}
+	for size := range imaging.ThumbnailSizes {
+		keys = append(keys, coverKey(produkt.ID, size, ""))
+	}
+	for _, key := range keys {
+		if err := store.Delete(c.Request.Context(), key); err != nil {
+			log.Printf("WARN DeleteCover - Delete %s: %v\n", key, err)
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// coverSichtbar prüft, ob das Cover eines Produkts ausgeliefert werden darf: Produkte des globalen
+// Katalogs sind öffentlich, Haushaltsprodukte nur für angemeldete Mitglieder des aktiven
+// Haushalts oder über eine gültige Freigabe (?freigabe=<token>) einer Sammlung mit dem Produkt.
+// global ist true, wenn das Produkt zum globalen Katalog gehört.
+func coverSichtbar(c *gin.Context, db *gorm.DB, produktID uint) (sichtbar bool, global bool, err error) {
+	var produkte []models.Produkt
+	if err := db.Scopes(katalogScope(c)).Where("id = ?", produktID).Limit(1).Find(&produkte).Error; err != nil {
+		return false, false, err
+	}
+	if len(produkte) > 0 {
+		return true, produkte[0].HaushaltID == nil, nil
+	}
+
+	token := c.Query("freigabe")
+	if token == "" {
+		return false, false, nil
+	}
+	var freigaben []models.Freigabe
+	err = db.Where("token = ? AND sammlung_id IN (?)", token,
+		db.Model(&models.SammlungProdukt{}).Select("sammlung_id").Where("produkt_id = ?", produktID)).
+		Limit(1).Find(&freigaben).Error
+	if err != nil {
+		return false, false, err
+	}
+	return len(freigaben) > 0 && freigaben[0].Gueltig(time.Now().UTC()), false, nil
+}
+
+// GetCoverImage liefert ein Cover-Bild (original, small oder medium) mit Caching-Headern aus.
+// Die Route ist öffentlich, damit Bilder direkt in <img>-Tags verwendet werden können; Cover von
+// Haushaltsprodukten gibt es nur unter den Bedingungen von coverSichtbar.
+func GetCoverImage(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	store := c.MustGet("storage").(storage.Store)
+
+	size := c.Param("size")
+	if _, ok := imaging.ThumbnailSizes[size]; !ok && size != "original" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cover size, allowed are original, small and medium"})
+		return
+	}
+	produktID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID format"})
+		return
+	}
+
+	sichtbar, global, err := coverSichtbar(c, db, uint(produktID))
+	if err != nil {
+		log.Printf("ERROR GetCoverImage - Check access to product %d: %v\n", produktID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cover"})
+		return
+	}
+	if !sichtbar {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		return
+	}
+
+	var cover models.Cover
+	if err := db.First(&cover, "produkt_id = ?", uint(produktID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cover"})
+		}
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%s"`, cover.Hash[:coverVersionLength], size)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", cover.AktualisiertAm.UTC().Format(http.TimeFormat))
+	// Cover von Haushaltsprodukten dürfen nicht in geteilten Caches landen
+	cacheScope := "public"
+	if !global {
+		cacheScope = "private"
+	}
+	if c.Query("v") == cover.Hash[:coverVersionLength] {
+		// Versionierte URLs ändern sich bei jedem Upload
+		c.Header("Cache-Control", cacheScope+", max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", cacheScope+", no-cache")
+	}
+
+	if notModified(c, etag, cover.AktualisiertAm) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	reader, object, err := store.Get(c.Request.Context(), coverKey(cover.ProduktID, size, cover.ContentType))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+			return
+		}
+		log.Printf("ERROR GetCoverImage - Load %s for product %d: %v\n", size, cover.ProduktID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cover"})
+		return
+	}
+	defer reader.Close()
+
+	contentType := "image/jpeg"
+	if size == "original" {
+		contentType = cover.ContentType
+	}
+	c.DataFromReader(http.StatusOK, object.Size, contentType, reader, nil)
+}
+
+// notModified wertet If-None-Match bzw. If-Modified-Since aus
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil {
+		return !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
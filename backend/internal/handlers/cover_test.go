@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupCoverTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Produkt{}, &models.Buch{}, &models.Cover{}))
+
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Set("storage", storage.Store(store))
+		c.Next()
+	})
+	router.PUT("/produkte/:id/cover", UploadCover)
+	router.DELETE("/produkte/:id/cover", DeleteCover)
+	router.GET("/api/produkte/:id/cover/:size", GetCoverImage)
+	router.GET("/books", ListBooks)
+	return router, db
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func uploadCover(router *gin.Engine, produktID uint, filename string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	_, _ = part.Write(data)
+	_ = writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("/produkte/%d/cover", produktID), &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadCover(t *testing.T) {
+	router, db := setupCoverTestRouter(t)
+	produkt := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+
+	w := uploadCover(router, produkt.ID, "cover.png", testPNG(t, 300, 600))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response CoverResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response.Small, fmt.Sprintf("/api/produkte/%d/cover/small?v=", produkt.ID))
+
+	var cover models.Cover
+	require.NoError(t, db.First(&cover, "produkt_id = ?", produkt.ID).Error)
+	assert.Equal(t, "image/png", cover.ContentType)
+	assert.Equal(t, 300, cover.Breite)
+	assert.Equal(t, 600, cover.Hoehe)
+
+	// Thumbnail is a JPEG fitted into 160x160
+	req, _ := http.NewRequest(http.MethodGet, response.Small, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	thumb, _, err := image.DecodeConfig(w.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 80, thumb.Width)
+	assert.Equal(t, 160, thumb.Height)
+
+	// Conditional request
+	req, _ = http.NewRequest(http.MethodGet, response.Small, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	// Original keeps its type
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/produkte/%d/cover/original", produkt.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, no-cache", w.Header().Get("Cache-Control"))
+}
+
+func TestUploadCoverValidation(t *testing.T) {
+	router, db := setupCoverTestRouter(t)
+	produkt := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+
+	// Content is sniffed, the file name does not matter
+	w := uploadCover(router, produkt.ID, "cover.png", []byte("<html><script>alert(1)</script></html>"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = uploadCover(router, produkt.ID, "cover.png", []byte("\x89PNG\r\n\x1a\nbroken"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = uploadCover(router, 9999, "cover.png", testPNG(t, 10, 10))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	original := CoverMaxBytes
+	CoverMaxBytes = 100
+	defer func() { CoverMaxBytes = original }()
+	w = uploadCover(router, produkt.ID, "cover.png", testPNG(t, 200, 200))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var count int64
+	db.Model(&models.Cover{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestDeleteCover(t *testing.T) {
+	router, db := setupCoverTestRouter(t)
+	produkt := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+
+	require.Equal(t, http.StatusOK, uploadCover(router, produkt.ID, "cover.png", testPNG(t, 20, 20)).Code)
+
+	w := doJSON(router, http.MethodDelete, fmt.Sprintf("/produkte/%d/cover", produkt.ID), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/api/produkte/%d/cover/medium", produkt.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/produkte/%d/cover", produkt.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetCoverImageInvalidSize(t *testing.T) {
+	router, _ := setupCoverTestRouter(t)
+	w := doJSON(router, http.MethodGet, "/api/produkte/1/cover/huge", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListBooksIncludesCover(t *testing.T) {
+	router, db := setupCoverTestRouter(t)
+	mitCover := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&mitCover).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: mitCover.ID}).Error)
+	ohneCover := models.Produkt{Name: "Krabat", Art: "Buch"}
+	require.NoError(t, db.Create(&ohneCover).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: ohneCover.ID}).Error)
+
+	require.Equal(t, http.StatusOK, uploadCover(router, mitCover.ID, "cover.png", testPNG(t, 20, 20)).Code)
+
+	w := doJSON(router, http.MethodGet, "/books", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var books []BookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &books))
+	require.Len(t, books, 2)
+	// Sorted by name: Krabat, Momo
+	assert.Nil(t, books[0].Cover)
+	require.NotNil(t, books[1].Cover)
+	assert.Contains(t, books[1].Cover.Medium, "/cover/medium?v=")
+}
+
+func TestGetCoverImageOfHouseholdProduct(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Set("storage", storage.Store(store))
+		c.Next()
+	})
+	router.PUT("/produkte/:id/cover", UploadCover)
+	router.GET("/api/produkte/:id/cover/:size", GetCoverImage)
+	router.GET("/public/sammlungen/:token", GetPublicSammlung)
+
+	produkt := models.Produkt{Name: "Familienalbum", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: produkt.ID}).Error)
+	w := uploadCover(router, produkt.ID, "cover.png", testPNG(t, 10, 10))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	haushalt := models.Haushalt{Name: "Familie Meier"}
+	require.NoError(t, db.Create(&haushalt).Error)
+	require.NoError(t, db.Model(&produkt).Update("haushalt_id", haushalt.ID).Error)
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	path := fmt.Sprintf("/api/produkte/%d/cover/small", produkt.ID)
+	assert.Equal(t, http.StatusNotFound, get(path).Code, "household covers are not public")
+	assert.Equal(t, http.StatusNotFound, get(path+"?freigabe=falsch").Code)
+
+	// A share link of a collection containing the product grants access
+	require.NoError(t, db.Create(&models.Webuser{ID: "anna"}).Error)
+	sammlung := models.Sammlung{WebuserID: "anna"}
+	require.NoError(t, db.Create(&sammlung).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: produkt.ID}).Error)
+	require.NoError(t, db.Create(&models.Freigabe{SammlungID: sammlung.ID, Token: "geteilt", ErstelltAm: time.Now()}).Error)
+
+	w = get("/public/sammlungen/geteilt")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var shared PublicSammlungResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	require.Len(t, shared.Items, 1)
+	require.NotNil(t, shared.Items[0].Produkt.Cover)
+	assert.Contains(t, shared.Items[0].Produkt.Cover.Small, "&freigabe=geteilt")
+
+	w = get(shared.Items[0].Produkt.Cover.Small)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+}
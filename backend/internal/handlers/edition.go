@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// --- Structs für Editionen ---
+
+// EditionRequest definiert die JSON-Struktur für Editions-Anfragen
+type EditionRequest struct {
+	Format            string  `json:"format" binding:"required"`
+	Verlag            *string `json:"verlag"`
+	Erscheinungsdatum *string `json:"erscheinungsdatum"` // Format YYYY-MM-DD
+	Code              *string `json:"code"`              // EAN bzw. ISBN, wird geprüft und normalisiert
+	Sprache           *string `json:"sprache"`
+}
+
+// EditionResponse definiert die JSON-Struktur für Editions-Antworten
+type EditionResponse struct {
+	ID                uint    `json:"id"`
+	ProduktID         uint    `json:"produktId"`
+	Format            string  `json:"format"`
+	Verlag            *string `json:"verlag"`
+	Erscheinungsdatum *string `json:"erscheinungsdatum"`
+	Code              *string `json:"code"`
+	Sprache           *string `json:"sprache"`
+}
+
+// --- Hilfsfunktionen ---
+
+func toEditionResponse(edition models.Edition) EditionResponse {
+	return EditionResponse{
+		ID:                edition.ID,
+		ProduktID:         edition.ProduktID,
+		Format:            edition.Format,
+		Verlag:            edition.Verlag,
+		Erscheinungsdatum: formatDatum(edition.Erscheinungsdatum),
+		Code:              edition.Code,
+		Sprache:           edition.Sprache,
+	}
+}
+
+// findProdukt lädt ein Produkt anhand eines URL-Parameters.
+// Bei Fehlern wird direkt geantwortet und nil zurückgegeben.
+func findProdukt(c *gin.Context, db *gorm.DB, param string) *models.Produkt {
+	produktID, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID format"})
+		return nil
+	}
+	var produkt models.Produkt
+	if err := db.Scopes(katalogScope(c)).First(&produkt, uint(produktID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		} else {
+			log.Printf("Error retrieving product ID %d: %v", produktID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve product"})
+		}
+		return nil
+	}
+	return &produkt
+}
+
+// findEdition lädt eine Edition anhand der ID aus der URL. Editionen von Produkten, die im
+// aktuellen Kontext nicht sichtbar sind, werden wie nicht vorhandene behandelt.
+func findEdition(c *gin.Context, db *gorm.DB) *models.Edition {
+	editionID, err := strconv.ParseUint(c.Param("editionId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid edition ID format"})
+		return nil
+	}
+	var edition models.Edition
+	if err := db.Preload("Produkt").Where("produkt_id IN (?)", sichtbareProdukte(c, db)).First(&edition, uint(editionID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Edition not found"})
+		} else {
+			log.Printf("Error retrieving edition ID %d: %v", editionID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve edition"})
+		}
+		return nil
+	}
+	return &edition
+}
+
+// applyEditionRequest validiert die Anfrage und überträgt sie auf die Edition
+func applyEditionRequest(edition *models.Edition, art string, request EditionRequest) error {
+	format, err := utils.ValidateEditionFormat(art, request.Format)
+	if err != nil {
+		return err
+	}
+	erscheinungsdatum, err := parseDatum(request.Erscheinungsdatum)
+	if err != nil {
+		return err
+	}
+
+	var code *string
+	if request.Code != nil && *request.Code != "" {
+		normalized, _, err := utils.NormalizeCodeForArt(art, *request.Code)
+		if err != nil {
+			return err
+		}
+		code = &normalized
+	}
+
+	edition.Format = format
+	edition.Verlag = request.Verlag
+	edition.Erscheinungsdatum = erscheinungsdatum
+	edition.Code = code
+	edition.Sprache = request.Sprache
+	return nil
+}
+
+// --- Handler für Editionen ---
+
+// ListEditionen listet alle Editionen eines Produkts
+func ListEditionen(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	produkt := findProdukt(c, db, "id")
+	if produkt == nil {
+		return
+	}
+
+	var editionen []models.Edition
+	if err := db.Where("produkt_id = ?", produkt.ID).Order("id asc").Find(&editionen).Error; err != nil {
+		log.Printf("Error retrieving editions for product ID %d: %v", produkt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve editions"})
+		return
+	}
+
+	response := make([]EditionResponse, len(editionen))
+	for i, edition := range editionen {
+		response[i] = toEditionResponse(edition)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateEdition legt eine neue Edition für ein Produkt an
+func CreateEdition(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request EditionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	produkt := findProdukt(c, db, "id")
+	if produkt == nil {
+		return
+	}
+
+	edition := models.Edition{ProduktID: produkt.ID}
+	if err := applyEditionRequest(&edition, produkt.Art, request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.Create(&edition).Error; err != nil {
+		log.Printf("Error creating edition for product ID %d: %v", produkt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create edition"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toEditionResponse(edition))
+}
+
+// UpdateEdition aktualisiert eine bestehende Edition
+func UpdateEdition(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request EditionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	edition := findEdition(c, db)
+	if edition == nil {
+		return
+	}
+
+	if err := applyEditionRequest(edition, edition.Produkt.Art, request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.Omit("Produkt").Save(edition).Error; err != nil {
+		log.Printf("Error updating edition ID %d: %v", edition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update edition"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toEditionResponse(*edition))
+}
+
+// DeleteEdition löscht eine Edition; Sammlungseinträge verlieren nur den Verweis
+func DeleteEdition(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	edition := findEdition(c, db)
+	if edition == nil {
+		return
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Verweise explizit lösen, falls die Datenbank kein ON DELETE SET NULL durchsetzt (z.B. SQLite)
+	if err := tx.Model(&models.SammlungProdukt{}).Where("edition_id = ?", edition.ID).Update("edition_id", nil).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error detaching edition ID %d from collections: %v", edition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete edition"})
+		return
+	}
+	if err := tx.Delete(&models.Edition{}, edition.ID).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error deleting edition ID %d: %v", edition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete edition"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing deletion of edition ID %d: %v", edition.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupEditionTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Sammlung{}, &models.SammlungMitglied{},
+		&models.Edition{}, &models.SammlungProdukt{}, &models.Cover{}, &models.Benutzereinstellungen{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestCreateEdition(t *testing.T) {
+	db := setupEditionTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/produkte/:id/editionen", CreateEdition)
+
+	buch := models.Produkt{Name: "Der Hobbit", Art: "Buch"}
+	db.Create(&buch)
+	spiel := models.Produkt{Name: "Zelda", Art: "Spiel"}
+	db.Create(&spiel)
+
+	tests := []struct {
+		name           string
+		produktID      uint
+		requestBody    interface{}
+		expectedStatus int
+		checkResponse  func(t *testing.T, body []byte)
+	}{
+		{
+			name:      "book hardcover with all fields",
+			produktID: buch.ID,
+			requestBody: EditionRequest{
+				Format:            "hardcover",
+				Verlag:            strPtr("Klett-Cotta"),
+				Erscheinungsdatum: strPtr("2012-10-01"),
+				Code:              strPtr("0-306-40615-2"),
+				Sprache:           strPtr("Deutsch"),
+			},
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response EditionResponse
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, "Hardcover", response.Format) // canonical spelling
+				assert.Equal(t, "Klett-Cotta", *response.Verlag)
+				assert.Equal(t, "2012-10-01", *response.Erscheinungsdatum)
+				assert.Equal(t, buch.ID, response.ProduktID)
+				assert.Equal(t, "9780306406157", *response.Code) // normalized to ISBN-13
+			},
+		},
+		{
+			name:           "invalid isbn checksum",
+			produktID:      buch.ID,
+			requestBody:    EditionRequest{Format: "Paperback", Code: strPtr("0-306-40615-3")},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "game digital",
+			produktID:      spiel.ID,
+			requestBody:    EditionRequest{Format: "Digital"},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "format not valid for product type",
+			produktID:      spiel.ID,
+			requestBody:    EditionRequest{Format: "Blu-ray"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid release date",
+			produktID:      buch.ID,
+			requestBody:    EditionRequest{Format: "E-Book", Erscheinungsdatum: strPtr("yesterday")},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing format",
+			produktID:      buch.ID,
+			requestBody:    map[string]interface{}{"verlag": "Someone"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown product",
+			produktID:      999,
+			requestBody:    EditionRequest{Format: "Paperback"},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(router, http.MethodPost, fmt.Sprintf("/produkte/%d/editionen", tt.produktID), tt.requestBody)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestSammlungItemEdition(t *testing.T) {
+	db := setupEditionTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.GET("/sammlung/:sammlungId/produkte", ListSammlungItems)
+	router.POST("/sammlung/:sammlungId/produkte", AddProduktToSammlung)
+	router.PUT("/sammlung/:sammlungId/produkte/:produktId/edition", SetSammlungItemEdition)
+	router.DELETE("/editionen/:editionId", DeleteEdition)
+
+	db.Create(&models.Webuser{ID: "test-user"})
+	sammlung := models.Sammlung{Name: strPtr("Filme"), WebuserID: "test-user"}
+	db.Create(&sammlung)
+	film := models.Produkt{Name: "Alien", Art: "Filmserie"}
+	db.Create(&film)
+	anderer := models.Produkt{Name: "Aliens", Art: "Filmserie"}
+	db.Create(&anderer)
+	bluray := models.Edition{ProduktID: film.ID, Format: "Blu-ray"}
+	db.Create(&bluray)
+	dvd := models.Edition{ProduktID: film.ID, Format: "DVD"}
+	db.Create(&dvd)
+	fremd := models.Edition{ProduktID: anderer.ID, Format: "4K"}
+	db.Create(&fremd)
+
+	basePath := fmt.Sprintf("/sammlung/%d/produkte", sammlung.ID)
+
+	// Edition of another product is rejected
+	w := doJSON(router, http.MethodPost, basePath, AddProduktRequest{ProduktID: film.ID, EditionID: &fremd.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodPost, basePath, AddProduktRequest{ProduktID: film.ID, EditionID: &bluray.ID})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var items []SammlungItemResponse
+	w = doJSON(router, http.MethodGet, basePath, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(t, items, 1)
+	assert.Equal(t, "Alien", items[0].Produkt.Name)
+	require.NotNil(t, items[0].Edition)
+	assert.Equal(t, "Blu-ray", items[0].Edition.Format)
+
+	// Switch to the DVD
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("%s/%d/edition", basePath, film.ID), SetEditionRequest{EditionID: &dvd.ID})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Product not in collection
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("%s/%d/edition", basePath, anderer.ID), SetEditionRequest{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Deleting the edition keeps the item but drops the reference
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/editionen/%d", dvd.ID), nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doJSON(router, http.MethodGet, basePath, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(t, items, 1)
+	assert.Nil(t, items[0].Edition)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/export"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// exportFormat liest das Format aus ?format= (Standard: csv).
+// Bei ungültigem Format wird direkt geantwortet und ok=false zurückgegeben.
+func exportFormat(c *gin.Context) (export.Format, bool) {
+	name := strings.ToLower(c.DefaultQuery("format", "csv"))
+	format, ok := export.Formats[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, allowed are csv, json and xlsx"})
+	}
+	return format, ok
+}
+
+// exportJobVersuche begrenzt die Versuche eines Exports
+const exportJobVersuche = 3
+
+// enqueueExport legt einen Hintergrundjob für den Export an und antwortet mit 202; die Datei
+// liefert /api/jobs/:id/download, sobald der Job erfolgreich beendet ist
+func enqueueExport(c *gin.Context, db *gorm.DB, format export.Format, userID string, sammlungID *uint, filename string) {
+	payload := export.JobPayload{
+		WebuserID:  userID,
+		SammlungID: sammlungID,
+		Format:     format.Extension,
+		Dateiname:  filename,
+	}
+	requester, _ := currentUserID(c)
+	job, err := jobs.Enqueue(db, requester, export.JobTyp, payload, exportJobVersuche)
+	if err != nil {
+		log.Printf("ERROR Export - Enqueue job for user %s: %v\n", requester, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+	jobAccepted(c, job)
+}
+
+// ExportSammlung exportiert eine Sammlung inkl. der typspezifischen Felder als Hintergrundjob
+func ExportSammlung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBetrachter)
+	if sammlung == nil {
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	enqueueExport(c, db, format, sammlung.WebuserID, &sammlung.ID, fmt.Sprintf("sammlung-%d", sammlung.ID))
+}
+
+// ExportSammlungen exportiert alle Sammlungen des Benutzers als Hintergrundjob in eine Datei
+func ExportSammlungen(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	enqueueExport(c, db, format, userID, nil, "sammlungen")
+}
+
+// DownloadJobExport liefert die Datei eines abgeschlossenen Exports
+func DownloadJobExport(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	store := c.MustGet("storage").(storage.Store)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	job := findOwnJob(c, db, userID)
+	if job == nil {
+		return
+	}
+	if job.Typ != export.JobTyp {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job has no file to download"})
+		return
+	}
+	if job.Status != models.JobErfolgreich || job.Ergebnis == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export has not finished yet"})
+		return
+	}
+
+	var payload export.JobPayload
+	var ergebnis export.JobErgebnis
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		log.Printf("ERROR DownloadJobExport - Decode payload of job %d: %v\n", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+	if err := json.Unmarshal([]byte(*job.Ergebnis), &ergebnis); err != nil {
+		log.Printf("ERROR DownloadJobExport - Decode result of job %d: %v\n", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+
+	reader, object, err := store.Get(c.Request.Context(), export.JobDatei(job.ID, export.Formats[payload.Format]))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export file not found"})
+			return
+		}
+		log.Printf("ERROR DownloadJobExport - Load file of job %d: %v\n", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, object.Size, ergebnis.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, ergebnis.Dateiname),
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/export"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportSammlung(t *testing.T) {
+	db := setupImportTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.Use(func(c *gin.Context) {
+		c.Set("storage", storage.Store(store))
+		c.Next()
+	})
+	router.GET("/sammlungen/export", ExportSammlungen)
+	router.GET("/sammlungen/:id/export", ExportSammlung)
+	router.GET("/jobs/:id/download", DownloadJobExport)
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "other-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user", Name: strPtr("Regal")}
+	require.NoError(t, db.Create(&sammlung).Error)
+	fremde := models.Sammlung{WebuserID: "other-user"}
+	require.NoError(t, db.Create(&fremde).Error)
+
+	produkt := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: produkt.ID, Autor: strPtr("Michael Ende")}).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: produkt.ID}).Error)
+
+	// Exports run as jobs, the file is downloaded afterwards
+	download := func(path string) *httptest.ResponseRecorder {
+		job := runQueuedJob(t, db, doJSON(router, http.MethodGet, path, nil), export.NewJobHandler(store), nil)
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/jobs/%d/download", job.ID), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+
+	w := download(fmt.Sprintf("/sammlungen/%d/export", sammlung.ID))
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), fmt.Sprintf(`filename="sammlung-%d.csv"`, sammlung.ID))
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Contains(t, records[1], "Michael Ende")
+
+	w = download("/sammlungen/export?format=json")
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rows))
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Regal", rows[0]["sammlung"])
+
+	w = download("/sammlungen/export?format=xlsx")
+	assert.Equal(t, "PK", w.Body.String()[:2])
+
+	w = doJSON(router, http.MethodGet, "/sammlungen/export?format=pdf", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/sammlungen/%d/export", fremde.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -3,9 +3,12 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -17,15 +20,23 @@ type FilmserieRequest struct {
 	Nummer *int    `json:"nummer"`                  // Vom Basisprodukt
 	Art    *string `json:"art"`                     // Filmserie-spezifisch (erwartet 'Film' oder 'Serie', da ENUM in DB)
 	Genre  *string `json:"genre"`                   // Filmserie-spezifisch
+	Jahr   *int    `json:"jahr"`                    // Filmserie-spezifisch (Erscheinungsjahr)
 }
 
 // FilmserieResponse definiert die JSON-Struktur für Filmserie-Antworten
 type FilmserieResponse struct {
-	ID     uint    `json:"id"`     // Vom Basisprodukt
-	Name   string  `json:"name"`   // Vom Basisprodukt
-	Nummer *int    `json:"nummer"` // Vom Basisprodukt
-	Art    *string `json:"art"`    // Filmserie-spezifisch ('Film' oder 'Serie')
-	Genre  *string `json:"genre"`  // Filmserie-spezifisch
+	ID     uint           `json:"id"`              // Vom Basisprodukt
+	Name   string         `json:"name"`            // Vom Basisprodukt
+	Nummer *int           `json:"nummer"`          // Vom Basisprodukt
+	Art    *string        `json:"art"`             // Filmserie-spezifisch ('Film' oder 'Serie')
+	Genre  *string        `json:"genre"`           // Filmserie-spezifisch
+	Jahr   *int           `json:"jahr"`            // Filmserie-spezifisch
+	Cover  *CoverResponse `json:"cover,omitempty"` // URLs von Original und Vorschaubildern
+}
+
+// gueltigesJahr prüft das Erscheinungsjahr grob auf Plausibilität
+func gueltigesJahr(jahr int) bool {
+	return jahr >= 1800 && jahr <= 2200
 }
 
 // --- Handler-Funktionen für Filmserie ---
@@ -41,10 +52,14 @@ func CreateFilmserie(c *gin.Context) {
 	}
 
 	// Optionale Validierung für das ENUM-Feld im Backend (zusätzlich zur DB)
-	if request.Art != nil && !(*request.Art == "Film" || *request.Art == "Serie") {
+	if request.Art != nil && !utils.ValidFilmArt(*request.Art) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'art' must be either 'Film' or 'Serie'"})
 		return
 	}
+	if request.Jahr != nil && !gueltigesJahr(*request.Jahr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'jahr' must be a year between 1800 and 2200"})
+		return
+	}
 
 	tx := db.Begin()
 	defer func() {
@@ -55,9 +70,11 @@ func CreateFilmserie(c *gin.Context) {
 
 	// 1. Basisprodukt erstellen
 	product := models.Produkt{
-		Name:   request.Name,
-		Nummer: request.Nummer,
-		Art:    "Filmserie", // Wichtig: Korrekten Haupt-Typ setzen!
+		Name:        request.Name,
+		Nummer:      request.Nummer,
+		Art:         "Filmserie", // Wichtig: Korrekten Haupt-Typ setzen!
+		ErstelltVon: optionalUserID(c),
+		HaushaltID:  activeHaushaltID(c),
 	}
 	if err := tx.Create(&product).Error; err != nil {
 		tx.Rollback()
@@ -71,6 +88,7 @@ func CreateFilmserie(c *gin.Context) {
 		ProdukteID: product.ID,
 		Art:        request.Art, // Nimmt 'Film' oder 'Serie' aus dem Request
 		Genre:      request.Genre,
+		Jahr:       request.Jahr,
 	}
 	if err := tx.Create(&filmserie).Error; err != nil {
 		tx.Rollback()
@@ -91,6 +109,7 @@ func CreateFilmserie(c *gin.Context) {
 		Nummer: product.Nummer,
 		Art:    filmserie.Art,
 		Genre:  filmserie.Genre,
+		Jahr:   filmserie.Jahr,
 	}
 	c.JSON(http.StatusCreated, response)
 }
@@ -102,7 +121,7 @@ func GetFilmserie(c *gin.Context) {
 
 	var filmserie models.Filmserie
 	// Lade Filmserie und das zugehörige Produkt
-	err := db.Preload("Produkt").First(&filmserie, "produkte_id = ?", id).Error
+	err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).First(&filmserie, "produkte_id = ?", id).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -127,6 +146,8 @@ func GetFilmserie(c *gin.Context) {
 		Nummer: filmserie.Produkt.Nummer,
 		Art:    filmserie.Art,
 		Genre:  filmserie.Genre,
+		Jahr:   filmserie.Jahr,
+		Cover:  loadCoverResponse(db, filmserie.ProdukteID),
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -143,10 +164,14 @@ func UpdateFilmserie(c *gin.Context) {
 	}
 
 	// Optionale Backend-Validierung für 'Art'
-	if request.Art != nil && !(*request.Art == "Film" || *request.Art == "Serie") {
+	if request.Art != nil && !utils.ValidFilmArt(*request.Art) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'art' must be either 'Film' or 'Serie'"})
 		return
 	}
+	if request.Jahr != nil && !gueltigesJahr(*request.Jahr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'jahr' must be a year between 1800 and 2200"})
+		return
+	}
 
 	tx := db.Begin()
 	defer func() {
@@ -157,7 +182,7 @@ func UpdateFilmserie(c *gin.Context) {
 
 	// 1. Basisprodukt finden und aktualisieren (sicherstellen, dass es eine Filmserie ist)
 	var product models.Produkt
-	if err := tx.First(&product, "id = ? AND art = 'Filmserie'", id).Error; err != nil {
+	if err := tx.Scopes(katalogScope(c)).First(&product, "id = ? AND art = 'Filmserie'", id).Error; err != nil {
 		tx.Rollback()
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Film/Serie not found"})
@@ -188,6 +213,7 @@ func UpdateFilmserie(c *gin.Context) {
 
 	filmserie.Art = request.Art // Update mit Wert aus Request ('Film'/'Serie' oder nil)
 	filmserie.Genre = request.Genre
+	filmserie.Jahr = request.Jahr
 	if err := tx.Save(&filmserie).Error; err != nil {
 		tx.Rollback()
 		log.Printf("Error updating filmserie details for ID %s: %v", id, err)
@@ -207,6 +233,7 @@ func UpdateFilmserie(c *gin.Context) {
 		Nummer: product.Nummer,
 		Art:    filmserie.Art,
 		Genre:  filmserie.Genre,
+		Jahr:   filmserie.Jahr,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -225,7 +252,7 @@ func DeleteFilmserie(c *gin.Context) {
 
 	// Lösche das Basisprodukt (Cascade sollte Filmserie löschen)
 	// Wichtig: Sicherstellen, dass es wirklich eine Filmserie ist
-	result := tx.Where("id = ? AND art = 'Filmserie'", id).Delete(&models.Produkt{})
+	result := tx.Scopes(katalogScope(c)).Where("id = ? AND art = 'Filmserie'", id).Delete(&models.Produkt{})
 
 	if result.Error != nil {
 		tx.Rollback()
@@ -251,10 +278,14 @@ func DeleteFilmserie(c *gin.Context) {
 // ListFilmserien holt alle Filmserien
 func ListFilmserien(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	sortierung, order, ok := produktSortierung(c, db)
+	if !ok {
+		return
+	}
 
 	var filmserien []models.Filmserie
 	// Lade alle Filmserien und ihre Produkt-Daten
-	err := db.Preload("Produkt").Find(&filmserien).Error
+	err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).Order(order).Find(&filmserien).Error
 
 	if err != nil {
 		log.Printf("Error retrieving filmserien: %v", err)
@@ -262,6 +293,12 @@ func ListFilmserien(c *gin.Context) {
 		return
 	}
 
+	produktIDs := make([]uint, len(filmserien))
+	for i, item := range filmserien {
+		produktIDs[i] = item.ProdukteID
+	}
+	covers := loadCoverResponses(db, produktIDs)
+
 	response := make([]FilmserieResponse, len(filmserien))
 	for i, fs := range filmserien {
 		var pName string
@@ -279,8 +316,16 @@ func ListFilmserien(c *gin.Context) {
 			Nummer: pNummer,
 			Art:    fs.Art,
 			Genre:  fs.Genre,
+			Jahr:   fs.Jahr,
+			Cover:  covers[fs.ProdukteID],
 		}
 	}
 
+	if sortierung == models.SortierungName {
+		sort.SliceStable(response, func(i, j int) bool {
+			return strings.ToLower(response[i].Name) < strings.ToLower(response[j].Name)
+		})
+	}
+
 	c.JSON(http.StatusOK, response)
 }
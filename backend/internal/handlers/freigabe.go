@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// freigabeTokenBytes bestimmt die Länge der Tokens (24 Byte = 32 Zeichen Base64)
+const freigabeTokenBytes = 24
+
+type CreateFreigabeRequest struct {
+	Bezeichnung *string    `json:"bezeichnung"`
+	GueltigBis  *time.Time `json:"gueltigBis"` // RFC 3339, ohne Angabe unbegrenzt
+}
+
+type FreigabeResponse struct {
+	ID          uint       `json:"id"`
+	Token       string     `json:"token"`
+	URL         string     `json:"url"`
+	Bezeichnung *string    `json:"bezeichnung"`
+	ErstelltAm  time.Time  `json:"erstelltAm"`
+	GueltigBis  *time.Time `json:"gueltigBis"`
+	Abgelaufen  bool       `json:"abgelaufen"`
+}
+
+func toFreigabeResponse(freigabe models.Freigabe, now time.Time) FreigabeResponse {
+	return FreigabeResponse{
+		ID:          freigabe.ID,
+		Token:       freigabe.Token,
+		URL:         "/api/public/sammlungen/" + freigabe.Token,
+		Bezeichnung: freigabe.Bezeichnung,
+		ErstelltAm:  freigabe.ErstelltAm,
+		GueltigBis:  freigabe.GueltigBis,
+		Abgelaufen:  !freigabe.Gueltig(now),
+	}
+}
+
+// PublicSammlungResponse ist die öffentliche Ansicht einer Sammlung. Sie enthält bewusst
+// keine Angaben zum Besitzer und keine persönlichen Felder der Einträge (Status, Bewertung).
+type PublicSammlungResponse struct {
+	Name  *string              `json:"name"`
+	Items []PublicSammlungItem `json:"items"`
+}
+
+type PublicSammlungItem struct {
+	Produkt ProduktResponse  `json:"produkt"`
+	Edition *EditionResponse `json:"edition"`
+}
+
+func newFreigabeToken() (string, error) {
+	raw := make([]byte, freigabeTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// CreateFreigabe erzeugt einen öffentlichen Link auf eine eigene Sammlung
+func CreateFreigabe(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
+		return
+	}
+
+	var request CreateFreigabeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+	now := time.Now().UTC()
+	if request.GueltigBis != nil && !request.GueltigBis.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'gueltigBis' must be in the future"})
+		return
+	}
+
+	token, err := newFreigabeToken()
+	if err != nil {
+		log.Printf("ERROR CreateFreigabe - Generate token: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+	freigabe := models.Freigabe{
+		SammlungID:  sammlung.ID,
+		Token:       token,
+		Bezeichnung: request.Bezeichnung,
+		ErstelltAm:  now,
+		GueltigBis:  request.GueltigBis,
+	}
+	if err := db.Create(&freigabe).Error; err != nil {
+		log.Printf("ERROR CreateFreigabe for Sammlung %d: %v\n", sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toFreigabeResponse(freigabe, now))
+}
+
+// ListFreigaben listet alle Links einer eigenen Sammlung, auch abgelaufene
+func ListFreigaben(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
+		return
+	}
+
+	var freigaben []models.Freigabe
+	if err := db.Where("sammlung_id = ?", sammlung.ID).Order("id asc").Find(&freigaben).Error; err != nil {
+		log.Printf("ERROR ListFreigaben for Sammlung %d: %v\n", sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share links"})
+		return
+	}
+
+	now := time.Now().UTC()
+	response := make([]FreigabeResponse, len(freigaben))
+	for i, freigabe := range freigaben {
+		response[i] = toFreigabeResponse(freigabe, now)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteFreigabe widerruft einen Link; er ist danach sofort ungültig
+func DeleteFreigabe(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
+		return
+	}
+	freigabeID, err := strconv.ParseUint(c.Param("freigabeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID format"})
+		return
+	}
+
+	result := db.Where("id = ? AND sammlung_id = ?", uint(freigabeID), sammlung.ID).Delete(&models.Freigabe{})
+	if result.Error != nil {
+		log.Printf("ERROR DeleteFreigabe %d: %v\n", freigabeID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetPublicSammlung liefert eine freigegebene Sammlung ohne Anmeldung. Unbekannte, widerrufene
+// und abgelaufene Tokens werden gleich behandelt, damit sich gültige Tokens nicht erraten lassen.
+func GetPublicSammlung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	notFound := func() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared collection not found"})
+	}
+
+	var freigabe models.Freigabe
+	if err := db.Preload("Sammlung").Where("token = ?", c.Param("token")).First(&freigabe).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			notFound()
+		} else {
+			log.Printf("ERROR GetPublicSammlung - Find Freigabe: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve shared collection"})
+		}
+		return
+	}
+	if !freigabe.Gueltig(time.Now().UTC()) {
+		notFound()
+		return
+	}
+
+	items, err := loadPublicSammlungItems(db, freigabe.SammlungID, freigabe.Token)
+	if err != nil {
+		log.Printf("ERROR GetPublicSammlung - Items of Sammlung %d: %v\n", freigabe.SammlungID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve shared collection"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, PublicSammlungResponse{Name: freigabe.Sammlung.Name, Items: items})
+}
+
+// freigegebenesCover hängt das Token einer Freigabe an die Cover-URLs an
+func freigegebenesCover(cover CoverResponse, token string) *CoverResponse {
+	param := "&freigabe=" + url.QueryEscape(token)
+	return &CoverResponse{Original: cover.Original + param, Small: cover.Small + param, Medium: cover.Medium + param}
+}
+
+// loadPublicSammlungItems lädt die Einträge einer Sammlung mit typspezifischen Produktdaten.
+// Cover-URLs von Haushaltsprodukten erhalten das Token der Freigabe, da sie sonst nicht öffentlich sind.
+func loadPublicSammlungItems(db *gorm.DB, sammlungID uint, token string) ([]PublicSammlungItem, error) {
+	var eintraege []models.SammlungProdukt
+	if err := db.Where("sammlung_id = ?", sammlungID).Preload("Edition").Order("produkt_id asc").Find(&eintraege).Error; err != nil {
+		return nil, fmt.Errorf("find items: %w", err)
+	}
+
+	produktIDs := make([]uint, len(eintraege))
+	for i, eintrag := range eintraege {
+		produktIDs[i] = eintrag.ProduktID
+	}
+	var produkte []models.Produkt
+	if err := db.Where("id IN ?", produktIDs).Order("id asc").Find(&produkte).Error; err != nil {
+		return nil, fmt.Errorf("find products: %w", err)
+	}
+	responses, err := loadProduktResponses(db, produkte)
+	if err != nil {
+		return nil, fmt.Errorf("load product details: %w", err)
+	}
+	haushaltsprodukte := make(map[uint]bool)
+	for _, produkt := range produkte {
+		haushaltsprodukte[produkt.ID] = produkt.HaushaltID != nil
+	}
+	produktByID := make(map[uint]ProduktResponse, len(responses))
+	for _, response := range responses {
+		if response.Cover != nil && haushaltsprodukte[response.ID] {
+			response.Cover = freigegebenesCover(*response.Cover, token)
+		}
+		produktByID[response.ID] = response
+	}
+
+	items := make([]PublicSammlungItem, 0, len(eintraege))
+	for _, eintrag := range eintraege {
+		produkt, ok := produktByID[eintrag.ProduktID]
+		if !ok {
+			continue
+		}
+		item := PublicSammlungItem{Produkt: produkt}
+		if eintrag.Edition != nil {
+			edition := toEditionResponse(*eintrag.Edition)
+			item.Edition = &edition
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupFreigabeTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{},
+		&models.Spiel{}, &models.Filmserie{}, &models.Sammlung{}, &models.SammlungMitglied{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}, &models.Kinderprofil{}, &models.SammlungProdukt{}, &models.Edition{},
+		&models.Cover{}, &models.Freigabe{}, &models.Benutzereinstellungen{}))
+	return db
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+// publicRouter hat bewusst keinen userId im Context
+func publicRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.GET("/public/sammlungen/:token", GetPublicSammlung)
+	return router
+}
+
+func getPublic(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/public/sammlungen/"+token, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFreigabeLifecycle(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/sammlungen/:id/freigaben", CreateFreigabe)
+	router.GET("/sammlungen/:id/freigaben", ListFreigaben)
+	router.DELETE("/sammlungen/:id/freigaben/:freigabeId", DeleteFreigabe)
+	public := publicRouter(db)
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user", Name: strPtr("Alice")}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "other-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user", Name: strPtr("Mangas")}
+	require.NoError(t, db.Create(&sammlung).Error)
+	fremde := models.Sammlung{WebuserID: "other-user"}
+	require.NoError(t, db.Create(&fremde).Error)
+
+	manga := models.Produkt{Name: "Berserk", Art: "Manga", ErstelltVon: strPtr("test-user")}
+	require.NoError(t, db.Create(&manga).Error)
+	require.NoError(t, db.Create(&models.Manga{ProdukteID: manga.ID, Mangaka: strPtr("Kentarou Miura"), Baende: intPtr(41)}).Error)
+	edition := models.Edition{ProduktID: manga.ID, Format: "Taschenbuch", Verlag: strPtr("Panini")}
+	require.NoError(t, db.Create(&edition).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: manga.ID, EditionID: &edition.ID,
+		Status: strPtr(models.StatusInBearbeitung), Bewertung: intPtr(5)}).Error)
+
+	path := fmt.Sprintf("/sammlungen/%d/freigaben", sammlung.ID)
+
+	// Only the owner can share
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/sammlungen/%d/freigaben", fremde.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodPost, path, map[string]interface{}{"gueltigBis": time.Now().Add(-time.Hour)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodPost, path, map[string]interface{}{"bezeichnung": "Für Tom"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var freigabe FreigabeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &freigabe))
+	assert.Len(t, freigabe.Token, 32)
+	assert.Equal(t, "/api/public/sammlungen/"+freigabe.Token, freigabe.URL)
+	assert.Nil(t, freigabe.GueltigBis)
+	assert.False(t, freigabe.Abgelaufen)
+
+	// Public view contains typed items but no personal data
+	w = getPublic(public, freigabe.Token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "test-user")
+	assert.NotContains(t, w.Body.String(), "Alice")
+	assert.NotContains(t, w.Body.String(), "bewertung")
+	var shared PublicSammlungResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	assert.Equal(t, "Mangas", *shared.Name)
+	require.Len(t, shared.Items, 1)
+	assert.Equal(t, "Manga", shared.Items[0].Produkt.Art)
+	assert.Equal(t, "Kentarou Miura", *shared.Items[0].Produkt.Mangaka)
+	assert.Equal(t, 41, *shared.Items[0].Produkt.Baende)
+	require.NotNil(t, shared.Items[0].Edition)
+	assert.Equal(t, "Panini", *shared.Items[0].Edition.Verlag)
+
+	// Expired links are listed but no longer work
+	expired := models.Freigabe{SammlungID: sammlung.ID, Token: "abgelaufen", ErstelltAm: time.Now().Add(-48 * time.Hour),
+		GueltigBis: timePtr(time.Now().Add(-time.Hour))}
+	require.NoError(t, db.Create(&expired).Error)
+	w = getPublic(public, expired.Token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []FreigabeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 2)
+	assert.False(t, list[0].Abgelaufen)
+	assert.True(t, list[1].Abgelaufen)
+
+	// Revoke
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/sammlungen/%d/freigaben/%d", fremde.ID, freigabe.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("%s/%d", path, freigabe.ID), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = getPublic(public, freigabe.Token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = getPublic(public, "unbekannt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
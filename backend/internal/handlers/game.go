@@ -3,6 +3,8 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	// Passe den Import-Pfad an deine Projektstruktur an
@@ -22,11 +24,12 @@ type SpielRequest struct {
 
 // SpielResponse definiert die JSON-Struktur für Spiel-Antworten
 type SpielResponse struct {
-	ID      uint    `json:"id"`      // Vom Basisprodukt
-	Name    string  `json:"name"`    // Vom Basisprodukt
-	Nummer  *int    `json:"nummer"`  // Vom Basisprodukt
-	Konsole *string `json:"konsole"` // Spiel-spezifisch
-	Genre   *string `json:"genre"`   // Spiel-spezifisch
+	ID      uint           `json:"id"`              // Vom Basisprodukt
+	Name    string         `json:"name"`            // Vom Basisprodukt
+	Nummer  *int           `json:"nummer"`          // Vom Basisprodukt
+	Konsole *string        `json:"konsole"`         // Spiel-spezifisch
+	Genre   *string        `json:"genre"`           // Spiel-spezifisch
+	Cover   *CoverResponse `json:"cover,omitempty"` // URLs von Original und Vorschaubildern
 }
 
 // --- Handler-Funktionen für Spiel ---
@@ -50,9 +53,11 @@ func CreateSpiel(c *gin.Context) {
 
 	// 1. Basisprodukt erstellen
 	product := models.Produkt{
-		Name:   request.Name,
-		Nummer: request.Nummer,
-		Art:    "Spiel", // Korrekten Typ setzen!
+		Name:        request.Name,
+		Nummer:      request.Nummer,
+		Art:         "Spiel", // Korrekten Typ setzen!
+		ErstelltVon: optionalUserID(c),
+		HaushaltID:  activeHaushaltID(c),
 	}
 	if err := tx.Create(&product).Error; err != nil {
 		tx.Rollback()
@@ -97,7 +102,7 @@ func GetSpiel(c *gin.Context) {
 
 	var spiel models.Spiel
 	// Lade Spiel und das zugehörige Produkt
-	err := db.Preload("Produkt").First(&spiel, "produkte_id = ?", id).Error
+	err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).First(&spiel, "produkte_id = ?", id).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -122,6 +127,7 @@ func GetSpiel(c *gin.Context) {
 		Nummer:  spiel.Produkt.Nummer,
 		Konsole: spiel.Konsole,
 		Genre:   spiel.Genre,
+		Cover:   loadCoverResponse(db, spiel.ProdukteID),
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -146,7 +152,7 @@ func UpdateSpiel(c *gin.Context) {
 
 	// 1. Basisprodukt finden und aktualisieren (sicherstellen, dass es ein Spiel ist)
 	var product models.Produkt
-	if err := tx.First(&product, "id = ? AND art = 'Spiel'", id).Error; err != nil {
+	if err := tx.Scopes(katalogScope(c)).First(&product, "id = ? AND art = 'Spiel'", id).Error; err != nil {
 		tx.Rollback()
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Spiel not found"})
@@ -213,7 +219,7 @@ func DeleteSpiel(c *gin.Context) {
 	}()
 
 	// Lösche das Basisprodukt (Cascade sollte Spiel löschen)
-	result := tx.Where("id = ? AND art = 'Spiel'", id).Delete(&models.Produkt{})
+	result := tx.Scopes(katalogScope(c)).Where("id = ? AND art = 'Spiel'", id).Delete(&models.Produkt{})
 
 	if result.Error != nil {
 		tx.Rollback()
@@ -239,10 +245,14 @@ func DeleteSpiel(c *gin.Context) {
 // ListSpiele holt alle Spiele
 func ListSpiele(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	sortierung, order, ok := produktSortierung(c, db)
+	if !ok {
+		return
+	}
 
 	var spiele []models.Spiel
 	// Lade alle Spiele und ihre Produkt-Daten
-	err := db.Preload("Produkt").Find(&spiele).Error
+	err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).Order(order).Find(&spiele).Error
 
 	if err != nil {
 		log.Printf("Error retrieving spiele: %v", err)
@@ -250,6 +260,12 @@ func ListSpiele(c *gin.Context) {
 		return
 	}
 
+	produktIDs := make([]uint, len(spiele))
+	for i, item := range spiele {
+		produktIDs[i] = item.ProdukteID
+	}
+	covers := loadCoverResponses(db, produktIDs)
+
 	response := make([]SpielResponse, len(spiele))
 	for i, spiel := range spiele {
 		var pName string
@@ -268,8 +284,15 @@ func ListSpiele(c *gin.Context) {
 			Nummer:  pNummer,
 			Konsole: spiel.Konsole,
 			Genre:   spiel.Genre,
+			Cover:   covers[spiel.ProdukteID],
 		}
 	}
 
+	if sortierung == models.SortierungName {
+		sort.SliceStable(response, func(i, j int) bool {
+			return strings.ToLower(response[i].Name) < strings.ToLower(response[j].Name)
+		})
+	}
+
 	c.JSON(http.StatusOK, response)
 }
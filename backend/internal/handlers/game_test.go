@@ -18,7 +18,7 @@ func setupSpielTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.Produkt{}, &models.Spiel{})
+	err = db.AutoMigrate(&models.Produkt{}, &models.Spiel{}, &models.Cover{})
 	require.NoError(t, err)
 
 	return db
@@ -0,0 +1,489 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HaushaltHeader wählt den aktiven Haushalt für einen Request; ohne Header gilt der haushalt-Claim
+const HaushaltHeader = "X-Haushalt-ID"
+
+type CreateHaushaltRequest struct {
+	Name   string  `json:"name" binding:"required"`
+	Gruppe *string `json:"gruppe"` // Optional: Keycloak-Gruppe für automatische Mitgliedschaft
+}
+
+type AddHaushaltMitgliedRequest struct {
+	WebuserID string `json:"webuserId" binding:"required"`
+	Rolle     string `json:"rolle"` // mitglied (Standard) oder verwalter
+}
+
+type CreateKinderprofilRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type HaushaltResponse struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Gruppe     *string   `json:"gruppe"`
+	ErstelltAm time.Time `json:"erstelltAm"`
+	Rolle      string    `json:"rolle"` // Rolle des anfragenden Benutzers
+}
+
+type HaushaltMitgliedResponse struct {
+	WebuserID string  `json:"webuserId"`
+	Name      *string `json:"name"`
+	Rolle     string  `json:"rolle"`
+	AusGruppe bool    `json:"ausGruppe"`
+}
+
+type KinderprofilResponse struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	ErstelltAm time.Time `json:"erstelltAm"`
+}
+
+type HaushaltDetailResponse struct {
+	HaushaltResponse
+	Mitglieder []HaushaltMitgliedResponse `json:"mitglieder"`
+	Kinder     []KinderprofilResponse     `json:"kinder"`
+}
+
+func toHaushaltResponse(haushalt models.Haushalt, rolle string) HaushaltResponse {
+	return HaushaltResponse{
+		ID:         haushalt.ID,
+		Name:       haushalt.Name,
+		Gruppe:     haushalt.Gruppe,
+		ErstelltAm: haushalt.ErstelltAm,
+		Rolle:      rolle,
+	}
+}
+
+func toKinderprofilResponse(kind models.Kinderprofil) KinderprofilResponse {
+	return KinderprofilResponse{ID: kind.ID, Name: kind.Name, ErstelltAm: kind.ErstelltAm}
+}
+
+// haushaltRolle ermittelt die Rolle des Benutzers im Haushalt ("" = kein Mitglied)
+func haushaltRolle(db *gorm.DB, haushaltID uint, userID string) (string, error) {
+	var mitgliedschaften []models.HaushaltMitgliedschaft
+	err := db.Where("haushalt_id = ? AND webuser_id = ?", haushaltID, userID).Limit(1).Find(&mitgliedschaften).Error
+	if err != nil || len(mitgliedschaften) == 0 {
+		return "", err
+	}
+	return mitgliedschaften[0].Rolle, nil
+}
+
+// HaushaltMiddleware bestimmt den aktiven Haushalt aus dem Header X-Haushalt-ID oder dem
+// haushalt-Claim und prüft die Mitgliedschaft. Ohne Angabe bleibt kein Haushalt aktiv und
+// nur der globale Katalog ist sichtbar. Muss nach der AuthMiddleware laufen.
+func HaushaltMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := strings.TrimSpace(c.GetHeader(HaushaltHeader))
+		if raw == "" {
+			raw = c.GetString("haushaltClaim")
+		}
+		if raw == "" {
+			c.Next()
+			return
+		}
+
+		haushaltID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID format"})
+			return
+		}
+		userID, ok := currentUserID(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		db := c.MustGet("db").(*gorm.DB)
+		rolle, err := haushaltRolle(db, uint(haushaltID), userID)
+		if err != nil {
+			log.Printf("ERROR HaushaltMiddleware for Haushalt %d: %v\n", haushaltID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check household membership"})
+			return
+		}
+		if rolle == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not a member of this household"})
+			return
+		}
+
+		c.Set("haushaltId", uint(haushaltID))
+		c.Next()
+	}
+}
+
+// activeHaushaltID liefert den von der HaushaltMiddleware gesetzten Haushalt oder nil
+func activeHaushaltID(c *gin.Context) *uint {
+	if haushaltID, ok := c.Get("haushaltId"); ok {
+		if id, isUint := haushaltID.(uint); isUint {
+			return &id
+		}
+	}
+	return nil
+}
+
+// katalogScope beschränkt Abfragen auf der Tabelle produkte auf den globalen Katalog und die
+// Produkte des aktiven Haushalts
+func katalogScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return database.KatalogScope(activeHaushaltID(c))
+}
+
+// sichtbareProdukte liefert die IDs aller im aktuellen Kontext sichtbaren Produkte als
+// Subquery, z.B. für Abfragen auf den Untertyp-Tabellen
+func sichtbareProdukte(c *gin.Context, db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Produkt{}).Select("produkte.id").Scopes(katalogScope(c))
+}
+
+// findHaushalt lädt den Haushalt aus dem URL-Parameter id und prüft die Mitgliedschaft.
+// Ohne Mitgliedschaft wird mit 404 geantwortet, fehlt die Verwalterrolle bei nurVerwalter mit 403.
+func findHaushalt(c *gin.Context, db *gorm.DB, userID string, nurVerwalter bool) (*models.Haushalt, string) {
+	haushaltID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid household ID format"})
+		return nil, ""
+	}
+
+	var haushalt models.Haushalt
+	rolle := ""
+	err = db.First(&haushalt, haushaltID).Error
+	if err == nil {
+		rolle, err = haushaltRolle(db, haushalt.ID, userID)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("ERROR findHaushalt %d: %v\n", haushaltID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find household"})
+		return nil, ""
+	}
+	if rolle == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Household not found or access denied"})
+		return nil, ""
+	}
+	if nurVerwalter && rolle != models.HaushaltVerwalter {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household administrators can do this"})
+		return nil, ""
+	}
+	return &haushalt, rolle
+}
+
+// CreateHaushalt legt einen Haushalt an; der Ersteller wird Verwalter
+func CreateHaushalt(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request CreateHaushaltRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	haushalt := models.Haushalt{Name: strings.TrimSpace(request.Name), ErstelltAm: time.Now().UTC()}
+	if haushalt.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'name' must not be empty"})
+		return
+	}
+	if request.Gruppe != nil {
+		if gruppe := database.NormalizeGruppe(*request.Gruppe); gruppe != "" {
+			haushalt.Gruppe = &gruppe
+		}
+	}
+
+	if haushalt.Gruppe != nil {
+		var count int64
+		if err := db.Model(&models.Haushalt{}).Where("gruppe = ?", *haushalt.Gruppe).Count(&count).Error; err != nil {
+			log.Printf("ERROR CreateHaushalt - Group check: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create household"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group is already linked to another household"})
+			return
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&haushalt).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Haushalt", "Webuser").Create(&models.HaushaltMitgliedschaft{
+			HaushaltID: haushalt.ID,
+			WebuserID:  userID,
+			Rolle:      models.HaushaltVerwalter,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("ERROR CreateHaushalt: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create household"})
+		return
+	}
+	if haushalt.Gruppe != nil {
+		// Gruppenmitglieder sollen beim nächsten Request beitreten, nicht erst nach Ablauf des Caches
+		database.Users.Reset()
+	}
+	c.JSON(http.StatusCreated, toHaushaltResponse(haushalt, models.HaushaltVerwalter))
+}
+
+// ListHaushalte listet die Haushalte des Benutzers
+func ListHaushalte(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var mitgliedschaften []models.HaushaltMitgliedschaft
+	err := db.Joins("Haushalt").Where("haushalt_mitglied.webuser_id = ?", userID).
+		Order("Haushalt.name, haushalt_mitglied.haushalt_id").Find(&mitgliedschaften).Error
+	if err != nil {
+		log.Printf("ERROR ListHaushalte: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve households"})
+		return
+	}
+
+	response := make([]HaushaltResponse, len(mitgliedschaften))
+	for i, mitgliedschaft := range mitgliedschaften {
+		response[i] = toHaushaltResponse(mitgliedschaft.Haushalt, mitgliedschaft.Rolle)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetHaushalt liefert einen Haushalt mit Mitgliedern und Kinderprofilen
+func GetHaushalt(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	haushalt, rolle := findHaushalt(c, db, userID, false)
+	if haushalt == nil {
+		return
+	}
+
+	var mitgliedschaften []models.HaushaltMitgliedschaft
+	if err := db.Preload("Webuser").Where("haushalt_id = ?", haushalt.ID).Order("webuser_id").Find(&mitgliedschaften).Error; err != nil {
+		log.Printf("ERROR GetHaushalt %d - Members: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve household"})
+		return
+	}
+	var kinder []models.Kinderprofil
+	if err := db.Where("haushalt_id = ?", haushalt.ID).Order("name, id").Find(&kinder).Error; err != nil {
+		log.Printf("ERROR GetHaushalt %d - Kid profiles: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve household"})
+		return
+	}
+
+	response := HaushaltDetailResponse{
+		HaushaltResponse: toHaushaltResponse(*haushalt, rolle),
+		Mitglieder:       make([]HaushaltMitgliedResponse, len(mitgliedschaften)),
+		Kinder:           make([]KinderprofilResponse, len(kinder)),
+	}
+	for i, mitgliedschaft := range mitgliedschaften {
+		response.Mitglieder[i] = HaushaltMitgliedResponse{
+			WebuserID: mitgliedschaft.WebuserID,
+			Name:      mitgliedschaft.Webuser.Name,
+			Rolle:     mitgliedschaft.Rolle,
+			AusGruppe: mitgliedschaft.AusGruppe,
+		}
+	}
+	for i, kind := range kinder {
+		response.Kinder[i] = toKinderprofilResponse(kind)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddHaushaltMitglied nimmt einen Benutzer in den Haushalt auf oder ändert seine Rolle (nur Verwalter)
+func AddHaushaltMitglied(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	haushalt, _ := findHaushalt(c, db, userID, true)
+	if haushalt == nil {
+		return
+	}
+
+	var request AddHaushaltMitgliedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	switch request.Rolle {
+	case "":
+		request.Rolle = models.HaushaltMitglied
+	case models.HaushaltMitglied, models.HaushaltVerwalter:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'rolle' must be 'mitglied' or 'verwalter'"})
+		return
+	}
+
+	var user models.Webuser
+	if err := db.First(&user, "id = ?", request.WebuserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("ERROR AddHaushaltMitglied - User %s: %v\n", request.WebuserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+
+	// Eine manuelle Aufnahme macht eine Gruppenmitgliedschaft dauerhaft
+	mitgliedschaft := models.HaushaltMitgliedschaft{HaushaltID: haushalt.ID, WebuserID: user.ID, Rolle: request.Rolle}
+	err := db.Omit("Haushalt", "Webuser").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "haushalt_id"}, {Name: "webuser_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rolle", "aus_gruppe"}),
+	}).Create(&mitgliedschaft).Error
+	if err != nil {
+		log.Printf("ERROR AddHaushaltMitglied in Haushalt %d: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+	c.JSON(http.StatusOK, HaushaltMitgliedResponse{WebuserID: user.ID, Name: user.Name, Rolle: mitgliedschaft.Rolle})
+}
+
+// RemoveHaushaltMitglied entfernt ein Mitglied (Verwalter) oder lässt den Benutzer selbst austreten.
+// Der letzte Verwalter kann nicht entfernt werden.
+func RemoveHaushaltMitglied(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	webuserID := c.Param("webuserId")
+	haushalt, _ := findHaushalt(c, db, userID, webuserID != userID)
+	if haushalt == nil {
+		return
+	}
+
+	rolle, err := haushaltRolle(db, haushalt.ID, webuserID)
+	if err != nil {
+		log.Printf("ERROR RemoveHaushaltMitglied in Haushalt %d: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if rolle == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if rolle == models.HaushaltVerwalter {
+		var verwalter int64
+		if err := db.Model(&models.HaushaltMitgliedschaft{}).Where("haushalt_id = ? AND rolle = ?", haushalt.ID, models.HaushaltVerwalter).Count(&verwalter).Error; err != nil {
+			log.Printf("ERROR RemoveHaushaltMitglied in Haushalt %d: %v\n", haushalt.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+			return
+		}
+		if verwalter <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The last administrator cannot leave the household"})
+			return
+		}
+	}
+
+	if err := db.Where("haushalt_id = ? AND webuser_id = ?", haushalt.ID, webuserID).Delete(&models.HaushaltMitgliedschaft{}).Error; err != nil {
+		log.Printf("ERROR RemoveHaushaltMitglied in Haushalt %d: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateKinderprofil legt ein Kinderprofil im Haushalt an (nur Verwalter)
+func CreateKinderprofil(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	haushalt, _ := findHaushalt(c, db, userID, true)
+	if haushalt == nil {
+		return
+	}
+
+	var request CreateKinderprofilRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	kind := models.Kinderprofil{HaushaltID: haushalt.ID, Name: strings.TrimSpace(request.Name), ErstelltAm: time.Now().UTC()}
+	if kind.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'name' must not be empty"})
+		return
+	}
+	if err := db.Omit("Haushalt").Create(&kind).Error; err != nil {
+		log.Printf("ERROR CreateKinderprofil in Haushalt %d: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create kid profile"})
+		return
+	}
+	c.JSON(http.StatusCreated, toKinderprofilResponse(kind))
+}
+
+// DeleteKinderprofil löscht ein Kinderprofil; seine Sammlungen bleiben im Haushalt erhalten
+func DeleteKinderprofil(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	haushalt, _ := findHaushalt(c, db, userID, true)
+	if haushalt == nil {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND haushalt_id = ?", c.Param("kindId"), haushalt.ID).Delete(&models.Kinderprofil{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// SQLite erzwingt den Fremdschlüssel nicht immer, daher explizit lösen
+		return tx.Model(&models.Sammlung{}).Where("kinderprofil_id = ?", c.Param("kindId")).
+			Update("kinderprofil_id", nil).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Kid profile not found"})
+			return
+		}
+		log.Printf("ERROR DeleteKinderprofil in Haushalt %d: %v\n", haushalt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete kid profile"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// kinderprofilImHaushalt prüft, ob das Kinderprofil zum aktiven Haushalt gehört.
+// Bei Fehlern wird direkt geantwortet und false zurückgegeben.
+func kinderprofilImHaushalt(c *gin.Context, db *gorm.DB, kinderprofilID uint) bool {
+	haushaltID := activeHaushaltID(c)
+	if haushaltID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kid profiles require an active household"})
+		return false
+	}
+	var count int64
+	if err := db.Model(&models.Kinderprofil{}).Where("id = ? AND haushalt_id = ?", kinderprofilID, *haushaltID).Count(&count).Error; err != nil {
+		log.Printf("ERROR kinderprofilImHaushalt %d: %v\n", kinderprofilID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check kid profile"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kid profile does not belong to the active household"})
+		return false
+	}
+	return true
+}
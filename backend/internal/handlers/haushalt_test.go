@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// haushaltRouter registriert Haushalts-, Katalog- und Sammlungsrouten hinter der HaushaltMiddleware
+func haushaltRouter(db *gorm.DB, userID string) *gin.Engine {
+	router := setupCollectionTestRouter(db, userID)
+	router.Use(HaushaltMiddleware())
+	router.POST("/haushalte", CreateHaushalt)
+	router.GET("/haushalte", ListHaushalte)
+	router.GET("/haushalte/:id", GetHaushalt)
+	router.POST("/haushalte/:id/mitglieder", AddHaushaltMitglied)
+	router.DELETE("/haushalte/:id/mitglieder/:webuserId", RemoveHaushaltMitglied)
+	router.POST("/haushalte/:id/kinder", CreateKinderprofil)
+	router.DELETE("/haushalte/:id/kinder/:kindId", DeleteKinderprofil)
+	router.POST("/books", CreateBook)
+	router.GET("/books", ListBooks)
+	router.GET("/books/:id", GetBook)
+	router.POST("/sammlungen", CreateSammlung)
+	router.GET("/sammlungen", ListUserSammlungen)
+	router.GET("/sammlung/:sammlungId/produkte", ListSammlungItems)
+	router.POST("/sammlung/:sammlungId/produkte", AddProduktToSammlung)
+	return router
+}
+
+// doHaushalt wie doJSON, aber mit aktivem Haushalt im Header (0 = keiner)
+func doHaushalt(router *gin.Engine, method, path string, haushaltID uint, body interface{}) *httptest.ResponseRecorder {
+	reader := &bytes.Buffer{}
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(encoded)
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if haushaltID != 0 {
+		req.Header.Set(HaushaltHeader, fmt.Sprint(haushaltID))
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHaushaltVerwaltung(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	for _, id := range []string{"anna", "ben", "clara"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id, Name: strPtr(id)}).Error)
+	}
+	anna, ben, clara := haushaltRouter(db, "anna"), haushaltRouter(db, "ben"), haushaltRouter(db, "clara")
+
+	w := doHaushalt(anna, http.MethodPost, "/haushalte", 0, map[string]string{"name": "Familie Meier", "gruppe": "/familie-meier"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var haushalt HaushaltResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &haushalt))
+	assert.Equal(t, models.HaushaltVerwalter, haushalt.Rolle)
+	require.NotNil(t, haushalt.Gruppe)
+	assert.Equal(t, "familie-meier", *haushalt.Gruppe)
+	base := fmt.Sprintf("/haushalte/%d", haushalt.ID)
+
+	w = doHaushalt(ben, http.MethodPost, "/haushalte", 0, map[string]string{"name": "Kopie", "gruppe": "familie-meier"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Members
+	w = doHaushalt(ben, http.MethodGet, base, 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doHaushalt(anna, http.MethodPost, base+"/mitglieder", 0, map[string]string{"webuserId": "ben"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doHaushalt(anna, http.MethodPost, base+"/mitglieder", 0, map[string]string{"webuserId": "ben", "rolle": "chef"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doHaushalt(anna, http.MethodPost, base+"/mitglieder", 0, map[string]string{"webuserId": "niemand"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doHaushalt(ben, http.MethodPost, base+"/mitglieder", 0, map[string]string{"webuserId": "clara"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Kid profiles
+	w = doHaushalt(ben, http.MethodPost, base+"/kinder", 0, map[string]string{"name": "Mia"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doHaushalt(anna, http.MethodPost, base+"/kinder", 0, map[string]string{"name": "Mia"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var kind KinderprofilResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &kind))
+
+	w = doHaushalt(ben, http.MethodGet, base, 0, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var detail HaushaltDetailResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, models.HaushaltMitglied, detail.Rolle)
+	require.Len(t, detail.Mitglieder, 2)
+	require.Len(t, detail.Kinder, 1)
+	assert.Equal(t, "Mia", detail.Kinder[0].Name)
+
+	w = doHaushalt(ben, http.MethodGet, "/haushalte", 0, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var liste []HaushaltResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &liste))
+	require.Len(t, liste, 1)
+	assert.Equal(t, "Familie Meier", liste[0].Name)
+
+	// The last administrator cannot leave; members can leave on their own
+	w = doHaushalt(anna, http.MethodDelete, base+"/mitglieder/anna", 0, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doHaushalt(clara, http.MethodDelete, base+"/mitglieder/ben", 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doHaushalt(ben, http.MethodDelete, base+"/mitglieder/ben", 0, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doHaushalt(ben, http.MethodGet, base, 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Deleting a kid profile keeps its collections in the household
+	sammlung := models.Sammlung{WebuserID: "anna", HaushaltID: &haushalt.ID, KinderprofilID: &kind.ID}
+	require.NoError(t, db.Create(&sammlung).Error)
+	w = doHaushalt(anna, http.MethodDelete, fmt.Sprintf("%s/kinder/%d", base, kind.ID), 0, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doHaushalt(anna, http.MethodDelete, fmt.Sprintf("%s/kinder/%d", base, kind.ID), 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var reloaded models.Sammlung
+	require.NoError(t, db.First(&reloaded, sammlung.ID).Error)
+	assert.Nil(t, reloaded.KinderprofilID)
+	require.NotNil(t, reloaded.HaushaltID)
+}
+
+func TestHaushaltScoping(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	for _, id := range []string{"anna", "ben", "fremd"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id, Name: strPtr(id)}).Error)
+	}
+	haushalt := models.Haushalt{Name: "Familie Meier"}
+	require.NoError(t, db.Create(&haushalt).Error)
+	require.NoError(t, db.Omit("Haushalt", "Webuser").Create(&[]models.HaushaltMitgliedschaft{
+		{HaushaltID: haushalt.ID, WebuserID: "anna", Rolle: models.HaushaltVerwalter},
+		{HaushaltID: haushalt.ID, WebuserID: "ben", Rolle: models.HaushaltMitglied},
+	}).Error)
+	kind := models.Kinderprofil{HaushaltID: haushalt.ID, Name: "Mia"}
+	require.NoError(t, db.Create(&kind).Error)
+	anna, ben, fremd := haushaltRouter(db, "anna"), haushaltRouter(db, "ben"), haushaltRouter(db, "fremd")
+
+	// Invalid or foreign household header
+	w := doHaushalt(fremd, http.MethodGet, "/books", haushalt.ID, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	req, _ := http.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set(HaushaltHeader, "abc")
+	w = httptest.NewRecorder()
+	fremd.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Products created with an active household are only visible inside it
+	w = doHaushalt(anna, http.MethodPost, "/books", 0, map[string]string{"name": "Momo"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = doHaushalt(anna, http.MethodPost, "/books", haushalt.ID, map[string]string{"name": "Familienalbum"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var privat BookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &privat))
+
+	var books []BookResponse
+	w = doHaushalt(ben, http.MethodGet, "/books", haushalt.ID, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &books))
+	assert.Len(t, books, 2)
+	w = doHaushalt(ben, http.MethodGet, "/books", 0, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &books))
+	require.Len(t, books, 1)
+	assert.Equal(t, "Momo", books[0].Name)
+	w = doHaushalt(fremd, http.MethodGet, fmt.Sprintf("/books/%d", privat.ID), 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doHaushalt(ben, http.MethodGet, fmt.Sprintf("/books/%d", privat.ID), haushalt.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Household collections: kid profiles must belong to the active household
+	w = doHaushalt(anna, http.MethodPost, "/sammlungen", 0, map[string]interface{}{"name": "Privat", "kinderprofilId": kind.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doHaushalt(anna, http.MethodPost, "/sammlungen", haushalt.ID, map[string]interface{}{"name": "Mias Bücher", "kinderprofilId": kind.ID})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var sammlung models.Sammlung
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sammlung))
+	require.NotNil(t, sammlung.HaushaltID)
+	assert.Equal(t, kind.ID, *sammlung.KinderprofilID)
+
+	// Household members may edit household collections
+	items := fmt.Sprintf("/sammlung/%d/produkte", sammlung.ID)
+	w = doHaushalt(ben, http.MethodPost, items, haushalt.ID, map[string]uint{"produktId": privat.ID})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doHaushalt(fremd, http.MethodGet, items, 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doHaushalt(ben, http.MethodGet, "/sammlungen", haushalt.ID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var liste []SammlungListItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &liste))
+	require.Len(t, liste, 1)
+	assert.Equal(t, models.RolleBearbeiter, liste[0].Rolle)
+	w = doHaushalt(ben, http.MethodGet, "/sammlungen", 0, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &liste))
+	assert.Empty(t, liste)
+}
+
+func TestHaushaltProdukteBleibenPrivat(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ProduktCode{}, &models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}))
+	for _, id := range []string{"anna", "ben", "fremd"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id}).Error)
+	}
+	haushalt := models.Haushalt{Name: "Familie Meier"}
+	require.NoError(t, db.Create(&haushalt).Error)
+	require.NoError(t, db.Omit("Haushalt", "Webuser").Create(&[]models.HaushaltMitgliedschaft{
+		{HaushaltID: haushalt.ID, WebuserID: "anna", Rolle: models.HaushaltMitglied},
+		{HaushaltID: haushalt.ID, WebuserID: "ben", Rolle: models.HaushaltVerwalter},
+	}).Error)
+
+	album := models.Produkt{Name: "Familienalbum", Art: "Buch", HaushaltID: &haushalt.ID}
+	require.NoError(t, db.Create(&album).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: album.ID}).Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: album.ID, HaushaltID: &haushalt.ID, Typ: "ISBN", Code: "9780306406157"}).Error)
+	edition := models.Edition{ProduktID: album.ID, Format: "Hardcover"}
+	require.NoError(t, db.Create(&edition).Error)
+	video := models.Produkt{Name: "Urlaub", Art: "Filmserie", HaushaltID: &haushalt.ID}
+	require.NoError(t, db.Create(&video).Error)
+	require.NoError(t, db.Create(&models.Filmserie{ProdukteID: video.ID, Art: strPtr("Serie")}).Error)
+	staffel := models.Staffel{FilmserieID: video.ID, Nummer: 1}
+	require.NoError(t, db.Create(&staffel).Error)
+	episode := models.Episode{StaffelID: staffel.ID, Nummer: 1}
+	require.NoError(t, db.Create(&episode).Error)
+	momo := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&momo).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: momo.ID}).Error)
+
+	router := func(userID string) *gin.Engine {
+		r := haushaltRouter(db, userID)
+		r.PUT("/editionen/:editionId", UpdateEdition)
+		r.PUT("/episoden/:episodeId/gesehen", MarkEpisodeGesehen)
+		r.POST("/produkte/:id/codes", CreateProduktCode)
+		r.GET("/produkte/code/:code", GetProduktByCode)
+		r.DELETE("/sammlungen/:id", DeleteSammlung)
+		return r
+	}
+	anna, fremd := router("anna"), router("fremd")
+
+	// Editions and episodes of household products are hidden outside the household
+	editionPath := fmt.Sprintf("/editionen/%d", edition.ID)
+	w := doHaushalt(fremd, http.MethodPut, editionPath, 0, map[string]string{"format": "Paperback"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doHaushalt(anna, http.MethodPut, editionPath, haushalt.ID, map[string]string{"format": "Paperback"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	episodePath := fmt.Sprintf("/episoden/%d/gesehen", episode.ID)
+	w = doHaushalt(fremd, http.MethodPut, episodePath, 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doHaushalt(anna, http.MethodPut, episodePath, haushalt.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Codes are unique per catalog: a private household code does not block the global catalog
+	w = doHaushalt(fremd, http.MethodGet, "/produkte/code/9780306406157", 0, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	codePath := fmt.Sprintf("/produkte/%d/codes", momo.ID)
+	w = doHaushalt(fremd, http.MethodPost, codePath, 0, map[string]string{"code": "978-0-306-40615-7"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doHaushalt(fremd, http.MethodGet, "/produkte/code/9780306406157", 0, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, momo.ID))
+	// Inside the household its own product wins
+	w = doHaushalt(anna, http.MethodGet, "/produkte/code/9780306406157", haushalt.ID, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, album.ID))
+	// A conflict with a visible product names it
+	w = doHaushalt(anna, http.MethodPost, codePath, haushalt.ID, map[string]string{"code": "978-0-306-40615-7"})
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"produktId":%d`, album.ID))
+
+	// Household administrators may delete household collections of other members
+	sammlung := models.Sammlung{WebuserID: "anna", Name: strPtr("Fotos"), HaushaltID: &haushalt.ID}
+	require.NoError(t, db.Create(&sammlung).Error)
+	w = doHaushalt(router("ben"), http.MethodDelete, fmt.Sprintf("/sammlungen/%d", sammlung.ID), haushalt.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	var count int64
+	db.Model(&models.Sammlung{}).Where("id = ?", sammlung.ID).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+type HealthResponse struct {
+	Status   string             `json:"status"` // ok oder degraded
+	Database string             `json:"database"`
+	JWKS     []utils.JWKSStatus `json:"jwks"` // Je vertrauenswürdigem Issuer; leer bei lokalen Konten
+}
+
+// Health meldet, ob Datenbank und die Signaturschlüssel aller Issuer verfügbar sind. Fehlt eines davon,
+// antwortet der Endpunkt mit 503, damit Load-Balancer die Instanz nicht bedienen.
+func Health(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	response := HealthResponse{Status: "ok", Database: "ok", JWKS: utils.JWKSStates()}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		log.Printf("ERROR Health - Database: %v\n", err)
+		response.Database = "unavailable"
+		response.Status = "degraded"
+	}
+	if len(response.JWKS) == 0 && !utils.LocalMode() {
+		response.Status = "degraded"
+	}
+	for _, jwks := range response.JWKS {
+		if !jwks.Ready {
+			response.Status = "degraded"
+		}
+	}
+
+	code := http.StatusOK
+	if response.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, response)
+}
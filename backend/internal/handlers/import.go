@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/importer"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// importOption liest eine Option aus dem Multipart-Formular oder der Query
+func importOption(c *gin.Context, name string) string {
+	if value := c.PostForm(name); value != "" {
+		return value
+	}
+	return c.Query(name)
+}
+
+// importDryRun liest die Option dryRun (Standard: false)
+func importDryRun(c *gin.Context) (bool, bool) {
+	return importBool(c, "dryRun")
+}
+
+// importBool liest eine boolesche Option (Standard: false). Bei ungültigen Werten wird direkt geantwortet.
+func importBool(c *gin.Context, name string) (bool, bool) {
+	raw := importOption(c, name)
+	if raw == "" {
+		return false, true
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Field '%s' must be true or false", name)})
+		return false, false
+	}
+	return value, true
+}
+
+// importJobVersuche begrenzt die Versuche eines Imports
+const importJobVersuche = 3
+
+// runImport legt einen Hintergrundjob für den Import an und antwortet mit 202; das Ergebnis
+// liefert /api/jobs/:id. Nur ein Probelauf wird direkt ausgeführt und mit der Vorschau beantwortet.
+func runImport(c *gin.Context, db *gorm.DB, opts importer.Options, rows []importer.Row) {
+	if opts.DryRun {
+		result, err := importer.Import(db, opts, rows)
+		if err != nil {
+			log.Printf("ERROR Import preview for user %s: %v\n", opts.WebuserID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Import preview failed"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	job, err := jobs.Enqueue(db, opts.WebuserID, importer.JobTyp, importer.JobPayload{Options: opts, Rows: rows}, importJobVersuche)
+	if err != nil {
+		log.Printf("ERROR Import - Enqueue job for user %s: %v\n", opts.WebuserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+	jobAccepted(c, job)
+}
+
+// readImportFile liest die Importdatei entweder aus dem Multipart-Feld "file" oder direkt aus dem Body.
+// Bei Fehlern wird direkt geantwortet und ok=false zurückgegeben.
+func readImportFile(c *gin.Context) (data []byte, filename string, contentType string, ok bool) {
+	return readImportFileMax(c, importer.MaxBytes)
+}
+
+// readImportFileMax wie readImportFile, aber mit eigener Größenbegrenzung
+func readImportFileMax(c *gin.Context, maxBytes int) (data []byte, filename string, contentType string, ok bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBytes)+64<<10)
+	tooLarge := func() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import file must not exceed %d bytes", maxBytes)})
+	}
+
+	var reader io.Reader = c.Request.Body
+	contentType = c.ContentType()
+	if strings.HasPrefix(contentType, "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				tooLarge()
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Missing import file (form field 'file')"})
+			}
+			return nil, "", "", false
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+			return nil, "", "", false
+		}
+		defer file.Close()
+		reader, filename, contentType = file, fileHeader.Filename, fileHeader.Header.Get("Content-Type")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, int64(maxBytes)+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			tooLarge()
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+		}
+		return nil, "", "", false
+	}
+	if len(data) > maxBytes {
+		tooLarge()
+		return nil, "", "", false
+	}
+	return data, filename, contentType, true
+}
+
+// ImportSammlung importiert Produkte aus einer CSV- oder JSON-Datei in eine Sammlung. Der Import
+// läuft als Hintergrundjob (Antwort 202, Status und Ergebnis unter /api/jobs/:id).
+// Optionen (als Formularfeld oder Query-Parameter):
+//   - format: csv oder json (sonst aus Dateiname bzw. Content-Type erkannt)
+//   - art: Produktart für Dateien ohne eigene Spalte
+//   - mapping: JSON-Objekt Zielfeld -> Spaltenname, z.B. {"name":"Titel","code":"ISBN"}
+//   - dryRun: true liefert sofort nur die Vorschau, ohne etwas zu speichern
+func ImportSammlung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "sammlungId", models.RolleBearbeiter)
+	if sammlung == nil {
+		return
+	}
+
+	data, filename, contentType, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+
+	format := importOption(c, "format")
+	if format == "" {
+		format = importer.DetectFormat(filename, contentType)
+	}
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown import format, set 'format' to csv or json"})
+		return
+	}
+
+	var mapping importer.Mapping
+	if raw := importOption(c, "mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'mapping' must be a JSON object of field to column"})
+			return
+		}
+	}
+
+	defaultArt := importOption(c, "art")
+	if defaultArt != "" {
+		if _, _, ok := importer.NormalizeArt(defaultArt); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'art', allowed are Buch, Manga, Spiel and Filmserie"})
+			return
+		}
+	}
+
+	dryRun, ok := importDryRun(c)
+	if !ok {
+		return
+	}
+
+	rows, err := importer.Parse(format, data, mapping, defaultArt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	runImport(c, db, importer.Options{WebuserID: userID, HaushaltID: activeHaushaltID(c), SammlungID: sammlung.ID, DryRun: dryRun}, rows)
+}
+
+// ImportGoodreads importiert den Bibliotheksexport von Goodreads. Jedes Regal wird zu einer
+// gleichnamigen Sammlung, Bewertung, Lesedatum und Status landen an den Sammlungseinträgen.
+// Als Hintergrundjob mit Option dryRun wie bei ImportSammlung.
+func ImportGoodreads(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	data, _, _, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	dryRun, ok := importDryRun(c)
+	if !ok {
+		return
+	}
+
+	rows, err := importer.ParseGoodreads(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	runImport(c, db, importer.Options{WebuserID: userID, HaushaltID: activeHaushaltID(c), DryRun: dryRun}, rows)
+}
+
+// ImportMyAnimeList importiert den XML-Export von MyAnimeList bzw. AniList (auch als .xml.gz)
+// in eine Sammlung. Mangas werden mit Bandanzahl angelegt, Anime als Filmserie (Serie oder Film).
+// Status, Bewertung und Abschlussdatum landen an den Sammlungseinträgen. Als Hintergrundjob mit Option dryRun wie bei ImportSammlung.
+func ImportMyAnimeList(c *gin.Context) {
+	importFileIntoSammlung(c, func(_ string, data []byte) ([]importer.Row, error) {
+		return importer.ParseMyAnimeList(data)
+	})
+}
+
+// ImportLetterboxd importiert den Letterboxd-Export (Zip-Archiv oder einzelne CSV-Datei) als Filme
+// in eine Sammlung. Watchlist-Einträge erhalten den Status geplant. Als Hintergrundjob mit Option dryRun wie bei ImportSammlung.
+func ImportLetterboxd(c *gin.Context) {
+	importFileIntoSammlung(c, importer.ParseLetterboxd)
+}
+
+// ImportNFO importiert .nfo-Dateien von Kodi oder Jellyfin (als Zip-Archiv) als Filme und Serien
+// in eine Sammlung. Als Hintergrundjob mit Option dryRun wie bei ImportSammlung.
+func ImportNFO(c *gin.Context) {
+	importFileIntoSammlung(c, func(_ string, data []byte) ([]importer.Row, error) {
+		return importer.ParseNFO(data)
+	})
+}
+
+// importFileIntoSammlung liest die Importdatei mit einem formatspezifischen Parser ein und
+// importiert sie in die Sammlung aus dem URL-Parameter sammlungId
+func importFileIntoSammlung(c *gin.Context, parse func(filename string, data []byte) ([]importer.Row, error)) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "sammlungId", models.RolleBearbeiter)
+	if sammlung == nil {
+		return
+	}
+
+	data, filename, _, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	dryRun, ok := importDryRun(c)
+	if !ok {
+		return
+	}
+
+	rows, err := parse(filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	runImport(c, db, importer.Options{WebuserID: userID, HaushaltID: activeHaushaltID(c), SammlungID: sammlung.ID, DryRun: dryRun}, rows)
+}
+
+// ImportCalibre importiert eine Calibre-Bibliothek (hochgeladene metadata.db) als Bücher.
+// Optionen (Formularfelder oder Query):
+//   - sammlungId: Sammlung für Bücher ohne zugeordnete Tags (optional)
+//   - tagMapping: JSON-Objekt Calibre-Tag -> Sammlungsname, z.B. {"gelesen":"Gelesen"}
+//   - dryRun: wie bei ImportSammlung, ohne Probelauf als Hintergrundjob
+func ImportCalibre(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	data, _, _, ok := readImportFileMax(c, importer.MaxCalibreBytes)
+	if !ok {
+		return
+	}
+
+	var sammlungID uint
+	if raw := importOption(c, "sammlungId"); raw != "" {
+		sammlung := findSammlungByID(c, db, userID, raw, models.RolleBearbeiter)
+		if sammlung == nil {
+			return
+		}
+		sammlungID = sammlung.ID
+	}
+
+	var opts importer.CalibreOptions
+	if raw := importOption(c, "tagMapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.TagSammlungen); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'tagMapping' must be a JSON object of tag to collection name"})
+			return
+		}
+	}
+
+	dryRun, ok := importDryRun(c)
+	if !ok {
+		return
+	}
+
+	rows, err := importer.ParseCalibre(data, opts)
+	if err != nil {
+		if errors.Is(err, importer.ErrNotCalibre) || errors.Is(err, importer.ErrTooManyRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("ERROR Reading Calibre library for user %s: %v\n", userID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read Calibre library"})
+		return
+	}
+
+	runImport(c, db, importer.Options{WebuserID: userID, HaushaltID: activeHaushaltID(c), SammlungID: sammlungID, DryRun: dryRun}, rows)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/importer"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupImportTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{}, &models.Spiel{},
+		&models.Filmserie{}, &models.Sammlung{}, &models.SammlungMitglied{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}, &models.Kinderprofil{}, &models.Edition{}, &models.SammlungProdukt{}, &models.ProduktCode{}, &models.Job{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func multipartImport(router *gin.Engine, path string, filename string, content string, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		_ = writer.WriteField(key, value)
+	}
+	part, _ := writer.CreateFormFile("file", filename)
+	_, _ = part.Write([]byte(content))
+	_ = writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportSammlung(t *testing.T) {
+	db := setupImportTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/sammlung/:sammlungId/import", ImportSammlung)
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "other-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user"}
+	require.NoError(t, db.Create(&sammlung).Error)
+	fremde := models.Sammlung{WebuserID: "other-user"}
+	require.NoError(t, db.Create(&fremde).Error)
+
+	csv := "Titel,Verfasser,ISBN\nMomo,Michael Ende,0-306-40615-2\nOhne ISBN,,123\n"
+	path := fmt.Sprintf("/sammlung/%d/import", sammlung.ID)
+	fields := map[string]string{"art": "Buch", "mapping": `{"autor":"Verfasser"}`, "dryRun": "true"}
+
+	// Dry run
+	w := multipartImport(router, path, "buecher.csv", csv, fields)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var preview importer.Result
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, 1, preview.Created)
+	assert.Equal(t, 1, preview.Rejected)
+	assert.Equal(t, 3, preview.Rows[1].Line)
+
+	var count int64
+	db.Model(&models.Produkt{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Real import
+	fields["dryRun"] = "false"
+	w = multipartImport(router, path, "buecher.csv", csv, fields)
+	var result importer.Result
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 1, result.Created)
+
+	var buch models.Buch
+	require.NoError(t, db.Preload("Produkt").First(&buch).Error)
+	assert.Equal(t, "Momo", buch.Produkt.Name)
+	assert.Equal(t, "Michael Ende", *buch.Autor)
+	db.Model(&models.SammlungProdukt{}).Where("sammlung_id = ?", sammlung.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Raw JSON body with options in the query
+	req, _ := http.NewRequest(http.MethodPost, path+"?art=Spiel",
+		strings.NewReader(`[{"name": "Zelda", "konsole": "Switch"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, "Spiel", result.Rows[0].Art)
+
+	// Errors
+	w = multipartImport(router, path, "buecher.txt", csv, map[string]string{"art": "Buch"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = multipartImport(router, path, "buecher.csv", csv, map[string]string{"art": "Comic"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = multipartImport(router, path, "buecher.csv", csv, map[string]string{"mapping": `{"name":"Nope"}`})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = multipartImport(router, fmt.Sprintf("/sammlung/%d/import", fremde.ID), "buecher.csv", csv, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestImportGoodreads(t *testing.T) {
+	db := setupImportTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/import/goodreads", ImportGoodreads)
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+
+	export := "Book Id,Title,Author,ISBN,ISBN13,My Rating,Date Read,Bookshelves,Exclusive Shelf\n" +
+		`1,"Momo",Michael Ende,"=""0306406152""","=""9780306406157""",4,2024/05/01,klassiker,read` + "\n"
+
+	w := multipartImport(router, "/import/goodreads", "goodreads_library_export.csv", export, map[string]string{"dryRun": "true"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var count int64
+	db.Model(&models.Sammlung{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = multipartImport(router, "/import/goodreads", "goodreads_library_export.csv", export, nil)
+	var result importer.Result
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, []int{2}, result.Unmatched)
+	assert.Equal(t, []string{"read", "klassiker"}, result.Rows[0].Sammlungen)
+
+	db.Model(&models.Sammlung{}).Where("webuser_id = ?", "test-user").Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	w = multipartImport(router, "/import/goodreads", "books.csv", "name\nMomo\n", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportMyAnimeList(t *testing.T) {
+	db := setupImportTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/sammlung/:sammlungId/import/myanimelist", ImportMyAnimeList)
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user"}
+	require.NoError(t, db.Create(&sammlung).Error)
+	path := fmt.Sprintf("/sammlung/%d/import/myanimelist", sammlung.ID)
+
+	export := `<myanimelist><anime><series_title>Mushishi</series_title><series_type>TV</series_type>` +
+		`<my_score>8</my_score><my_status>On-Hold</my_status></anime></myanimelist>`
+
+	w := multipartImport(router, path, "animelist.xml", export, nil)
+	var result importer.Result
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 1, result.Created)
+
+	var eintrag models.SammlungProdukt
+	require.NoError(t, db.Where("sammlung_id = ?", sammlung.ID).First(&eintrag).Error)
+	assert.Equal(t, models.StatusPausiert, *eintrag.Status)
+	assert.Equal(t, 4, *eintrag.Bewertung)
+
+	w = multipartImport(router, path, "books.csv", "name\nMomo\n", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportCalibre(t *testing.T) {
+	db := setupImportTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/import/calibre", ImportCalibre)
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user"}
+	require.NoError(t, db.Create(&sammlung).Error)
+
+	// Minimale Calibre-Bibliothek mit zwei Büchern
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	library, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	for _, statement := range []string{
+		`CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT, series_index REAL)`,
+		`CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER, author INTEGER)`,
+		`CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER, series INTEGER)`,
+		`CREATE TABLE languages (id INTEGER PRIMARY KEY, lang_code TEXT)`,
+		`CREATE TABLE books_languages_link (id INTEGER PRIMARY KEY, book INTEGER, lang_code INTEGER, item_order INTEGER)`,
+		`CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER, tag INTEGER)`,
+		`CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER, type TEXT, val TEXT)`,
+		`INSERT INTO books VALUES (1, 'Momo', 1.0), (2, 'Die unendliche Geschichte', 1.0)`,
+		`INSERT INTO tags VALUES (1, 'gelesen')`,
+		`INSERT INTO books_tags_link VALUES (1, 1, 1)`,
+	} {
+		require.NoError(t, library.Exec(statement).Error)
+	}
+	sqlDB, _ := library.DB()
+	require.NoError(t, sqlDB.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	fields := map[string]string{"sammlungId": fmt.Sprint(sammlung.ID), "tagMapping": `{"Gelesen":"Gelesen"}`}
+	w := multipartImport(router, "/import/calibre", "metadata.db", string(data), fields)
+	var result importer.Result
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, []string{"Gelesen"}, result.Rows[0].Sammlungen)
+
+	var count int64
+	db.Model(&models.SammlungProdukt{}).Where("sammlung_id = ?", sammlung.ID).Count(&count)
+	assert.Equal(t, int64(1), count, "only the untagged book lands in the default collection")
+
+	w = multipartImport(router, "/import/calibre", "metadata.db", string(data), map[string]string{"tagMapping": "[1]"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = multipartImport(router, "/import/calibre", "metadata.db", "name\nMomo\n", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = multipartImport(router, "/import/calibre", "metadata.db", string(data), map[string]string{"sammlungId": "999"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestImportLetterboxdAndNFO(t *testing.T) {
+	db := setupImportTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/sammlung/:sammlungId/import/letterboxd", ImportLetterboxd)
+	router.POST("/sammlung/:sammlungId/import/nfo", ImportNFO)
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user"}
+	require.NoError(t, db.Create(&sammlung).Error)
+
+	diary := "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n2024-02-11,Alien,1979,x,5,,,2024-02-10\n"
+	w := multipartImport(router, fmt.Sprintf("/sammlung/%d/import/letterboxd", sammlung.ID), "diary.csv", diary, nil)
+	var result importer.Result
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 1, result.Created)
+
+	// Der Film aus der NFO-Datei wird dem bereits importierten Produkt zugeordnet
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	part, _ := writer.Create("Alien (1979)/movie.nfo")
+	_, _ = part.Write([]byte(`<movie><title>Alien</title><genre>Horror</genre></movie>`))
+	require.NoError(t, writer.Close())
+
+	w = multipartImport(router, fmt.Sprintf("/sammlung/%d/import/nfo", sammlung.ID), "kodi.zip", archive.String(), nil)
+	result = importer.Result{}
+	runQueuedJob(t, db, w, importer.RunJob, &result)
+	assert.Equal(t, 1, result.Matched)
+	assert.True(t, result.Rows[0].InSammlung)
+
+	w = multipartImport(router, fmt.Sprintf("/sammlung/%d/import/nfo", sammlung.ID), "movie.nfo", "<movie/>", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
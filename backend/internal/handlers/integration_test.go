@@ -29,6 +29,12 @@ func setupIntegrationDB(t *testing.T) *gorm.DB {
 		&models.Filmserie{},
 		&models.Webuser{},
 		&models.Sammlung{},
+		&models.SammlungMitglied{},
+		&models.Haushalt{},
+		&models.HaushaltMitgliedschaft{},
+		&models.Kinderprofil{},
+		&models.Cover{},
+		&models.Benutzereinstellungen{},
 	)
 	require.NoError(t, err)
 
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type JobResponse struct {
+	ID                 uint            `json:"id"`
+	Typ                string          `json:"typ"`
+	Status             string          `json:"status"`
+	Fortschritt        int             `json:"fortschritt"`
+	Versuche           int             `json:"versuche"`
+	MaxVersuche        int             `json:"maxVersuche"`
+	AbbruchAngefordert bool            `json:"abbruchAngefordert"`
+	Fehler             *string         `json:"fehler,omitempty"`
+	Ergebnis           json.RawMessage `json:"ergebnis,omitempty"`
+	NaechsterVersuch   *time.Time      `json:"naechsterVersuch,omitempty"` // Nur bei wartenden Jobs
+	ErstelltAm         time.Time       `json:"erstelltAm"`
+	GestartetAm        *time.Time      `json:"gestartetAm,omitempty"`
+	BeendetAm          *time.Time      `json:"beendetAm,omitempty"`
+}
+
+func toJobResponse(job models.Job) JobResponse {
+	response := JobResponse{
+		ID:                 job.ID,
+		Typ:                job.Typ,
+		Status:             job.Status,
+		Fortschritt:        job.Fortschritt,
+		Versuche:           job.Versuche,
+		MaxVersuche:        job.MaxVersuche,
+		AbbruchAngefordert: job.AbbruchAngefordert,
+		Fehler:             job.Fehler,
+		ErstelltAm:         job.ErstelltAm,
+		GestartetAm:        job.GestartetAm,
+		BeendetAm:          job.BeendetAm,
+	}
+	if job.Ergebnis != nil {
+		response.Ergebnis = json.RawMessage(*job.Ergebnis)
+	}
+	if job.Status == models.JobWartend {
+		naechsterVersuch := job.NaechsterVersuch
+		response.NaechsterVersuch = &naechsterVersuch
+	}
+	return response
+}
+
+// jobAccepted antwortet auf einen neu angelegten Job mit 202; den Status liefert /api/jobs/:id
+func jobAccepted(c *gin.Context, job *models.Job) {
+	c.Header("Location", fmt.Sprintf("/api/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, toJobResponse(*job))
+}
+
+// findOwnJob lädt einen Job des Benutzers anhand der ID aus der URL.
+// Jobs anderer Benutzer werden wie nicht vorhandene behandelt.
+func findOwnJob(c *gin.Context, db *gorm.DB, userID string) *models.Job {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID format"})
+		return nil
+	}
+	var job models.Job
+	if err := db.Where("id = ? AND webuser_id = ?", uint(jobID), userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		} else {
+			log.Printf("ERROR retrieving job %d: %v\n", jobID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve job"})
+		}
+		return nil
+	}
+	return &job
+}
+
+// ListJobs liefert die letzten Jobs des Benutzers, optional gefiltert nach ?status=
+func ListJobs(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := db.Where("webuser_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var found []models.Job
+	if err := query.Order("id DESC").Limit(100).Find(&found).Error; err != nil {
+		log.Printf("ERROR ListJobs for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve jobs"})
+		return
+	}
+
+	response := make([]JobResponse, len(found))
+	for i, job := range found {
+		response[i] = toJobResponse(job)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetJob liefert Status, Fortschritt und ggf. Ergebnis eines Jobs
+func GetJob(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	job := findOwnJob(c, db, userID)
+	if job == nil {
+		return
+	}
+	c.JSON(http.StatusOK, toJobResponse(*job))
+}
+
+// CancelJob bricht einen wartenden Job ab bzw. fordert den Abbruch eines laufenden Jobs an
+func CancelJob(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	job := findOwnJob(c, db, userID)
+	if job == nil {
+		return
+	}
+	if err := jobs.Cancel(db, job); err != nil {
+		if errors.Is(err, jobs.ErrFinished) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Job has already finished"})
+			return
+		}
+		log.Printf("ERROR CancelJob %d: %v\n", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+		return
+	}
+	c.JSON(http.StatusOK, toJobResponse(*job))
+}
+
+// RetryJob stellt einen fehlgeschlagenen oder abgebrochenen Job erneut ein
+func RetryJob(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	job := findOwnJob(c, db, userID)
+	if job == nil {
+		return
+	}
+	if err := jobs.Retry(db, job); err != nil {
+		if errors.Is(err, jobs.ErrNotRetryable) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only failed or canceled jobs can be retried"})
+			return
+		}
+		log.Printf("ERROR RetryJob %d: %v\n", job.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		return
+	}
+	c.JSON(http.StatusOK, toJobResponse(*job))
+}
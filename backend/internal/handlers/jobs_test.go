@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/export"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/importer"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// runQueuedJob checks the 202 response for a new job, runs the job like a worker would and
+// decodes its result into result
+func runQueuedJob(t *testing.T, db *gorm.DB, w *httptest.ResponseRecorder, handler jobs.Handler, result interface{}) models.Job {
+	t.Helper()
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var queued JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, fmt.Sprintf("/api/jobs/%d", queued.ID), w.Header().Get("Location"))
+
+	var job models.Job
+	require.NoError(t, db.First(&job, queued.ID).Error)
+	value, err := handler(context.Background(), &jobs.Run{Job: &job, DB: db})
+	require.NoError(t, err)
+	encoded, err := json.Marshal(value)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&job).Updates(map[string]interface{}{
+		"status": models.JobErfolgreich, "ergebnis": string(encoded), "fortschritt": 100, "versuche": 1,
+	}).Error)
+	if result != nil {
+		require.NoError(t, json.Unmarshal(encoded, result))
+	}
+	return job
+}
+
+func TestImportJob(t *testing.T) {
+	db := setupImportTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/sammlung/:sammlungId/import", ImportSammlung)
+	router.GET("/jobs", ListJobs)
+	router.GET("/jobs/:id", GetJob)
+	router.POST("/jobs/:id/cancel", CancelJob)
+	router.POST("/jobs/:id/retry", RetryJob)
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "other-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user"}
+	require.NoError(t, db.Create(&sammlung).Error)
+
+	csv := "Titel,ISBN\nMomo,0-306-40615-2\n"
+	path := fmt.Sprintf("/sammlung/%d/import", sammlung.ID)
+
+	w := multipartImport(router, path, "buecher.csv", csv, map[string]string{"art": "Buch"})
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var queued JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, importer.JobTyp, queued.Typ)
+	assert.Equal(t, models.JobWartend, queued.Status)
+	assert.NotNil(t, queued.NaechsterVersuch)
+	assert.Equal(t, fmt.Sprintf("/api/jobs/%d", queued.ID), w.Header().Get("Location"))
+
+	var count int64
+	db.Model(&models.Produkt{}).Count(&count)
+	assert.Equal(t, int64(0), count, "nothing is imported before a worker runs the job")
+
+	// Run the job like a worker would
+	var job models.Job
+	require.NoError(t, db.First(&job, queued.ID).Error)
+	result, err := importer.RunJob(context.Background(), &jobs.Run{Job: &job, DB: db})
+	require.NoError(t, err)
+	encoded, _ := json.Marshal(result)
+	ergebnis := string(encoded)
+	require.NoError(t, db.Model(&job).Updates(map[string]interface{}{
+		"status": models.JobErfolgreich, "ergebnis": ergebnis, "fortschritt": 100, "versuche": 1,
+	}).Error)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/jobs/%d", job.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var done struct {
+		JobResponse
+		Ergebnis importer.Result `json:"ergebnis"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &done))
+	assert.Equal(t, models.JobErfolgreich, done.Status)
+	assert.Equal(t, 100, done.Fortschritt)
+	assert.Equal(t, 1, done.Ergebnis.Created)
+	assert.Nil(t, done.NaechsterVersuch)
+
+	// Finished jobs can neither be canceled nor retried
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/jobs/%d/cancel", job.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/jobs/%d/retry", job.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(router, http.MethodGet, "/jobs?status=wartend", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+func TestCancelAndRetryJob(t *testing.T) {
+	db := setupImportTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+	router := setupCollectionTestRouter(db, "test-user")
+	router.GET("/jobs", ListJobs)
+	router.GET("/jobs/:id", GetJob)
+	router.POST("/jobs/:id/cancel", CancelJob)
+	router.POST("/jobs/:id/retry", RetryJob)
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "other-user"}).Error)
+	own, err := jobs.Enqueue(db, "test-user", "export", nil, 3)
+	require.NoError(t, err)
+	foreign, err := jobs.Enqueue(db, "other-user", "export", nil, 3)
+	require.NoError(t, err)
+
+	w := doJSON(router, http.MethodGet, fmt.Sprintf("/jobs/%d", foreign.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/jobs/%d/cancel", foreign.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodGet, "/jobs/abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodGet, "/jobs", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, own.ID, list[0].ID)
+
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/jobs/%d/cancel", own.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var canceled JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &canceled))
+	assert.Equal(t, models.JobAbgebrochen, canceled.Status)
+	assert.NotNil(t, canceled.BeendetAm)
+
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/jobs/%d/retry", own.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var retried JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &retried))
+	assert.Equal(t, models.JobWartend, retried.Status)
+	assert.False(t, retried.AbbruchAngefordert)
+	assert.Nil(t, retried.BeendetAm)
+}
+
+func TestExportJob(t *testing.T) {
+	db := setupImportTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.Use(func(c *gin.Context) {
+		c.Set("storage", storage.Store(store))
+		c.Next()
+	})
+	router.GET("/sammlungen/:id/export", ExportSammlung)
+	router.GET("/jobs/:id/download", DownloadJobExport)
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user"}).Error)
+	sammlung := models.Sammlung{WebuserID: "test-user", Name: strPtr("Regal")}
+	require.NoError(t, db.Create(&sammlung).Error)
+	produkt := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: produkt.ID}).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: sammlung.ID, ProduktID: produkt.ID}).Error)
+
+	w := doJSON(router, http.MethodGet, fmt.Sprintf("/sammlungen/%d/export?format=json", sammlung.ID), nil)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var queued JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, export.JobTyp, queued.Typ)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/jobs/%d/download", queued.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code, "nothing to download before a worker runs the job")
+
+	// Run the job like a worker would
+	var job models.Job
+	require.NoError(t, db.First(&job, queued.ID).Error)
+	result, err := export.NewJobHandler(store)(context.Background(), &jobs.Run{Job: &job, DB: db})
+	require.NoError(t, err)
+	encoded, _ := json.Marshal(result)
+	require.NoError(t, db.Model(&job).Updates(map[string]interface{}{"status": models.JobErfolgreich, "ergebnis": string(encoded)}).Error)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/jobs/%d/download", queued.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), fmt.Sprintf(`filename="sammlung-%d.json"`, sammlung.ID))
+	assert.Contains(t, w.Body.String(), `"Momo"`)
+}
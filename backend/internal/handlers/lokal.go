@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Präfixe der Tokens im lokalen Modus
+const (
+	refreshTokenPraefix = "drt_"
+	einladungPraefix    = "dinv_"
+)
+
+// passwortMaxLaenge begrenzt den Aufwand für argon2id bei sehr langen Eingaben
+const passwortMaxLaenge = 256
+
+var benutzernameRegex = regexp.MustCompile(`^[a-z0-9._-]{3,64}$`)
+
+// Fehler bei Registrierung und Anmeldung, die als 4xx beantwortet werden
+var (
+	errRegistrierungGeschlossen = errors.New("registration is closed")
+	errEinladungUngueltig       = errors.New("invalid or expired invitation")
+	errBenutzernameVergeben     = errors.New("username already taken")
+	errRefreshTokenUngueltig    = errors.New("invalid refresh token")
+)
+
+type RegisterRequest struct {
+	Benutzername string  `json:"benutzername" binding:"required"`
+	Passwort     string  `json:"passwort" binding:"required"`
+	Name         *string `json:"name"`      // Anzeigename, ohne Angabe der Benutzername
+	Einladung    string  `json:"einladung"` // Pflicht bei LOCAL_AUTH_REGISTRATION=invite
+}
+
+type LoginRequest struct {
+	Benutzername string `json:"benutzername" binding:"required"`
+	Passwort     string `json:"passwort" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	AltesPasswort string `json:"altesPasswort" binding:"required"`
+	NeuesPasswort string `json:"neuesPasswort" binding:"required"`
+}
+
+// TokenResponse folgt in Aufbau und Bedeutung der Antwort eines OAuth2-Token-Endpunkts
+type TokenResponse struct {
+	AccessToken      string `json:"accessToken"`
+	TokenType        string `json:"tokenType"`
+	ExpiresIn        int64  `json:"expiresIn"` // Sekunden
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresIn int64  `json:"refreshExpiresIn"`
+}
+
+type RegistrierungseinladungResponse struct {
+	Code       string    `json:"code"`
+	GueltigBis time.Time `json:"gueltigBis"`
+}
+
+func newLokalToken(praefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return praefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// newWebuserID erzeugt eine zufällige UUID (Version 4) wie die sub-Claims von Keycloak
+func newWebuserID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	raw[6] = raw[6]&0x0f | 0x40
+	raw[8] = raw[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", raw[0:4], raw[4:6], raw[6:8], raw[8:10], raw[10:16]), nil
+}
+
+// validatePasswort prüft die Länge eines neuen Passworts und antwortet bei Fehlern mit 400
+func validatePasswort(c *gin.Context, passwort string) bool {
+	if len([]rune(passwort)) < utils.Local.PasswordMinLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Password must be at least %d characters long", utils.Local.PasswordMinLength),
+		})
+		return false
+	}
+	if len(passwort) > passwortMaxLaenge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at most %d bytes long", passwortMaxLaenge)})
+		return false
+	}
+	return true
+}
+
+// dummyPasswortHash wird bei unbekannten Benutzernamen geprüft, damit die Antwortzeit nicht
+// verrät, ob ein Konto existiert
+var dummyPasswortHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword("diplodocu-dummy-password")
+	if err != nil {
+		log.Printf("ERROR creating dummy password hash: %v\n", err)
+	}
+	return hash
+})
+
+// issueTokens stellt ein Access Token und ein neues Refresh Token für ein lokales Konto aus
+func issueTokens(db *gorm.DB, konto models.LokalesKonto, now time.Time) (*TokenResponse, error) {
+	var webuser models.Webuser
+	if err := db.Where("id = ?", konto.WebuserID).First(&webuser).Error; err != nil {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+	anzeigename := konto.Benutzername
+	if webuser.Anzeigename != nil && *webuser.Anzeigename != "" {
+		anzeigename = *webuser.Anzeigename
+	}
+
+	accessToken, expiresAt, err := utils.IssueAccessToken(konto.WebuserID, konto.Benutzername, anzeigename, konto.RollenListe(), now)
+	if err != nil {
+		return nil, fmt.Errorf("sign access token: %w", err)
+	}
+	refreshToken, err := newLokalToken(refreshTokenPraefix)
+	if err != nil {
+		return nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+	gespeichert := models.RefreshToken{
+		WebuserID:  konto.WebuserID,
+		Hash:       database.HashToken(refreshToken),
+		ErstelltAm: now,
+		GueltigBis: now.Add(utils.Local.RefreshTokenTTL),
+	}
+	if err := db.Omit("Webuser").Create(&gespeichert).Error; err != nil {
+		return nil, fmt.Errorf("store refresh token: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(expiresAt.Sub(now).Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int64(gespeichert.GueltigBis.Sub(now).Seconds()),
+	}, nil
+}
+
+// revokeRefreshTokens widerruft alle noch gültigen Refresh Tokens eines Benutzers
+func revokeRefreshTokens(db *gorm.DB, webuserID string, now time.Time) error {
+	return db.Model(&models.RefreshToken{}).Where("webuser_id = ? AND widerrufen_am IS NULL", webuserID).
+		UpdateColumn("widerrufen_am", now).Error
+}
+
+// Register legt ein lokales Konto an und meldet es direkt an. Das erste Konto erhält die
+// Rolle admin und kann sich unabhängig von LOCAL_AUTH_REGISTRATION registrieren.
+func Register(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request RegisterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	benutzername := strings.ToLower(strings.TrimSpace(request.Benutzername))
+	if !benutzernameRegex.MatchString(benutzername) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Field 'benutzername' must be 3-64 characters of a-z, 0-9, '.', '_' or '-'",
+		})
+		return
+	}
+	if !validatePasswort(c, request.Passwort) {
+		return
+	}
+	name := benutzername
+	if request.Name != nil && strings.TrimSpace(*request.Name) != "" {
+		name = strings.TrimSpace(*request.Name)
+	}
+
+	// Das Hashen dauert bewusst lange und geschieht daher vor der Transaktion
+	hash, err := utils.HashPassword(request.Passwort)
+	if err != nil {
+		log.Printf("ERROR Register - Hash password: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+	webuserID, err := newWebuserID()
+	if err != nil {
+		log.Printf("ERROR Register - Generate user ID: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+
+	now := time.Now().UTC()
+	var tokens *TokenResponse
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := sperreKonten(tx); err != nil {
+			return err
+		}
+		var konten int64
+		if err := tx.Model(&models.LokalesKonto{}).Count(&konten).Error; err != nil {
+			return err
+		}
+		var rollen string
+		if konten == 0 {
+			rollen = utils.RoleAdmin
+		} else {
+			switch utils.Local.Registration {
+			case config.RegistrationClosed:
+				return errRegistrierungGeschlossen
+			case config.RegistrationInvite:
+				if err := redeemEinladung(tx, request.Einladung, now); err != nil {
+					return err
+				}
+			}
+		}
+
+		var vergeben int64
+		if err := tx.Model(&models.LokalesKonto{}).Where("benutzername = ?", benutzername).Count(&vergeben).Error; err != nil {
+			return err
+		}
+		if vergeben > 0 {
+			return errBenutzernameVergeben
+		}
+
+		if err := tx.Create(&models.Webuser{ID: webuserID, Name: &benutzername, Anzeigename: &name}).Error; err != nil {
+			return err
+		}
+		konto := models.LokalesKonto{
+			WebuserID:           webuserID,
+			Benutzername:        benutzername,
+			PasswortHash:        hash,
+			Rollen:              rollen,
+			ErstelltAm:          now,
+			PasswortGeaendertAm: now,
+		}
+		if err := tx.Omit("Webuser").Create(&konto).Error; err != nil {
+			return err
+		}
+		var err error
+		tokens, err = issueTokens(tx, konto, now)
+		return err
+	})
+	switch {
+	case errors.Is(err, errRegistrierungGeschlossen):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is closed"})
+	case errors.Is(err, errEinladungUngueltig):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration requires a valid invitation"})
+	case errors.Is(err, errBenutzernameVergeben):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+	case err != nil:
+		log.Printf("ERROR Register %s: %v\n", benutzername, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+	default:
+		log.Printf("Registered local account %s (%s)", benutzername, webuserID)
+		c.JSON(http.StatusCreated, tokens)
+	}
+}
+
+// sperreKonten serialisiert Registrierungen bis zum Ende der Transaktion. Sonst könnten zwei
+// gleichzeitige erste Registrierungen beide keine Konten sehen und beide admin werden.
+// PostgreSQL sperrt dazu die Tabelle gegen weitere Schreiber (Lesen bleibt möglich); SQLite
+// erlaubt ohnehin nur eine schreibende Transaktion.
+func sperreKonten(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("LOCK TABLE lokales_konto IN SHARE ROW EXCLUSIVE MODE").Error
+}
+
+// redeemEinladung löst einen Einladungscode ein; jeder Code gilt nur für ein Konto
+func redeemEinladung(tx *gorm.DB, code string, now time.Time) error {
+	if code == "" {
+		return errEinladungUngueltig
+	}
+	var einladung models.Registrierungseinladung
+	if err := tx.Where("hash = ?", database.HashToken(code)).First(&einladung).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errEinladungUngueltig
+		}
+		return err
+	}
+	if !einladung.Gueltig(now) {
+		return errEinladungUngueltig
+	}
+	result := tx.Model(&models.Registrierungseinladung{}).Where("id = ? AND eingeloest_am IS NULL", einladung.ID).
+		UpdateColumn("eingeloest_am", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errEinladungUngueltig
+	}
+	return nil
+}
+
+// Login prüft Benutzername und Passwort und stellt Access und Refresh Token aus
+func Login(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request LoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	invalid := func() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	}
+
+	var konto models.LokalesKonto
+	err := db.Where("benutzername = ?", strings.ToLower(strings.TrimSpace(request.Benutzername))).First(&konto).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("ERROR Login - Find account: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+			return
+		}
+		_, _ = utils.VerifyPassword(request.Passwort, dummyPasswortHash())
+		invalid()
+		return
+	}
+	if len(request.Passwort) > passwortMaxLaenge {
+		invalid()
+		return
+	}
+	ok, err := utils.VerifyPassword(request.Passwort, konto.PasswortHash)
+	if err != nil {
+		log.Printf("ERROR Login - Verify password of %s: %v\n", konto.WebuserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	if !ok {
+		invalid()
+		return
+	}
+
+	tokens, err := issueTokens(db, konto, time.Now().UTC())
+	if err != nil {
+		log.Printf("ERROR Login - Issue tokens for %s: %v\n", konto.WebuserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh tauscht ein Refresh Token gegen neue Tokens. Wird ein bereits verwendetes Token
+// erneut vorgelegt, wurde es vermutlich gestohlen; dann werden alle Refresh Tokens des
+// Benutzers widerrufen.
+func Refresh(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	var gespeichert models.RefreshToken
+	if err := db.Where("hash = ?", database.HashToken(request.RefreshToken)).First(&gespeichert).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		} else {
+			log.Printf("ERROR Refresh - Find token: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh tokens"})
+		}
+		return
+	}
+	if gespeichert.WiderrufenAm != nil {
+		log.Printf("WARNING: reuse of refresh token %d, revoking all sessions of %s", gespeichert.ID, gespeichert.WebuserID)
+		if err := revokeRefreshTokens(db, gespeichert.WebuserID, now); err != nil {
+			log.Printf("ERROR Refresh - Revoke sessions of %s: %v\n", gespeichert.WebuserID, err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	var tokens *TokenResponse
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !gespeichert.Gueltig(now) {
+			return errRefreshTokenUngueltig
+		}
+		// Bei gleichzeitiger Verwendung gewinnt nur eine Anfrage
+		result := tx.Model(&models.RefreshToken{}).Where("id = ? AND widerrufen_am IS NULL", gespeichert.ID).
+			UpdateColumn("widerrufen_am", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenUngueltig
+		}
+
+		var konto models.LokalesKonto
+		if err := tx.Where("webuser_id = ?", gespeichert.WebuserID).First(&konto).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTokenUngueltig
+			}
+			return err
+		}
+		var err error
+		tokens, err = issueTokens(tx, konto, now)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errRefreshTokenUngueltig) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		log.Printf("ERROR Refresh: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout widerruft ein Refresh Token. Unbekannte Tokens werden ebenso mit 204 beantwortet.
+func Logout(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	err := db.Model(&models.RefreshToken{}).
+		Where("hash = ? AND widerrufen_am IS NULL", database.HashToken(request.RefreshToken)).
+		UpdateColumn("widerrufen_am", time.Now().UTC()).Error
+	if err != nil {
+		log.Printf("ERROR Logout: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword ändert das Passwort des eigenen lokalen Kontos und meldet alle anderen
+// Sitzungen ab (Refresh Tokens; ausgestellte Access Tokens laufen regulär ab)
+func ChangePassword(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request ChangePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var konto models.LokalesKonto
+	if err := db.Where("webuser_id = ?", userID).First(&konto).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No local account for this user"})
+		} else {
+			log.Printf("ERROR ChangePassword - Find account %s: %v\n", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		}
+		return
+	}
+	valid, err := utils.VerifyPassword(request.AltesPasswort, konto.PasswortHash)
+	if err != nil {
+		log.Printf("ERROR ChangePassword - Verify password of %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if !validatePasswort(c, request.NeuesPasswort) {
+		return
+	}
+
+	hash, err := utils.HashPassword(request.NeuesPasswort)
+	if err != nil {
+		log.Printf("ERROR ChangePassword - Hash password: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	now := time.Now().UTC()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.LokalesKonto{}).Where("webuser_id = ?", userID).
+			Updates(map[string]interface{}{"passwort_hash": hash, "passwort_geaendert_am": now}).Error
+		if err != nil {
+			return err
+		}
+		return revokeRefreshTokens(tx, userID, now)
+	})
+	if err != nil {
+		log.Printf("ERROR ChangePassword for %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateRegistrierungseinladung erzeugt einen einmal verwendbaren Einladungscode für die
+// Registrierung. Der Code wird nur in dieser Antwort angezeigt.
+func CreateRegistrierungseinladung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	code, err := newLokalToken(einladungPraefix)
+	if err != nil {
+		log.Printf("ERROR CreateRegistrierungseinladung - Generate code: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	now := time.Now().UTC()
+	einladung := models.Registrierungseinladung{
+		Hash:        database.HashToken(code),
+		ErstelltVon: userID,
+		ErstelltAm:  now,
+		GueltigBis:  now.Add(utils.Local.InvitationTTL),
+	}
+	if err := db.Omit("Ersteller").Create(&einladung).Error; err != nil {
+		log.Printf("ERROR CreateRegistrierungseinladung for %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	c.JSON(http.StatusCreated, RegistrierungseinladungResponse{Code: code, GueltigBis: einladung.GueltigBis})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLokalTest aktiviert den lokalen Modus mit schnellen argon2id-Parametern
+func setupLokalTest(t *testing.T, registration string) (*gin.Engine, *gorm.DB) {
+	previousParams, previousLocal := utils.PasswordParams, utils.Local
+	t.Cleanup(func() { utils.PasswordParams, utils.Local = previousParams, previousLocal })
+	utils.PasswordParams.Memory = 1024
+	utils.PasswordParams.Iterations = 1
+	utils.PasswordParams.Parallelism = 1
+	require.NoError(t, utils.InitLocalAuth(&config.AuthConfig{
+		Mode:              config.AuthModeLocal,
+		LocalSecret:       "0123456789abcdef0123456789abcdef",
+		LocalIssuer:       "diplodocu-test",
+		AccessTokenTTL:    15 * time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		Registration:      registration,
+		InvitationTTL:     time.Hour,
+		PasswordMinLength: 10,
+	}))
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.LokalesKonto{}, &models.RefreshToken{}, &models.Registrierungseinladung{}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		// Wie die AuthMiddleware: Benutzer aus dem Access Token
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("userId", userID)
+		}
+		c.Next()
+	})
+	router.POST("/auth/register", Register)
+	router.POST("/auth/login", Login)
+	router.POST("/auth/refresh", Refresh)
+	router.POST("/auth/logout", Logout)
+	router.PUT("/me/passwort", ChangePassword)
+	router.POST("/auth/einladungen", CreateRegistrierungseinladung)
+	return router, db
+}
+
+func doLokal(router *gin.Engine, method, path, userID string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("X-Test-User", userID)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeTokens(t *testing.T, w *httptest.ResponseRecorder) TokenResponse {
+	var tokens TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens), w.Body.String())
+	return tokens
+}
+
+func TestRegisterWithInvitation(t *testing.T) {
+	router, db := setupLokalTest(t, config.RegistrationInvite)
+
+	// The first account can always register and becomes admin
+	w := doLokal(router, http.MethodPost, "/auth/register", "", gin.H{"benutzername": " Anna ", "passwort": "geheim-genug-1", "name": "Anna Meier"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	tokens := decodeTokens(t, w)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(900), tokens.ExpiresIn)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	var anna models.LokalesKonto
+	require.NoError(t, db.Where("benutzername = ?", "anna").First(&anna).Error)
+	assert.Equal(t, []string{utils.RoleAdmin}, anna.RollenListe())
+	assert.NotContains(t, anna.PasswortHash, "geheim")
+	var webuser models.Webuser
+	require.NoError(t, db.Where("id = ?", anna.WebuserID).First(&webuser).Error)
+	assert.Equal(t, "anna", *webuser.Name)
+	assert.Equal(t, "Anna Meier", *webuser.Anzeigename)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, webuser.ID)
+
+	// Further accounts need an invitation
+	ben := gin.H{"benutzername": "ben", "passwort": "auch-geheim-2"}
+	assert.Equal(t, http.StatusForbidden, doLokal(router, http.MethodPost, "/auth/register", "", ben).Code)
+	ben["einladung"] = "dinv_unknown"
+	assert.Equal(t, http.StatusForbidden, doLokal(router, http.MethodPost, "/auth/register", "", ben).Code)
+
+	w = doLokal(router, http.MethodPost, "/auth/einladungen", anna.WebuserID, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var einladung RegistrierungseinladungResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &einladung))
+	ben["einladung"] = einladung.Code
+	require.Equal(t, http.StatusCreated, doLokal(router, http.MethodPost, "/auth/register", "", ben).Code)
+
+	var benKonto models.LokalesKonto
+	require.NoError(t, db.Where("benutzername = ?", "ben").First(&benKonto).Error)
+	assert.Empty(t, benKonto.RollenListe())
+
+	// Invitations are single-use
+	carl := gin.H{"benutzername": "carl", "passwort": "noch-geheimer-3", "einladung": einladung.Code}
+	assert.Equal(t, http.StatusForbidden, doLokal(router, http.MethodPost, "/auth/register", "", carl).Code)
+}
+
+func TestRegisterValidation(t *testing.T) {
+	router, _ := setupLokalTest(t, config.RegistrationOpen)
+
+	require.Equal(t, http.StatusCreated, doLokal(router, http.MethodPost, "/auth/register", "",
+		gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"}).Code)
+	// Open registration needs no invitation, but usernames are unique
+	assert.Equal(t, http.StatusCreated, doLokal(router, http.MethodPost, "/auth/register", "",
+		gin.H{"benutzername": "ben", "passwort": "geheim-genug-2"}).Code)
+	assert.Equal(t, http.StatusConflict, doLokal(router, http.MethodPost, "/auth/register", "",
+		gin.H{"benutzername": "ANNA", "passwort": "geheim-genug-3"}).Code)
+
+	for _, body := range []gin.H{
+		{"passwort": "geheim-genug-1"},
+		{"benutzername": "a", "passwort": "geheim-genug-1"},
+		{"benutzername": "anna meier", "passwort": "geheim-genug-1"},
+		{"benutzername": "carl", "passwort": "kurz"},
+	} {
+		assert.Equal(t, http.StatusBadRequest, doLokal(router, http.MethodPost, "/auth/register", "", body).Code, body)
+	}
+}
+
+func TestRegisterClosed(t *testing.T) {
+	router, _ := setupLokalTest(t, config.RegistrationClosed)
+
+	assert.Equal(t, http.StatusCreated, doLokal(router, http.MethodPost, "/auth/register", "",
+		gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"}).Code)
+	assert.Equal(t, http.StatusForbidden, doLokal(router, http.MethodPost, "/auth/register", "",
+		gin.H{"benutzername": "ben", "passwort": "geheim-genug-2"}).Code)
+}
+
+func TestLoginRefreshLogout(t *testing.T) {
+	router, db := setupLokalTest(t, config.RegistrationOpen)
+	require.Equal(t, http.StatusCreated, doLokal(router, http.MethodPost, "/auth/register", "",
+		gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"}).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/login", "",
+		gin.H{"benutzername": "anna", "passwort": "falsch-falsch"}).Code)
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/login", "",
+		gin.H{"benutzername": "niemand", "passwort": "geheim-genug-1"}).Code)
+	w := doLokal(router, http.MethodPost, "/auth/login", "", gin.H{"benutzername": "Anna", "passwort": "geheim-genug-1"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	login := decodeTokens(t, w)
+
+	// Refresh tokens rotate
+	w = doLokal(router, http.MethodPost, "/auth/refresh", "", gin.H{"refreshToken": login.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	refreshed := decodeTokens(t, w)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+
+	// Reusing a rotated token revokes all sessions, including the new one
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/refresh", "", gin.H{"refreshToken": login.RefreshToken}).Code)
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/refresh", "", gin.H{"refreshToken": refreshed.RefreshToken}).Code)
+
+	w = doLokal(router, http.MethodPost, "/auth/login", "", gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"})
+	session := decodeTokens(t, w)
+	assert.Equal(t, http.StatusNoContent, doLokal(router, http.MethodPost, "/auth/logout", "", gin.H{"refreshToken": session.RefreshToken}).Code)
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/refresh", "", gin.H{"refreshToken": session.RefreshToken}).Code)
+	assert.Equal(t, http.StatusNoContent, doLokal(router, http.MethodPost, "/auth/logout", "", gin.H{"refreshToken": "drt_unknown"}).Code)
+
+	// Expired refresh tokens are rejected
+	w = doLokal(router, http.MethodPost, "/auth/login", "", gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"})
+	expired := decodeTokens(t, w)
+	require.NoError(t, db.Model(&models.RefreshToken{}).Where("widerrufen_am IS NULL").
+		UpdateColumn("gueltig_bis", time.Now().Add(-time.Minute)).Error)
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/refresh", "", gin.H{"refreshToken": expired.RefreshToken}).Code)
+}
+
+func TestChangePassword(t *testing.T) {
+	router, db := setupLokalTest(t, config.RegistrationOpen)
+	w := doLokal(router, http.MethodPost, "/auth/register", "", gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	session := decodeTokens(t, w)
+	var konto models.LokalesKonto
+	require.NoError(t, db.First(&konto).Error)
+
+	assert.Equal(t, http.StatusForbidden, doLokal(router, http.MethodPut, "/me/passwort", konto.WebuserID,
+		gin.H{"altesPasswort": "falsch-falsch", "neuesPasswort": "ganz-neu-und-lang"}).Code)
+	assert.Equal(t, http.StatusBadRequest, doLokal(router, http.MethodPut, "/me/passwort", konto.WebuserID,
+		gin.H{"altesPasswort": "geheim-genug-1", "neuesPasswort": "kurz"}).Code)
+	assert.Equal(t, http.StatusNotFound, doLokal(router, http.MethodPut, "/me/passwort", "oidc-user",
+		gin.H{"altesPasswort": "geheim-genug-1", "neuesPasswort": "ganz-neu-und-lang"}).Code)
+	require.Equal(t, http.StatusNoContent, doLokal(router, http.MethodPut, "/me/passwort", konto.WebuserID,
+		gin.H{"altesPasswort": "geheim-genug-1", "neuesPasswort": "ganz-neu-und-lang"}).Code)
+
+	// Existing sessions end, only the new password works
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/refresh", "", gin.H{"refreshToken": session.RefreshToken}).Code)
+	assert.Equal(t, http.StatusUnauthorized, doLokal(router, http.MethodPost, "/auth/login", "",
+		gin.H{"benutzername": "anna", "passwort": "geheim-genug-1"}).Code)
+	assert.Equal(t, http.StatusOK, doLokal(router, http.MethodPost, "/auth/login", "",
+		gin.H{"benutzername": "anna", "passwort": "ganz-neu-und-lang"}).Code)
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
@@ -18,16 +20,19 @@ type MangaRequest struct {
 	Mangaka *string `json:"mangaka"`                 // Manga-spezifisch
 	Sprache *string `json:"sprache"`                 // Manga-spezifisch
 	Genre   *string `json:"genre"`                   // Manga-spezifisch
+	Baende  *int    `json:"baende"`                  // Manga-spezifisch, Anzahl der Bände der Reihe
 }
 
 // MangaResponse definiert die JSON-Struktur für Manga-Antworten
 type MangaResponse struct {
-	ID      uint    `json:"id"`      // Vom Basisprodukt
-	Name    string  `json:"name"`    // Vom Basisprodukt
-	Nummer  *int    `json:"nummer"`  // Vom Basisprodukt
-	Mangaka *string `json:"mangaka"` // Manga-spezifisch
-	Sprache *string `json:"sprache"` // Manga-spezifisch
-	Genre   *string `json:"genre"`   // Manga-spezifisch
+	ID      uint           `json:"id"`              // Vom Basisprodukt
+	Name    string         `json:"name"`            // Vom Basisprodukt
+	Nummer  *int           `json:"nummer"`          // Vom Basisprodukt
+	Mangaka *string        `json:"mangaka"`         // Manga-spezifisch
+	Sprache *string        `json:"sprache"`         // Manga-spezifisch
+	Genre   *string        `json:"genre"`           // Manga-spezifisch
+	Baende  *int           `json:"baende"`          // Manga-spezifisch
+	Cover   *CoverResponse `json:"cover,omitempty"` // URLs von Original und Vorschaubildern
 }
 
 // --- Handler-Funktionen für Manga ---
@@ -54,9 +59,11 @@ func CreateManga(c *gin.Context) {
 
 	// 1. Basisprodukt erstellen
 	product := models.Produkt{
-		Name:   request.Name,
-		Nummer: request.Nummer,
-		Art:    "Manga", // Wichtig: Korrekten Typ setzen!
+		Name:        request.Name,
+		Nummer:      request.Nummer,
+		Art:         "Manga", // Wichtig: Korrekten Typ setzen!
+		ErstelltVon: optionalUserID(c),
+		HaushaltID:  activeHaushaltID(c),
 	}
 	if err := tx.Create(&product).Error; err != nil {
 		tx.Rollback()
@@ -71,6 +78,7 @@ func CreateManga(c *gin.Context) {
 		Mangaka:    request.Mangaka,
 		Sprache:    request.Sprache,
 		Genre:      request.Genre,
+		Baende:     request.Baende,
 	}
 	if err := tx.Create(&manga).Error; err != nil {
 		tx.Rollback()
@@ -94,6 +102,7 @@ func CreateManga(c *gin.Context) {
 		Mangaka: manga.Mangaka,
 		Sprache: manga.Sprache,
 		Genre:   manga.Genre,
+		Baende:  manga.Baende,
 	}
 	c.JSON(http.StatusCreated, response)
 }
@@ -106,7 +115,7 @@ func GetManga(c *gin.Context) {
 	var manga models.Manga
 	// Lade Manga und das zugehörige Produkt gleichzeitig
 	// Wichtig: Das Feld "Produkt" muss im Manga-Struct definiert sein (wie in deinem Beispiel)
-	err := db.Preload("Produkt").First(&manga, "produkte_id = ?", id).Error
+	err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).First(&manga, "produkte_id = ?", id).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -132,6 +141,8 @@ func GetManga(c *gin.Context) {
 		Mangaka: manga.Mangaka,
 		Sprache: manga.Sprache,
 		Genre:   manga.Genre,
+		Baende:  manga.Baende,
+		Cover:   loadCoverResponse(db, manga.ProdukteID),
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -157,7 +168,7 @@ func UpdateManga(c *gin.Context) {
 
 	// 1. Basisprodukt finden und aktualisieren (sicherstellen, dass es ein Manga ist)
 	var product models.Produkt
-	if err := tx.First(&product, "id = ? AND art = 'Manga'", id).Error; err != nil {
+	if err := tx.Scopes(katalogScope(c)).First(&product, "id = ? AND art = 'Manga'", id).Error; err != nil {
 		tx.Rollback() // Wichtig: Rollback auch hier!
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
@@ -192,6 +203,7 @@ func UpdateManga(c *gin.Context) {
 	manga.Mangaka = request.Mangaka
 	manga.Sprache = request.Sprache
 	manga.Genre = request.Genre
+	manga.Baende = request.Baende
 	if err := tx.Save(&manga).Error; err != nil {
 		tx.Rollback()
 		log.Printf("Error updating manga details for ID %s: %v", id, err) // Logging
@@ -214,6 +226,7 @@ func UpdateManga(c *gin.Context) {
 		Mangaka: manga.Mangaka,
 		Sprache: manga.Sprache,
 		Genre:   manga.Genre,
+		Baende:  manga.Baende,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -234,7 +247,7 @@ func DeleteManga(c *gin.Context) {
 	// Lösche das Basisprodukt. Durch 'ON DELETE CASCADE' im Model/DB-Schema
 	// sollte der zugehörige Manga-Eintrag automatisch mitgelöscht werden.
 	// Wichtig: Stelle sicher, dass die Art korrekt ist!
-	result := tx.Where("id = ? AND art = 'Manga'", id).Delete(&models.Produkt{})
+	result := tx.Scopes(katalogScope(c)).Where("id = ? AND art = 'Manga'", id).Delete(&models.Produkt{})
 
 	if result.Error != nil {
 		tx.Rollback()
@@ -264,10 +277,14 @@ func DeleteManga(c *gin.Context) {
 // ListMangas holt alle Mangas
 func ListMangas(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	sortierung, order, ok := produktSortierung(c, db)
+	if !ok {
+		return
+	}
 
 	var mangas []models.Manga
 	// Lade alle Mangas und ihre zugehörigen Produkt-Daten
-	err := db.Preload("Produkt").Find(&mangas).Error
+	err := db.Preload("Produkt").Where("produkte_id IN (?)", sichtbareProdukte(c, db)).Order(order).Find(&mangas).Error
 
 	if err != nil {
 		log.Printf("Error retrieving mangas: %v", err) // Logging
@@ -276,6 +293,12 @@ func ListMangas(c *gin.Context) {
 	}
 
 	// Konvertiere das Ergebnis in das Response-Format
+	produktIDs := make([]uint, len(mangas))
+	for i, item := range mangas {
+		produktIDs[i] = item.ProdukteID
+	}
+	covers := loadCoverResponses(db, produktIDs)
+
 	response := make([]MangaResponse, len(mangas))
 	for i, manga := range mangas {
 		// Sicherheitscheck, ob Produkt wirklich geladen wurde
@@ -296,8 +319,16 @@ func ListMangas(c *gin.Context) {
 			Mangaka: manga.Mangaka,
 			Sprache: manga.Sprache,
 			Genre:   manga.Genre,
+			Baende:  manga.Baende,
+			Cover:   covers[manga.ProdukteID],
 		}
 	}
 
+	if sortierung == models.SortierungName {
+		sort.SliceStable(response, func(i, j int) bool {
+			return strings.ToLower(response[i].Name) < strings.ToLower(response[j].Name)
+		})
+	}
+
 	c.JSON(http.StatusOK, response)
 }
@@ -18,7 +18,7 @@ func setupMangaTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&models.Produkt{}, &models.Manga{})
+	err = db.AutoMigrate(&models.Produkt{}, &models.Manga{}, &models.Cover{})
 	require.NoError(t, err)
 
 	return db
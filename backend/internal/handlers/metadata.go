@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/metadata"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+)
+
+// MetadataCandidateResponse beschreibt einen Treffer der Metadatensuche.
+// Request kann unverändert an den passenden Create*-Endpunkt geschickt werden.
+type MetadataCandidateResponse struct {
+	Provider   string      `json:"provider"`
+	ExternalID string      `json:"externalId,omitempty"`
+	Art        string      `json:"art"`                // Produktart und damit Ziel-Endpunkt
+	ISBN       *string     `json:"isbn,omitempty"`     // Kann anschließend als Code hinterlegt werden
+	Baende     *int        `json:"baende,omitempty"`   // Manga
+	Episoden   *int        `json:"episoden,omitempty"` // Serie
+	Request    interface{} `json:"request"`            // BookRequest, MangaRequest, SpielRequest oder FilmserieRequest
+}
+
+// MetadataLookupResponse fasst alle Treffer und Fehler einzelner Provider zusammen
+type MetadataLookupResponse struct {
+	Candidates []MetadataCandidateResponse `json:"candidates"`
+	Errors     map[string]string           `json:"errors,omitempty"`
+}
+
+// candidateRequest baut aus einem Treffer den Request-Body für den Create*-Handler der Produktart
+func candidateRequest(candidate metadata.Candidate) interface{} {
+	switch candidate.Art {
+	case "Buch":
+		return BookRequest{Name: candidate.Name, Nummer: candidate.Nummer, Autor: candidate.Autor, Sprache: candidate.Sprache, Genre: candidate.Genre}
+	case "Manga":
+		return MangaRequest{Name: candidate.Name, Nummer: candidate.Nummer, Mangaka: candidate.Mangaka, Sprache: candidate.Sprache, Genre: candidate.Genre, Baende: candidate.Baende}
+	case "Spiel":
+		return SpielRequest{Name: candidate.Name, Nummer: candidate.Nummer, Konsole: candidate.Konsole, Genre: candidate.Genre}
+	case "Filmserie":
+		return FilmserieRequest{Name: candidate.Name, Nummer: candidate.Nummer, Art: candidate.FilmArt, Genre: candidate.Genre}
+	default:
+		return nil
+	}
+}
+
+// LookupMetadata sucht Metadaten zu einem Namen oder einer ISBN bei den konfigurierten Providern
+func LookupMetadata(c *gin.Context) {
+	service := c.MustGet("metadata").(*metadata.Service)
+
+	query := metadata.Query{
+		Art:  c.Query("art"),
+		Name: strings.TrimSpace(c.Query("name")),
+	}
+	if query.Art != "" && !isProduktArt(query.Art) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'art' must be one of Buch, Manga, Spiel, Filmserie"})
+		return
+	}
+	if isbn := c.Query("isbn"); isbn != "" {
+		normalized, err := utils.NormalizeISBN(isbn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query.ISBN = normalized
+	}
+	if query.Name == "" && query.ISBN == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'name' or 'isbn' is required"})
+		return
+	}
+
+	result := service.Lookup(c.Request.Context(), query)
+
+	response := MetadataLookupResponse{
+		Candidates: make([]MetadataCandidateResponse, 0, len(result.Candidates)),
+		Errors:     result.Errors,
+	}
+	for _, candidate := range result.Candidates {
+		response.Candidates = append(response.Candidates, MetadataCandidateResponse{
+			Provider:   candidate.Provider,
+			ExternalID: candidate.ExternalID,
+			Art:        candidate.Art,
+			ISBN:       candidate.ISBN,
+			Baende:     candidate.Baende,
+			Episoden:   candidate.Episoden,
+			Request:    candidateRequest(candidate),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
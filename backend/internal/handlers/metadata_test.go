@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupMetadata(t *testing.T) {
+	openLibrary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"docs": [{"key": "/works/OL1W", "title": "Momo", "author_name": ["Michael Ende"], "language": ["ger"], "subject": ["Fantasy"]}]}`))
+	}))
+	defer openLibrary.Close()
+
+	service := metadata.NewService([]metadata.Provider{
+		metadata.NewOpenLibrary(openLibrary.URL, openLibrary.Client()),
+	}, time.Second, time.Minute, 10)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("metadata", service)
+		c.Next()
+	})
+	router.GET("/metadata/lookup", LookupMetadata)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		checkResponse  func(t *testing.T, body []byte)
+	}{
+		{
+			name:           "lookup by isbn-10 returns book request",
+			query:          "?isbn=0-306-40615-2",
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response struct {
+					Candidates []struct {
+						Provider string      `json:"provider"`
+						Art      string      `json:"art"`
+						ISBN     string      `json:"isbn"`
+						Request  BookRequest `json:"request"`
+					} `json:"candidates"`
+				}
+				require.NoError(t, json.Unmarshal(body, &response))
+				require.Len(t, response.Candidates, 1)
+				candidate := response.Candidates[0]
+				assert.Equal(t, "openlibrary", candidate.Provider)
+				assert.Equal(t, "Buch", candidate.Art)
+				assert.Equal(t, "9780306406157", candidate.ISBN)
+				assert.Equal(t, "Momo", candidate.Request.Name)
+				assert.Equal(t, "Michael Ende", *candidate.Request.Autor)
+				assert.Equal(t, "Deutsch", *candidate.Request.Sprache)
+			},
+		},
+		{
+			name:           "no provider for games",
+			query:          "?name=Zelda&art=Spiel",
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response MetadataLookupResponse
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Empty(t, response.Candidates)
+			},
+		},
+		{
+			name:           "missing name and isbn",
+			query:          "?art=Buch",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid isbn",
+			query:          "?isbn=123",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid art",
+			query:          "?name=Momo&art=Comic",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/metadata/lookup"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type InviteMitgliedRequest struct {
+	WebuserID string `json:"webuserId" binding:"required"`
+	Rolle     string `json:"rolle"` // betrachter (Standard) oder bearbeiter
+}
+
+type UpdateMitgliedRequest struct {
+	Rolle string `json:"rolle" binding:"required"`
+}
+
+type TransferSammlungRequest struct {
+	WebuserID string `json:"webuserId" binding:"required"` // Muss bereits Mitglied sein
+}
+
+type MitgliedResponse struct {
+	WebuserID    string     `json:"webuserId"`
+	Name         *string    `json:"name"`
+	Rolle        string     `json:"rolle"`
+	Ausstehend   bool       `json:"ausstehend"` // Einladung noch nicht angenommen
+	EingeladenAm *time.Time `json:"eingeladenAm,omitempty"`
+	AngenommenAm *time.Time `json:"angenommenAm,omitempty"`
+}
+
+type EinladungResponse struct {
+	SammlungID   uint      `json:"sammlungId"`
+	Name         *string   `json:"name"`
+	BesitzerID   string    `json:"besitzerId"`
+	Rolle        string    `json:"rolle"`
+	EingeladenAm time.Time `json:"eingeladenAm"`
+}
+
+func toMitgliedResponse(mitglied models.SammlungMitglied) MitgliedResponse {
+	eingeladenAm := mitglied.EingeladenAm
+	return MitgliedResponse{
+		WebuserID:    mitglied.WebuserID,
+		Name:         mitglied.Webuser.Name,
+		Rolle:        mitglied.Rolle,
+		Ausstehend:   mitglied.AngenommenAm == nil,
+		EingeladenAm: &eingeladenAm,
+		AngenommenAm: mitglied.AngenommenAm,
+	}
+}
+
+// mitgliedRolle prüft die Rolle aus einem Request. Besitzer wird man nur per Übertragung.
+func mitgliedRolle(c *gin.Context, rolle string) (string, bool) {
+	switch rolle {
+	case "":
+		return models.RolleBetrachter, true
+	case models.RolleBetrachter, models.RolleBearbeiter:
+		return rolle, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'rolle' must be 'betrachter' or 'bearbeiter'"})
+		return "", false
+	}
+}
+
+// findMitglied lädt die Mitgliedschaft aus dem URL-Parameter webuserId (auch offene Einladungen).
+// Bei Fehlern wird direkt geantwortet und nil zurückgegeben.
+func findMitglied(c *gin.Context, db *gorm.DB, sammlungID uint) *models.SammlungMitglied {
+	var mitglied models.SammlungMitglied
+	err := db.Preload("Webuser").First(&mitglied, "sammlung_id = ? AND webuser_id = ?", sammlungID, c.Param("webuserId")).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		} else {
+			log.Printf("ERROR findMitglied in Sammlung %d: %v\n", sammlungID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find member"})
+		}
+		return nil
+	}
+	return &mitglied
+}
+
+// ListMitglieder listet Besitzer, Mitglieder und offene Einladungen einer Sammlung
+func ListMitglieder(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBetrachter)
+	if sammlung == nil {
+		return
+	}
+
+	var besitzer models.Webuser
+	if err := db.First(&besitzer, "id = ?", sammlung.WebuserID).Error; err != nil {
+		log.Printf("ERROR ListMitglieder - Owner of Sammlung %d: %v\n", sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+		return
+	}
+	var mitglieder []models.SammlungMitglied
+	if err := db.Preload("Webuser").Where("sammlung_id = ?", sammlung.ID).Order("eingeladen_am, webuser_id").Find(&mitglieder).Error; err != nil {
+		log.Printf("ERROR ListMitglieder for Sammlung %d: %v\n", sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+		return
+	}
+
+	response := make([]MitgliedResponse, 0, len(mitglieder)+1)
+	response = append(response, MitgliedResponse{WebuserID: besitzer.ID, Name: besitzer.Name, Rolle: models.RolleBesitzer})
+	for _, mitglied := range mitglieder {
+		response = append(response, toMitgliedResponse(mitglied))
+	}
+
+	verborgen, err := verborgeneNamen(db, response)
+	if err != nil {
+		log.Printf("ERROR ListMitglieder - Privacy settings for Sammlung %d: %v\n", sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+		return
+	}
+	for i := range response {
+		if verborgen[response[i].WebuserID] && response[i].WebuserID != userID {
+			response[i].Name = nil
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// verborgeneNamen liefert die Mitglieder, die ihren Namen in Mitgliederlisten nicht zeigen wollen
+func verborgeneNamen(db *gorm.DB, mitglieder []MitgliedResponse) (map[string]bool, error) {
+	ids := make([]string, len(mitglieder))
+	for i, mitglied := range mitglieder {
+		ids[i] = mitglied.WebuserID
+	}
+	var einstellungen []models.Benutzereinstellungen
+	if err := db.Where("webuser_id IN ? AND name_sichtbar = ?", ids, false).Find(&einstellungen).Error; err != nil {
+		return nil, err
+	}
+	verborgen := make(map[string]bool, len(einstellungen))
+	for _, e := range einstellungen {
+		verborgen[e.WebuserID] = true
+	}
+	return verborgen, nil
+}
+
+// InviteMitglied lädt einen Benutzer anhand seiner Webuser-ID in eine Sammlung ein.
+// Zugriff erhält er erst, wenn er die Einladung annimmt.
+func InviteMitglied(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
+		return
+	}
+
+	var request InviteMitgliedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	rolle, ok := mitgliedRolle(c, request.Rolle)
+	if !ok {
+		return
+	}
+	if request.WebuserID == sammlung.WebuserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner cannot be invited"})
+		return
+	}
+
+	var eingeladener models.Webuser
+	if err := db.First(&eingeladener, "id = ?", request.WebuserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			log.Printf("ERROR InviteMitglied - Find user %s: %v\n", request.WebuserID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		}
+		return
+	}
+	einstellungen, err := loadEinstellungen(db, eingeladener.ID)
+	if err != nil {
+		log.Printf("ERROR InviteMitglied - Settings of user %s: %v\n", eingeladener.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+	if !einstellungen.EinladungenErlauben {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User does not accept invitations"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.SammlungMitglied{}).Where("sammlung_id = ? AND webuser_id = ?", sammlung.ID, eingeladener.ID).Count(&count).Error; err != nil {
+		log.Printf("ERROR InviteMitglied - Check membership: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member or invited"})
+		return
+	}
+
+	mitglied := models.SammlungMitglied{
+		SammlungID:   sammlung.ID,
+		WebuserID:    eingeladener.ID,
+		Rolle:        rolle,
+		EingeladenAm: time.Now().UTC(),
+		Webuser:      eingeladener,
+	}
+	if err := db.Omit("Sammlung", "Webuser").Create(&mitglied).Error; err != nil {
+		log.Printf("ERROR InviteMitglied to Sammlung %d: %v\n", sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toMitgliedResponse(mitglied))
+}
+
+// UpdateMitglied ändert die Rolle eines Mitglieds
+func UpdateMitglied(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
+		return
+	}
+
+	var request UpdateMitgliedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	rolle, ok := mitgliedRolle(c, request.Rolle)
+	if !ok {
+		return
+	}
+
+	mitglied := findMitglied(c, db, sammlung.ID)
+	if mitglied == nil {
+		return
+	}
+	err := db.Model(&models.SammlungMitglied{}).
+		Where("sammlung_id = ? AND webuser_id = ?", sammlung.ID, mitglied.WebuserID).
+		Update("rolle", rolle).Error
+	if err != nil {
+		log.Printf("ERROR UpdateMitglied %s in Sammlung %d: %v\n", mitglied.WebuserID, sammlung.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	mitglied.Rolle = rolle
+
+	c.JSON(http.StatusOK, toMitgliedResponse(*mitglied))
+}
+
+// RemoveMitglied entfernt ein Mitglied oder zieht eine Einladung zurück. Mitglieder
+// können sich selbst entfernen und verlassen damit die Sammlung.
+func RemoveMitglied(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	minRolle := models.RolleBesitzer
+	if c.Param("webuserId") == userID {
+		minRolle = models.RolleBetrachter
+	}
+	sammlung := findSammlung(c, db, userID, "id", minRolle)
+	if sammlung == nil {
+		return
+	}
+
+	result := db.Where("sammlung_id = ? AND webuser_id = ?", sammlung.ID, c.Param("webuserId")).Delete(&models.SammlungMitglied{})
+	if result.Error != nil {
+		log.Printf("ERROR RemoveMitglied from Sammlung %d: %v\n", sammlung.ID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TransferSammlung überträgt die Sammlung an ein Mitglied. Der bisherige Besitzer bleibt
+// als Bearbeiter Mitglied und kann die Sammlung danach selbst verlassen. Überträgt ein
+// Haushaltsverwalter die Sammlung eines anderen, gilt das ebenso für deren Besitzer.
+func TransferSammlung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlung := findSammlung(c, db, userID, "id", models.RolleBesitzer)
+	if sammlung == nil {
+		return
+	}
+
+	var request TransferSammlungRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var mitglieder []models.SammlungMitglied
+	err := db.Where("sammlung_id = ? AND webuser_id = ? AND angenommen_am IS NOT NULL", sammlung.ID, request.WebuserID).
+		Limit(1).Find(&mitglieder).Error
+	if err != nil {
+		log.Printf("ERROR TransferSammlung - Find member: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer collection"})
+		return
+	}
+	if len(mitglieder) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Collections can only be transferred to members who accepted their invitation"})
+		return
+	}
+
+	now := time.Now().UTC()
+	bisherigerBesitzer := sammlung.WebuserID
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sammlung_id = ? AND webuser_id = ?", sammlung.ID, request.WebuserID).Delete(&models.SammlungMitglied{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Sammlung{}).Where("id = ?", sammlung.ID).Update("webuser_id", request.WebuserID).Error; err != nil {
+			return err
+		}
+		bisheriger := models.SammlungMitglied{
+			SammlungID:   sammlung.ID,
+			WebuserID:    bisherigerBesitzer,
+			Rolle:        models.RolleBearbeiter,
+			EingeladenAm: now,
+			AngenommenAm: &now,
+		}
+		return tx.Omit("Sammlung", "Webuser").Create(&bisheriger).Error
+	})
+	if err != nil {
+		log.Printf("ERROR TransferSammlung %d to %s: %v\n", sammlung.ID, request.WebuserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer collection"})
+		return
+	}
+
+	sammlung.WebuserID = request.WebuserID
+	rolle, err := sammlungRolle(db, *sammlung, userID)
+	if err != nil {
+		log.Printf("ERROR TransferSammlung - Role of user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer collection"})
+		return
+	}
+	c.JSON(http.StatusOK, SammlungListItem{Sammlung: *sammlung, Rolle: rolle})
+}
+
+// ListEinladungen listet die offenen Einladungen des eingeloggten Benutzers
+func ListEinladungen(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var einladungen []models.SammlungMitglied
+	err := db.Preload("Sammlung").Where("webuser_id = ? AND angenommen_am IS NULL", userID).
+		Order("eingeladen_am").Find(&einladungen).Error
+	if err != nil {
+		log.Printf("ERROR ListEinladungen for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitations"})
+		return
+	}
+
+	response := make([]EinladungResponse, len(einladungen))
+	for i, einladung := range einladungen {
+		response[i] = EinladungResponse{
+			SammlungID:   einladung.SammlungID,
+			Name:         einladung.Sammlung.Name,
+			BesitzerID:   einladung.Sammlung.WebuserID,
+			Rolle:        einladung.Rolle,
+			EingeladenAm: einladung.EingeladenAm,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AcceptEinladung nimmt eine Einladung an; danach hat der Benutzer Zugriff gemäß seiner Rolle
+func AcceptEinladung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlungID, err := strconv.ParseUint(c.Param("sammlungId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+		return
+	}
+
+	result := db.Model(&models.SammlungMitglied{}).
+		Where("sammlung_id = ? AND webuser_id = ? AND angenommen_am IS NULL", uint(sammlungID), userID).
+		Update("angenommen_am", time.Now().UTC())
+	if result.Error != nil {
+		log.Printf("ERROR AcceptEinladung %d for user %s: %v\n", sammlungID, userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "Invitation accepted"})
+}
+
+// DeclineEinladung lehnt eine offene Einladung ab
+func DeclineEinladung(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sammlungID, err := strconv.ParseUint(c.Param("sammlungId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID format"})
+		return
+	}
+
+	result := db.Where("sammlung_id = ? AND webuser_id = ? AND angenommen_am IS NULL", uint(sammlungID), userID).
+		Delete(&models.SammlungMitglied{})
+	if result.Error != nil {
+		log.Printf("ERROR DeclineEinladung %d for user %s: %v\n", sammlungID, userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// mitgliedRouter registriert alle Sammlungsrouten für einen Benutzer
+func mitgliedRouter(db *gorm.DB, userID string) *gin.Engine {
+	router := setupCollectionTestRouter(db, userID)
+	router.GET("/sammlungen", ListUserSammlungen)
+	router.GET("/sammlungen/:id", GetSammlungDetail)
+	router.DELETE("/sammlungen/:id", DeleteSammlung)
+	router.GET("/sammlungen/:id/mitglieder", ListMitglieder)
+	router.POST("/sammlungen/:id/mitglieder", InviteMitglied)
+	router.PUT("/sammlungen/:id/mitglieder/:webuserId", UpdateMitglied)
+	router.DELETE("/sammlungen/:id/mitglieder/:webuserId", RemoveMitglied)
+	router.POST("/sammlungen/:id/besitzer", TransferSammlung)
+	router.GET("/einladungen", ListEinladungen)
+	router.POST("/einladungen/:sammlungId", AcceptEinladung)
+	router.DELETE("/einladungen/:sammlungId", DeclineEinladung)
+	router.GET("/sammlung/:sammlungId/produkte", ListSammlungItems)
+	router.POST("/sammlung/:sammlungId/produkte", AddProduktToSammlung)
+	router.DELETE("/sammlung/:sammlungId/produkte/:produktId", RemoveProduktFromSammlung)
+	return router
+}
+
+func TestSammlungMitglieder(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id, Name: strPtr(id)}).Error)
+	}
+	sammlung := models.Sammlung{WebuserID: "alice", Name: strPtr("Familienbibliothek")}
+	require.NoError(t, db.Create(&sammlung).Error)
+	produkt := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&produkt).Error)
+
+	alice, bob, carol, dave := mitgliedRouter(db, "alice"), mitgliedRouter(db, "bob"), mitgliedRouter(db, "carol"), mitgliedRouter(db, "dave")
+	base := fmt.Sprintf("/sammlungen/%d", sammlung.ID)
+	items := fmt.Sprintf("/sammlung/%d/produkte", sammlung.ID)
+
+	// Invitations
+	w := doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "bob", "rolle": "bearbeiter"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var eingeladen MitgliedResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &eingeladen))
+	assert.True(t, eingeladen.Ausstehend)
+	assert.Equal(t, "bob", *eingeladen.Name)
+	w = doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "carol"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "dave"})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "bob"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "alice"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "niemand"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(alice, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "carol", "rolle": "besitzer"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Pending invitations grant no access
+	w = doJSON(bob, http.MethodGet, items, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(bob, http.MethodGet, "/einladungen", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var einladungen []EinladungResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &einladungen))
+	require.Len(t, einladungen, 1)
+	assert.Equal(t, "alice", einladungen[0].BesitzerID)
+	assert.Equal(t, models.RolleBearbeiter, einladungen[0].Rolle)
+
+	require.Equal(t, http.StatusOK, doJSON(bob, http.MethodPost, fmt.Sprintf("/einladungen/%d", sammlung.ID), nil).Code)
+	require.Equal(t, http.StatusOK, doJSON(carol, http.MethodPost, fmt.Sprintf("/einladungen/%d", sammlung.ID), nil).Code)
+	require.Equal(t, http.StatusNoContent, doJSON(dave, http.MethodDelete, fmt.Sprintf("/einladungen/%d", sammlung.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, doJSON(dave, http.MethodPost, fmt.Sprintf("/einladungen/%d", sammlung.ID), nil).Code)
+
+	// Editors may change items, viewers only read them
+	w = doJSON(bob, http.MethodPost, items, map[string]uint{"produktId": produkt.ID})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doJSON(carol, http.MethodPost, items, map[string]uint{"produktId": produkt.ID})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(carol, http.MethodDelete, fmt.Sprintf("%s/%d", items, produkt.ID), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doJSON(carol, http.MethodGet, items, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var eintraege []SammlungItemResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &eintraege))
+	assert.Len(t, eintraege, 1)
+	assert.Equal(t, http.StatusNotFound, doJSON(dave, http.MethodGet, items, nil).Code)
+
+	// Only the owner manages members and deletes the collection
+	assert.Equal(t, http.StatusForbidden, doJSON(bob, http.MethodPost, base+"/mitglieder", map[string]string{"webuserId": "dave"}).Code)
+	assert.Equal(t, http.StatusForbidden, doJSON(bob, http.MethodDelete, base, nil).Code)
+	assert.Equal(t, http.StatusForbidden, doJSON(bob, http.MethodDelete, base+"/mitglieder/carol", nil).Code)
+	w = doJSON(alice, http.MethodPut, base+"/mitglieder/carol", map[string]string{"rolle": "bearbeiter"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(carol, http.MethodGet, base+"/mitglieder", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var mitglieder []MitgliedResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &mitglieder))
+	require.Len(t, mitglieder, 3)
+	assert.Equal(t, "alice", mitglieder[0].WebuserID)
+	assert.Equal(t, models.RolleBesitzer, mitglieder[0].Rolle)
+	assert.Equal(t, models.RolleBearbeiter, mitglieder[2].Rolle)
+
+	// Members see the shared collection with their role
+	w = doJSON(bob, http.MethodGet, "/sammlungen", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var liste []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &liste))
+	require.Len(t, liste, 1)
+	assert.Equal(t, "bearbeiter", liste[0]["rolle"])
+	assert.Equal(t, "Familienbibliothek", liste[0]["Name"])
+
+	// Ownership transfer
+	assert.Equal(t, http.StatusBadRequest, doJSON(alice, http.MethodPost, base+"/besitzer", map[string]string{"webuserId": "dave"}).Code)
+	w = doJSON(alice, http.MethodPost, base+"/besitzer", map[string]string{"webuserId": "bob"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, db.First(&sammlung, sammlung.ID).Error)
+	assert.Equal(t, "bob", sammlung.WebuserID)
+	assert.Equal(t, http.StatusForbidden, doJSON(alice, http.MethodDelete, base, nil).Code)
+
+	// Members can leave
+	assert.Equal(t, http.StatusNoContent, doJSON(alice, http.MethodDelete, base+"/mitglieder/alice", nil).Code)
+	assert.Equal(t, http.StatusNotFound, doJSON(alice, http.MethodGet, base, nil).Code)
+	assert.Equal(t, http.StatusNoContent, doJSON(bob, http.MethodDelete, base, nil).Code)
+}
+
+func TestTransferHaushaltSammlungByVerwalter(t *testing.T) {
+	db := setupFreigabeTestDB(t)
+	for _, id := range []string{"anna", "ben", "clara"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id}).Error)
+	}
+	haushalt := models.Haushalt{Name: "Familie Meier"}
+	require.NoError(t, db.Create(&haushalt).Error)
+	require.NoError(t, db.Omit("Haushalt", "Webuser").Create(&models.HaushaltMitgliedschaft{
+		HaushaltID: haushalt.ID, WebuserID: "ben", Rolle: models.HaushaltVerwalter,
+	}).Error)
+	sammlung := models.Sammlung{WebuserID: "anna", Name: strPtr("Fotos"), HaushaltID: &haushalt.ID}
+	require.NoError(t, db.Create(&sammlung).Error)
+	now := time.Now().UTC()
+	require.NoError(t, db.Omit("Sammlung", "Webuser").Create(&[]models.SammlungMitglied{
+		{SammlungID: sammlung.ID, WebuserID: "ben", Rolle: models.RolleBetrachter, EingeladenAm: now, AngenommenAm: &now},
+		{SammlungID: sammlung.ID, WebuserID: "clara", Rolle: models.RolleBearbeiter, EingeladenAm: now, AngenommenAm: &now},
+	}).Error)
+
+	// The administrator transfers Anna's collection; Anna, not Ben, becomes a member
+	w := doJSON(mitgliedRouter(db, "ben"), http.MethodPost, fmt.Sprintf("/sammlungen/%d/besitzer", sammlung.ID), map[string]string{"webuserId": "clara"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"rolle":"besitzer"`)
+
+	require.NoError(t, db.First(&sammlung, sammlung.ID).Error)
+	assert.Equal(t, "clara", sammlung.WebuserID)
+	var mitglieder []models.SammlungMitglied
+	require.NoError(t, db.Where("sammlung_id = ?", sammlung.ID).Order("webuser_id").Find(&mitglieder).Error)
+	require.Len(t, mitglieder, 2)
+	assert.Equal(t, "anna", mitglieder[0].WebuserID)
+	assert.Equal(t, models.RolleBearbeiter, mitglieder[0].Rolle)
+	assert.Equal(t, "ben", mitglieder[1].WebuserID)
+	assert.Equal(t, models.RolleBetrachter, mitglieder[1].Rolle)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// produktArten sind die Werte der Diskriminator-Spalte produkte.art
+var produktArten = []string{"Buch", "Manga", "Spiel", "Filmserie"}
+
+func isProduktArt(art string) bool {
+	for _, a := range produktArten {
+		if a == art {
+			return true
+		}
+	}
+	return false
+}
+
+// ProduktResponse beschreibt ein Produkt beliebiger Art inkl. der typspezifischen Felder
+type ProduktResponse struct {
+	ID      uint           `json:"id"`
+	Name    string         `json:"name"`
+	Nummer  *int           `json:"nummer"`
+	Art     string         `json:"art"`               // Buch, Manga, Spiel oder Filmserie
+	Autor   *string        `json:"autor,omitempty"`   // Buch
+	Mangaka *string        `json:"mangaka,omitempty"` // Manga
+	Baende  *int           `json:"baende,omitempty"`  // Manga
+	Konsole *string        `json:"konsole,omitempty"` // Spiel
+	FilmArt *string        `json:"filmArt,omitempty"` // Filmserie ('Film' oder 'Serie')
+	Sprache *string        `json:"sprache,omitempty"` // Buch, Manga
+	Genre   *string        `json:"genre,omitempty"`
+	Cover   *CoverResponse `json:"cover,omitempty"`
+}
+
+// loadProduktResponses lädt zu den Basisprodukten die typspezifischen Details nach.
+// Pro Produktart wird genau eine Abfrage ausgeführt, die Reihenfolge bleibt erhalten.
+func loadProduktResponses(db *gorm.DB, produkte []models.Produkt) ([]ProduktResponse, error) {
+	idsByArt := make(map[string][]uint)
+	for _, produkt := range produkte {
+		idsByArt[produkt.Art] = append(idsByArt[produkt.Art], produkt.ID)
+	}
+
+	ids := make([]uint, len(produkte))
+	responses := make([]ProduktResponse, len(produkte))
+	index := make(map[uint]*ProduktResponse, len(produkte))
+	for i, produkt := range produkte {
+		ids[i] = produkt.ID
+		responses[i] = ProduktResponse{ID: produkt.ID, Name: produkt.Name, Nummer: produkt.Nummer, Art: produkt.Art}
+		index[produkt.ID] = &responses[i]
+	}
+
+	if ids := idsByArt["Buch"]; len(ids) > 0 {
+		var buecher []models.Buch
+		if err := db.Where("produkte_id IN ?", ids).Find(&buecher).Error; err != nil {
+			return nil, err
+		}
+		for _, b := range buecher {
+			r := index[b.ProdukteID]
+			r.Autor, r.Sprache, r.Genre = b.Autor, b.Sprache, b.Genre
+		}
+	}
+	if ids := idsByArt["Manga"]; len(ids) > 0 {
+		var mangas []models.Manga
+		if err := db.Where("produkte_id IN ?", ids).Find(&mangas).Error; err != nil {
+			return nil, err
+		}
+		for _, m := range mangas {
+			r := index[m.ProdukteID]
+			r.Mangaka, r.Baende, r.Sprache, r.Genre = m.Mangaka, m.Baende, m.Sprache, m.Genre
+		}
+	}
+	if ids := idsByArt["Spiel"]; len(ids) > 0 {
+		var spiele []models.Spiel
+		if err := db.Where("produkte_id IN ?", ids).Find(&spiele).Error; err != nil {
+			return nil, err
+		}
+		for _, s := range spiele {
+			r := index[s.ProdukteID]
+			r.Konsole, r.Genre = s.Konsole, s.Genre
+		}
+	}
+	if ids := idsByArt["Filmserie"]; len(ids) > 0 {
+		var filmserien []models.Filmserie
+		if err := db.Where("produkte_id IN ?", ids).Find(&filmserien).Error; err != nil {
+			return nil, err
+		}
+		for _, f := range filmserien {
+			r := index[f.ProdukteID]
+			r.FilmArt, r.Genre = f.Art, f.Genre
+		}
+	}
+
+	covers := loadCoverResponses(db, ids)
+	for i := range responses {
+		responses[i].Cover = covers[responses[i].ID]
+	}
+
+	return responses, nil
+}
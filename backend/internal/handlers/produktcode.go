@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// --- Structs für Produktkennungen ---
+
+// ProduktCodeRequest definiert die JSON-Struktur zum Hinzufügen einer Kennung
+type ProduktCodeRequest struct {
+	Code string `json:"code" binding:"required"` // ISBN-10/13, EAN-8/13 oder UPC-A, Bindestriche erlaubt
+}
+
+// ProduktCodeResponse definiert die JSON-Struktur für Kennungen
+type ProduktCodeResponse struct {
+	ID        uint   `json:"id"`
+	ProduktID uint   `json:"produktId"`
+	Typ       string `json:"typ"`
+	Code      string `json:"code"`             // Normalisiert (ISBN-13 bzw. EAN)
+	ISBN10    string `json:"isbn10,omitempty"` // Nur für ISBNs mit Präfix 978
+}
+
+// ProduktByCodeResponse ist die Antwort auf eine Barcode-Suche
+type ProduktByCodeResponse struct {
+	ProduktResponse
+	Code    string           `json:"code"`    // Normalisierter Suchcode
+	Edition *EditionResponse `json:"edition"` // Gesetzt, wenn der Code zu einer konkreten Edition gehört
+}
+
+func toProduktCodeResponse(code models.ProduktCode) ProduktCodeResponse {
+	response := ProduktCodeResponse{
+		ID:        code.ID,
+		ProduktID: code.ProduktID,
+		Typ:       code.Typ,
+		Code:      code.Code,
+	}
+	if code.Typ == utils.CodeTypeISBN {
+		response.ISBN10, _ = utils.ISBN13To10(code.Code)
+	}
+	return response
+}
+
+// --- Handler für Produktkennungen ---
+
+// ListProduktCodes listet alle Kennungen eines Produkts
+func ListProduktCodes(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	produkt := findProdukt(c, db, "id")
+	if produkt == nil {
+		return
+	}
+
+	var codes []models.ProduktCode
+	if err := db.Where("produkt_id = ?", produkt.ID).Order("id asc").Find(&codes).Error; err != nil {
+		log.Printf("Error retrieving codes for product ID %d: %v", produkt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve codes"})
+		return
+	}
+
+	response := make([]ProduktCodeResponse, len(codes))
+	for i, code := range codes {
+		response[i] = toProduktCodeResponse(code)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateProduktCode prüft, normalisiert und speichert eine Kennung für ein Produkt
+func CreateProduktCode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request ProduktCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	produkt := findProdukt(c, db, "id")
+	if produkt == nil {
+		return
+	}
+
+	normalized, codeType, err := utils.NormalizeCodeForArt(produkt.Art, request.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Eindeutigkeit vorab prüfen, um eine verständliche Antwort zu liefern. Codes sind je Katalog
+	// eindeutig; Produkte anderer Haushalte stehen dem Code daher nicht im Weg.
+	var existing []models.ProduktCode
+	err = db.Where("code = ? AND produkt_id IN (?)", normalized, sichtbareProdukte(c, db)).Order("id").Limit(1).Find(&existing).Error
+	if err != nil {
+		log.Printf("Error checking code %s: %v", normalized, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check code"})
+		return
+	}
+	if len(existing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Code is already assigned", "produktId": existing[0].ProduktID})
+		return
+	}
+
+	code := models.ProduktCode{ProduktID: produkt.ID, HaushaltID: produkt.HaushaltID, Typ: codeType, Code: normalized}
+	if err := db.Create(&code).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Code is already assigned"})
+			return
+		}
+		log.Printf("Error creating code for product ID %d: %v", produkt.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toProduktCodeResponse(code))
+}
+
+// DeleteProduktCode entfernt eine Kennung von einem Produkt
+func DeleteProduktCode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	result := db.Where("id = ? AND produkt_id = ?", c.Param("codeId"), c.Param("id")).Delete(&models.ProduktCode{})
+	if result.Error != nil {
+		log.Printf("Error deleting code ID %s: %v", c.Param("codeId"), result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete code"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetProduktByCode sucht ein Produkt über eine gescannte ISBN/EAN.
+// Ein 404 signalisiert dem Client, dass das Produkt neu angelegt werden muss.
+func GetProduktByCode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	normalized, _, err := utils.NormalizeCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var produktID uint
+	var edition *models.Edition
+
+	// Nur sichtbare Produkte; ein Produkt des eigenen Haushalts hat Vorrang vor dem globalen
+	var code models.ProduktCode
+	err = db.Where("code = ? AND produkt_id IN (?)", normalized, sichtbareProdukte(c, db)).
+		Order("haushalt_id IS NULL, id").First(&code).Error
+	switch {
+	case err == nil:
+		produktID = code.ProduktID
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Fallback: Code einer konkreten Edition
+		var treffer models.Edition
+		err = db.Where("code = ? AND produkt_id IN (?)", normalized, sichtbareProdukte(c, db)).Order("id").First(&treffer).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found", "code": normalized})
+			return
+		}
+		if err != nil {
+			log.Printf("Error looking up edition code %s: %v", normalized, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up code"})
+			return
+		}
+		produktID = treffer.ProduktID
+		edition = &treffer
+	default:
+		log.Printf("Error looking up code %s: %v", normalized, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up code"})
+		return
+	}
+
+	var produkt models.Produkt
+	err = db.Scopes(katalogScope(c)).First(&produkt, produktID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Produkt eines anderen Haushalts
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found", "code": normalized})
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading product ID %d for code %s: %v", produktID, normalized, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve product"})
+		return
+	}
+	details, err := loadProduktResponses(db, []models.Produkt{produkt})
+	if err != nil {
+		log.Printf("Error loading details for product ID %d: %v", produktID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve product"})
+		return
+	}
+
+	response := ProduktByCodeResponse{ProduktResponse: details[0], Code: normalized}
+	if edition != nil {
+		editionResponse := toEditionResponse(*edition)
+		response.Edition = &editionResponse
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupProduktCodeTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Produkt{}, &models.Buch{}, &models.Spiel{}, &models.Edition{}, &models.ProduktCode{}, &models.Cover{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestCreateProduktCode(t *testing.T) {
+	db := setupProduktCodeTestDB(t)
+	router := setupTestRouter(db)
+	router.POST("/produkte/:id/codes", CreateProduktCode)
+
+	buch := models.Produkt{Name: "Test Book", Art: "Buch"}
+	db.Create(&buch)
+	spiel := models.Produkt{Name: "Test Game", Art: "Spiel"}
+	db.Create(&spiel)
+
+	tests := []struct {
+		name           string
+		produktID      uint
+		code           string
+		expectedStatus int
+		checkResponse  func(t *testing.T, body []byte)
+	}{
+		{
+			name:           "isbn-10 is stored as isbn-13",
+			produktID:      buch.ID,
+			code:           "0-306-40615-2",
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response ProduktCodeResponse
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, "ISBN", response.Typ)
+				assert.Equal(t, "9780306406157", response.Code)
+				assert.Equal(t, "0306406152", response.ISBN10)
+			},
+		},
+		{
+			name:           "same isbn as isbn-13 is a duplicate",
+			produktID:      buch.ID,
+			code:           "9780306406157",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid checksum",
+			produktID:      buch.ID,
+			code:           "9780306406158",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "book requires isbn",
+			produktID:      buch.ID,
+			code:           "4006381333931",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "upc for game",
+			produktID:      spiel.ID,
+			code:           "036000291452",
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response ProduktCodeResponse
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, "UPC", response.Typ)
+				assert.Equal(t, "0036000291452", response.Code)
+			},
+		},
+		{
+			name:           "unknown product",
+			produktID:      999,
+			code:           "4006381333931",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(router, http.MethodPost, fmt.Sprintf("/produkte/%d/codes", tt.produktID), ProduktCodeRequest{Code: tt.code})
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestGetProduktByCode(t *testing.T) {
+	db := setupProduktCodeTestDB(t)
+	router := setupTestRouter(db)
+	router.GET("/produkte/by-code/:code", GetProduktByCode)
+
+	buch := models.Produkt{Name: "Test Book", Art: "Buch"}
+	db.Create(&buch)
+	db.Create(&models.Buch{ProdukteID: buch.ID, Autor: strPtr("Jane Doe")})
+	db.Create(&models.ProduktCode{ProduktID: buch.ID, Typ: "ISBN", Code: "9780306406157"})
+
+	spiel := models.Produkt{Name: "Test Game", Art: "Spiel"}
+	db.Create(&spiel)
+	db.Create(&models.Spiel{ProdukteID: spiel.ID, Konsole: strPtr("Switch")})
+	db.Create(&models.Edition{ProduktID: spiel.ID, Format: "Physical", Code: strPtr("4006381333931")})
+
+	tests := []struct {
+		name           string
+		code           string
+		expectedStatus int
+		checkResponse  func(t *testing.T, body []byte)
+	}{
+		{
+			name:           "lookup by isbn-10 finds isbn-13",
+			code:           "0306406152",
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response ProduktByCodeResponse
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, buch.ID, response.ID)
+				assert.Equal(t, "Buch", response.Art)
+				assert.Equal(t, "Jane Doe", *response.Autor)
+				assert.Nil(t, response.Edition)
+			},
+		},
+		{
+			name:           "lookup by edition code",
+			code:           "4006381333931",
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, body []byte) {
+				var response ProduktByCodeResponse
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, spiel.ID, response.ID)
+				assert.Equal(t, "Switch", *response.Konsole)
+				require.NotNil(t, response.Edition)
+				assert.Equal(t, "Physical", response.Edition.Format)
+			},
+		},
+		{
+			name:           "unknown but valid code",
+			code:           "9780804429573",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid code",
+			code:           "12345",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(router, http.MethodGet, "/produkte/by-code/"+tt.code, nil)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// waehrungPattern prüft Währungscodes nach ISO 4217
+var waehrungPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// avatarURLMaxLaenge entspricht der Spaltenbreite in Benutzereinstellungen
+const avatarURLMaxLaenge = 1024
+
+type PrivatsphaereResponse struct {
+	NameSichtbar        bool `json:"nameSichtbar"`
+	EinladungenErlauben bool `json:"einladungenErlauben"`
+}
+
+type ProfilStatistik struct {
+	Sammlungen        int64 `json:"sammlungen"`        // Eigene Sammlungen
+	Mitgliedschaften  int64 `json:"mitgliedschaften"`  // Angenommene Einladungen in fremde Sammlungen
+	Eintraege         int64 `json:"eintraege"`         // Einträge in eigenen Sammlungen
+	Abgeschlossen     int64 `json:"abgeschlossen"`     // Davon mit Status abgeschlossen
+	Haushalte         int64 `json:"haushalte"`         // Mitgliedschaften in Haushalten
+	AngelegteProdukte int64 `json:"angelegteProdukte"` // Vom Benutzer angelegte Katalogprodukte
+	GeseheneEpisoden  int64 `json:"geseheneEpisoden"`
+	Zugangstokens     int64 `json:"zugangstokens"` // Gültige Zugangstokens
+}
+
+type ProfilResponse struct {
+	ID                 string                `json:"id"`
+	Benutzername       *string               `json:"benutzername"`
+	Anzeigename        *string               `json:"anzeigename"` // Eigener Name, sonst aus dem Token
+	Email              *string               `json:"email"`
+	AvatarURL          *string               `json:"avatarUrl"`
+	Sprache            *string               `json:"sprache"` // null = Sprache des Browsers
+	StandardSammlungID *uint                 `json:"standardSammlungId"`
+	Waehrung           string                `json:"waehrung"`
+	Datumsformat       string                `json:"datumsformat"`
+	Sortierung         string                `json:"sortierung"`
+	Privatsphaere      PrivatsphaereResponse `json:"privatsphaere"`
+	ZuletztGesehenAm   *time.Time            `json:"zuletztGesehenAm"`
+	Statistik          ProfilStatistik       `json:"statistik"`
+}
+
+type UpdatePrivatsphaereRequest struct {
+	NameSichtbar        *bool `json:"nameSichtbar"`
+	EinladungenErlauben *bool `json:"einladungenErlauben"`
+}
+
+// UpdateProfilRequest ändert nur die angegebenen Felder. Ein leerer Anzeigename, Avatar oder
+// eine leere Sprache setzen den Wert zurück, ebenso standardSammlungId 0.
+type UpdateProfilRequest struct {
+	Anzeigename        *string                     `json:"anzeigename"`
+	AvatarURL          *string                     `json:"avatarUrl"`
+	Sprache            *string                     `json:"sprache"`
+	StandardSammlungID *uint                       `json:"standardSammlungId"`
+	Waehrung           *string                     `json:"waehrung"`
+	Datumsformat       *string                     `json:"datumsformat"`
+	Sortierung         *string                     `json:"sortierung"`
+	Privatsphaere      *UpdatePrivatsphaereRequest `json:"privatsphaere"`
+}
+
+// loadEinstellungen lädt die Einstellungen eines Benutzers; ohne Eintrag gelten die Standardwerte
+func loadEinstellungen(db *gorm.DB, userID string) (models.Benutzereinstellungen, error) {
+	var einstellungen []models.Benutzereinstellungen
+	if err := db.Where("webuser_id = ?", userID).Limit(1).Find(&einstellungen).Error; err != nil {
+		return models.Benutzereinstellungen{}, err
+	}
+	if len(einstellungen) == 0 {
+		return models.StandardEinstellungen(userID), nil
+	}
+	return einstellungen[0], nil
+}
+
+// loadProfil stellt Profil, Einstellungen und Statistik eines Benutzers zusammen
+func loadProfil(db *gorm.DB, userID string) (*ProfilResponse, error) {
+	var user models.Webuser
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	einstellungen, err := loadEinstellungen(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	anzeigename := user.Anzeigename
+	if einstellungen.Anzeigename != nil {
+		anzeigename = einstellungen.Anzeigename
+	}
+	if anzeigename == nil {
+		anzeigename = user.Name
+	}
+	profil := &ProfilResponse{
+		ID:           user.ID,
+		Benutzername: user.Name,
+		Anzeigename:  anzeigename,
+		Email:        user.Email,
+		AvatarURL:    einstellungen.AvatarURL,
+		Sprache:      einstellungen.Sprache,
+		Waehrung:     einstellungen.Waehrung,
+		Datumsformat: einstellungen.Datumsformat,
+		Sortierung:   einstellungen.Sortierung,
+		Privatsphaere: PrivatsphaereResponse{
+			NameSichtbar:        einstellungen.NameSichtbar,
+			EinladungenErlauben: einstellungen.EinladungenErlauben,
+		},
+		ZuletztGesehenAm: user.ZuletztGesehenAm,
+	}
+
+	// Die Standardsammlung entfällt, sobald der Benutzer keinen Zugriff mehr darauf hat
+	if einstellungen.StandardSammlungID != nil {
+		var sammlungen []models.Sammlung
+		if err := db.Where("id = ?", *einstellungen.StandardSammlungID).Limit(1).Find(&sammlungen).Error; err != nil {
+			return nil, err
+		}
+		if len(sammlungen) > 0 {
+			rolle, err := sammlungRolle(db, sammlungen[0], userID)
+			if err != nil {
+				return nil, err
+			}
+			if rolle != "" {
+				profil.StandardSammlungID = einstellungen.StandardSammlungID
+			}
+		}
+	}
+
+	if err := loadProfilStatistik(db, userID, &profil.Statistik); err != nil {
+		return nil, err
+	}
+	return profil, nil
+}
+
+func loadProfilStatistik(db *gorm.DB, userID string, statistik *ProfilStatistik) error {
+	eigeneSammlungen := db.Model(&models.Sammlung{}).Select("id").Where("webuser_id = ?", userID)
+	now := time.Now().UTC()
+	counts := []struct {
+		target *int64
+		query  *gorm.DB
+	}{
+		{&statistik.Sammlungen, db.Model(&models.Sammlung{}).Where("webuser_id = ?", userID)},
+		{&statistik.Mitgliedschaften, db.Model(&models.SammlungMitglied{}).Where("webuser_id = ? AND angenommen_am IS NOT NULL", userID)},
+		{&statistik.Eintraege, db.Model(&models.SammlungProdukt{}).Where("sammlung_id IN (?)", eigeneSammlungen)},
+		{&statistik.Abgeschlossen, db.Model(&models.SammlungProdukt{}).Where("sammlung_id IN (?) AND status = ?", eigeneSammlungen, models.StatusAbgeschlossen)},
+		{&statistik.Haushalte, db.Model(&models.HaushaltMitgliedschaft{}).Where("webuser_id = ?", userID)},
+		{&statistik.AngelegteProdukte, db.Model(&models.Produkt{}).Where("erstellt_von = ?", userID)},
+		{&statistik.GeseheneEpisoden, db.Model(&models.EpisodeGesehen{}).Where("webuser_id = ?", userID)},
+		{&statistik.Zugangstokens, db.Model(&models.Zugangstoken{}).
+			Where("webuser_id = ? AND widerrufen_am IS NULL AND (gueltig_bis IS NULL OR gueltig_bis > ?)", userID, now)},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.target).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProfil liefert Profil, Einstellungen und Statistik des eingeloggten Benutzers
+func GetProfil(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	profil, err := loadProfil(db, userID)
+	if err != nil {
+		log.Printf("ERROR GetProfil for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profil)
+}
+
+// UpdateProfil ändert Profilangaben und Einstellungen des eingeloggten Benutzers
+func UpdateProfil(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateProfilRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	einstellungen, err := loadEinstellungen(db, userID)
+	if err != nil {
+		log.Printf("ERROR UpdateProfil - Load settings for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	if request.Anzeigename != nil {
+		anzeigename := strings.TrimSpace(*request.Anzeigename)
+		if utf8.RuneCountInString(anzeigename) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'anzeigename' must be at most 255 characters long"})
+			return
+		}
+		einstellungen.Anzeigename = optionalText(anzeigename)
+	}
+	if request.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*request.AvatarURL)
+		if avatarURL != "" {
+			parsed, err := url.Parse(avatarURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(avatarURL) > avatarURLMaxLaenge {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'avatarUrl' must be an http or https URL"})
+				return
+			}
+		}
+		einstellungen.AvatarURL = optionalText(avatarURL)
+	}
+	if request.Sprache != nil {
+		sprache := strings.ToLower(strings.TrimSpace(*request.Sprache))
+		if sprache != "" && !models.GueltigeSprache(sprache) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'sprache' must be one of " + strings.Join(models.Sprachen, ", ")})
+			return
+		}
+		einstellungen.Sprache = optionalText(sprache)
+	}
+	if request.StandardSammlungID != nil {
+		einstellungen.StandardSammlungID = nil
+		if *request.StandardSammlungID != 0 {
+			sammlung := loadSammlung(c, db, userID, *request.StandardSammlungID, models.RolleBetrachter)
+			if sammlung == nil {
+				return
+			}
+			einstellungen.StandardSammlungID = &sammlung.ID
+		}
+	}
+	if request.Waehrung != nil {
+		waehrung := strings.ToUpper(strings.TrimSpace(*request.Waehrung))
+		if !waehrungPattern.MatchString(waehrung) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'waehrung' must be an ISO 4217 currency code"})
+			return
+		}
+		einstellungen.Waehrung = waehrung
+	}
+	if request.Datumsformat != nil {
+		if !models.GueltigesDatumsformat(*request.Datumsformat) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'datumsformat' must be one of " + strings.Join(models.Datumsformate, ", ")})
+			return
+		}
+		einstellungen.Datumsformat = *request.Datumsformat
+	}
+	if request.Sortierung != nil {
+		if !models.GueltigeSortierung(*request.Sortierung) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'sortierung' must be one of " + strings.Join(models.Sortierungen, ", ")})
+			return
+		}
+		einstellungen.Sortierung = *request.Sortierung
+	}
+	if request.Privatsphaere != nil {
+		if request.Privatsphaere.NameSichtbar != nil {
+			einstellungen.NameSichtbar = *request.Privatsphaere.NameSichtbar
+		}
+		if request.Privatsphaere.EinladungenErlauben != nil {
+			einstellungen.EinladungenErlauben = *request.Privatsphaere.EinladungenErlauben
+		}
+	}
+
+	err = db.Omit("Webuser").Clauses(clause.OnConflict{UpdateAll: true}).Create(&einstellungen).Error
+	if err != nil {
+		log.Printf("ERROR UpdateProfil for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	profil, err := loadProfil(db, userID)
+	if err != nil {
+		log.Printf("ERROR UpdateProfil - Reload for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profil)
+}
+
+// listSortierung liefert die Sortierung einer Liste: ?sort= oder die Einstellung des Benutzers.
+// Bei einem ungültigen Parameter wird direkt geantwortet.
+func listSortierung(c *gin.Context, db *gorm.DB, userID string) (string, bool) {
+	if sortierung := c.Query("sort"); sortierung != "" {
+		if !models.GueltigeSortierung(sortierung) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'sort' must be one of " + strings.Join(models.Sortierungen, ", ")})
+			return "", false
+		}
+		return sortierung, true
+	}
+	if userID == "" {
+		return models.SortierungName, true
+	}
+	einstellungen, err := loadEinstellungen(db, userID)
+	if err != nil {
+		// Die Liste soll nicht an der Sortierung scheitern
+		log.Printf("ERROR listSortierung - Load settings for user %s: %v\n", userID, err)
+		return models.SortierungName, true
+	}
+	return einstellungen.Sortierung, true
+}
+
+// produktSortierung liefert Sortierung und ORDER BY für die Produktlisten (Bücher, Mangas,
+// Spiele, Filmserien). Nach Namen sortieren die Handler erst nach dem Laden, da der Name in
+// produkte steht; bei gleichem Namen bleibt die Reihenfolge der IDs.
+func produktSortierung(c *gin.Context, db *gorm.DB) (string, string, bool) {
+	userID := ""
+	if id := optionalUserID(c); id != nil {
+		userID = *id
+	}
+	sortierung, ok := listSortierung(c, db, userID)
+	if !ok {
+		return "", "", false
+	}
+	if sortierung == models.SortierungNeueste {
+		return sortierung, "produkte_id desc", true
+	}
+	return sortierung, "produkte_id asc", true
+}
+
+func optionalText(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupProfilTestDB(t *testing.T) *gorm.DB {
+	db := setupFreigabeTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}, &models.Zugangstoken{}))
+	return db
+}
+
+func profilRouter(db *gorm.DB, userID string) *gin.Engine {
+	router := mitgliedRouter(db, userID)
+	router.GET("/me", GetProfil)
+	router.PUT("/me", UpdateProfil)
+	return router
+}
+
+func getProfil(t *testing.T, router *gin.Engine) ProfilResponse {
+	w := doJSON(router, http.MethodGet, "/me", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var profil ProfilResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profil))
+	return profil
+}
+
+func TestProfil(t *testing.T) {
+	db := setupProfilTestDB(t)
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice", Name: strPtr("alice"), Email: strPtr("alice@example.org")}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "bob", Name: strPtr("bob"), Anzeigename: strPtr("Bob Builder")}).Error)
+	eigene := models.Sammlung{WebuserID: "alice", Name: strPtr("Bücher")}
+	require.NoError(t, db.Create(&eigene).Error)
+	fremde := models.Sammlung{WebuserID: "bob", Name: strPtr("Spiele")}
+	require.NoError(t, db.Create(&fremde).Error)
+	produkt := models.Produkt{Name: "Momo", Art: "Buch", ErstelltVon: strPtr("alice")}
+	require.NoError(t, db.Create(&produkt).Error)
+	require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: eigene.ID, ProduktID: produkt.ID, Status: strPtr(models.StatusAbgeschlossen)}).Error)
+	now := time.Now().UTC()
+	require.NoError(t, db.Create(&models.Zugangstoken{WebuserID: "alice", Name: "cli", Anfang: "dpat_a", Hash: "a", Scopes: "voll", ErstelltAm: now}).Error)
+	require.NoError(t, db.Create(&models.Zugangstoken{WebuserID: "alice", Name: "alt", Anfang: "dpat_b", Hash: "b", Scopes: "voll", ErstelltAm: now, WiderrufenAm: &now}).Error)
+
+	alice := profilRouter(db, "alice")
+
+	// Defaults
+	profil := getProfil(t, alice)
+	assert.Equal(t, "alice", profil.ID)
+	assert.Equal(t, "alice", *profil.Anzeigename)
+	assert.Equal(t, "alice@example.org", *profil.Email)
+	assert.Nil(t, profil.Sprache)
+	assert.Equal(t, "EUR", profil.Waehrung)
+	assert.Equal(t, "DD.MM.YYYY", profil.Datumsformat)
+	assert.Equal(t, models.SortierungName, profil.Sortierung)
+	assert.True(t, profil.Privatsphaere.NameSichtbar)
+	assert.True(t, profil.Privatsphaere.EinladungenErlauben)
+	assert.Equal(t, ProfilStatistik{Sammlungen: 1, Eintraege: 1, Abgeschlossen: 1, AngelegteProdukte: 1, Zugangstokens: 1}, profil.Statistik)
+	assert.Equal(t, "Bob Builder", *getProfil(t, profilRouter(db, "bob")).Anzeigename)
+
+	// Partial update
+	w := doJSON(alice, http.MethodPut, "/me", map[string]interface{}{
+		"anzeigename":        "  Alice A.  ",
+		"avatarUrl":          "https://example.org/alice.png",
+		"sprache":            "DE",
+		"standardSammlungId": eigene.ID,
+		"waehrung":           "chf",
+		"datumsformat":       "YYYY-MM-DD",
+		"sortierung":         models.SortierungNeueste,
+		"privatsphaere":      map[string]bool{"nameSichtbar": false},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profil))
+	assert.Equal(t, "Alice A.", *profil.Anzeigename)
+	assert.Equal(t, "https://example.org/alice.png", *profil.AvatarURL)
+	assert.Equal(t, models.SpracheDeutsch, *profil.Sprache)
+	assert.Equal(t, eigene.ID, *profil.StandardSammlungID)
+	assert.Equal(t, "CHF", profil.Waehrung)
+	assert.Equal(t, "YYYY-MM-DD", profil.Datumsformat)
+	assert.False(t, profil.Privatsphaere.NameSichtbar)
+	assert.True(t, profil.Privatsphaere.EinladungenErlauben)
+
+	w = doJSON(alice, http.MethodPut, "/me", map[string]interface{}{"waehrung": "EUR"})
+	require.Equal(t, http.StatusOK, w.Code)
+	profil = getProfil(t, alice)
+	assert.Equal(t, "EUR", profil.Waehrung)
+	assert.Equal(t, "Alice A.", *profil.Anzeigename, "fields not in the request stay unchanged")
+
+	// Reset to the values from the token
+	w = doJSON(alice, http.MethodPut, "/me", map[string]interface{}{"anzeigename": "", "sprache": "", "avatarUrl": "", "standardSammlungId": 0})
+	require.Equal(t, http.StatusOK, w.Code)
+	profil = getProfil(t, alice)
+	assert.Equal(t, "alice", *profil.Anzeigename)
+	assert.Nil(t, profil.Sprache)
+	assert.Nil(t, profil.AvatarURL)
+	assert.Nil(t, profil.StandardSammlungID)
+
+	// Validation
+	for _, body := range []map[string]interface{}{
+		{"avatarUrl": "javascript:alert(1)"},
+		{"sprache": "fr"},
+		{"waehrung": "Euro"},
+		{"datumsformat": "DD/MM/YY"},
+		{"sortierung": "zufall"},
+	} {
+		w = doJSON(alice, http.MethodPut, "/me", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	w = doJSON(alice, http.MethodPut, "/me", map[string]interface{}{"standardSammlungId": fremde.ID})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The default collection is dropped once access is lost
+	require.NoError(t, db.Create(&models.SammlungMitglied{SammlungID: fremde.ID, WebuserID: "alice", Rolle: models.RolleBetrachter, EingeladenAm: now, AngenommenAm: &now}).Error)
+	w = doJSON(alice, http.MethodPut, "/me", map[string]interface{}{"standardSammlungId": fremde.ID})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profil))
+	assert.Equal(t, fremde.ID, *profil.StandardSammlungID)
+	assert.Equal(t, int64(1), profil.Statistik.Mitgliedschaften)
+	require.NoError(t, db.Where("sammlung_id = ? AND webuser_id = ?", fremde.ID, "alice").Delete(&models.SammlungMitglied{}).Error)
+	assert.Nil(t, getProfil(t, alice).StandardSammlungID)
+}
+
+func TestProfilSortierung(t *testing.T) {
+	db := setupProfilTestDB(t)
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+	var ids []uint
+	for _, name := range []string{"Bücher", "Comics", "Alben"} {
+		sammlung := models.Sammlung{WebuserID: "alice", Name: strPtr(name)}
+		require.NoError(t, db.Create(&sammlung).Error)
+		ids = append(ids, sammlung.ID)
+	}
+	for _, name := range []string{"Momo", "zorro", "Anna"} {
+		produkt := models.Produkt{Name: name, Art: "Buch"}
+		require.NoError(t, db.Create(&produkt).Error)
+		require.NoError(t, db.Create(&models.SammlungProdukt{SammlungID: ids[0], ProduktID: produkt.ID}).Error)
+	}
+	alice := profilRouter(db, "alice")
+
+	sammlungNamen := func(query string) []string {
+		w := doJSON(alice, http.MethodGet, "/sammlungen"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var sammlungen []SammlungListItem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sammlungen))
+		names := make([]string, len(sammlungen))
+		for i, sammlung := range sammlungen {
+			names[i] = *sammlung.Name
+		}
+		return names
+	}
+	produktNamen := func(query string) []string {
+		w := doJSON(alice, http.MethodGet, fmt.Sprintf("/sammlung/%d/produkte%s", ids[0], query), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var items []SammlungItemResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		names := make([]string, len(items))
+		for i, item := range items {
+			names[i] = item.Produkt.Name
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Alben", "Bücher", "Comics"}, sammlungNamen(""))
+	assert.Equal(t, []string{"Anna", "Momo", "zorro"}, produktNamen(""))
+
+	w := doJSON(alice, http.MethodPut, "/me", map[string]string{"sortierung": models.SortierungNeueste})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Alben", "Comics", "Bücher"}, sammlungNamen(""))
+	assert.Equal(t, []string{"Anna", "zorro", "Momo"}, produktNamen(""))
+
+	// The parameter takes precedence over the preference
+	assert.Equal(t, []string{"Alben", "Bücher", "Comics"}, sammlungNamen("?sort=name"))
+	assert.Equal(t, []string{"Anna", "Momo", "zorro"}, produktNamen("?sort=name"))
+	w = doJSON(alice, http.MethodGet, "/sammlungen?sort=zufall", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestProfilPrivatsphaere(t *testing.T) {
+	db := setupProfilTestDB(t)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, db.Create(&models.Webuser{ID: id, Name: strPtr(id)}).Error)
+	}
+	sammlung := models.Sammlung{WebuserID: "alice", Name: strPtr("Bibliothek")}
+	require.NoError(t, db.Create(&sammlung).Error)
+	alice, bob, carol := profilRouter(db, "alice"), profilRouter(db, "bob"), profilRouter(db, "carol")
+	mitglieder := fmt.Sprintf("/sammlungen/%d/mitglieder", sammlung.ID)
+
+	// Carol does not accept invitations
+	w := doJSON(carol, http.MethodPut, "/me", map[string]interface{}{"privatsphaere": map[string]bool{"einladungenErlauben": false}})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(alice, http.MethodPost, mitglieder, map[string]string{"webuserId": "carol"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Bob hides his name from other members, but still sees it himself
+	w = doJSON(alice, http.MethodPost, mitglieder, map[string]string{"webuserId": "bob"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doJSON(bob, http.MethodPut, "/me", map[string]interface{}{"privatsphaere": map[string]bool{"nameSichtbar": false}})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(bob, http.MethodPost, fmt.Sprintf("/einladungen/%d", sammlung.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	namen := func(router *gin.Engine) map[string]*string {
+		w := doJSON(router, http.MethodGet, mitglieder, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response []MitgliedResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		result := make(map[string]*string, len(response))
+		for _, mitglied := range response {
+			result[mitglied.WebuserID] = mitglied.Name
+		}
+		return result
+	}
+	assert.Nil(t, namen(alice)["bob"])
+	assert.Equal(t, "alice", *namen(alice)["alice"])
+	assert.Equal(t, "bob", *namen(bob)["bob"])
+}
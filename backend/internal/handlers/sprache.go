@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+// Fehlermeldungen werden auf Englisch geschrieben und bei Bedarf von der SpracheMiddleware
+// übersetzt. Nicht übersetzte Meldungen bleiben englisch, Serverfehler (5xx) werden durch
+// eine allgemeine Meldung ersetzt.
+
+// spracheMatcher wählt aus Accept-Language die passende unterstützte Sprache
+var spracheMatcher = language.NewMatcher([]language.Tag{language.English, language.German})
+
+// serverfehlerDeutsch ersetzt nicht übersetzte Serverfehler
+const serverfehlerDeutsch = "Interner Fehler, bitte später erneut versuchen"
+
+// meldungenDeutsch enthält die Übersetzungen fester Meldungen
+var meldungenDeutsch = map[string]string{
+	// Anmeldung und Berechtigungen (utils.AuthMiddleware)
+	"Authorization header is required":                              "Der Authorization-Header fehlt",
+	"Invalid or expired authentication token":                       "Ungültiges oder abgelaufenes Anmeldetoken",
+	"Authentication keys are not available yet, please retry later": "Die Anmeldeschlüssel sind noch nicht verfügbar, bitte später erneut versuchen",
+	"Token missing valid user identifier":                           "Das Token enthält keine gültige Benutzerkennung",
+	"Could not process user information":                            "Die Benutzerdaten konnten nicht verarbeitet werden",
+	"Internal server error":                                         serverfehlerDeutsch,
+	"The access token does not permit this request":                 "Das Zugangstoken erlaubt diese Anfrage nicht",
+	"User ID not found in context":                                  "Benutzerkennung fehlt",
+	"Invalid User ID format in context":                             "Ungültige Benutzerkennung",
+	// Ungültige IDs
+	"Invalid access token ID format": "Ungültige Zugangstoken-ID",
+	"Invalid collection ID format":   "Ungültige Sammlungs-ID",
+	"Invalid edition ID format":      "Ungültige Editions-ID",
+	"Invalid episode ID format":      "Ungültige Episoden-ID",
+	"Invalid household ID format":    "Ungültige Haushalts-ID",
+	"Invalid job ID format":          "Ungültige Job-ID",
+	"Invalid product ID format":      "Ungültige Produkt-ID",
+	"Invalid share link ID format":   "Ungültige Freigabe-ID",
+	// Nicht gefunden
+	"Access token not found":                "Zugangstoken nicht gefunden",
+	"Book not found":                        "Buch nicht gefunden",
+	"Code not found":                        "Code nicht gefunden",
+	"Collection not found or access denied": "Sammlung nicht gefunden oder kein Zugriff",
+	"Cover not found":                       "Cover nicht gefunden",
+	"Edition not found":                     "Edition nicht gefunden",
+	"Episode not found":                     "Episode nicht gefunden",
+	"Export file not found":                 "Exportdatei nicht gefunden",
+	"Film/Serie not found":                  "Film/Serie nicht gefunden",
+	"Household not found or access denied":  "Haushalt nicht gefunden oder kein Zugriff",
+	"Invitation not found":                  "Einladung nicht gefunden",
+	"Job not found":                         "Job nicht gefunden",
+	"Kid profile not found":                 "Kinderprofil nicht gefunden",
+	"Manga not found":                       "Manga nicht gefunden",
+	"Member not found":                      "Mitglied nicht gefunden",
+	"Product not found":                     "Produkt nicht gefunden",
+	"Product to add does not exist":         "Das hinzuzufügende Produkt existiert nicht",
+	"Season not found":                      "Staffel nicht gefunden",
+	"Share link not found":                  "Freigabe nicht gefunden",
+	"Shared collection not found":           "Freigegebene Sammlung nicht gefunden",
+	"Spiel not found":                       "Spiel nicht gefunden",
+	"User not found":                        "Benutzer nicht gefunden",
+	"User in 'uebertragenAn' not found":     "Benutzer in 'uebertragenAn' nicht gefunden",
+	"No account deletion requested":         "Keine Kontolöschung beantragt",
+	"No local account for this user":        "Kein lokales Konto für diesen Benutzer",
+	// Konflikte und fachliche Regeln
+	"Account deletion has already been requested":                                  "Die Kontolöschung wurde bereits beantragt",
+	"Code is already assigned":                                                     "Der Code ist bereits vergeben",
+	"Collections can only be transferred to members who accepted their invitation": "Sammlungen können nur an Mitglieder übertragen werden, die ihre Einladung angenommen haben",
+	"Edition does not belong to this product":                                      "Die Edition gehört nicht zu diesem Produkt",
+	"Episode number already exists in this season":                                 "Die Episodennummer existiert in dieser Staffel bereits",
+	"Export has not finished yet":                                                  "Der Export ist noch nicht fertig",
+	"Group is already linked to another household":                                 "Die Gruppe ist bereits mit einem anderen Haushalt verknüpft",
+	"Import failed, no changes were saved":                                         "Der Import ist fehlgeschlagen, es wurde nichts gespeichert",
+	"Job has already finished":                                                     "Der Job ist bereits beendet",
+	"Job has no file to download":                                                  "Der Job hat keine Datei zum Herunterladen",
+	"Kid profile does not belong to the active household":                          "Das Kinderprofil gehört nicht zum aktiven Haushalt",
+	"Kid profiles require an active household":                                     "Kinderprofile erfordern einen aktiven Haushalt",
+	"Maximum number of collections reached (3)":                                    "Die maximale Anzahl an Sammlungen ist erreicht (3)",
+	"Only failed or canceled jobs can be retried":                                  "Nur fehlgeschlagene oder abgebrochene Jobs können wiederholt werden",
+	"Only household administrators can do this":                                    "Das dürfen nur Verwalter des Haushalts",
+	"Product is not part of this collection":                                       "Das Produkt ist nicht Teil dieser Sammlung",
+	"Restore failed, no changes were saved":                                        "Die Wiederherstellung ist fehlgeschlagen, es wurde nichts gespeichert",
+	"Season number already exists":                                                 "Die Staffelnummer existiert bereits",
+	"Seasons are only available for entries with art 'Serie'":                      "Staffeln gibt es nur für Einträge mit art 'Serie'",
+	"The last administrator cannot leave the household":                            "Der letzte Verwalter kann den Haushalt nicht verlassen",
+	"The owner cannot be invited":                                                  "Der Besitzer kann nicht eingeladen werden",
+	"User does not accept invitations":                                             "Der Benutzer nimmt keine Einladungen an",
+	"User is already a member or invited":                                          "Der Benutzer ist bereits Mitglied oder eingeladen",
+	"You are not a member of this household":                                       "Du bist kein Mitglied dieses Haushalts",
+	"Your role in this collection does not allow this action":                      "Deine Rolle in dieser Sammlung erlaubt diese Aktion nicht",
+	// Lokale Konten
+	"Current password is incorrect":            "Das aktuelle Passwort ist falsch",
+	"Invalid or expired refresh token":         "Ungültiges oder abgelaufenes Refresh-Token",
+	"Invalid username or password":             "Benutzername oder Passwort ist falsch",
+	"Registration is closed":                   "Die Registrierung ist geschlossen",
+	"Registration requires a valid invitation": "Die Registrierung erfordert eine gültige Einladung",
+	"Username is already taken":                "Der Benutzername ist bereits vergeben",
+	// Eingaben
+	"Field 'art' must be either 'Film' or 'Serie'":                              "Feld 'art' muss 'Film' oder 'Serie' sein",
+	"Field 'jahr' must be a year between 1800 and 2200":                         "Feld 'jahr' muss ein Jahr zwischen 1800 und 2200 sein",
+	"Field 'benutzername' must be 3-64 characters of a-z, 0-9, '.', '_' or '-'": "Feld 'benutzername' muss aus 3-64 Zeichen a-z, 0-9, '.', '_' oder '-' bestehen",
+	"Field 'conflict' must be skip or overwrite":                                "Feld 'conflict' muss skip oder overwrite sein",
+	"Field 'gueltigBis' must be in the future":                                  "Feld 'gueltigBis' muss in der Zukunft liegen",
+	"Field 'mapping' must be a JSON object of field to column":                  "Feld 'mapping' muss ein JSON-Objekt von Feld zu Spalte sein",
+	"Field 'produkte' must be anonymisieren or uebertragen":                     "Feld 'produkte' muss anonymisieren oder uebertragen sein",
+	"Field 'rolle' must be 'betrachter' or 'bearbeiter'":                        "Feld 'rolle' muss 'betrachter' oder 'bearbeiter' sein",
+	"Field 'rolle' must be 'mitglied' or 'verwalter'":                           "Feld 'rolle' muss 'mitglied' oder 'verwalter' sein",
+	"Field 'tagMapping' must be a JSON object of tag to collection name":        "Feld 'tagMapping' muss ein JSON-Objekt von Tag zu Sammlungsname sein",
+	"Field 'uebertragenAn' must name another user":                              "Feld 'uebertragenAn' muss einen anderen Benutzer angeben",
+	"Field 'anzeigename' must be at most 255 characters long":                   "Feld 'anzeigename' darf höchstens 255 Zeichen lang sein",
+	"Field 'avatarUrl' must be an http or https URL":                            "Feld 'avatarUrl' muss eine http- oder https-URL sein",
+	"Field 'waehrung' must be an ISO 4217 currency code":                        "Feld 'waehrung' muss ein Währungscode nach ISO 4217 sein",
+	"Invalid 'art', allowed are Buch, Manga, Spiel and Filmserie":               "Ungültige 'art', erlaubt sind Buch, Manga, Spiel und Filmserie",
+	"Invalid cover size, allowed are original, small and medium":                "Ungültige Covergröße, erlaubt sind original, small und medium",
+	"Invalid format, allowed are csv, json and xlsx":                            "Ungültiges Format, erlaubt sind csv, json und xlsx",
+	"Missing image file (form field 'file')":                                    "Bilddatei fehlt (Formularfeld 'file')",
+	"Missing import file (form field 'file')":                                   "Importdatei fehlt (Formularfeld 'file')",
+	"Parameter 'art' must be one of Buch, Manga, Spiel, Filmserie":              "Parameter 'art' muss Buch, Manga, Spiel oder Filmserie sein",
+	"Parameter 'covers' must be true or false":                                  "Parameter 'covers' muss true oder false sein",
+	"Parameter 'name' or 'isbn' is required":                                    "Parameter 'name' oder 'isbn' fehlt",
+	"Unknown import format, set 'format' to csv or json":                        "Unbekanntes Importformat, 'format' muss csv oder json sein",
+}
+
+// musterDeutsch übersetzt Meldungen mit variablen Teilen
+var musterDeutsch = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`^Invalid request body: (.*)$`), "Ungültiger Request-Body: $1"},
+	{regexp.MustCompile(`^Field '([^']+)' must not be empty$`), "Feld '$1' darf nicht leer sein"},
+	{regexp.MustCompile(`^Field '([^']+)' must be true or false$`), "Feld '$1' muss true oder false sein"},
+	{regexp.MustCompile(`^Field '([^']+)' must be one of (.+)$`), "Feld '$1' muss einer dieser Werte sein: $2"},
+	{regexp.MustCompile(`^Parameter '([^']+)' must be one of (.+)$`), "Parameter '$1' muss einer dieser Werte sein: $2"},
+	{regexp.MustCompile(`^Invalid scope '([^']*)', allowed: (.+)$`), "Ungültiger Scope '$1', erlaubt: $2"},
+	{regexp.MustCompile(`^This action requires one of the roles: (.+)$`), "Diese Aktion erfordert eine der Rollen: $1"},
+	{regexp.MustCompile(`^Image must not exceed (\d+) bytes$`), "Das Bild darf höchstens $1 Bytes groß sein"},
+	{regexp.MustCompile(`^Import file must not exceed (\d+) bytes$`), "Die Importdatei darf höchstens $1 Bytes groß sein"},
+	{regexp.MustCompile(`^Password must be at least (\d+) characters long$`), "Das Passwort muss mindestens $1 Zeichen lang sein"},
+	{regexp.MustCompile(`^Password must be at most (\d+) bytes long$`), "Das Passwort darf höchstens $1 Bytes lang sein"},
+	// Beschriftungen in erfolgreichen Antworten
+	{regexp.MustCompile(`^Season (\d+), (\d+)/(\d+) watched$`), "Staffel $1, $2/$3 gesehen"},
+}
+
+// uebersetzeDeutsch übersetzt eine Meldung; ok ist false, wenn keine Übersetzung bekannt ist
+func uebersetzeDeutsch(meldung string) (string, bool) {
+	if uebersetzt, ok := meldungenDeutsch[meldung]; ok {
+		return uebersetzt, true
+	}
+	for _, muster := range musterDeutsch {
+		if muster.pattern.MatchString(meldung) {
+			return muster.pattern.ReplaceAllString(meldung, muster.replacement), true
+		}
+	}
+	return meldung, false
+}
+
+// fehlerWriter hält den Body von Fehlerantworten zurück, damit er übersetzt werden kann
+type fehlerWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *fehlerWriter) Write(data []byte) (int, error) {
+	if w.Status() >= 400 {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *fehlerWriter) WriteString(s string) (int, error) {
+	if w.Status() >= 400 {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// requestSprache bestimmt die Sprache für Fehlermeldungen: die Einstellung des angemeldeten
+// Benutzers, sonst der Accept-Language-Header, sonst Englisch
+func requestSprache(c *gin.Context) string {
+	if userID := optionalUserID(c); userID != nil {
+		if db, ok := c.Get("db"); ok {
+			einstellungen, err := loadEinstellungen(db.(*gorm.DB), *userID)
+			if err != nil {
+				log.Printf("ERROR requestSprache - Load settings for user %s: %v\n", *userID, err)
+			} else if einstellungen.Sprache != nil {
+				return *einstellungen.Sprache
+			}
+		}
+	}
+	tags, _, err := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return models.SpracheEnglisch
+	}
+	tag, _, confidence := spracheMatcher.Match(tags...)
+	if confidence == language.No {
+		return models.SpracheEnglisch
+	}
+	if base, _ := tag.Base(); base.String() == models.SpracheDeutsch {
+		return models.SpracheDeutsch
+	}
+	return models.SpracheEnglisch
+}
+
+// SpracheMiddleware übersetzt die Meldung von JSON-Fehlerantworten in die Sprache des Benutzers. Erfolgreiche Antworten werden unverändert durchgereicht.
+func SpracheMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &fehlerWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if writer.body.Len() == 0 {
+			return
+		}
+		body := writer.body.Bytes()
+		sprache := requestSprache(c)
+		original.Header().Set("Content-Language", sprache)
+		if sprache == models.SpracheDeutsch {
+			body = uebersetzeFehler(body, original.Status())
+		}
+		if _, err := original.Write(body); err != nil {
+			log.Printf("ERROR SpracheMiddleware - Write response: %v\n", err)
+		}
+	}
+}
+
+// uebersetzeFehler übersetzt einen JSON-Fehlerbody; andere Inhalte bleiben unverändert.
+// Enthält er "message" (AuthMiddleware), ist "error" ein Fehlercode und bleibt erhalten.
+func uebersetzeFehler(body []byte, status int) []byte {
+	var fehler map[string]interface{}
+	if err := json.Unmarshal(body, &fehler); err != nil {
+		return body
+	}
+	feld := "error"
+	if _, ok := fehler["message"]; ok {
+		feld = "message"
+	}
+	meldung, ok := fehler[feld].(string)
+	if !ok {
+		return body
+	}
+	uebersetzt, ok := uebersetzeDeutsch(meldung)
+	if !ok && status >= 500 {
+		uebersetzt = serverfehlerDeutsch
+	}
+	fehler[feld] = uebersetzt
+	translated, err := json.Marshal(fehler)
+	if err != nil {
+		return body
+	}
+	return translated
+}
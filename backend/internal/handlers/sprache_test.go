@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func spracheRouter(db *gorm.DB, userID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.Use(SpracheMiddleware())
+	router.Use(func(c *gin.Context) {
+		// Wie die AuthMiddleware: die Benutzer-ID steht erst nach der Middleware fest
+		if userID != "" {
+			c.Set("userId", userID)
+		}
+		c.Next()
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "Collection not found or access denied"})
+	})
+	router.GET("/nicht-gefunden", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found or access denied"})
+	})
+	router.GET("/ungueltig", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'sprache' must be one of de, en"})
+	})
+	router.GET("/unbekannt", func(c *gin.Context) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Something new"})
+	})
+	router.GET("/serverfehler", func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collections"})
+	})
+	router.GET("/auth", func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":      "invalid_token",
+			"message":    "Invalid or expired authentication token",
+			"statusCode": http.StatusUnauthorized,
+		})
+	})
+	return router
+}
+
+func TestSpracheMiddleware(t *testing.T) {
+	db := setupProfilTestDB(t)
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+
+	request := func(router *gin.Engine, path, acceptLanguage string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+		return w, body
+	}
+	anonym := spracheRouter(db, "")
+
+	// English stays the default
+	w, body := request(anonym, "/nicht-gefunden", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "Collection not found or access denied", body["error"])
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+	_, body = request(anonym, "/nicht-gefunden", "fr-FR, en;q=0.5")
+	assert.Equal(t, "Collection not found or access denied", body["error"])
+
+	// Accept-Language
+	w, body = request(anonym, "/nicht-gefunden", "de-CH, de;q=0.9, en;q=0.8")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "Sammlung nicht gefunden oder kein Zugriff", body["error"])
+	assert.Equal(t, "de", w.Header().Get("Content-Language"))
+	_, body = request(anonym, "/ungueltig", "de")
+	assert.Equal(t, "Feld 'sprache' muss einer dieser Werte sein: de, en", body["error"])
+	_, body = request(anonym, "/unbekannt", "de")
+	assert.Equal(t, "Something new", body["error"])
+	_, body = request(anonym, "/serverfehler", "de")
+	assert.Equal(t, serverfehlerDeutsch, body["error"])
+	w, body = request(anonym, "/auth", "de")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "invalid_token", body["error"])
+	assert.Equal(t, "Ungültiges oder abgelaufenes Anmeldetoken", body["message"])
+	assert.Equal(t, float64(http.StatusUnauthorized), body["statusCode"])
+
+	// Successful responses are passed through
+	w, body = request(anonym, "/ok", "de")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Collection not found or access denied", body["status"])
+	assert.Empty(t, w.Header().Get("Content-Language"))
+
+	// The preference of the user takes precedence over the header
+	alice := spracheRouter(db, "alice")
+	_, body = request(alice, "/nicht-gefunden", "de")
+	assert.Equal(t, "Sammlung nicht gefunden oder kein Zugriff", body["error"])
+	einstellungen := models.StandardEinstellungen("alice")
+	einstellungen.Sprache = strPtr(models.SpracheEnglisch)
+	require.NoError(t, db.Create(&einstellungen).Error)
+	_, body = request(alice, "/nicht-gefunden", "de")
+	assert.Equal(t, "Collection not found or access denied", body["error"])
+}
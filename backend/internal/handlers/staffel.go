@@ -0,0 +1,613 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// datumLayout ist das Format für reine Datumsangaben (z.B. Erstausstrahlung)
+const datumLayout = "2006-01-02"
+
+// --- Structs für Staffeln und Episoden ---
+
+// StaffelRequest definiert die JSON-Struktur für Staffel-Anfragen
+type StaffelRequest struct {
+	Nummer int     `json:"nummer" binding:"required,min=1"`
+	Titel  *string `json:"titel"`
+}
+
+// EpisodeRequest definiert die JSON-Struktur für Episoden-Anfragen
+type EpisodeRequest struct {
+	Nummer       int     `json:"nummer" binding:"required,min=1"`
+	Titel        *string `json:"titel"`
+	Laufzeit     *int    `json:"laufzeit" binding:"omitempty,min=0"` // Minuten
+	Ausstrahlung *string `json:"ausstrahlung"`                       // Format YYYY-MM-DD
+}
+
+// EpisodeResponse definiert die JSON-Struktur für Episoden-Antworten
+type EpisodeResponse struct {
+	ID           uint    `json:"id"`
+	StaffelID    uint    `json:"staffelId"`
+	Nummer       int     `json:"nummer"`
+	Titel        *string `json:"titel"`
+	Laufzeit     *int    `json:"laufzeit"`
+	Ausstrahlung *string `json:"ausstrahlung"`
+	Gesehen      bool    `json:"gesehen"` // Für den eingeloggten Benutzer
+}
+
+// StaffelResponse definiert die JSON-Struktur für Staffel-Antworten
+type StaffelResponse struct {
+	ID          uint              `json:"id"`
+	FilmserieID uint              `json:"filmserieId"`
+	Nummer      int               `json:"nummer"`
+	Titel       *string           `json:"titel"`
+	Episoden    []EpisodeResponse `json:"episoden"`
+}
+
+// StaffelFortschritt fasst den Fortschritt eines Benutzers in einer Staffel zusammen
+type StaffelFortschritt struct {
+	StaffelID     uint    `json:"staffelId"`
+	Nummer        int     `json:"nummer"`
+	Titel         *string `json:"titel"`
+	Gesehen       int     `json:"gesehen"`
+	Gesamt        int     `json:"gesamt"`
+	Abgeschlossen bool    `json:"abgeschlossen"`
+	Label         string  `json:"label"` // z.B. "Season 2, 5/10 watched", in der Sprache des Benutzers
+}
+
+// FortschrittResponse fasst den Fortschritt eines Benutzers über alle Staffeln zusammen
+type FortschrittResponse struct {
+	FilmserieID     uint                 `json:"filmserieId"`
+	Gesehen         int                  `json:"gesehen"`
+	Gesamt          int                  `json:"gesamt"`
+	AktuelleStaffel *StaffelFortschritt  `json:"aktuelleStaffel"` // Erste nicht abgeschlossene Staffel
+	Staffeln        []StaffelFortschritt `json:"staffeln"`
+}
+
+// --- Hilfsfunktionen ---
+
+// parseDatum wandelt einen optionalen Datumsstring (YYYY-MM-DD) in time.Time um
+func parseDatum(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(datumLayout, *value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected format YYYY-MM-DD", *value)
+	}
+	return &parsed, nil
+}
+
+// formatDatum ist das Gegenstück zu parseDatum
+func formatDatum(value *time.Time) *string {
+	if value == nil {
+		return nil
+	}
+	formatted := value.Format(datumLayout)
+	return &formatted
+}
+
+// findSerie lädt eine Filmserie und stellt sicher, dass es sich um eine Serie handelt.
+// Bei Fehlern wird direkt geantwortet und nil zurückgegeben.
+func findSerie(c *gin.Context, db *gorm.DB, id string) *models.Filmserie {
+	var filmserie models.Filmserie
+	if err := db.Where("produkte_id IN (?)", sichtbareProdukte(c, db)).First(&filmserie, "produkte_id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Film/Serie not found"})
+		} else {
+			log.Printf("Error retrieving filmserie ID %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve film/serie"})
+		}
+		return nil
+	}
+	if filmserie.Art == nil || *filmserie.Art != "Serie" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Seasons are only available for entries with art 'Serie'"})
+		return nil
+	}
+	return &filmserie
+}
+
+// findStaffel lädt eine Staffel, die zur angegebenen Filmserie gehört
+func findStaffel(c *gin.Context, db *gorm.DB, filmserieID uint, staffelID string) *models.Staffel {
+	var staffel models.Staffel
+	if err := db.First(&staffel, "id = ? AND filmserie_id = ?", staffelID, filmserieID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Season not found"})
+		} else {
+			log.Printf("Error retrieving season ID %s: %v", staffelID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve season"})
+		}
+		return nil
+	}
+	return &staffel
+}
+
+// findEpisode lädt eine Episode anhand der ID aus der URL. Episoden von Serien, die im
+// aktuellen Kontext nicht sichtbar sind, werden wie nicht vorhandene behandelt.
+func findEpisode(c *gin.Context, db *gorm.DB) *models.Episode {
+	episodeID, err := strconv.ParseUint(c.Param("episodeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID format"})
+		return nil
+	}
+	var episode models.Episode
+	sichtbareStaffeln := db.Model(&models.Staffel{}).Select("id").Where("filmserie_id IN (?)", sichtbareProdukte(c, db))
+	if err := db.Where("staffel_id IN (?)", sichtbareStaffeln).First(&episode, uint(episodeID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		} else {
+			log.Printf("Error retrieving episode ID %d: %v", episodeID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve episode"})
+		}
+		return nil
+	}
+	return &episode
+}
+
+// isUniqueViolation erkennt Verletzungen eines Unique-Index, auch ohne TranslateError im GORM-Config
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint") || strings.Contains(msg, "duplicate key")
+}
+
+func toEpisodeResponse(episode models.Episode, gesehen bool) EpisodeResponse {
+	return EpisodeResponse{
+		ID:           episode.ID,
+		StaffelID:    episode.StaffelID,
+		Nummer:       episode.Nummer,
+		Titel:        episode.Titel,
+		Laufzeit:     episode.Laufzeit,
+		Ausstrahlung: formatDatum(episode.Ausstrahlung),
+		Gesehen:      gesehen,
+	}
+}
+
+// fortschrittLabel beschriftet den Fortschritt einer Staffel; übersetzt wird wie bei Fehlermeldungen
+func fortschrittLabel(sprache string, nummer, gesehen, gesamt int) string {
+	label := fmt.Sprintf("Season %d, %d/%d watched", nummer, gesehen, gesamt)
+	if sprache == models.SpracheDeutsch {
+		label, _ = uebersetzeDeutsch(label)
+	}
+	return label
+}
+
+// --- Handler für Staffeln ---
+
+// ListStaffeln listet alle Staffeln einer Serie inkl. Episoden und Gesehen-Status
+func ListStaffeln(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+
+	var staffeln []models.Staffel
+	err := db.Where("filmserie_id = ?", filmserie.ProdukteID).
+		Preload("Episoden", func(tx *gorm.DB) *gorm.DB { return tx.Order("nummer asc") }).
+		Order("nummer asc").
+		Find(&staffeln).Error
+	if err != nil {
+		log.Printf("Error retrieving seasons for filmserie ID %d: %v", filmserie.ProdukteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve seasons"})
+		return
+	}
+
+	// Gesehen-Status des Benutzers für alle Episoden der Serie in einer Abfrage laden
+	var gesehenIDs []uint
+	err = db.Model(&models.EpisodeGesehen{}).
+		Joins("JOIN episode ON episode.id = episode_gesehen.episode_id").
+		Joins("JOIN staffel ON staffel.id = episode.staffel_id").
+		Where("episode_gesehen.webuser_id = ? AND staffel.filmserie_id = ?", userID, filmserie.ProdukteID).
+		Pluck("episode_gesehen.episode_id", &gesehenIDs).Error
+	if err != nil {
+		log.Printf("Error retrieving watched episodes for filmserie ID %d: %v", filmserie.ProdukteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watched status"})
+		return
+	}
+	gesehen := make(map[uint]bool, len(gesehenIDs))
+	for _, id := range gesehenIDs {
+		gesehen[id] = true
+	}
+
+	response := make([]StaffelResponse, len(staffeln))
+	for i, staffel := range staffeln {
+		episoden := make([]EpisodeResponse, len(staffel.Episoden))
+		for j, episode := range staffel.Episoden {
+			episoden[j] = toEpisodeResponse(episode, gesehen[episode.ID])
+		}
+		response[i] = StaffelResponse{
+			ID:          staffel.ID,
+			FilmserieID: staffel.FilmserieID,
+			Nummer:      staffel.Nummer,
+			Titel:       staffel.Titel,
+			Episoden:    episoden,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateStaffel legt eine neue Staffel für eine Serie an
+func CreateStaffel(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request StaffelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+
+	staffel := models.Staffel{
+		FilmserieID: filmserie.ProdukteID,
+		Nummer:      request.Nummer,
+		Titel:       request.Titel,
+	}
+	if err := db.Create(&staffel).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Season number already exists"})
+			return
+		}
+		log.Printf("Error creating season for filmserie ID %d: %v", filmserie.ProdukteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create season"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, StaffelResponse{
+		ID:          staffel.ID,
+		FilmserieID: staffel.FilmserieID,
+		Nummer:      staffel.Nummer,
+		Titel:       staffel.Titel,
+		Episoden:    []EpisodeResponse{},
+	})
+}
+
+// UpdateStaffel aktualisiert Nummer und Titel einer Staffel
+func UpdateStaffel(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request StaffelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+	staffel := findStaffel(c, db, filmserie.ProdukteID, c.Param("staffelId"))
+	if staffel == nil {
+		return
+	}
+
+	staffel.Nummer = request.Nummer
+	staffel.Titel = request.Titel
+	if err := db.Save(staffel).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Season number already exists"})
+			return
+		}
+		log.Printf("Error updating season ID %d: %v", staffel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update season"})
+		return
+	}
+
+	c.JSON(http.StatusOK, StaffelResponse{
+		ID:          staffel.ID,
+		FilmserieID: staffel.FilmserieID,
+		Nummer:      staffel.Nummer,
+		Titel:       staffel.Titel,
+		Episoden:    []EpisodeResponse{},
+	})
+}
+
+// DeleteStaffel löscht eine Staffel (Cascade löscht Episoden und Gesehen-Einträge)
+func DeleteStaffel(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+
+	result := db.Where("id = ? AND filmserie_id = ?", c.Param("staffelId"), filmserie.ProdukteID).Delete(&models.Staffel{})
+	if result.Error != nil {
+		log.Printf("Error deleting season ID %s: %v", c.Param("staffelId"), result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete season"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Season not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// --- Handler für Episoden ---
+
+// CreateEpisode legt eine neue Episode in einer Staffel an
+func CreateEpisode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request EpisodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ausstrahlung, err := parseDatum(request.Ausstrahlung)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+	staffel := findStaffel(c, db, filmserie.ProdukteID, c.Param("staffelId"))
+	if staffel == nil {
+		return
+	}
+
+	episode := models.Episode{
+		StaffelID:    staffel.ID,
+		Nummer:       request.Nummer,
+		Titel:        request.Titel,
+		Laufzeit:     request.Laufzeit,
+		Ausstrahlung: ausstrahlung,
+	}
+	if err := db.Create(&episode).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Episode number already exists in this season"})
+			return
+		}
+		log.Printf("Error creating episode for season ID %d: %v", staffel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create episode"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toEpisodeResponse(episode, false))
+}
+
+// UpdateEpisode aktualisiert eine bestehende Episode
+func UpdateEpisode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request EpisodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ausstrahlung, err := parseDatum(request.Ausstrahlung)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	episode := findEpisode(c, db)
+	if episode == nil {
+		return
+	}
+
+	episode.Nummer = request.Nummer
+	episode.Titel = request.Titel
+	episode.Laufzeit = request.Laufzeit
+	episode.Ausstrahlung = ausstrahlung
+	if err := db.Save(episode).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Episode number already exists in this season"})
+			return
+		}
+		log.Printf("Error updating episode ID %d: %v", episode.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update episode"})
+		return
+	}
+
+	var gesehen int64
+	err = db.Model(&models.EpisodeGesehen{}).Where("webuser_id = ? AND episode_id = ?", userID, episode.ID).Count(&gesehen).Error
+	if err != nil {
+		log.Printf("Error retrieving watched status of episode ID %d: %v", episode.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve watched status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toEpisodeResponse(*episode, gesehen > 0))
+}
+
+// DeleteEpisode löscht eine Episode
+func DeleteEpisode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	episode := findEpisode(c, db)
+	if episode == nil {
+		return
+	}
+
+	if err := db.Delete(episode).Error; err != nil {
+		log.Printf("Error deleting episode ID %d: %v", episode.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete episode"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkEpisodeGesehen markiert eine Episode für den eingeloggten Benutzer als gesehen
+func MarkEpisodeGesehen(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	episode := findEpisode(c, db)
+	if episode == nil {
+		return
+	}
+
+	eintrag := models.EpisodeGesehen{
+		WebuserID: userID,
+		EpisodeID: episode.ID,
+		GesehenAm: time.Now(),
+	}
+	// Erneutes Markieren ist idempotent
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&eintrag).Error; err != nil {
+		log.Printf("Error marking episode ID %d as watched for user %s: %v", episode.ID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark episode as watched"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toEpisodeResponse(*episode, true))
+}
+
+// UnmarkEpisodeGesehen entfernt die Gesehen-Markierung einer Episode
+func UnmarkEpisodeGesehen(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	episode := findEpisode(c, db)
+	if episode == nil {
+		return
+	}
+
+	err := db.Where("webuser_id = ? AND episode_id = ?", userID, episode.ID).Delete(&models.EpisodeGesehen{}).Error
+	if err != nil {
+		log.Printf("Error unmarking episode ID %d for user %s: %v", episode.ID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmark episode"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkStaffelGesehen markiert alle Episoden einer Staffel als gesehen
+func MarkStaffelGesehen(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+	staffel := findStaffel(c, db, filmserie.ProdukteID, c.Param("staffelId"))
+	if staffel == nil {
+		return
+	}
+
+	var episodeIDs []uint
+	if err := db.Model(&models.Episode{}).Where("staffel_id = ?", staffel.ID).Pluck("id", &episodeIDs).Error; err != nil {
+		log.Printf("Error retrieving episodes for season ID %d: %v", staffel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve episodes"})
+		return
+	}
+
+	if len(episodeIDs) > 0 {
+		now := time.Now()
+		eintraege := make([]models.EpisodeGesehen, len(episodeIDs))
+		for i, id := range episodeIDs {
+			eintraege[i] = models.EpisodeGesehen{WebuserID: userID, EpisodeID: id, GesehenAm: now}
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&eintraege).Error; err != nil {
+			log.Printf("Error marking season ID %d as watched for user %s: %v", staffel.ID, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark season as watched"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Season marked as watched", "episoden": len(episodeIDs)})
+}
+
+// GetSerienFortschritt fasst den Gesehen-Status des Benutzers je Staffel zusammen
+func GetSerienFortschritt(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filmserie := findSerie(c, db, c.Param("id"))
+	if filmserie == nil {
+		return
+	}
+
+	type fortschrittRow struct {
+		StaffelID uint
+		Nummer    int
+		Titel     *string
+		Gesamt    int
+		Gesehen   int
+	}
+	var rows []fortschrittRow
+	err := db.Table("staffel").
+		Select("staffel.id AS staffel_id, staffel.nummer, staffel.titel, "+
+			"COUNT(episode.id) AS gesamt, COUNT(episode_gesehen.episode_id) AS gesehen").
+		Joins("LEFT JOIN episode ON episode.staffel_id = staffel.id").
+		Joins("LEFT JOIN episode_gesehen ON episode_gesehen.episode_id = episode.id AND episode_gesehen.webuser_id = ?", userID).
+		Where("staffel.filmserie_id = ?", filmserie.ProdukteID).
+		Group("staffel.id, staffel.nummer, staffel.titel").
+		Order("staffel.nummer asc").
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("Error aggregating progress for filmserie ID %d: %v", filmserie.ProdukteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve progress"})
+		return
+	}
+
+	sprache := requestSprache(c)
+	response := FortschrittResponse{
+		FilmserieID: filmserie.ProdukteID,
+		Staffeln:    make([]StaffelFortschritt, len(rows)),
+	}
+	for i, row := range rows {
+		response.Staffeln[i] = StaffelFortschritt{
+			StaffelID:     row.StaffelID,
+			Nummer:        row.Nummer,
+			Titel:         row.Titel,
+			Gesehen:       row.Gesehen,
+			Gesamt:        row.Gesamt,
+			Abgeschlossen: row.Gesamt > 0 && row.Gesehen == row.Gesamt,
+			Label:         fortschrittLabel(sprache, row.Nummer, row.Gesehen, row.Gesamt),
+		}
+		response.Gesehen += row.Gesehen
+		response.Gesamt += row.Gesamt
+		if response.AktuelleStaffel == nil && !response.Staffeln[i].Abgeschlossen && row.Gesamt > 0 {
+			response.AktuelleStaffel = &response.Staffeln[i]
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupStaffelTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&models.Produkt{}, &models.Filmserie{}, &models.Webuser{},
+		&models.Staffel{}, &models.Episode{}, &models.EpisodeGesehen{}, &models.Benutzereinstellungen{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func setupStaffelTestRouter(db *gorm.DB, userID string) *gin.Engine {
+	router := setupCollectionTestRouter(db, userID)
+	router.GET("/filmserie/:id/staffeln", ListStaffeln)
+	router.POST("/filmserie/:id/staffeln", CreateStaffel)
+	router.PUT("/filmserie/:id/staffeln/:staffelId", UpdateStaffel)
+	router.DELETE("/filmserie/:id/staffeln/:staffelId", DeleteStaffel)
+	router.POST("/filmserie/:id/staffeln/:staffelId/episoden", CreateEpisode)
+	router.PUT("/filmserie/:id/staffeln/:staffelId/gesehen", MarkStaffelGesehen)
+	router.GET("/filmserie/:id/fortschritt", GetSerienFortschritt)
+	router.PUT("/episoden/:episodeId", UpdateEpisode)
+	router.DELETE("/episoden/:episodeId", DeleteEpisode)
+	router.PUT("/episoden/:episodeId/gesehen", MarkEpisodeGesehen)
+	router.DELETE("/episoden/:episodeId/gesehen", UnmarkEpisodeGesehen)
+	return router
+}
+
+func createTestSerie(t *testing.T, db *gorm.DB, name string, art string) uint {
+	product := models.Produkt{Name: name, Art: "Filmserie"}
+	require.NoError(t, db.Create(&product).Error)
+	require.NoError(t, db.Create(&models.Filmserie{ProdukteID: product.ID, Art: strPtr(art)}).Error)
+	return product.ID
+}
+
+func doJSON(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Buffer
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(encoded)
+	} else {
+		reader = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateStaffel(t *testing.T) {
+	db := setupStaffelTestDB(t)
+	router := setupStaffelTestRouter(db, "test-user-123")
+	serieID := createTestSerie(t, db, "Dark", "Serie")
+	filmID := createTestSerie(t, db, "Inception", "Film")
+
+	tests := []struct {
+		name           string
+		path           string
+		requestBody    interface{}
+		expectedStatus int
+	}{
+		{
+			name:           "successful creation",
+			path:           fmt.Sprintf("/filmserie/%d/staffeln", serieID),
+			requestBody:    StaffelRequest{Nummer: 1, Titel: strPtr("Season One")},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "duplicate season number",
+			path:           fmt.Sprintf("/filmserie/%d/staffeln", serieID),
+			requestBody:    StaffelRequest{Nummer: 1},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "missing number",
+			path:           fmt.Sprintf("/filmserie/%d/staffeln", serieID),
+			requestBody:    map[string]interface{}{"titel": "No number"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "film has no seasons",
+			path:           fmt.Sprintf("/filmserie/%d/staffeln", filmID),
+			requestBody:    StaffelRequest{Nummer: 1},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown filmserie",
+			path:           "/filmserie/999/staffeln",
+			requestBody:    StaffelRequest{Nummer: 1},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(router, http.MethodPost, tt.path, tt.requestBody)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestCreateAndUpdateEpisode(t *testing.T) {
+	db := setupStaffelTestDB(t)
+	router := setupStaffelTestRouter(db, "test-user-123")
+	serieID := createTestSerie(t, db, "Dark", "Serie")
+
+	staffel := models.Staffel{FilmserieID: serieID, Nummer: 1}
+	require.NoError(t, db.Create(&staffel).Error)
+	basePath := fmt.Sprintf("/filmserie/%d/staffeln/%d/episoden", serieID, staffel.ID)
+
+	w := doJSON(router, http.MethodPost, basePath, EpisodeRequest{
+		Nummer:       1,
+		Titel:        strPtr("Secrets"),
+		Laufzeit:     intPtr(51),
+		Ausstrahlung: strPtr("2017-12-01"),
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created EpisodeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "Secrets", *created.Titel)
+	assert.Equal(t, 51, *created.Laufzeit)
+	assert.Equal(t, "2017-12-01", *created.Ausstrahlung)
+	assert.False(t, created.Gesehen)
+
+	// Invalid date
+	w = doJSON(router, http.MethodPost, basePath, EpisodeRequest{Nummer: 2, Ausstrahlung: strPtr("01.12.2017")})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Duplicate episode number
+	w = doJSON(router, http.MethodPost, basePath, EpisodeRequest{Nummer: 1})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Update
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/episoden/%d", created.ID), EpisodeRequest{Nummer: 1, Titel: strPtr("Geheimnisse")})
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated EpisodeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "Geheimnisse", *updated.Titel)
+	assert.Nil(t, updated.Ausstrahlung)
+	assert.False(t, updated.Gesehen)
+
+	// The response keeps the watched status of the caller
+	require.NoError(t, db.Create(&models.EpisodeGesehen{WebuserID: "test-user-123", EpisodeID: created.ID, GesehenAm: time.Now()}).Error)
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/episoden/%d", created.ID), EpisodeRequest{Nummer: 1, Titel: strPtr("Geheimnisse")})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.True(t, updated.Gesehen)
+
+	// Unknown episode
+	w = doJSON(router, http.MethodPut, "/episoden/999", EpisodeRequest{Nummer: 1})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSerienFortschritt(t *testing.T) {
+	db := setupStaffelTestDB(t)
+	db.Create(&models.Webuser{ID: "test-user-123"})
+	db.Create(&models.Webuser{ID: "other-user"})
+	router := setupStaffelTestRouter(db, "test-user-123")
+	serieID := createTestSerie(t, db, "Dark", "Serie")
+
+	// Season 1 with 3 episodes, season 2 with 10 episodes
+	episodeIDs := map[int][]uint{}
+	for nummer, anzahl := range map[int]int{1: 3, 2: 10} {
+		staffel := models.Staffel{FilmserieID: serieID, Nummer: nummer}
+		require.NoError(t, db.Create(&staffel).Error)
+		for i := 1; i <= anzahl; i++ {
+			episode := models.Episode{StaffelID: staffel.ID, Nummer: i}
+			require.NoError(t, db.Create(&episode).Error)
+			episodeIDs[nummer] = append(episodeIDs[nummer], episode.ID)
+		}
+	}
+
+	// Watch all of season 1 at once and 5 episodes of season 2 one by one
+	var staffel1 models.Staffel
+	require.NoError(t, db.First(&staffel1, "filmserie_id = ? AND nummer = 1", serieID).Error)
+	w := doJSON(router, http.MethodPut, fmt.Sprintf("/filmserie/%d/staffeln/%d/gesehen", serieID, staffel1.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, id := range episodeIDs[2][:5] {
+		w := doJSON(router, http.MethodPut, fmt.Sprintf("/episoden/%d/gesehen", id), nil)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	// Marking twice is idempotent
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/episoden/%d/gesehen", episodeIDs[2][0]), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Another user's progress must not be counted
+	db.Create(&models.EpisodeGesehen{WebuserID: "other-user", EpisodeID: episodeIDs[2][9]})
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/filmserie/%d/fortschritt", serieID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response FortschrittResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 8, response.Gesehen)
+	assert.Equal(t, 13, response.Gesamt)
+	require.Len(t, response.Staffeln, 2)
+	assert.True(t, response.Staffeln[0].Abgeschlossen)
+	assert.Equal(t, "Season 1, 3/3 watched", response.Staffeln[0].Label)
+	require.NotNil(t, response.AktuelleStaffel)
+	assert.Equal(t, 2, response.AktuelleStaffel.Nummer)
+	assert.Equal(t, "Season 2, 5/10 watched", response.AktuelleStaffel.Label)
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/filmserie/%d/fortschritt", serieID), nil)
+	req.Header.Set("Accept-Language", "de-DE")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Staffel 2, 5/10 gesehen", response.AktuelleStaffel.Label)
+
+	// Unmark one episode and check the listing reflects it
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/episoden/%d/gesehen", episodeIDs[2][0]), nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/filmserie/%d/staffeln", serieID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var staffeln []StaffelResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &staffeln))
+	require.Len(t, staffeln, 2)
+	assert.Len(t, staffeln[1].Episoden, 10)
+	assert.False(t, staffeln[1].Episoden[0].Gesehen)
+	assert.True(t, staffeln[1].Episoden[1].Gesehen)
+	assert.False(t, staffeln[1].Episoden[9].Gesehen)
+}
+
+func TestDeleteStaffel(t *testing.T) {
+	db := setupStaffelTestDB(t)
+	router := setupStaffelTestRouter(db, "test-user-123")
+	serieID := createTestSerie(t, db, "Dark", "Serie")
+	otherID := createTestSerie(t, db, "Lost", "Serie")
+
+	staffel := models.Staffel{FilmserieID: serieID, Nummer: 1}
+	require.NoError(t, db.Create(&staffel).Error)
+
+	// Season must belong to the series in the URL
+	w := doJSON(router, http.MethodDelete, fmt.Sprintf("/filmserie/%d/staffeln/%d", otherID, staffel.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/filmserie/%d/staffeln/%d", serieID, staffel.ID), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var count int64
+	db.Model(&models.Staffel{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// zugangstokenBytes bestimmt die Länge der Tokens (32 Byte = 43 Zeichen Base64 nach dem Präfix)
+const zugangstokenBytes = 32
+
+// zugangstokenAnfangLaenge ist die Zahl der Zeichen, die zum Wiedererkennen gespeichert werden
+const zugangstokenAnfangLaenge = 12
+
+type CreateZugangstokenRequest struct {
+	Name       string     `json:"name" binding:"required"`
+	Scopes     []string   `json:"scopes" binding:"required"`
+	GueltigBis *time.Time `json:"gueltigBis"` // RFC 3339, ohne Angabe unbegrenzt
+}
+
+type ZugangstokenResponse struct {
+	ID                 uint       `json:"id"`
+	Name               string     `json:"name"`
+	Anfang             string     `json:"anfang"`
+	Scopes             []string   `json:"scopes"`
+	ErstelltAm         time.Time  `json:"erstelltAm"`
+	GueltigBis         *time.Time `json:"gueltigBis"`
+	ZuletztVerwendetAm *time.Time `json:"zuletztVerwendetAm"`
+	WiderrufenAm       *time.Time `json:"widerrufenAm"`
+	Abgelaufen         bool       `json:"abgelaufen"`
+}
+
+// CreateZugangstokenResponse enthält zusätzlich das Token selbst; es wird nur hier angezeigt
+type CreateZugangstokenResponse struct {
+	ZugangstokenResponse
+	Token string `json:"token"`
+}
+
+func toZugangstokenResponse(zugangstoken models.Zugangstoken, now time.Time) ZugangstokenResponse {
+	return ZugangstokenResponse{
+		ID:                 zugangstoken.ID,
+		Name:               zugangstoken.Name,
+		Anfang:             zugangstoken.Anfang,
+		Scopes:             zugangstoken.ScopeListe(),
+		ErstelltAm:         zugangstoken.ErstelltAm,
+		GueltigBis:         zugangstoken.GueltigBis,
+		ZuletztVerwendetAm: zugangstoken.ZuletztVerwendetAm,
+		WiderrufenAm:       zugangstoken.WiderrufenAm,
+		Abgelaufen:         !zugangstoken.Gueltig(now),
+	}
+}
+
+func newZugangstoken() (string, error) {
+	raw := make([]byte, zugangstokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return models.ZugangstokenPraefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// CreateZugangstoken legt ein persönliches Zugangstoken an und gibt es einmalig zurück
+func CreateZugangstoken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request CreateZugangstokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'name' must not be empty"})
+		return
+	}
+	if len(request.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'scopes' must not be empty"})
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !models.GueltigerScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid scope '" + scope + "', allowed: " + strings.Join(models.Scopes, ", "),
+			})
+			return
+		}
+	}
+	now := time.Now().UTC()
+	if request.GueltigBis != nil && !request.GueltigBis.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'gueltigBis' must be in the future"})
+		return
+	}
+
+	token, err := newZugangstoken()
+	if err != nil {
+		log.Printf("ERROR CreateZugangstoken - Generate token: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token"})
+		return
+	}
+	zugangstoken := models.Zugangstoken{
+		WebuserID:  userID,
+		Name:       request.Name,
+		Anfang:     token[:zugangstokenAnfangLaenge],
+		Hash:       database.HashToken(token),
+		Scopes:     strings.Join(request.Scopes, ","),
+		ErstelltAm: now,
+		GueltigBis: request.GueltigBis,
+	}
+	if err := db.Omit("Webuser").Create(&zugangstoken).Error; err != nil {
+		log.Printf("ERROR CreateZugangstoken for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateZugangstokenResponse{
+		ZugangstokenResponse: toZugangstokenResponse(zugangstoken, now),
+		Token:                token,
+	})
+}
+
+// ListZugangstokens listet die eigenen Zugangstokens, auch abgelaufene und widerrufene
+func ListZugangstokens(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var zugangstokens []models.Zugangstoken
+	if err := db.Where("webuser_id = ?", userID).Order("id asc").Find(&zugangstokens).Error; err != nil {
+		log.Printf("ERROR ListZugangstokens for user %s: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve access tokens"})
+		return
+	}
+
+	now := time.Now().UTC()
+	response := make([]ZugangstokenResponse, len(zugangstokens))
+	for i, zugangstoken := range zugangstokens {
+		response[i] = toZugangstokenResponse(zugangstoken, now)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeZugangstoken widerruft ein eigenes Zugangstoken; es ist danach sofort ungültig
+func RevokeZugangstoken(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	tokenID, err := strconv.ParseUint(c.Param("tokenId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access token ID format"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.Zugangstoken{}).Where("id = ? AND webuser_id = ?", uint(tokenID), userID).Count(&count).Error; err != nil {
+		log.Printf("ERROR RevokeZugangstoken %d - Find: %v\n", tokenID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Access token not found"})
+		return
+	}
+
+	// Ein bereits widerrufenes Token behält seinen ursprünglichen Zeitpunkt
+	err = db.Model(&models.Zugangstoken{}).Where("id = ? AND widerrufen_am IS NULL", uint(tokenID)).
+		UpdateColumn("widerrufen_am", time.Now().UTC()).Error
+	if err != nil {
+		log.Printf("ERROR RevokeZugangstoken %d: %v\n", tokenID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupZugangstokenTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Zugangstoken{}))
+	require.NoError(t, db.Create(&models.Webuser{ID: "test-user", Name: strPtr("Alice")}).Error)
+	require.NoError(t, db.Create(&models.Webuser{ID: "other-user"}).Error)
+	return db
+}
+
+func TestZugangstokenLifecycle(t *testing.T) {
+	db := setupZugangstokenTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/me/tokens", CreateZugangstoken)
+	router.GET("/me/tokens", ListZugangstokens)
+	router.DELETE("/me/tokens/:tokenId", RevokeZugangstoken)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	gueltigBis := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	w := do(http.MethodPost, "/me/tokens", `{"name":" Backup-Skript ","scopes":["sammlungen:lesen","katalog:lesen"],"gueltigBis":"`+gueltigBis+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created CreateZugangstokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Token, models.ZugangstokenPraefix))
+	assert.Equal(t, "Backup-Skript", created.Name)
+	assert.Equal(t, created.Token[:12], created.Anfang)
+	assert.Equal(t, []string{"sammlungen:lesen", "katalog:lesen"}, created.Scopes)
+
+	// Only the hash is stored
+	var stored models.Zugangstoken
+	require.NoError(t, db.First(&stored, created.ID).Error)
+	assert.Equal(t, database.HashToken(created.Token), stored.Hash)
+	assert.NotContains(t, stored.Hash, created.Token)
+
+	// The list never contains the token itself
+	require.NoError(t, db.Create(&models.Zugangstoken{WebuserID: "other-user", Name: "Fremd", Anfang: "dpat_x", Hash: "x",
+		Scopes: models.ScopeVoll, ErstelltAm: time.Now()}).Error)
+	w = do(http.MethodGet, "/me/tokens", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Token)
+	var list []ZugangstokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.False(t, list[0].Abgelaufen)
+	assert.Nil(t, list[0].WiderrufenAm)
+
+	// Revoked tokens are still listed but no longer accepted
+	w = do(http.MethodDelete, fmt.Sprintf("/me/tokens/%d", created.ID), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err := database.FindZugangstoken(db, created.Token, time.Now())
+	assert.ErrorIs(t, err, database.ErrZugangstokenUngueltig)
+	w = do(http.MethodGet, "/me/tokens", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.True(t, list[0].Abgelaufen)
+	assert.NotNil(t, list[0].WiderrufenAm)
+
+	// Tokens of other users cannot be revoked
+	var fremd models.Zugangstoken
+	require.NoError(t, db.Where("webuser_id = ?", "other-user").First(&fremd).Error)
+	w = do(http.MethodDelete, fmt.Sprintf("/me/tokens/%d", fremd.ID), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = do(http.MethodDelete, "/me/tokens/abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateZugangstokenValidation(t *testing.T) {
+	db := setupZugangstokenTestDB(t)
+	router := setupCollectionTestRouter(db, "test-user")
+	router.POST("/me/tokens", CreateZugangstoken)
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	for _, body := range []string{
+		`{"scopes":["lesen"]}`,
+		`{"name":"  ","scopes":["lesen"]}`,
+		`{"name":"CLI","scopes":[]}`,
+		`{"name":"CLI","scopes":["admin"]}`,
+		`{"name":"CLI","scopes":["lesen"],"gueltigBis":"` + past + `"}`,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, "/me/tokens", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&models.Zugangstoken{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,116 @@
+// Package imaging prüft hochgeladene Bilder und erzeugt Vorschaubilder.
+// Es wird bewusst nur die Standardbibliothek verwendet.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Decoder für image.Decode registrieren
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+// MaxPixels begrenzt die Bildgröße, um Dekompressionsbomben abzuwehren
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, allowed are JPEG, PNG and GIF")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+// Thumbnail-Größen: das Bild wird proportional in ein Quadrat dieser Kantenlänge eingepasst
+var ThumbnailSizes = map[string]int{
+	"small":  160,
+	"medium": 480,
+}
+
+// Sniff erkennt den Bildtyp anhand des Inhalts (nicht anhand des angegebenen Content-Types)
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return contentType, nil
+	default:
+		return "", ErrUnsupportedType
+	}
+}
+
+// Decode prüft die Abmessungen und dekodiert das Bild
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	return img, nil
+}
+
+// Thumbnail skaliert das Bild proportional, sodass es in ein Quadrat mit Kantenlänge size passt.
+// Kleinere Bilder werden nicht vergrößert. Skaliert wird per Flächenmittelung (Box-Filter).
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return src
+	}
+
+	newWidth, newHeight := size, size
+	if width > height {
+		newHeight = max(1, height*size/width)
+	} else {
+		newWidth = max(1, width*size/height)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0 := bounds.Min.Y + y*height/newHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/newHeight)
+		for x := 0; x < newWidth; x++ {
+			x0 := bounds.Min.X + x*width/newWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/newWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG kodiert ein Vorschaubild. Transparente Bereiche werden auf Weiß gelegt.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Alpha-Compositing auf weißen Hintergrund (Werte sind vormultipliziert)
+			white := 0xffff - a
+			flat.Set(x, y, color.RGBA64{R: uint16(r + white), G: uint16(g + white), B: uint16(b + white), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
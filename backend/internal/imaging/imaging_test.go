@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 20, B: 20, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	contentType, err := Sniff(encodePNG(t, 4, 4))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	_, err = Sniff([]byte("<html><body>not an image</body></html>"))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestThumbnailKeepsAspectRatio(t *testing.T) {
+	img, err := Decode(encodePNG(t, 600, 900))
+	require.NoError(t, err)
+
+	thumb := Thumbnail(img, ThumbnailSizes["small"])
+	assert.Equal(t, 106, thumb.Bounds().Dx())
+	assert.Equal(t, 160, thumb.Bounds().Dy())
+
+	r, g, b, _ := thumb.At(50, 50).RGBA()
+	assert.InDelta(t, 200, r>>8, 1)
+	assert.InDelta(t, 20, g>>8, 1)
+	assert.InDelta(t, 20, b>>8, 1)
+
+	// Small images are not upscaled
+	small, err := Decode(encodePNG(t, 40, 30))
+	require.NoError(t, err)
+	assert.Equal(t, small.Bounds(), Thumbnail(small, 160).Bounds())
+}
+
+func TestEncodeJPEG(t *testing.T) {
+	img, err := Decode(encodePNG(t, 20, 10))
+	require.NoError(t, err)
+
+	data, err := EncodeJPEG(img)
+	require.NoError(t, err)
+	contentType, err := Sniff(data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+}
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	_, err := Decode([]byte("\x89PNG\r\n\x1a\ngarbage"))
+	assert.Error(t, err)
+}
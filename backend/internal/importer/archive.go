@@ -0,0 +1,59 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxArchiveEntries begrenzt die Anzahl der gelesenen Dateien eines Zip-Archivs
+const maxArchiveEntries = 2 * MaxRows
+
+// archiveFile ist eine entpackte Datei aus einem Zip-Archiv
+type archiveFile struct {
+	Name string
+	Data []byte
+}
+
+// isZip erkennt Zip-Archive anhand der Signatur
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// readZip entpackt alle Dateien, für die match true liefert, in der Reihenfolge des Archivs.
+// Die entpackte Gesamtgröße ist auf MaxUnpackedBytes begrenzt.
+func readZip(data []byte, match func(name string) bool) ([]archiveFile, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %w", err)
+	}
+
+	var files []archiveFile
+	var total int64
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !match(entry.Name) {
+			continue
+		}
+		if len(files) == maxArchiveEntries {
+			return nil, ErrTooManyRows
+		}
+
+		reader, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip entry %s: %w", entry.Name, err)
+		}
+		// Die Größenangabe im Archiv ist nicht vertrauenswürdig, daher wird beim Lesen begrenzt
+		content, err := io.ReadAll(io.LimitReader(reader, MaxUnpackedBytes-total+1))
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip entry %s: %w", entry.Name, err)
+		}
+		total += int64(len(content))
+		if total > MaxUnpackedBytes {
+			return nil, ErrTooLarge
+		}
+		files = append(files, archiveFile{Name: entry.Name, Data: content})
+	}
+	return files, nil
+}
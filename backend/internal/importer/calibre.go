@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/metadata"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// MaxCalibreBytes begrenzt die Größe einer hochgeladenen metadata.db
+const MaxCalibreBytes = 64 << 20
+
+var ErrNotCalibre = errors.New("not a Calibre library (metadata.db with table books expected)")
+
+// CalibreOptions steuern das Einlesen einer Calibre-Bibliothek
+type CalibreOptions struct {
+	// TagSammlungen ordnet Calibre-Tags (ohne Beachtung der Groß-/Kleinschreibung)
+	// Sammlungsnamen zu. Bücher mit zugeordneten Tags landen in diesen Sammlungen.
+	TagSammlungen map[string]string
+}
+
+type calibreBook struct {
+	ID          int
+	Title       string
+	SeriesIndex *float64
+	Series      *string
+	Lang        *string
+	ISBN        *string
+}
+
+type calibreLink struct {
+	Book int
+	Name string
+}
+
+// ParseCalibre liest die Datenbank einer Calibre-Bibliothek (metadata.db) und liefert eine
+// Buch-Zeile je Buch. Line ist dabei die Buch-ID in Calibre.
+func ParseCalibre(data []byte, opts CalibreOptions) ([]Row, error) {
+	if !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+		return nil, ErrNotCalibre
+	}
+
+	// SQLite kann nur Dateien öffnen; die Kopie wird ausschließlich lesend geöffnet. Der Treiber
+	// ist reines Go, weil der Server ohne cgo gebaut wird (CGO_ENABLED=0 im Dockerfile).
+	file, err := os.CreateTemp("", "calibre-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+file.Name()+"?mode=ro&immutable=1"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Calibre database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDB.Close()
+
+	if !db.Migrator().HasTable("books") {
+		return nil, ErrNotCalibre
+	}
+
+	var books []calibreBook
+	err = db.Raw(`SELECT b.id, b.title, b.series_index,
+		(SELECT s.name FROM books_series_link l JOIN series s ON s.id = l.series WHERE l.book = b.id LIMIT 1) AS series,
+		(SELECT lc.lang_code FROM books_languages_link l JOIN languages lc ON lc.id = l.lang_code
+			WHERE l.book = b.id ORDER BY l.item_order LIMIT 1) AS lang,
+		(SELECT i.val FROM identifiers i WHERE i.book = b.id AND i.type = 'isbn' LIMIT 1) AS isbn
+		FROM books b ORDER BY b.id LIMIT ?`, MaxRows+1).Scan(&books).Error
+	if err != nil {
+		return nil, fmt.Errorf("invalid Calibre database: %w", err)
+	}
+	if len(books) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+
+	authors, err := calibreLinks(db, `SELECT l.book, a.name FROM books_authors_link l
+		JOIN authors a ON a.id = l.author ORDER BY l.book, l.id`)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := calibreLinks(db, `SELECT l.book, t.name FROM books_tags_link l
+		JOIN tags t ON t.id = l.tag ORDER BY l.book, t.name`)
+	if err != nil {
+		return nil, err
+	}
+
+	tagSammlungen := make(map[string]string, len(opts.TagSammlungen))
+	for tag, sammlung := range opts.TagSammlungen {
+		tagSammlungen[strings.ToLower(strings.TrimSpace(tag))] = strings.TrimSpace(sammlung)
+	}
+
+	rows := make([]Row, 0, len(books))
+	for _, book := range books {
+		rows = append(rows, calibreRow(book, authors[book.ID], tags[book.ID], tagSammlungen))
+	}
+	return rows, nil
+}
+
+// calibreLinks lädt eine n:m-Zuordnung (Autoren, Tags) gruppiert nach Buch-ID
+func calibreLinks(db *gorm.DB, query string) (map[int][]string, error) {
+	var links []calibreLink
+	if err := db.Raw(query).Scan(&links).Error; err != nil {
+		return nil, fmt.Errorf("invalid Calibre database: %w", err)
+	}
+	byBook := make(map[int][]string)
+	for _, link := range links {
+		byBook[link.Book] = append(byBook[link.Book], link.Name)
+	}
+	return byBook, nil
+}
+
+func calibreRow(book calibreBook, authors []string, tags []string, tagSammlungen map[string]string) Row {
+	row := Row{Line: book.ID, Art: "Buch", Name: strings.TrimSpace(book.Title)}
+
+	// Nur ganzzahlige Reihennummern werden übernommen, Zwischenbände wie 1.5 bleiben ohne Nummer
+	if book.Series != nil && book.SeriesIndex != nil && *book.SeriesIndex >= 1 && *book.SeriesIndex == math.Trunc(*book.SeriesIndex) {
+		nummer := int(*book.SeriesIndex)
+		row.Nummer = &nummer
+	}
+	if len(authors) > 0 {
+		autor := strings.Join(authors, ", ")
+		row.Autor = &autor
+	}
+	if book.Lang != nil {
+		row.Sprache = metadata.LanguageName(*book.Lang)
+	}
+	row.Genre = metadata.JoinGenres(tags)
+
+	// Ungültige ISBNs werden ignoriert, die Zuordnung erfolgt dann über den Titel
+	if book.ISBN != nil {
+		if _, err := utils.NormalizeISBN(*book.ISBN); err == nil {
+			row.Code = *book.ISBN
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		sammlung, ok := tagSammlungen[strings.ToLower(strings.TrimSpace(tag))]
+		if ok && sammlung != "" && !seen[sammlung] {
+			seen[sammlung] = true
+			row.Sammlungen = append(row.Sammlungen, sammlung)
+		}
+	}
+	return row
+}
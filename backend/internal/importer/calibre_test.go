@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// calibreSchema ist ein Auszug aus dem Schema von Calibre mit den gelesenen Tabellen
+var calibreSchema = []string{
+	`CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT NOT NULL DEFAULT 'Unknown', series_index REAL NOT NULL DEFAULT 1.0)`,
+	`CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`,
+	`CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, author INTEGER NOT NULL)`,
+	`CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`,
+	`CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, series INTEGER NOT NULL)`,
+	`CREATE TABLE languages (id INTEGER PRIMARY KEY, lang_code TEXT NOT NULL)`,
+	`CREATE TABLE books_languages_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, lang_code INTEGER NOT NULL, item_order INTEGER NOT NULL DEFAULT 0)`,
+	`CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`,
+	`CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, tag INTEGER NOT NULL)`,
+	`CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, type TEXT NOT NULL DEFAULT 'isbn', val TEXT NOT NULL)`,
+}
+
+func calibreLibrary(t *testing.T, statements ...string) []byte {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	for _, statement := range append(calibreSchema, statements...) {
+		require.NoError(t, db.Exec(statement).Error)
+	}
+	sqlDB, _ := db.DB()
+	require.NoError(t, sqlDB.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseCalibre(t *testing.T) {
+	data := calibreLibrary(t,
+		`INSERT INTO books (id, title, series_index) VALUES (1, 'The Final Empire', 1.0), (2, 'Momo', 1.0), (3, 'The Eleventh Metal', 1.5)`,
+		`INSERT INTO authors (id, name) VALUES (1, 'Brandon Sanderson'), (2, 'Michael Ende'), (3, 'Ko-Autor')`,
+		`INSERT INTO books_authors_link (book, author) VALUES (1, 1), (2, 2), (2, 3), (3, 1)`,
+		`INSERT INTO series (id, name) VALUES (1, 'Mistborn')`,
+		`INSERT INTO books_series_link (book, series) VALUES (1, 1), (3, 1)`,
+		`INSERT INTO languages (id, lang_code) VALUES (1, 'eng'), (2, 'deu')`,
+		`INSERT INTO books_languages_link (book, lang_code) VALUES (1, 1), (2, 2)`,
+		`INSERT INTO tags (id, name) VALUES (1, 'Fantasy'), (2, 'Gelesen'), (3, 'Kinderbuch')`,
+		`INSERT INTO books_tags_link (book, tag) VALUES (1, 1), (1, 2), (2, 3), (2, 2)`,
+		`INSERT INTO identifiers (book, type, val) VALUES (1, 'isbn', '9780306406157'), (1, 'amazon', 'B000'), (2, 'isbn', '123')`,
+	)
+
+	rows, err := ParseCalibre(data, CalibreOptions{TagSammlungen: map[string]string{"gelesen": "Gelesen", "Kinderbuch": "Kinder"}})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	mistborn := rows[0]
+	assert.Equal(t, 1, mistborn.Line)
+	assert.Equal(t, "Buch", mistborn.Art)
+	assert.Equal(t, "The Final Empire", mistborn.Name)
+	assert.Equal(t, 1, *mistborn.Nummer)
+	assert.Equal(t, "Brandon Sanderson", *mistborn.Autor)
+	assert.Equal(t, "Englisch", *mistborn.Sprache)
+	assert.Equal(t, "Fantasy, Gelesen", *mistborn.Genre)
+	assert.Equal(t, "9780306406157", mistborn.Code)
+	assert.Equal(t, []string{"Gelesen"}, mistborn.Sammlungen)
+
+	momo := rows[1]
+	assert.Nil(t, momo.Nummer, "books without series have no number")
+	assert.Equal(t, "Michael Ende, Ko-Autor", *momo.Autor)
+	assert.Equal(t, "Deutsch", *momo.Sprache)
+	assert.Empty(t, momo.Code, "invalid ISBNs are ignored")
+	assert.Equal(t, []string{"Gelesen", "Kinder"}, momo.Sammlungen)
+
+	novella := rows[2]
+	assert.Nil(t, novella.Nummer, "fractional series index is not a volume number")
+	assert.Nil(t, novella.Sprache)
+	assert.Empty(t, novella.Sammlungen)
+}
+
+func TestParseCalibreRejectsOtherFiles(t *testing.T) {
+	_, err := ParseCalibre([]byte("name\nMomo\n"), CalibreOptions{})
+	assert.ErrorIs(t, err, ErrNotCalibre)
+
+	path := filepath.Join(t.TempDir(), "other.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE filme (id INTEGER PRIMARY KEY)`).Error)
+	sqlDB, _ := db.DB()
+	require.NoError(t, sqlDB.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = ParseCalibre(data, CalibreOptions{})
+	assert.ErrorIs(t, err, ErrNotCalibre)
+}
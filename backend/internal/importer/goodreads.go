@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+)
+
+var ErrNotGoodreads = errors.New("not a Goodreads library export (missing columns Title and Exclusive Shelf)")
+
+// goodreadsSeries erkennt Reihenangaben im Titel, z.B. "The Final Empire (Mistborn, #1)"
+var goodreadsSeries = regexp.MustCompile(`^(.+?)\s*\(([^()]+),\s*#(\d+)\)$`)
+
+// goodreadsStatus bildet die exklusiven Standardregale auf den Status ab
+var goodreadsStatus = map[string]string{
+	"read":              models.StatusAbgeschlossen,
+	"currently-reading": models.StatusInBearbeitung,
+	"to-read":           models.StatusGeplant,
+}
+
+// goodreadsDateLayouts sind die Datumsformate, die in Goodreads-Exporten vorkommen
+var goodreadsDateLayouts = []string{"2006/01/02", "2006-01-02", "2006/1/2"}
+
+// ParseGoodreads liest den Bibliotheksexport von Goodreads (goodreads_library_export.csv).
+// Jedes Regal wird zu einer Sammlung, das exklusive Regal bestimmt zusätzlich den Status.
+func ParseGoodreads(data []byte) ([]Row, error) {
+	records, columns, err := readCSV(trimBOM(data))
+	if err != nil {
+		return nil, err
+	}
+	if !hasColumns(columns, "Title", "Exclusive Shelf") {
+		return nil, ErrNotGoodreads
+	}
+
+	rows := make([]Row, 0, len(records))
+	for _, rec := range records {
+		rows = append(rows, goodreadsRow(rec))
+	}
+	return rows, nil
+}
+
+func goodreadsRow(rec record) Row {
+	values := rec.values
+	row := Row{Line: rec.line, Art: "Buch", Name: values["Title"]}
+
+	if match := goodreadsSeries.FindStringSubmatch(row.Name); match != nil {
+		nummer, _ := strconv.Atoi(match[3])
+		row.Name, row.Nummer = match[1], &nummer
+	}
+	if author := values["Author"]; author != "" {
+		row.Autor = &author
+	}
+
+	// Goodreads exportiert ISBNs als Excel-Formel (="0306406152"); fehlerhafte Angaben werden
+	// ignoriert, die Zuordnung erfolgt dann über den Titel
+	for _, column := range []string{"ISBN13", "ISBN"} {
+		code := strings.Trim(strings.TrimPrefix(values[column], "="), `"`)
+		if code == "" {
+			continue
+		}
+		if _, err := utils.NormalizeISBN(code); err == nil {
+			row.Code = code
+			break
+		}
+	}
+
+	shelf := values["Exclusive Shelf"]
+	if status, ok := goodreadsStatus[shelf]; ok {
+		row.Status = &status
+	}
+	seen := make(map[string]bool)
+	for _, name := range append([]string{shelf}, strings.Split(values["Bookshelves"], ",")...) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			row.Sammlungen = append(row.Sammlungen, name)
+		}
+	}
+
+	if rating := values["My Rating"]; rating != "" && rating != "0" {
+		bewertung, err := strconv.Atoi(rating)
+		if err != nil || bewertung < 1 || bewertung > 5 {
+			row.Errors = append(row.Errors, fmt.Sprintf("rating %q must be between 1 and 5", rating))
+		} else {
+			row.Bewertung = &bewertung
+		}
+	}
+
+	if dateRead := values["Date Read"]; dateRead != "" {
+		parsed, err := parseGoodreadsDate(dateRead)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid date read %q", dateRead))
+		} else {
+			row.AbgeschlossenAm = &parsed
+		}
+	}
+	return row
+}
+
+func parseGoodreadsDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range goodreadsDateLayouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func hasColumns(columns []string, required ...string) bool {
+	present := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		present[strings.TrimSpace(column)] = true
+	}
+	for _, column := range required {
+		if !present[column] {
+			return false
+		}
+	}
+	return true
+}
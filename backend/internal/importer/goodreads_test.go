@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goodreadsExport = `Book Id,Title,Author,Author l-f,Additional Authors,ISBN,ISBN13,My Rating,Average Rating,Publisher,Binding,Number of Pages,Year Published,Original Publication Year,Date Read,Date Added,Bookshelves,Bookshelves with positions,Exclusive Shelf,My Review,Spoiler,Private Notes,Read Count,Owned Copies
+68428,"The Final Empire (Mistborn, #1)",Brandon Sanderson,"Sanderson, Brandon",,"=""0306406152""","=""9780306406157""",5,4.46,Tor Fantasy,Paperback,544,2006,2006,2021/03/14,2020/12/01,"fantasy, favorites","fantasy (#3), favorites (#1)",read,,,,1,0
+3,Momo,Michael Ende,"Ende, Michael",,"=""""","=""""",0,4.2,Thienemann,Hardcover,304,1973,1973,,2022/01/10,,,to-read,,,,0,0
+4,Kaputt,Jemand,"Jemand",,"=""""","=""""",7,3.0,,,,,,,2022/01/10,,,currently-reading,,,,0,0
+`
+
+func TestParseGoodreads(t *testing.T) {
+	rows, err := ParseGoodreads([]byte(goodreadsExport))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	mistborn := rows[0]
+	assert.Equal(t, "Buch", mistborn.Art)
+	assert.Equal(t, "The Final Empire", mistborn.Name)
+	assert.Equal(t, 1, *mistborn.Nummer)
+	assert.Equal(t, "Brandon Sanderson", *mistborn.Autor)
+	assert.Equal(t, "9780306406157", mistborn.Code)
+	assert.Equal(t, []string{"read", "fantasy", "favorites"}, mistborn.Sammlungen)
+	assert.Equal(t, models.StatusAbgeschlossen, *mistborn.Status)
+	assert.Equal(t, 5, *mistborn.Bewertung)
+	assert.Equal(t, "2021-03-14", mistborn.AbgeschlossenAm.Format("2006-01-02"))
+
+	momo := rows[1]
+	assert.Equal(t, "Momo", momo.Name)
+	assert.Nil(t, momo.Nummer)
+	assert.Empty(t, momo.Code)
+	assert.Nil(t, momo.Bewertung)
+	assert.Equal(t, models.StatusGeplant, *momo.Status)
+	assert.Equal(t, []string{"to-read"}, momo.Sammlungen)
+
+	assert.Contains(t, rows[2].Errors, `rating "7" must be between 1 and 5`)
+
+	_, err = ParseGoodreads([]byte("name,autor\nMomo,Ende\n"))
+	assert.ErrorIs(t, err, ErrNotGoodreads)
+}
+
+func TestImportGoodreadsCreatesShelves(t *testing.T) {
+	db, _ := setupImportTestDB(t)
+
+	// Existing catalog entry is matched by title and number instead of duplicated
+	vorhanden := models.Produkt{Name: "momo", Art: "Buch"}
+	require.NoError(t, db.Create(&vorhanden).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: vorhanden.ID}).Error)
+	favorites := "favorites"
+	require.NoError(t, db.Create(&models.Sammlung{WebuserID: "user-1", Name: &favorites}).Error)
+
+	rows, err := ParseGoodreads([]byte(goodreadsExport))
+	require.NoError(t, err)
+	result, err := Import(db, Options{WebuserID: "user-1"}, rows)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Matched)
+	assert.Equal(t, 1, result.Rejected)
+	assert.Equal(t, []int{2, 4}, result.Unmatched)
+	assert.Equal(t, vorhanden.ID, *result.Rows[1].ProduktID)
+
+	var namen []string
+	db.Model(&models.Sammlung{}).Where("webuser_id = ?", "user-1").Order("name").Pluck("name", &namen)
+	assert.Equal(t, []string{"", "fantasy", "favorites", "read", "to-read"}, namen)
+
+	var eintrag models.SammlungProdukt
+	require.NoError(t, db.Joins("JOIN sammlung ON sammlung.id = sammlung_produkte.sammlung_id").
+		Where("sammlung.name = ? AND produkt_id = ?", "read", *result.Rows[0].ProduktID).First(&eintrag).Error)
+	assert.Equal(t, models.StatusAbgeschlossen, *eintrag.Status)
+	assert.Equal(t, 5, *eintrag.Bewertung)
+	require.NotNil(t, eintrag.AbgeschlossenAm)
+
+	var count int64
+	db.Model(&models.SammlungProdukt{}).Where("produkt_id = ?", *result.Rows[0].ProduktID).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
@@ -0,0 +1,398 @@
+// Package importer legt Produkte aus Importdateien an bzw. ordnet sie vorhandenen
+// Produkten zu und fügt sie einer Sammlung hinzu.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Status einer Importzeile
+const (
+	StatusCreated  = "created"
+	StatusMatched  = "matched"
+	StatusRejected = "rejected"
+)
+
+// Row ist eine formatunabhängige Importzeile
+type Row struct {
+	Line    int // Zeilennummer (CSV) bzw. Position (JSON) in der Quelldatei
+	Art     string
+	Name    string
+	Nummer  *int
+	Autor   *string
+	Mangaka *string
+	Baende  *int
+	Konsole *string
+	FilmArt *string
+	Jahr    *int // Erscheinungsjahr (Filmserie), wird beim Abgleich über den Namen berücksichtigt
+	Sprache *string
+	Genre   *string
+	Code    string   // ISBN bzw. EAN, wird normalisiert
+	Errors  []string // Fehler beim Einlesen; die Zeile wird dann abgelehnt
+
+	// Sammlungen sind die Namen der Ziel-Sammlungen (z.B. Goodreads-Regale). Fehlende
+	// Sammlungen werden angelegt. Ist die Liste leer, gilt die Sammlung aus den Options.
+	Sammlungen []string
+	// Persönliche Angaben, werden an den Sammlungseinträgen gespeichert
+	Status            *string
+	Bewertung         *int
+	BewertungOriginal *int // 1 bis 10, falls die Quelle feiner bewertet
+	AbgeschlossenAm   *time.Time
+}
+
+// Options steuern einen Import
+type Options struct {
+	WebuserID  string // Importierender Benutzer, Besitzer neu angelegter Sammlungen
+	SammlungID uint   // Ziel für Zeilen ohne eigene Sammlungen (0 = keine)
+	HaushaltID *uint  // Aktiver Haushalt: Zuordnung auch zu dessen Produkten, neue Produkte und Sammlungen gehören ihm
+	DryRun     bool
+}
+
+// RowResult beschreibt, was mit einer Zeile passiert ist bzw. im Probelauf passieren würde
+type RowResult struct {
+	Line       int      `json:"line"`
+	Status     string   `json:"status"` // created, matched oder rejected
+	Art        string   `json:"art,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	ProduktID  *uint    `json:"produktId,omitempty"`         // Im Probelauf nur für Treffer gesetzt
+	MatchedBy  string   `json:"matchedBy,omitempty"`         // code oder name
+	Sammlungen []string `json:"sammlungen,omitempty"`        // Namen der Ziel-Sammlungen, falls aus der Datei
+	InSammlung bool     `json:"alreadyInSammlung,omitempty"` // War bereits in allen Ziel-Sammlungen enthalten
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// Result fasst einen Import zusammen
+type Result struct {
+	DryRun   bool        `json:"dryRun"`
+	Created  int         `json:"created"`
+	Matched  int         `json:"matched"`
+	Rejected int         `json:"rejected"`
+	Rows     []RowResult `json:"rows"`
+	// Zeilen ohne Treffer im vorhandenen Katalog (neu angelegt oder abgelehnt)
+	Unmatched []int `json:"unmatchedLines"`
+}
+
+// errDryRun bricht die Transaktion eines Probelaufs ab
+var errDryRun = errors.New("dry run")
+
+// Import verarbeitet alle Zeilen in einer Transaktion. Fehlerhafte Zeilen werden abgelehnt,
+// ohne den restlichen Import abzubrechen. Ein Probelauf führt dieselben Schritte aus und
+// rollt am Ende zurück, damit die Vorschau exakt dem echten Import entspricht.
+func Import(db *gorm.DB, opts Options, rows []Row) (*Result, error) {
+	result := &Result{DryRun: opts.DryRun, Rows: make([]RowResult, 0, len(rows)), Unmatched: []int{}}
+	// Im Probelauf angelegte Produkte existieren danach nicht, ihre IDs werden nicht ausgegeben
+	created := make(map[uint]bool)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		run := &importRun{tx: tx, opts: opts, sammlungen: make(map[string]uint)}
+		for _, row := range rows {
+			rowResult, err := run.importRow(row)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", row.Line, err)
+			}
+			if rowResult.Status == StatusCreated {
+				created[*rowResult.ProduktID] = true
+			}
+			if opts.DryRun && rowResult.ProduktID != nil && created[*rowResult.ProduktID] {
+				rowResult.ProduktID = nil
+			}
+			switch rowResult.Status {
+			case StatusCreated:
+				result.Created++
+			case StatusMatched:
+				result.Matched++
+			case StatusRejected:
+				result.Rejected++
+			}
+			if rowResult.Status != StatusMatched {
+				result.Unmatched = append(result.Unmatched, rowResult.Line)
+			}
+			result.Rows = append(result.Rows, rowResult)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importRun hält den Zustand eines Imports innerhalb der Transaktion
+type importRun struct {
+	tx         *gorm.DB
+	opts       Options
+	sammlungen map[string]uint // Name -> ID der bereits aufgelösten Sammlungen
+}
+
+// importRow liefert nur bei Datenbankfehlern einen Fehler, fachliche Fehler landen im Ergebnis
+func (r *importRun) importRow(row Row) (RowResult, error) {
+	tx := r.tx
+	result := RowResult{Line: row.Line, Art: row.Art, Name: row.Name, Sammlungen: row.Sammlungen}
+	reject := func(messages ...string) (RowResult, error) {
+		result.Status = StatusRejected
+		result.Errors = append(result.Errors, messages...)
+		return result, nil
+	}
+	if len(row.Errors) > 0 {
+		return reject(row.Errors...)
+	}
+	if messages := checkLengths(row); len(messages) > 0 {
+		return reject(messages...)
+	}
+	if len(row.Sammlungen) == 0 && r.opts.SammlungID == 0 {
+		return reject("no target collection")
+	}
+
+	var code, codeType string
+	if row.Code != "" {
+		var err error
+		code, codeType, err = utils.NormalizeCodeForArt(row.Art, row.Code)
+		if err != nil {
+			return reject(fmt.Sprintf("code %q: %v", row.Code, err))
+		}
+	}
+
+	// 1. Zuordnung über den Code, 2. über Name, Art und Nummer
+	var produkt *models.Produkt
+	if code != "" {
+		found, err := FindByCode(tx, r.opts.HaushaltID, code)
+		if err != nil {
+			return result, err
+		}
+		if found != nil {
+			if found.Art != row.Art {
+				return reject(fmt.Sprintf("code %s belongs to a product of type %s", code, found.Art))
+			}
+			produkt, result.MatchedBy = found, "code"
+		}
+	}
+	if produkt == nil {
+		if strings.TrimSpace(row.Name) == "" {
+			return reject("name is required")
+		}
+		found, err := FindByName(tx, r.opts.HaushaltID, row.Art, row.Name, row.Nummer, row.Jahr)
+		if err != nil {
+			return result, err
+		}
+		if found != nil {
+			produkt, result.MatchedBy = found, "name"
+		}
+	}
+
+	if produkt != nil {
+		result.Status = StatusMatched
+		result.Name = produkt.Name
+	} else {
+		created, err := createProdukt(tx, r.opts, row, code, codeType)
+		if err != nil {
+			return result, err
+		}
+		produkt = created
+		result.Status = StatusCreated
+	}
+	result.ProduktID = &produkt.ID
+
+	sammlungIDs := []uint{r.opts.SammlungID}
+	if len(row.Sammlungen) > 0 {
+		sammlungIDs = sammlungIDs[:0]
+		for _, name := range row.Sammlungen {
+			id, err := r.sammlungByName(name)
+			if err != nil {
+				return result, err
+			}
+			sammlungIDs = append(sammlungIDs, id)
+		}
+	}
+
+	result.InSammlung = true
+	for _, sammlungID := range sammlungIDs {
+		existed, err := addToSammlung(tx, sammlungID, produkt.ID, row)
+		if err != nil {
+			return result, err
+		}
+		result.InSammlung = result.InSammlung && existed
+	}
+	return result, nil
+}
+
+// sammlungByName sucht eine Sammlung des Benutzers im aktiven Haushalt bzw. ohne Haushalt über
+// den Namen oder legt sie dort an
+func (r *importRun) sammlungByName(name string) (uint, error) {
+	if id, ok := r.sammlungen[name]; ok {
+		return id, nil
+	}
+	query := r.tx.Where("webuser_id = ? AND name = ?", r.opts.WebuserID, name)
+	if r.opts.HaushaltID != nil {
+		query = query.Where("haushalt_id = ?", *r.opts.HaushaltID)
+	} else {
+		query = query.Where("haushalt_id IS NULL")
+	}
+	var sammlungen []models.Sammlung
+	if err := query.Order("id").Limit(1).Find(&sammlungen).Error; err != nil {
+		return 0, err
+	}
+	if len(sammlungen) == 0 {
+		sammlung := models.Sammlung{WebuserID: r.opts.WebuserID, Name: &name, HaushaltID: r.opts.HaushaltID}
+		if err := r.tx.Create(&sammlung).Error; err != nil {
+			return 0, err
+		}
+		sammlungen = append(sammlungen, sammlung)
+	}
+	r.sammlungen[name] = sammlungen[0].ID
+	return sammlungen[0].ID, nil
+}
+
+// checkLengths prüft die Textfelder gegen die Spaltenbreiten der Modelle, damit ein zu langer
+// Wert nur seine Zeile ablehnt und nicht den ganzen Import abbricht
+func checkLengths(row Row) []string {
+	var messages []string
+	check := func(field string, value *string, max int) {
+		if value != nil && utf8.RuneCountInString(*value) > max {
+			messages = append(messages, fmt.Sprintf("%s must be at most %d characters long", field, max))
+		}
+	}
+	check("name", &row.Name, 255)
+	check("autor", row.Autor, 255)
+	check("mangaka", row.Mangaka, 255)
+	check("konsole", row.Konsole, 100)
+	check("sprache", row.Sprache, 50)
+	check("genre", row.Genre, 100)
+	for i := range row.Sammlungen {
+		check("collection name", &row.Sammlungen[i], 255)
+	}
+	return messages
+}
+
+// FindByCode sucht zuerst in den Produktkennungen, dann in den Codes der Editionen. Berücksichtigt
+// werden der globale Katalog und die Produkte des Haushalts haushaltID (nil = nur global).
+func FindByCode(tx *gorm.DB, haushaltID *uint, code string) (*models.Produkt, error) {
+	var produkte []models.Produkt
+	err := tx.Scopes(database.KatalogScope(haushaltID)).
+		Where("id IN (?) OR id IN (?)",
+			tx.Model(&models.ProduktCode{}).Select("produkt_id").Where("code = ?", code),
+			tx.Model(&models.Edition{}).Select("produkt_id").Where("code = ?", code)).
+		Order("id").Limit(1).Find(&produkte).Error
+	if err != nil || len(produkte) == 0 {
+		return nil, err
+	}
+	return &produkte[0], nil
+}
+
+// FindByName sucht ein Produkt über Art, Name und Nummer. Der Name wird ohne Beachtung der Groß-/Kleinschreibung verglichen.
+// Ist jahr gesetzt, scheiden Filmserien mit einem anderen Erscheinungsjahr aus; Filmserien mit
+// passendem Jahr haben Vorrang vor solchen ohne Jahr. Sichtbarkeit wie bei FindByCode.
+func FindByName(tx *gorm.DB, haushaltID *uint, art string, name string, nummer *int, jahr *int) (*models.Produkt, error) {
+	query := tx.Scopes(database.KatalogScope(haushaltID)).Where("art = ? AND LOWER(name) = LOWER(?)", art, strings.TrimSpace(name))
+	if nummer != nil {
+		query = query.Where("nummer = ?", *nummer)
+	} else {
+		query = query.Where("nummer IS NULL")
+	}
+
+	var produkte []models.Produkt
+	if jahr != nil && art == "Filmserie" {
+		gleichesJahr := tx.Model(&models.Filmserie{}).Select("produkte_id").Where("jahr = ?", *jahr)
+		if err := query.Session(&gorm.Session{}).Where("id IN (?)", gleichesJahr).Order("id").Limit(1).Find(&produkte).Error; err != nil || len(produkte) > 0 {
+			return firstProdukt(produkte), err
+		}
+		query = query.Where("id IN (?)", tx.Model(&models.Filmserie{}).Select("produkte_id").Where("jahr IS NULL"))
+	}
+	if err := query.Order("id").Limit(1).Find(&produkte).Error; err != nil {
+		return nil, err
+	}
+	return firstProdukt(produkte), nil
+}
+
+func firstProdukt(produkte []models.Produkt) *models.Produkt {
+	if len(produkte) == 0 {
+		return nil
+	}
+	return &produkte[0]
+}
+
+func createProdukt(tx *gorm.DB, opts Options, row Row, code string, codeType string) (*models.Produkt, error) {
+	webuserID := opts.WebuserID
+	produkt := models.Produkt{
+		Name:        strings.TrimSpace(row.Name),
+		Nummer:      row.Nummer,
+		Art:         row.Art,
+		ErstelltVon: &webuserID,
+		HaushaltID:  opts.HaushaltID,
+	}
+	if err := tx.Create(&produkt).Error; err != nil {
+		return nil, err
+	}
+
+	var details interface{}
+	switch row.Art {
+	case "Buch":
+		details = &models.Buch{ProdukteID: produkt.ID, Autor: row.Autor, Sprache: row.Sprache, Genre: row.Genre}
+	case "Manga":
+		details = &models.Manga{ProdukteID: produkt.ID, Mangaka: row.Mangaka, Baende: row.Baende, Sprache: row.Sprache, Genre: row.Genre}
+	case "Spiel":
+		details = &models.Spiel{ProdukteID: produkt.ID, Konsole: row.Konsole, Genre: row.Genre}
+	case "Filmserie":
+		details = &models.Filmserie{ProdukteID: produkt.ID, Art: row.FilmArt, Genre: row.Genre, Jahr: row.Jahr}
+	}
+	if err := tx.Create(details).Error; err != nil {
+		return nil, err
+	}
+
+	if code != "" {
+		if err := tx.Create(&models.ProduktCode{ProduktID: produkt.ID, HaushaltID: produkt.HaushaltID, Typ: codeType, Code: code}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &produkt, nil
+}
+
+// addToSammlung verknüpft das Produkt mit der Sammlung und meldet, ob es dort schon enthalten war.
+// Persönliche Angaben der Zeile überschreiben vorhandene Werte nur, wenn sie gesetzt sind.
+func addToSammlung(tx *gorm.DB, sammlungID uint, produktID uint, row Row) (bool, error) {
+	var eintraege []models.SammlungProdukt
+	err := tx.Where("sammlung_id = ? AND produkt_id = ?", sammlungID, produktID).Limit(1).Find(&eintraege).Error
+	if err != nil {
+		return false, err
+	}
+	if len(eintraege) == 0 {
+		return false, tx.Create(&models.SammlungProdukt{
+			SammlungID:        sammlungID,
+			ProduktID:         produktID,
+			Status:            row.Status,
+			Bewertung:         row.Bewertung,
+			BewertungOriginal: row.BewertungOriginal,
+			AbgeschlossenAm:   row.AbgeschlossenAm,
+		}).Error
+	}
+
+	updates := map[string]interface{}{}
+	if row.Status != nil {
+		updates["status"] = *row.Status
+	}
+	if row.Bewertung != nil {
+		updates["bewertung"] = *row.Bewertung
+		// Eine Bewertung ohne Originalwert ersetzt auch den alten Originalwert
+		updates["bewertung_original"] = row.BewertungOriginal
+	}
+	if row.AbgeschlossenAm != nil {
+		updates["abgeschlossen_am"] = *row.AbgeschlossenAm
+	}
+	if len(updates) > 0 {
+		err = tx.Model(&models.SammlungProdukt{}).
+			Where("sammlung_id = ? AND produkt_id = ?", sammlungID, produktID).
+			Updates(updates).Error
+	}
+	return true, err
+}
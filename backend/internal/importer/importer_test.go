@@ -0,0 +1,215 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupImportTestDB(t *testing.T) (*gorm.DB, uint) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Produkt{}, &models.Buch{}, &models.Manga{},
+		&models.Spiel{}, &models.Filmserie{}, &models.Sammlung{}, &models.Edition{}, &models.SammlungProdukt{},
+		&models.ProduktCode{}))
+
+	require.NoError(t, db.Create(&models.Webuser{ID: "user-1"}).Error)
+	sammlung := models.Sammlung{WebuserID: "user-1"}
+	require.NoError(t, db.Create(&sammlung).Error)
+	return db, sammlung.ID
+}
+
+func TestParseCSVWithMappingAndAliases(t *testing.T) {
+	data := "\xef\xbb\xbfTitel;Verfasser;ISBN;Band\n" +
+		"Momo;Michael Ende;0-306-40615-2;\n" +
+		"\n" +
+		"Die unendliche Geschichte;Michael Ende;;zwei\n"
+
+	rows, err := Parse("csv", []byte(data), Mapping{"autor": "Verfasser"}, "Buch")
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "Buch", rows[0].Art)
+	assert.Equal(t, "Momo", rows[0].Name)
+	assert.Equal(t, "Michael Ende", *rows[0].Autor)
+	assert.Equal(t, "0-306-40615-2", rows[0].Code)
+	assert.Empty(t, rows[0].Errors)
+
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Contains(t, rows[1].Errors, `nummer "zwei" is not a number`)
+}
+
+func TestParseRejectsUnknownMapping(t *testing.T) {
+	_, err := Parse("csv", []byte("name\nMomo\n"), Mapping{"titel": "name"}, "Buch")
+	assert.ErrorContains(t, err, "unknown target field")
+
+	_, err = Parse("csv", []byte("name\nMomo\n"), Mapping{"autor": "Verfasser"}, "Buch")
+	assert.ErrorContains(t, err, "not found in file")
+
+	_, err = Parse("xml", []byte("<x/>"), nil, "Buch")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `[
+		{"art": "movie", "title": "Alien", "genre": "Horror"},
+		{"art": "Spiel", "name": "Zelda", "nummer": 3, "konsole": "Switch"},
+		{"art": "Comic", "name": "Asterix"}
+	]`
+	rows, err := Parse("json", []byte(data), nil, "")
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, "Filmserie", rows[0].Art)
+	assert.Equal(t, "Film", *rows[0].FilmArt)
+	assert.Equal(t, "Alien", rows[0].Name)
+	assert.Equal(t, 3, *rows[1].Nummer)
+	assert.Equal(t, "Switch", *rows[1].Konsole)
+	assert.Equal(t, 3, rows[2].Line)
+	assert.Contains(t, rows[2].Errors, `unknown art "Comic"`)
+}
+
+func TestImportMatchesCreatesAndRejects(t *testing.T) {
+	db, sammlungID := setupImportTestDB(t)
+
+	vorhanden := models.Produkt{Name: "Momo", Art: "Buch"}
+	require.NoError(t, db.Create(&vorhanden).Error)
+	require.NoError(t, db.Create(&models.Buch{ProdukteID: vorhanden.ID}).Error)
+	spiel := models.Produkt{Name: "Zelda", Art: "Spiel"}
+	require.NoError(t, db.Create(&spiel).Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: spiel.ID, Typ: "EAN", Code: "4006381333931"}).Error)
+
+	rows := []Row{
+		{Line: 2, Art: "Buch", Name: "momo"},                                       // matched by name
+		{Line: 3, Art: "Buch", Name: "Krabat", Code: "0-306-40615-2"},              // created
+		{Line: 4, Art: "Buch", Name: "Krabat (Neuauflage)", Code: "9780306406157"}, // matched by code of line 3
+		{Line: 5, Art: "Buch", Name: "Kaputt", Code: "0-306-40615-3"},              // invalid checksum
+		{Line: 6, Art: "Buch", Name: "Falsche Art", Code: "4006381333931"},         // not an ISBN
+		{Line: 7, Art: "Spiel", Name: "Anderer Name", Code: "4006381333931"},       // matched by code
+		{Line: 8, Errors: []string{"art is required (column or default)"}},         // parse error
+		{Line: 9, Art: "Manga", Name: ""},                                          // no name
+	}
+
+	// Dry run reports the same result but changes nothing
+	preview, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID, DryRun: true}, rows)
+	require.NoError(t, err)
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, 1, preview.Created)
+	assert.Equal(t, 3, preview.Matched)
+	assert.Equal(t, 4, preview.Rejected)
+	assert.Nil(t, preview.Rows[1].ProduktID)
+	assert.Nil(t, preview.Rows[2].ProduktID, "match against a product planned in the same dry run")
+	require.NotNil(t, preview.Rows[0].ProduktID)
+	assert.Equal(t, vorhanden.ID, *preview.Rows[0].ProduktID)
+
+	var count int64
+	db.Model(&models.Produkt{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+	db.Model(&models.SammlungProdukt{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	result, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, preview.Created, result.Created)
+	assert.Equal(t, preview.Matched, result.Matched)
+	assert.Equal(t, preview.Rejected, result.Rejected)
+
+	statuses := make([]string, len(result.Rows))
+	for i, row := range result.Rows {
+		statuses[i] = row.Status
+	}
+	assert.Equal(t, []string{StatusMatched, StatusCreated, StatusMatched, StatusRejected, StatusRejected,
+		StatusMatched, StatusRejected, StatusRejected}, statuses)
+	assert.Equal(t, "name", result.Rows[0].MatchedBy)
+	assert.Equal(t, "code", result.Rows[2].MatchedBy)
+	assert.Equal(t, *result.Rows[1].ProduktID, *result.Rows[2].ProduktID)
+	assert.True(t, result.Rows[2].InSammlung)
+	assert.Contains(t, result.Rows[3].Errors[0], "check digit")
+
+	var krabat models.Buch
+	require.NoError(t, db.Preload("Produkt").First(&krabat, "produkte_id = ?", *result.Rows[1].ProduktID).Error)
+	assert.Equal(t, "Krabat", krabat.Produkt.Name)
+	var code models.ProduktCode
+	require.NoError(t, db.First(&code, "produkt_id = ?", krabat.ProdukteID).Error)
+	assert.Equal(t, "9780306406157", code.Code)
+
+	db.Model(&models.SammlungProdukt{}).Where("sammlung_id = ?", sammlungID).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	// Re-importing only matches
+	again, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 0, again.Created)
+	assert.True(t, again.Rows[0].InSammlung)
+}
+
+func TestImportRejectsOverlongFields(t *testing.T) {
+	db, sammlungID := setupImportTestDB(t)
+	autor, genre := strings.Repeat("ä", 256), strings.Repeat("g", 101)
+
+	rows := []Row{
+		{Line: 2, Art: "Buch", Name: strings.Repeat("x", 256)},
+		{Line: 3, Art: "Buch", Name: "Momo", Autor: &autor, Genre: &genre},
+		{Line: 4, Art: "Buch", Name: "Krabat", Sammlungen: []string{strings.Repeat("s", 256)}},
+		{Line: 5, Art: "Buch", Name: strings.Repeat("ü", 255)}, // Characters, not bytes
+	}
+	result, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Rejected)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, []string{"name must be at most 255 characters long"}, result.Rows[0].Errors)
+	assert.Equal(t, []string{"autor must be at most 255 characters long", "genre must be at most 100 characters long"}, result.Rows[1].Errors)
+	assert.Equal(t, []string{"collection name must be at most 255 characters long"}, result.Rows[2].Errors)
+	assert.Equal(t, StatusCreated, result.Rows[3].Status)
+}
+
+func TestImportCodeOfOtherHousehold(t *testing.T) {
+	db, sammlungID := setupImportTestDB(t)
+	haushaltA, haushaltB := uint(1), uint(2)
+	privat := models.Produkt{Name: "Familienalbum", Art: "Buch", HaushaltID: &haushaltA}
+	require.NoError(t, db.Create(&privat).Error)
+	require.NoError(t, db.Create(&models.ProduktCode{ProduktID: privat.ID, HaushaltID: &haushaltA, Typ: "ISBN", Code: "9780306406157"}).Error)
+
+	// The private product of household A is neither matched nor blocks the code in household B
+	rows := []Row{{Line: 2, Art: "Buch", Name: "Momo", Code: "978-0-306-40615-7"}}
+	result, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID, HaushaltID: &haushaltB}, rows)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Created, result.Rows)
+	var code models.ProduktCode
+	require.NoError(t, db.First(&code, "produkt_id = ?", *result.Rows[0].ProduktID).Error)
+	require.NotNil(t, code.HaushaltID)
+	assert.Equal(t, haushaltB, *code.HaushaltID)
+}
+
+func TestImportNamedCollectionsInHousehold(t *testing.T) {
+	db, _ := setupImportTestDB(t)
+	haushaltID := uint(1)
+	name := "Regal"
+	privat := models.Sammlung{WebuserID: "user-1", Name: &name}
+	require.NoError(t, db.Create(&privat).Error)
+
+	// The personal collection of the same name is not used, a household collection is created once
+	rows := []Row{
+		{Line: 2, Art: "Buch", Name: "Momo", Sammlungen: []string{"Regal"}},
+		{Line: 3, Art: "Buch", Name: "Krabat", Sammlungen: []string{"Regal"}},
+	}
+	_, err := Import(db, Options{WebuserID: "user-1", HaushaltID: &haushaltID}, rows)
+	require.NoError(t, err)
+
+	var sammlungen []models.Sammlung
+	require.NoError(t, db.Where("name = ?", "Regal").Order("id").Find(&sammlungen).Error)
+	require.Len(t, sammlungen, 2)
+	require.NotNil(t, sammlungen[1].HaushaltID)
+	assert.Equal(t, haushaltID, *sammlungen[1].HaushaltID)
+	var count int64
+	require.NoError(t, db.Model(&models.SammlungProdukt{}).Where("sammlung_id = ?", sammlungen[1].ID).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	require.NoError(t, db.Model(&models.SammlungProdukt{}).Where("sammlung_id = ?", privat.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,25 @@
+package importer
+
+import (
+	"context"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/jobs"
+)
+
+// JobTyp ist der Typ der Hintergrundjobs für asynchrone Importe
+const JobTyp = "import"
+
+// JobPayload enthält die bereits eingelesenen Zeilen, damit die Datei nicht gespeichert werden muss
+type JobPayload struct {
+	Options Options
+	Rows    []Row
+}
+
+// RunJob führt einen asynchronen Import aus. Das Ergebnis entspricht der Antwort des synchronen Imports.
+func RunJob(ctx context.Context, run *jobs.Run) (interface{}, error) {
+	var payload JobPayload
+	if err := run.Decode(&payload); err != nil {
+		return nil, err
+	}
+	return Import(run.DB.WithContext(ctx), payload.Options, payload.Rows)
+}
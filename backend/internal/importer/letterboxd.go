@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+)
+
+var ErrNotLetterboxd = errors.New("not a Letterboxd export (missing columns Name and Year)")
+
+// letterboxdFiles sind die ausgewerteten Dateien des Exports in Verarbeitungsreihenfolge.
+// Listen, Likes und gelöschte Einträge liegen in Unterordnern und werden ignoriert.
+var letterboxdFiles = []string{"watched.csv", "diary.csv", "ratings.csv", "watchlist.csv"}
+
+// ParseLetterboxd liest den Export von Letterboxd, entweder das gesamte Zip-Archiv oder eine
+// einzelne CSV-Datei daraus. Letterboxd kennt nur Filme. Einträge mehrerer Dateien werden über
+// Titel und Jahr zusammengeführt und über beides mit dem Katalog abgeglichen: die Watchlist ergibt den Status geplant, gesehene Filme
+// abgeschlossen mit dem letzten Sichtungsdatum, Bewertungen (halbe Sterne) werden aufgerundet.
+func ParseLetterboxd(filename string, data []byte) ([]Row, error) {
+	if !isZip(data) {
+		return parseLetterboxdFiles([]archiveFile{{Name: filename, Data: data}}, false)
+	}
+
+	files, err := readZip(data, func(name string) bool {
+		return !strings.Contains(name, "/") && letterboxdKind(name) != ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNotLetterboxd
+	}
+	// Feste Reihenfolge, damit z.B. Bewertungen immer nach den Sichtungen angewendet werden
+	ordered := make([]archiveFile, 0, len(files))
+	for _, kind := range letterboxdFiles {
+		for _, file := range files {
+			if letterboxdKind(file.Name) == kind {
+				ordered = append(ordered, file)
+			}
+		}
+	}
+	return parseLetterboxdFiles(ordered, true)
+}
+
+// letterboxdKind liefert den Dateinamen aus letterboxdFiles, dem die Datei entspricht
+func letterboxdKind(filename string) string {
+	base := strings.ToLower(path.Base(filename))
+	for _, kind := range letterboxdFiles {
+		if base == kind {
+			return kind
+		}
+	}
+	return ""
+}
+
+// parseLetterboxdFiles führt die Einträge zusammen. Bei einzelnen Dateien ist Line die
+// Zeilennummer, bei Archiven die Position des Films in der Ergebnisliste.
+func parseLetterboxdFiles(files []archiveFile, archive bool) ([]Row, error) {
+	var rows []Row
+	index := make(map[string]int)
+	for _, file := range files {
+		records, columns, err := readCSV(trimBOM(file.Data))
+		if err != nil {
+			return nil, err
+		}
+		if !hasColumns(columns, "Name", "Year") {
+			return nil, ErrNotLetterboxd
+		}
+
+		kind := letterboxdKind(file.Name)
+		for _, rec := range records {
+			values := rec.values
+			key := strings.ToLower(strings.TrimSpace(values["Name"])) + "|" + values["Year"]
+			i, ok := index[key]
+			if !ok {
+				if len(rows) == MaxRows {
+					return nil, ErrTooManyRows
+				}
+				filmArt := "Film"
+				row := Row{Line: rec.line, Art: "Filmserie", Name: strings.TrimSpace(values["Name"]), FilmArt: &filmArt}
+				if year := strings.TrimSpace(values["Year"]); year != "" {
+					if jahr, err := strconv.Atoi(year); err != nil {
+						row.Errors = append(row.Errors, fmt.Sprintf("invalid year %q", year))
+					} else {
+						row.Jahr = &jahr
+					}
+				}
+				if archive {
+					row.Line = len(rows) + 1
+				}
+				i = len(rows)
+				index[key] = i
+				rows = append(rows, row)
+			}
+			applyLetterboxd(&rows[i], kind, values)
+		}
+	}
+	return rows, nil
+}
+
+func applyLetterboxd(row *Row, kind string, values map[string]string) {
+	if kind == "watchlist.csv" {
+		if row.Status == nil {
+			status := models.StatusGeplant
+			row.Status = &status
+		}
+		return
+	}
+	status := models.StatusAbgeschlossen
+	row.Status = &status
+
+	// Im Tagebuch zählt das Sichtungsdatum, sonst das Datum des Eintrags
+	var watched string
+	switch kind {
+	case "diary.csv":
+		watched = values["Watched Date"]
+	case "watched.csv", "":
+		watched = values["Date"]
+	}
+	if watched != "" {
+		parsed, err := time.Parse("2006-01-02", watched)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid watch date %q", watched))
+		} else if row.AbgeschlossenAm == nil || parsed.After(*row.AbgeschlossenAm) {
+			row.AbgeschlossenAm = &parsed
+		}
+	}
+
+	if rating := values["Rating"]; rating != "" {
+		stars, err := strconv.ParseFloat(rating, 64)
+		if err != nil || stars < 0.5 || stars > 5 {
+			row.Errors = append(row.Errors, fmt.Sprintf("rating %q must be between 0.5 and 5", rating))
+		} else {
+			bewertung := int(math.Ceil(stars))
+			row.Bewertung = &bewertung
+		}
+	}
+}
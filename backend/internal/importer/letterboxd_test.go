@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zipArchive erstellt ein Zip-Archiv mit den angegebenen Dateien (Name, Inhalt abwechselnd)
+func zipArchive(t *testing.T, files ...string) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		part, err := writer.Create(files[i])
+		require.NoError(t, err)
+		_, _ = part.Write([]byte(files[i+1]))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestParseLetterboxdArchive(t *testing.T) {
+	data := zipArchive(t,
+		"profile.csv", "Date Joined,Username\n2020-01-01,someone\n",
+		"watchlist.csv", "Date,Name,Year,Letterboxd URI\n2024-01-02,Dune: Part Two,2024,https://boxd.it/a\n",
+		"diary.csv", "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n"+
+			"2023-05-02,Alien,1979,https://boxd.it/b,4.5,,,2023-05-01\n"+
+			"2024-02-11,Alien,1979,https://boxd.it/c,,Yes,,2024-02-10\n",
+		"ratings.csv", "Date,Name,Year,Letterboxd URI,Rating\n2023-05-02,Alien,1979,https://boxd.it/d,3.5\n",
+		"watched.csv", "Date,Name,Year,Letterboxd URI\n2023-05-02,Alien,1979,https://boxd.it/d\n2022-01-01,Alien,1986,https://boxd.it/e\n",
+		"deleted/diary.csv", "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n2020-01-01,Cats,2019,x,0.5,,,2020-01-01\n",
+	)
+
+	rows, err := ParseLetterboxd("letterboxd-export.zip", data)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	alien := rows[0]
+	assert.Equal(t, 1, alien.Line)
+	assert.Equal(t, "Filmserie", alien.Art)
+	assert.Equal(t, "Alien", alien.Name)
+	assert.Equal(t, "Film", *alien.FilmArt)
+	assert.Equal(t, 1979, *alien.Jahr)
+	assert.Equal(t, models.StatusAbgeschlossen, *alien.Status)
+	assert.Equal(t, "2024-02-10", alien.AbgeschlossenAm.Format("2006-01-02"), "latest viewing wins")
+	assert.Equal(t, 4, *alien.Bewertung, "ratings.csv holds the current rating")
+
+	assert.Equal(t, "Alien", rows[1].Name, "films are kept apart by year")
+	assert.Nil(t, rows[1].Bewertung)
+	assert.Equal(t, 1986, *rows[1].Jahr)
+
+	dune := rows[2]
+	assert.Equal(t, "Dune: Part Two", dune.Name)
+	assert.Equal(t, models.StatusGeplant, *dune.Status)
+	assert.Nil(t, dune.AbgeschlossenAm)
+}
+
+func TestParseLetterboxdSingleFile(t *testing.T) {
+	rows, err := ParseLetterboxd("watchlist.csv", []byte("Date,Name,Year,Letterboxd URI\n2024-01-02,Dune,2021,x\n"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, models.StatusGeplant, *rows[0].Status)
+
+	rows, err = ParseLetterboxd("diary.csv", []byte("Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n2024-01-02,Dune,2021,x,6,,,gestern\n"))
+	require.NoError(t, err)
+	assert.Contains(t, rows[0].Errors, `invalid watch date "gestern"`)
+	assert.Contains(t, rows[0].Errors, `rating "6" must be between 0.5 and 5`)
+
+	rows, err = ParseLetterboxd("watched.csv", []byte("Date,Name,Year,Letterboxd URI\n2024-01-02,Dune,neu,x\n2024-01-02,Metropolis,,x\n"))
+	require.NoError(t, err)
+	assert.Contains(t, rows[0].Errors, `invalid year "neu"`)
+	assert.Nil(t, rows[1].Jahr)
+	assert.Empty(t, rows[1].Errors)
+
+	_, err = ParseLetterboxd("filme.csv", []byte("Titel,Jahr\nDune,2021\n"))
+	assert.ErrorIs(t, err, ErrNotLetterboxd)
+	_, err = ParseLetterboxd("export.zip", zipArchive(t, "profile.csv", "Username\nsomeone\n"))
+	assert.ErrorIs(t, err, ErrNotLetterboxd)
+}
+
+func TestImportLetterboxdMatchesByYear(t *testing.T) {
+	db, sammlungID := setupImportTestDB(t)
+
+	data := zipArchive(t, "watched.csv", "Date,Name,Year,Letterboxd URI\n2023-05-02,Alien,1979,x\n2022-01-01,Alien,1986,y\n")
+	rows, err := ParseLetterboxd("letterboxd.zip", data)
+	require.NoError(t, err)
+	result, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created, "same title, different year")
+
+	// Ein erneuter Import findet beide Filme wieder
+	result, err = Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Matched)
+
+	var filme []models.Filmserie
+	require.NoError(t, db.Order("jahr").Find(&filme).Error)
+	require.Len(t, filme, 2)
+	assert.Equal(t, 1979, *filme[0].Jahr)
+	assert.Equal(t, 1986, *filme[1].Jahr)
+
+	// Filme ohne Jahr im Katalog passen weiterhin, solange kein Film mit dem Jahr existiert
+	produkt := models.Produkt{Name: "Metropolis", Art: "Filmserie"}
+	require.NoError(t, db.Create(&produkt).Error)
+	require.NoError(t, db.Create(&models.Filmserie{ProdukteID: produkt.ID}).Error)
+	rows, err = ParseLetterboxd("watched.csv", []byte("Date,Name,Year,Letterboxd URI\n2023-05-02,Metropolis,1927,x\n"))
+	require.NoError(t, err)
+	result, err = Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Matched)
+	assert.Equal(t, produkt.ID, *result.Rows[0].ProduktID)
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+)
+
+var ErrNotMyAnimeList = errors.New("not a MyAnimeList XML export (missing <myanimelist> root element)")
+
+// malEntry enthält die Felder eines <anime>- bzw. <manga>-Eintrags, die übernommen werden
+type malEntry struct {
+	SeriesTitle  string `xml:"series_title"`
+	SeriesType   string `xml:"series_type"`
+	MangaTitle   string `xml:"manga_title"`
+	MangaVolumes string `xml:"manga_volumes"`
+	MyScore      string `xml:"my_score"`
+	MyStatus     string `xml:"my_status"`
+	MyFinishDate string `xml:"my_finish_date"`
+}
+
+// malStatus bildet die Status von MyAnimeList ab; ältere Exporte verwenden Zahlen
+var malStatus = map[string]string{
+	"watching":      models.StatusInBearbeitung,
+	"reading":       models.StatusInBearbeitung,
+	"1":             models.StatusInBearbeitung,
+	"completed":     models.StatusAbgeschlossen,
+	"2":             models.StatusAbgeschlossen,
+	"on-hold":       models.StatusPausiert,
+	"3":             models.StatusPausiert,
+	"dropped":       models.StatusAbgebrochen,
+	"4":             models.StatusAbgebrochen,
+	"plan to watch": models.StatusGeplant,
+	"plan to read":  models.StatusGeplant,
+	"6":             models.StatusGeplant,
+}
+
+// ParseMyAnimeList liest den XML-Export von MyAnimeList (auch von AniList erzeugt).
+// Mangas werden zu Manga-Zeilen mit Bandanzahl, Anime zu Filmserien (Film oder Serie).
+// Gepackte Exporte (.xml.gz) werden automatisch entpackt.
+func ParseMyAnimeList(data []byte) ([]Row, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		data, err = io.ReadAll(io.LimitReader(reader, MaxUnpackedBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		if len(data) > MaxUnpackedBytes {
+			return nil, ErrTooLarge
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var rows []Row
+	rootSeen := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xml: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "myanimelist":
+			rootSeen = true
+		case "anime", "manga":
+			if !rootSeen {
+				return nil, ErrNotMyAnimeList
+			}
+			line, _ := decoder.InputPos()
+			var entry malEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return nil, fmt.Errorf("invalid xml at line %d: %w", line, err)
+			}
+			if len(rows) >= MaxRows {
+				return nil, ErrTooManyRows
+			}
+			rows = append(rows, malRow(line, start.Name.Local, entry))
+		}
+	}
+	if !rootSeen {
+		return nil, ErrNotMyAnimeList
+	}
+	return rows, nil
+}
+
+func malRow(line int, kind string, entry malEntry) Row {
+	row := Row{Line: line}
+	if kind == "manga" {
+		row.Art = "Manga"
+		row.Name = strings.TrimSpace(entry.MangaTitle)
+		if volumes, err := strconv.Atoi(entry.MangaVolumes); err == nil && volumes > 0 {
+			row.Baende = &volumes
+		}
+	} else {
+		row.Art = "Filmserie"
+		row.Name = strings.TrimSpace(entry.SeriesTitle)
+		filmArt := "Serie"
+		if strings.EqualFold(entry.SeriesType, "Movie") {
+			filmArt = "Film"
+		}
+		row.FilmArt = &filmArt
+	}
+
+	if status, ok := malStatus[strings.ToLower(strings.TrimSpace(entry.MyStatus))]; ok {
+		row.Status = &status
+	} else if entry.MyStatus != "" {
+		row.Errors = append(row.Errors, fmt.Sprintf("unknown status %q", entry.MyStatus))
+	}
+
+	// MyAnimeList bewertet von 1 bis 10, 0 bedeutet "nicht bewertet"
+	if score, err := strconv.Atoi(strings.TrimSpace(entry.MyScore)); err == nil && score > 0 {
+		if score > 10 {
+			row.Errors = append(row.Errors, fmt.Sprintf("score %d must be between 1 and 10", score))
+		} else {
+			bewertung := (score + 1) / 2
+			row.Bewertung = &bewertung
+			row.BewertungOriginal = &score
+		}
+	}
+
+	// Unbekannte Daten werden als 0000-00-00 exportiert
+	if finish := strings.TrimSpace(entry.MyFinishDate); finish != "" && !strings.HasPrefix(finish, "0000") {
+		parsed, err := time.Parse("2006-01-02", finish)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid finish date %q", finish))
+		} else {
+			row.AbgeschlossenAm = &parsed
+		}
+	}
+	return row
+}
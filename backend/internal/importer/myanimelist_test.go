@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const malExport = `<?xml version="1.0" encoding="UTF-8" ?>
+<myanimelist>
+	<myinfo>
+		<user_export_type>2</user_export_type>
+	</myinfo>
+	<manga>
+		<manga_mangadb_id>2</manga_mangadb_id>
+		<manga_title><![CDATA[Berserk]]></manga_title>
+		<manga_volumes>41</manga_volumes>
+		<my_score>9</my_score>
+		<my_status>Reading</my_status>
+		<my_finish_date>0000-00-00</my_finish_date>
+	</manga>
+	<anime>
+		<series_animedb_id>199</series_animedb_id>
+		<series_title><![CDATA[Sen to Chihiro no Kamikakushi]]></series_title>
+		<series_type>Movie</series_type>
+		<my_score>10</my_score>
+		<my_status>Completed</my_status>
+		<my_finish_date>2023-08-12</my_finish_date>
+	</anime>
+	<anime>
+		<series_title>Mushishi</series_title>
+		<series_type>TV</series_type>
+		<my_score>0</my_score>
+		<my_status>6</my_status>
+	</anime>
+	<anime>
+		<series_title>Kaputt</series_title>
+		<my_status>Rewatching</my_status>
+	</anime>
+</myanimelist>
+`
+
+func TestParseMyAnimeList(t *testing.T) {
+	rows, err := ParseMyAnimeList([]byte(malExport))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	berserk := rows[0]
+	assert.Equal(t, "Manga", berserk.Art)
+	assert.Equal(t, "Berserk", berserk.Name)
+	assert.Equal(t, 41, *berserk.Baende)
+	assert.Equal(t, 5, *berserk.Bewertung)
+	assert.Equal(t, 9, *berserk.BewertungOriginal)
+	assert.Equal(t, models.StatusInBearbeitung, *berserk.Status)
+	assert.Nil(t, berserk.AbgeschlossenAm)
+	assert.Equal(t, 6, berserk.Line)
+
+	chihiro := rows[1]
+	assert.Equal(t, "Filmserie", chihiro.Art)
+	assert.Equal(t, "Film", *chihiro.FilmArt)
+	assert.Equal(t, 5, *chihiro.Bewertung)
+	assert.Equal(t, models.StatusAbgeschlossen, *chihiro.Status)
+	assert.Equal(t, "2023-08-12", chihiro.AbgeschlossenAm.Format("2006-01-02"))
+
+	mushishi := rows[2]
+	assert.Equal(t, "Serie", *mushishi.FilmArt)
+	assert.Nil(t, mushishi.Bewertung)
+	assert.Nil(t, mushishi.BewertungOriginal)
+	assert.Equal(t, models.StatusGeplant, *mushishi.Status)
+
+	assert.Contains(t, rows[3].Errors, `unknown status "Rewatching"`)
+
+	_, err = ParseMyAnimeList([]byte(`<library><manga/></library>`))
+	assert.ErrorIs(t, err, ErrNotMyAnimeList)
+}
+
+func TestParseMyAnimeListGzip(t *testing.T) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, _ = writer.Write([]byte(malExport))
+	require.NoError(t, writer.Close())
+
+	rows, err := ParseMyAnimeList(buf.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, rows, 4)
+}
+
+func TestImportMyAnimeList(t *testing.T) {
+	db, sammlungID := setupImportTestDB(t)
+
+	rows, err := ParseMyAnimeList([]byte(malExport))
+	require.NoError(t, err)
+	result, err := Import(db, Options{WebuserID: "user-1", SammlungID: sammlungID}, rows[:3])
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Created)
+
+	var manga models.Manga
+	require.NoError(t, db.Joins("JOIN produkte ON produkte.id = manga.produkte_id").
+		Where("produkte.name = ?", "Berserk").First(&manga).Error)
+	assert.Equal(t, 41, *manga.Baende)
+
+	var eintrag models.SammlungProdukt
+	require.NoError(t, db.Where("sammlung_id = ? AND produkt_id = ?", sammlungID, manga.ProdukteID).First(&eintrag).Error)
+	assert.Equal(t, models.StatusInBearbeitung, *eintrag.Status)
+	assert.Equal(t, 5, *eintrag.Bewertung)
+	assert.Equal(t, 9, *eintrag.BewertungOriginal, "the MyAnimeList score is kept")
+}
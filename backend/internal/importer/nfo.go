@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/metadata"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+)
+
+var ErrNoNFOFiles = errors.New("archive contains no .nfo files of movies or tv shows")
+
+// nfoEntry enthält die ausgewerteten Felder von movie.nfo bzw. tvshow.nfo (Kodi, Jellyfin, Emby)
+type nfoEntry struct {
+	Title      string   `xml:"title"`
+	Genres     []string `xml:"genre"`
+	Year       string   `xml:"year"`
+	UserRating string   `xml:"userrating"`
+	PlayCount  string   `xml:"playcount"`
+	Watched    string   `xml:"watched"`
+	LastPlayed string   `xml:"lastplayed"`
+}
+
+// ParseNFO liest ein Zip-Archiv mit .nfo-Dateien von Kodi oder Jellyfin. Filme (<movie>) werden
+// zu Filmen, Serien (<tvshow>) zu Serien; Episoden und andere Dateien werden übersprungen.
+// Gesehene Titel erhalten den Status abgeschlossen mit dem Datum der letzten Wiedergabe.
+// Line ist die Position der Datei im Archiv.
+func ParseNFO(data []byte) ([]Row, error) {
+	if !isZip(data) {
+		return nil, errors.New("upload the .nfo files as a zip archive")
+	}
+	files, err := readZip(data, func(name string) bool {
+		return strings.EqualFold(path.Ext(name), ".nfo")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for i, file := range files {
+		row, ok := nfoRow(i+1, file)
+		if !ok {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoNFOFiles
+	}
+	return rows, nil
+}
+
+// nfoRow liefert ok=false für Dateien, die weder Film noch Serie beschreiben
+func nfoRow(line int, file archiveFile) (Row, bool) {
+	row := Row{Line: line, Art: "Filmserie"}
+
+	// Nur das erste Element wird gelesen; Kodi erlaubt danach z.B. eine Scraper-URL
+	decoder := xml.NewDecoder(bytes.NewReader(file.Data))
+	decoder.Strict = false
+	var start xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return row, false
+		}
+		if err != nil {
+			row.Name = path.Base(file.Name)
+			row.Errors = append(row.Errors, fmt.Sprintf("%s: invalid xml: %v", file.Name, err))
+			return row, true
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			start = element
+			break
+		}
+	}
+
+	var filmArt string
+	switch start.Name.Local {
+	case "movie":
+		filmArt = "Film"
+	case "tvshow":
+		filmArt = "Serie"
+	default:
+		return row, false
+	}
+	row.FilmArt = &filmArt
+
+	var entry nfoEntry
+	if err := decoder.DecodeElement(&entry, &start); err != nil {
+		row.Name = path.Base(file.Name)
+		row.Errors = append(row.Errors, fmt.Sprintf("%s: invalid xml: %v", file.Name, err))
+		return row, true
+	}
+	row.Name = strings.TrimSpace(entry.Title)
+	if row.Name == "" {
+		row.Errors = append(row.Errors, fmt.Sprintf("%s: missing title", file.Name))
+	}
+
+	var genres []string
+	for _, genre := range entry.Genres {
+		// Manche Scraper schreiben mehrere Genres mit " / " getrennt in ein Element
+		genres = append(genres, strings.Split(genre, " / ")...)
+	}
+	row.Genre = metadata.JoinGenres(genres)
+	if jahr, err := strconv.Atoi(strings.TrimSpace(entry.Year)); err == nil && jahr > 0 {
+		row.Jahr = &jahr
+	}
+
+	// userrating ist die persönliche Bewertung von 1 bis 10
+	if rating, err := strconv.Atoi(strings.TrimSpace(entry.UserRating)); err == nil && rating > 0 && rating <= 10 {
+		bewertung := (rating + 1) / 2
+		row.Bewertung = &bewertung
+		row.BewertungOriginal = &rating
+	}
+
+	playCount, _ := strconv.Atoi(strings.TrimSpace(entry.PlayCount))
+	if playCount > 0 || strings.EqualFold(strings.TrimSpace(entry.Watched), "true") {
+		status := models.StatusAbgeschlossen
+		row.Status = &status
+		if lastPlayed := strings.TrimSpace(entry.LastPlayed); len(lastPlayed) >= 10 {
+			if parsed, err := time.Parse("2006-01-02", lastPlayed[:10]); err == nil {
+				row.AbgeschlossenAm = &parsed
+			}
+		}
+	}
+	return row, true
+}
@@ -0,0 +1,58 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNFO(t *testing.T) {
+	data := zipArchive(t,
+		"Filme/Alien (1979)/movie.nfo", `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<movie>
+	<title>Alien</title>
+	<year>1979</year>
+	<genre>Horror</genre>
+	<genre>Science Fiction</genre>
+	<userrating>9</userrating>
+	<playcount>2</playcount>
+	<lastplayed>2024-02-10 21:14:00</lastplayed>
+</movie>
+https://www.themoviedb.org/movie/348`,
+		"Filme/Alien (1979)/poster.jpg", "binary",
+		"Serien/Mushishi/tvshow.nfo", `<tvshow><title>Mushishi</title><genre>Animation / Drama</genre></tvshow>`,
+		"Serien/Mushishi/Season 1/S01E01.nfo", `<episodedetails><title>The Green Seat</title></episodedetails>`,
+		"Filme/Kaputt/movie.nfo", `<movie><title>Kaputt`,
+	)
+
+	rows, err := ParseNFO(data)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	alien := rows[0]
+	assert.Equal(t, 1, alien.Line)
+	assert.Equal(t, "Filmserie", alien.Art)
+	assert.Equal(t, "Alien", alien.Name)
+	assert.Equal(t, "Film", *alien.FilmArt)
+	assert.Equal(t, "Horror, Science Fiction", *alien.Genre)
+	assert.Equal(t, 5, *alien.Bewertung)
+	assert.Equal(t, 9, *alien.BewertungOriginal)
+	assert.Equal(t, 1979, *alien.Jahr)
+	assert.Equal(t, models.StatusAbgeschlossen, *alien.Status)
+	assert.Equal(t, "2024-02-10", alien.AbgeschlossenAm.Format("2006-01-02"))
+
+	mushishi := rows[1]
+	assert.Equal(t, "Serie", *mushishi.FilmArt)
+	assert.Equal(t, "Animation, Drama", *mushishi.Genre)
+	assert.Nil(t, mushishi.Status)
+	assert.Nil(t, mushishi.Jahr)
+
+	assert.NotEmpty(t, rows[2].Errors)
+
+	_, err = ParseNFO(zipArchive(t, "readme.txt", "nothing here"))
+	assert.ErrorIs(t, err, ErrNoNFOFiles)
+	_, err = ParseNFO([]byte("<movie><title>Alien</title></movie>"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,338 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxRows begrenzt die Anzahl der Zeilen pro Import
+	MaxRows = 5000
+	// MaxBytes begrenzt die Größe einer Importdatei
+	MaxBytes = 10 << 20
+	// MaxUnpackedBytes begrenzt die entpackte Größe gepackter Importe (gzip, zip)
+	MaxUnpackedBytes = 8 * MaxBytes
+)
+
+// Fields sind die Zielfelder, auf die Spalten der Importdatei abgebildet werden können
+var Fields = []string{"art", "name", "nummer", "autor", "mangaka", "baende", "konsole", "filmArt", "sprache", "genre", "code"}
+
+// fieldAliases werden ohne explizites Mapping als Spaltennamen erkannt (Kleinschreibung)
+var fieldAliases = map[string][]string{
+	"art":     {"typ", "type", "medientyp"},
+	"name":    {"titel", "title"},
+	"nummer":  {"band", "volume", "nr"},
+	"autor":   {"author", "autorin"},
+	"baende":  {"bände", "volumes"},
+	"konsole": {"plattform", "platform"},
+	"filmArt": {"filmart"},
+	"sprache": {"language"},
+	"code":    {"isbn", "ean", "upc", "barcode"},
+}
+
+var (
+	ErrNoHeader      = errors.New("file has no header row")
+	ErrTooManyRows   = fmt.Errorf("import is limited to %d rows", MaxRows)
+	ErrUnknownFormat = errors.New("unknown import format, allowed are csv and json")
+	ErrTooLarge      = fmt.Errorf("unpacked import must not exceed %d bytes", MaxUnpackedBytes)
+)
+
+// Mapping bildet Zielfelder auf Spaltennamen der Datei ab, z.B. {"name": "Titel"}
+type Mapping map[string]string
+
+// record ist eine Zeile der Quelldatei als Spalte -> Wert
+type record struct {
+	line   int
+	values map[string]string
+}
+
+// Parse liest CSV oder JSON und bildet die Spalten auf Zeilen ab.
+// defaultArt wird verwendet, wenn die Datei keine Spalte für die Produktart hat.
+func Parse(format string, data []byte, mapping Mapping, defaultArt string) ([]Row, error) {
+	data = trimBOM(data)
+
+	var records []record
+	var columns []string
+	var err error
+	switch strings.ToLower(format) {
+	case "csv":
+		records, columns, err = readCSV(data)
+	case "json":
+		records, columns, err = readJSON(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+
+	resolved, err := resolveMapping(columns, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, len(records))
+	for i, rec := range records {
+		rows[i] = buildRow(rec, resolved, defaultArt)
+	}
+	return rows, nil
+}
+
+// trimBOM entfernt die UTF-8 BOM, die z.B. Excel voranstellt
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+}
+
+// DetectFormat leitet das Format aus Dateiname bzw. Content-Type ab
+func DetectFormat(filename string, contentType string) string {
+	lowerName := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lowerName, ".json"), strings.Contains(contentType, "json"):
+		return "json"
+	case strings.HasSuffix(lowerName, ".csv"), strings.HasSuffix(lowerName, ".tsv"), strings.Contains(contentType, "csv"):
+		return "csv"
+	default:
+		return ""
+	}
+}
+
+// resolveMapping ermittelt für jedes Zielfeld die Spalten der Datei in absteigender Priorität.
+// Ohne explizites Mapping kommen alle passenden Aliase in Frage, da JSON-Objekte
+// unterschiedliche Schlüssel haben können.
+func resolveMapping(columns []string, mapping Mapping) (map[string][]string, error) {
+	byLower := make(map[string]string, len(columns))
+	for _, column := range columns {
+		byLower[strings.ToLower(strings.TrimSpace(column))] = column
+	}
+
+	resolved := make(map[string][]string)
+	for field, column := range mapping {
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown target field %q in mapping", field)
+		}
+		actual, ok := byLower[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("column %q mapped to %q not found in file", column, field)
+		}
+		resolved[field] = []string{actual}
+	}
+
+	for _, field := range Fields {
+		if _, ok := resolved[field]; ok {
+			continue
+		}
+		for _, candidate := range append([]string{strings.ToLower(field)}, fieldAliases[field]...) {
+			if actual, ok := byLower[candidate]; ok {
+				resolved[field] = append(resolved[field], actual)
+			}
+		}
+	}
+	return resolved, nil
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readCSV erkennt das Trennzeichen (Komma, Semikolon oder Tab) anhand der Kopfzeile
+func readCSV(data []byte) ([]record, []string, error) {
+	headerLine, _, _ := bytes.Cut(data, []byte("\n"))
+	delimiter := ','
+	best := bytes.Count(headerLine, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if count := bytes.Count(headerLine, []byte(string(candidate))); count > best {
+			delimiter, best = candidate, count
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, ErrNoHeader
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid csv: %w", err)
+	}
+
+	var records []record
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		if len(records) >= MaxRows {
+			return nil, nil, ErrTooManyRows
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string, len(header))
+		empty := true
+		for i, column := range header {
+			if i < len(fields) {
+				values[column] = strings.TrimSpace(fields[i])
+				if values[column] != "" {
+					empty = false
+				}
+			}
+		}
+		if empty {
+			continue // Leerzeilen am Dateiende o.ä.
+		}
+		records = append(records, record{line: line, values: values})
+	}
+	return records, header, nil
+}
+
+// readJSON erwartet ein Array von Objekten. Als Zeilennummer dient die Position im Array.
+func readJSON(data []byte) ([]record, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var items []map[string]interface{}
+	if err := decoder.Decode(&items); err != nil {
+		return nil, nil, fmt.Errorf("invalid json, expected an array of objects: %w", err)
+	}
+	if len(items) > MaxRows {
+		return nil, nil, ErrTooManyRows
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	records := make([]record, len(items))
+	for i, item := range items {
+		values := make(map[string]string, len(item))
+		for key, value := range item {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+			switch v := value.(type) {
+			case nil:
+			case string:
+				values[key] = strings.TrimSpace(v)
+			case json.Number:
+				values[key] = v.String()
+			case bool:
+				values[key] = strconv.FormatBool(v)
+			default:
+				encoded, _ := json.Marshal(v)
+				values[key] = string(encoded)
+			}
+		}
+		records[i] = record{line: i + 1, values: values}
+	}
+	return records, columns, nil
+}
+
+// buildRow wandelt eine Zeile der Datei in eine Importzeile um und prüft die Werte
+func buildRow(rec record, columns map[string][]string, defaultArt string) Row {
+	value := func(field string) string {
+		for _, column := range columns[field] {
+			if v := rec.values[column]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	optional := func(field string) *string {
+		if v := value(field); v != "" {
+			return &v
+		}
+		return nil
+	}
+
+	row := Row{
+		Line:    rec.line,
+		Name:    value("name"),
+		Autor:   optional("autor"),
+		Mangaka: optional("mangaka"),
+		Konsole: optional("konsole"),
+		Sprache: optional("sprache"),
+		Genre:   optional("genre"),
+		Code:    value("code"),
+	}
+
+	artValue := value("art")
+	if artValue == "" {
+		artValue = defaultArt
+	}
+	if artValue == "" {
+		row.Errors = append(row.Errors, "art is required (column or default)")
+	} else if art, filmArt, ok := NormalizeArt(artValue); ok {
+		row.Art = art
+		if filmArt != "" {
+			row.FilmArt = &filmArt
+		}
+	} else {
+		row.Errors = append(row.Errors, fmt.Sprintf("unknown art %q", artValue))
+	}
+
+	if v := value("filmArt"); v != "" {
+		switch strings.ToLower(v) {
+		case "film":
+			film := "Film"
+			row.FilmArt = &film
+		case "serie":
+			serie := "Serie"
+			row.FilmArt = &serie
+		default:
+			row.Errors = append(row.Errors, "filmArt must be either 'Film' or 'Serie'")
+		}
+	}
+
+	for _, number := range []struct {
+		field  string
+		target **int
+	}{{"nummer", &row.Nummer}, {"baende", &row.Baende}} {
+		if v := value(number.field); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("%s %q is not a number", number.field, v))
+			} else {
+				*number.target = &parsed
+			}
+		}
+	}
+	return row
+}
+
+// NormalizeArt erkennt die Produktart; Film und Serie werden als Filmserie mit passender FilmArt geführt
+func NormalizeArt(value string) (art string, filmArt string, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "buch", "book":
+		return "Buch", "", true
+	case "manga":
+		return "Manga", "", true
+	case "spiel", "game":
+		return "Spiel", "", true
+	case "filmserie":
+		return "Filmserie", "", true
+	case "film", "movie":
+		return "Filmserie", "Film", true
+	case "serie", "series", "tv":
+		return "Filmserie", "Serie", true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,250 @@
+// Package jobs führt lang laufende Aufträge (Importe, Exporte, Metadaten-Aktualisierungen)
+// außerhalb der HTTP-Requests aus. Jobs liegen in der Tabelle job und werden von Workern im
+// Serverprozess abgearbeitet; fehlgeschlagene Jobs werden mit wachsendem Abstand wiederholt.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFinished     = errors.New("job has already finished")
+	ErrNotRetryable = errors.New("only failed or canceled jobs can be retried")
+)
+
+// permanentError kennzeichnet Fehler, bei denen ein weiterer Versuch sinnlos ist
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent markiert einen Fehler als endgültig; der Job wird nicht wiederholt
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// Enqueue legt einen Job an. Der Payload wird als JSON gespeichert.
+func Enqueue(db *gorm.DB, webuserID string, typ string, payload interface{}, maxVersuche int) (*models.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+	if maxVersuche < 1 {
+		maxVersuche = 1
+	}
+
+	now := time.Now().UTC()
+	job := models.Job{
+		WebuserID:        webuserID,
+		Typ:              typ,
+		Status:           models.JobWartend,
+		Payload:          string(encoded),
+		MaxVersuche:      maxVersuche,
+		NaechsterVersuch: now,
+		ErstelltAm:       now,
+	}
+	if err := db.Create(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Cancel bricht einen Job ab. Wartende Jobs werden sofort beendet, bei laufenden Jobs wird
+// der Abbruch angefordert und vom Worker beim nächsten Lebenszeichen umgesetzt; ist der Worker
+// ausgefallen, beendet ihn die nächste Vergabe nach Ablauf der Lease.
+func Cancel(db *gorm.DB, job *models.Job) error {
+	now := time.Now().UTC()
+	result := db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", job.ID, models.JobWartend).
+		Updates(map[string]interface{}{"status": models.JobAbgebrochen, "abbruch_angefordert": true, "beendet_am": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		result = db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobLaeuft).
+			Update("abbruch_angefordert", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrFinished
+		}
+	}
+	return reload(db, job)
+}
+
+// Retry stellt einen fehlgeschlagenen oder abgebrochenen Job mit allen Versuchen neu ein
+func Retry(db *gorm.DB, job *models.Job) error {
+	result := db.Model(&models.Job{}).
+		Where("id = ? AND status IN ?", job.ID, []string{models.JobFehlgeschlagen, models.JobAbgebrochen}).
+		Updates(map[string]interface{}{
+			"status":              models.JobWartend,
+			"versuche":            0,
+			"fortschritt":         0,
+			"fehler":              nil,
+			"abbruch_angefordert": false,
+			"naechster_versuch":   time.Now().UTC(),
+			"gestartet_am":        nil,
+			"beendet_am":          nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotRetryable
+	}
+	return reload(db, job)
+}
+
+// Dateien liefert die Speicherschlüssel der Dateien, die ein Job im Speicher ablegt bzw. liest
+type Dateien func(job models.Job) []string
+
+// Sweep löscht beendete Jobs der Typen in dateien, die vor before beendet wurden, samt ihrer
+// Dateien und liefert deren Anzahl. Ein Job, dessen Dateien nicht gelöscht werden können,
+// bleibt für den nächsten Lauf.
+func Sweep(ctx context.Context, db *gorm.DB, store storage.Store, before time.Time, dateien map[string]Dateien) (int, error) {
+	typen := make([]string, 0, len(dateien))
+	for typ := range dateien {
+		typen = append(typen, typ)
+	}
+	var beendet []models.Job
+	err := db.Where("typ IN ? AND status IN ? AND beendet_am < ?", typen,
+		[]string{models.JobErfolgreich, models.JobFehlgeschlagen, models.JobAbgebrochen}, before).
+		Order("id").Find(&beendet).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	ids := make([]uint, 0, len(beendet))
+jobs:
+	for _, job := range beendet {
+		for _, key := range dateien[job.Typ](job) {
+			if err := store.Delete(ctx, key); err != nil {
+				errs = append(errs, fmt.Errorf("job %d: %w", job.ID, err))
+				continue jobs
+			}
+		}
+		ids = append(ids, job.ID)
+	}
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Delete(&models.Job{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), errors.Join(errs...)
+}
+
+// RunSweeper führt Sweep im angegebenen Intervall aus, bis ctx beendet wird. Die Dateien
+// beendeter Jobs bleiben retention lang abrufbar.
+func RunSweeper(ctx context.Context, db *gorm.DB, store storage.Store, retention time.Duration, interval time.Duration, dateien map[string]Dateien) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := Sweep(ctx, db, store, time.Now().UTC().Add(-retention), dateien)
+		if err != nil {
+			log.Printf("ERROR Job sweep: %v\n", err)
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d finished job(s) with their files", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reload liest den Job neu ein. Ein frisches Struct ist nötig, da GORM beim Einlesen
+// NULL-Werte nicht auf bereits gesetzte Felder überträgt.
+func reload(db *gorm.DB, job *models.Job) error {
+	var current models.Job
+	if err := db.First(&current, job.ID).Error; err != nil {
+		return err
+	}
+	*job = current
+	return nil
+}
+
+// claimCondition beschreibt fällige Jobs: wartende nach Ablauf der Wartezeit und laufende,
+// deren Worker kein Lebenszeichen mehr gibt (z.B. nach einem Absturz). Laufende Jobs mit
+// angefordertem Abbruch werden nicht neu vergeben, sondern von abortOrphaned beendet.
+const claimCondition = `((status = ? AND naechster_versuch <= ?) OR (status = ? AND gesperrt_bis < ?)) AND abbruch_angefordert = ?`
+
+// abortOrphaned beendet laufende Jobs, deren Abbruch angefordert wurde, deren Worker aber kein
+// Lebenszeichen mehr gibt. Ohne Worker würde der Abbruch sonst nie umgesetzt.
+func abortOrphaned(db *gorm.DB, now time.Time) error {
+	return db.Model(&models.Job{}).
+		Where("status = ? AND abbruch_angefordert = ? AND gesperrt_bis < ?", models.JobLaeuft, true, now).
+		Updates(map[string]interface{}{"status": models.JobAbgebrochen, "gesperrt_bis": nil, "beendet_am": now}).Error
+}
+
+// claim vergibt den nächsten fälligen Job an den aufrufenden Worker. PostgreSQL sperrt die
+// Zeile mit SKIP LOCKED, sodass sich Worker nicht gegenseitig blockieren; andere Datenbanken
+// (SQLite) verwenden ein bedingtes Update und versuchen es bei Konkurrenz erneut.
+func claim(db *gorm.DB, lease time.Duration) (*models.Job, error) {
+	now := time.Now().UTC()
+	if err := abortOrphaned(db, now); err != nil {
+		return nil, err
+	}
+	gesperrtBis := now.Add(lease)
+	args := []interface{}{models.JobWartend, now, models.JobLaeuft, now, false}
+
+	if db.Dialector.Name() == "postgres" {
+		var claimed []models.Job
+		err := db.Raw(`UPDATE job SET status = ?, versuche = versuche + 1, gestartet_am = ?, gesperrt_bis = ?
+			WHERE id = (SELECT id FROM job WHERE `+claimCondition+`
+				ORDER BY naechster_versuch, id LIMIT 1 FOR UPDATE SKIP LOCKED)
+			RETURNING *`, append([]interface{}{models.JobLaeuft, now, gesperrtBis}, args...)...).
+			Scan(&claimed).Error
+		if err != nil || len(claimed) == 0 {
+			return nil, err
+		}
+		return &claimed[0], nil
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		var candidates []models.Job
+		err := db.Where(claimCondition, args...).Order("naechster_versuch, id").Limit(1).Find(&candidates).Error
+		if err != nil || len(candidates) == 0 {
+			return nil, err
+		}
+		result := db.Model(&models.Job{}).
+			Where("id = ?", candidates[0].ID).
+			Where(claimCondition, args...).
+			Updates(map[string]interface{}{
+				"status":       models.JobLaeuft,
+				"versuche":     gorm.Expr("versuche + 1"),
+				"gestartet_am": now,
+				"gesperrt_bis": gesperrtBis,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			var job models.Job
+			if err := db.First(&job, candidates[0].ID).Error; err != nil {
+				return nil, err
+			}
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
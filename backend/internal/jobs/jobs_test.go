@@ -0,0 +1,286 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupJobsTestDB verwendet eine Datei statt :memory:, damit alle Worker dieselbe Datenbank sehen
+func setupJobsTestDB(t *testing.T) *gorm.DB {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	db, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=5000"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Job{}))
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice"}).Error)
+	return db
+}
+
+func testConfig() *config.JobsConfig {
+	return &config.JobsConfig{
+		Workers:      2,
+		PollInterval: 10 * time.Millisecond,
+		Lease:        300 * time.Millisecond,
+		BackoffBase:  20 * time.Millisecond,
+		BackoffMax:   time.Second,
+		ShutdownWait: time.Second,
+	}
+}
+
+func waitForStatus(t *testing.T, db *gorm.DB, id uint, status string) models.Job {
+	var job models.Job
+	require.Eventually(t, func() bool {
+		job = models.Job{}
+		require.NoError(t, db.First(&job, id).Error)
+		return job.Status == status
+	}, 5*time.Second, 10*time.Millisecond, "job should reach status %s", status)
+	return job
+}
+
+func TestManagerRunsJob(t *testing.T) {
+	db := setupJobsTestDB(t)
+	manager := NewManager(db, testConfig())
+	manager.Register("echo", func(ctx context.Context, run *Run) (interface{}, error) {
+		var payload struct{ Name string }
+		if err := run.Decode(&payload); err != nil {
+			return nil, err
+		}
+		require.NoError(t, run.Progress(50))
+		return map[string]string{"hallo": payload.Name}, nil
+	})
+	manager.Start()
+	defer manager.Shutdown(context.Background())
+
+	job, err := Enqueue(db, "alice", "echo", map[string]string{"Name": "Welt"}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobWartend, job.Status)
+
+	done := waitForStatus(t, db, job.ID, models.JobErfolgreich)
+	assert.Equal(t, 100, done.Fortschritt)
+	assert.Equal(t, 1, done.Versuche)
+	assert.JSONEq(t, `{"hallo": "Welt"}`, *done.Ergebnis)
+	assert.NotNil(t, done.BeendetAm)
+	assert.Nil(t, done.GesperrtBis)
+}
+
+func TestManagerRetriesWithBackoff(t *testing.T) {
+	db := setupJobsTestDB(t)
+	manager := NewManager(db, testConfig())
+	var calls int32
+	manager.Register("flaky", func(ctx context.Context, run *Run) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errors.New("temporarily unavailable")
+		}
+		return nil, nil
+	})
+	manager.Start()
+	defer manager.Shutdown(context.Background())
+
+	job, err := Enqueue(db, "alice", "flaky", nil, 3)
+	require.NoError(t, err)
+
+	done := waitForStatus(t, db, job.ID, models.JobErfolgreich)
+	assert.Equal(t, 3, done.Versuche)
+	assert.Nil(t, done.Fehler)
+	assert.Nil(t, done.Ergebnis)
+}
+
+func TestManagerFailsAndRetryRequeues(t *testing.T) {
+	db := setupJobsTestDB(t)
+	manager := NewManager(db, testConfig())
+	var calls int32
+	manager.Register("broken", func(ctx context.Context, run *Run) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("still broken")
+	})
+	manager.Register("invalid", func(ctx context.Context, run *Run) (interface{}, error) {
+		return nil, Permanent(errors.New("invalid input"))
+	})
+	manager.Start()
+	defer manager.Shutdown(context.Background())
+
+	broken, err := Enqueue(db, "alice", "broken", nil, 2)
+	require.NoError(t, err)
+	invalid, err := Enqueue(db, "alice", "invalid", nil, 5)
+	require.NoError(t, err)
+	unknown, err := Enqueue(db, "alice", "unknown", nil, 5)
+	require.NoError(t, err)
+
+	failed := waitForStatus(t, db, broken.ID, models.JobFehlgeschlagen)
+	assert.Equal(t, 2, failed.Versuche)
+	assert.Equal(t, "still broken", *failed.Fehler)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	failed = waitForStatus(t, db, invalid.ID, models.JobFehlgeschlagen)
+	assert.Equal(t, 1, failed.Versuche, "permanent errors are not retried")
+	failed = waitForStatus(t, db, unknown.ID, models.JobFehlgeschlagen)
+	assert.Contains(t, *failed.Fehler, "unknown job type")
+
+	// Manual retry starts over with all attempts
+	require.NoError(t, Retry(db, broken))
+	waitForStatus(t, db, broken.ID, models.JobFehlgeschlagen)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	assert.ErrorIs(t, Retry(db, &models.Job{ID: 999}), ErrNotRetryable)
+}
+
+func TestCancel(t *testing.T) {
+	db := setupJobsTestDB(t)
+	started := make(chan struct{})
+	manager := NewManager(db, testConfig())
+	manager.Register("slow", func(ctx context.Context, run *Run) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	// A queued job is canceled immediately
+	queued, err := Enqueue(db, "alice", "slow", nil, 1)
+	require.NoError(t, err)
+	require.NoError(t, Cancel(db, queued))
+	assert.Equal(t, models.JobAbgebrochen, queued.Status)
+	assert.ErrorIs(t, Cancel(db, queued), ErrFinished)
+
+	// A running job is interrupted by the worker
+	manager.Start()
+	defer manager.Shutdown(context.Background())
+	running, err := Enqueue(db, "alice", "slow", nil, 3)
+	require.NoError(t, err)
+	<-started
+	require.NoError(t, Cancel(db, running))
+	assert.True(t, running.AbbruchAngefordert)
+
+	done := waitForStatus(t, db, running.ID, models.JobAbgebrochen)
+	assert.Equal(t, 1, done.Versuche)
+	assert.NotNil(t, done.BeendetAm)
+}
+
+func TestShutdownRequeuesRunningJobs(t *testing.T) {
+	db := setupJobsTestDB(t)
+	cfg := testConfig()
+	cfg.ShutdownWait = 50 * time.Millisecond
+	started := make(chan struct{})
+	manager := NewManager(db, cfg)
+	manager.Register("slow", func(ctx context.Context, run *Run) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	manager.Start()
+
+	job, err := Enqueue(db, "alice", "slow", nil, 1)
+	require.NoError(t, err)
+	<-started
+	require.NoError(t, manager.Shutdown(context.Background()))
+
+	require.NoError(t, db.First(job, job.ID).Error)
+	assert.Equal(t, models.JobWartend, job.Status)
+	assert.Equal(t, 0, job.Versuche, "interrupted attempt must not count")
+	assert.Nil(t, job.Fehler)
+}
+
+func TestClaimReclaimsExpiredLease(t *testing.T) {
+	db := setupJobsTestDB(t)
+	job, err := Enqueue(db, "alice", "echo", nil, 3)
+	require.NoError(t, err)
+
+	claimed, err := claim(db, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, job.ID, claimed.ID)
+	assert.Equal(t, models.JobLaeuft, claimed.Status)
+	assert.Equal(t, 1, claimed.Versuche)
+
+	// Locked by the first worker
+	none, err := claim(db, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	// The worker disappeared without renewing its lease
+	require.NoError(t, db.Model(&models.Job{}).Where("id = ?", job.ID).
+		Update("gesperrt_bis", time.Now().UTC().Add(-time.Second)).Error)
+	reclaimed, err := claim(db, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, reclaimed)
+	assert.Equal(t, 2, reclaimed.Versuche)
+}
+
+func TestClaimAbortsCanceledJobOfCrashedWorker(t *testing.T) {
+	db := setupJobsTestDB(t)
+	job, err := Enqueue(db, "alice", "echo", nil, 3)
+	require.NoError(t, err)
+	claimed, err := claim(db, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.NoError(t, Cancel(db, claimed))
+	assert.Equal(t, models.JobLaeuft, claimed.Status, "the worker has to apply the cancellation")
+
+	// The worker crashed before it noticed the cancellation
+	require.NoError(t, db.Model(&models.Job{}).Where("id = ?", job.ID).
+		Update("gesperrt_bis", time.Now().UTC().Add(-time.Second)).Error)
+	none, err := claim(db, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, none, "canceled jobs are not handed to another worker")
+
+	require.NoError(t, reload(db, job))
+	assert.Equal(t, models.JobAbgebrochen, job.Status)
+	assert.NotNil(t, job.BeendetAm)
+	assert.Nil(t, job.GesperrtBis)
+}
+
+func TestBackoff(t *testing.T) {
+	manager := NewManager(nil, &config.JobsConfig{BackoffBase: 10 * time.Second, BackoffMax: time.Minute})
+	assert.Equal(t, 10*time.Second, manager.backoff(1))
+	assert.Equal(t, 20*time.Second, manager.backoff(2))
+	assert.Equal(t, 40*time.Second, manager.backoff(3))
+	assert.Equal(t, time.Minute, manager.backoff(4))
+	assert.Equal(t, time.Minute, manager.backoff(30))
+}
+
+func TestSweepDeletesExpiredJobsWithFiles(t *testing.T) {
+	db := setupJobsTestDB(t)
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	datei := func(job models.Job) []string { return []string{fmt.Sprintf("exports/%d.csv", job.ID)} }
+
+	now := time.Now().UTC()
+	alt, neu := now.Add(-48*time.Hour), now.Add(-time.Hour)
+	create := func(typ string, status string, beendetAm *time.Time) models.Job {
+		job := models.Job{WebuserID: "alice", Typ: typ, Status: status, Payload: "{}", NaechsterVersuch: now,
+			ErstelltAm: now, BeendetAm: beendetAm}
+		require.NoError(t, db.Create(&job).Error)
+		require.NoError(t, store.Put(ctx, datei(job)[0], strings.NewReader("name\n"), 5, "text/csv"))
+		return job
+	}
+	abgelaufen := create("export", models.JobErfolgreich, &alt)
+	aktuell := create("export", models.JobErfolgreich, &neu)
+	laufend := create("export", models.JobLaeuft, nil)
+	ohneDateien := create("import", models.JobErfolgreich, &alt)
+
+	deleted, err := Sweep(ctx, db, store, now.Add(-24*time.Hour), map[string]Dateien{"export": datei})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	var ids []uint
+	require.NoError(t, db.Model(&models.Job{}).Order("id").Pluck("id", &ids).Error)
+	assert.Equal(t, []uint{aktuell.ID, laufend.ID, ohneDateien.ID}, ids)
+	_, _, err = store.Get(ctx, datei(abgelaufen)[0])
+	assert.True(t, errors.Is(err, storage.ErrNotFound))
+	reader, _, err := store.Get(ctx, datei(aktuell)[0])
+	require.NoError(t, err)
+	reader.Close()
+}
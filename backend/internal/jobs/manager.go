@@ -0,0 +1,253 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Run gibt einem Handler Zugriff auf den auszuführenden Job
+type Run struct {
+	Job *models.Job
+	DB  *gorm.DB
+}
+
+// Decode liest den Payload des Jobs
+func (r *Run) Decode(v interface{}) error {
+	if err := json.Unmarshal([]byte(r.Job.Payload), v); err != nil {
+		return Permanent(fmt.Errorf("decode payload: %w", err))
+	}
+	return nil
+}
+
+// Progress speichert den Fortschritt in Prozent (0 bis 100)
+func (r *Run) Progress(percent int) error {
+	percent = min(max(percent, 0), 100)
+	r.Job.Fortschritt = percent
+	return r.DB.Model(&models.Job{}).Where("id = ?", r.Job.ID).Update("fortschritt", percent).Error
+}
+
+// Handler führt einen Job aus. Das Ergebnis wird als JSON am Job gespeichert. Der Context
+// wird bei einem Abbruch durch den Benutzer oder beim Beenden des Servers abgebrochen.
+type Handler func(ctx context.Context, run *Run) (interface{}, error)
+
+// Manager verteilt fällige Jobs auf eine feste Anzahl von Workern
+type Manager struct {
+	db       *gorm.DB
+	cfg      *config.JobsConfig
+	handlers map[string]Handler
+
+	stop    context.CancelFunc // beendet die Vergabe neuer Jobs
+	stopCtx context.Context
+	abort   context.CancelFunc // bricht laufende Jobs ab
+	runCtx  context.Context
+	wg      sync.WaitGroup
+}
+
+func NewManager(db *gorm.DB, cfg *config.JobsConfig) *Manager {
+	m := &Manager{db: db, cfg: cfg, handlers: make(map[string]Handler)}
+	m.stopCtx, m.stop = context.WithCancel(context.Background())
+	m.runCtx, m.abort = context.WithCancel(context.Background())
+	return m
+}
+
+// Register ordnet einem Jobtyp seinen Handler zu. Muss vor Start aufgerufen werden.
+func (m *Manager) Register(typ string, handler Handler) {
+	m.handlers[typ] = handler
+}
+
+// Start startet die Worker
+func (m *Manager) Start() {
+	for i := 0; i < m.cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+}
+
+// Shutdown vergibt keine neuen Jobs mehr und wartet bis zu ShutdownWait auf laufende Jobs.
+// Danach werden diese abgebrochen und ohne Anrechnung des Versuchs wieder eingereiht.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.stop()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	wait := time.NewTimer(m.cfg.ShutdownWait)
+	defer wait.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-wait.C:
+	case <-ctx.Done():
+	}
+
+	m.abort()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		if m.stopCtx.Err() != nil {
+			return
+		}
+
+		job, err := claim(m.db, m.cfg.Lease)
+		if err != nil {
+			log.Printf("ERROR claiming job: %v\n", err)
+		}
+		if job == nil {
+			select {
+			case <-m.stopCtx.Done():
+				return
+			case <-time.After(m.cfg.PollInterval):
+			}
+			continue
+		}
+		m.execute(job)
+	}
+}
+
+func (m *Manager) execute(job *models.Job) {
+	ctx, cancel := context.WithCancel(m.runCtx)
+	defer cancel()
+
+	var canceled bool
+	var mu sync.Mutex
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		m.heartbeat(ctx, job.ID, func() {
+			mu.Lock()
+			canceled = true
+			mu.Unlock()
+			cancel()
+		})
+	}()
+
+	result, err := m.run(ctx, job)
+	cancel()
+	<-heartbeatDone
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil && !canceled {
+		// Der Abbruch kann nach dem letzten Lebenszeichen angefordert worden sein
+		var current models.Job
+		if m.db.Select("abbruch_angefordert").First(&current, job.ID).Error == nil {
+			canceled = current.AbbruchAngefordert
+		}
+	}
+	if err := m.finish(job, result, err, canceled); err != nil {
+		log.Printf("ERROR finishing job %d: %v\n", job.ID, err)
+	}
+}
+
+// run ruft den Handler auf; Panics werden wie endgültige Fehler behandelt
+func (m *Manager) run(ctx context.Context, job *models.Job) (result interface{}, err error) {
+	handler, ok := m.handlers[job.Typ]
+	if !ok {
+		return nil, Permanent(fmt.Errorf("unknown job type %q", job.Typ))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return handler(ctx, &Run{Job: job, DB: m.db})
+}
+
+// heartbeat verlängert die Lease und prüft, ob der Abbruch angefordert wurde
+func (m *Manager) heartbeat(ctx context.Context, id uint, onCancel func()) {
+	ticker := time.NewTicker(m.cfg.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		gesperrtBis := time.Now().UTC().Add(m.cfg.Lease)
+		if err := m.db.Model(&models.Job{}).Where("id = ?", id).Update("gesperrt_bis", gesperrtBis).Error; err != nil {
+			log.Printf("ERROR extending lease of job %d: %v\n", id, err)
+			continue
+		}
+		var job models.Job
+		if err := m.db.Select("abbruch_angefordert").First(&job, id).Error; err != nil {
+			log.Printf("ERROR checking job %d: %v\n", id, err)
+			continue
+		}
+		if job.AbbruchAngefordert {
+			onCancel()
+			return
+		}
+	}
+}
+
+// finish speichert das Ergebnis eines Versuchs. Die Bedingung auf Status und Versuche
+// verhindert, dass ein Job überschrieben wird, der nach Ablauf der Lease neu vergeben wurde.
+func (m *Manager) finish(job *models.Job, result interface{}, runErr error, canceled bool) error {
+	now := time.Now().UTC()
+	updates := map[string]interface{}{"gesperrt_bis": nil}
+
+	switch {
+	case runErr == nil:
+		updates["status"] = models.JobErfolgreich
+		updates["fortschritt"] = 100
+		updates["fehler"] = nil
+		updates["beendet_am"] = now
+		if result != nil {
+			encoded, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("encode result: %w", err)
+			}
+			updates["ergebnis"] = string(encoded)
+		}
+	case canceled:
+		updates["status"] = models.JobAbgebrochen
+		updates["beendet_am"] = now
+	case m.runCtx.Err() != nil && errors.Is(runErr, context.Canceled):
+		// Server wird beendet: der Versuch zählt nicht
+		updates["status"] = models.JobWartend
+		updates["versuche"] = job.Versuche - 1
+		updates["naechster_versuch"] = now
+	case isPermanent(runErr) || job.Versuche >= job.MaxVersuche:
+		updates["status"] = models.JobFehlgeschlagen
+		updates["fehler"] = runErr.Error()
+		updates["beendet_am"] = now
+	default:
+		updates["status"] = models.JobWartend
+		updates["fehler"] = runErr.Error()
+		updates["naechster_versuch"] = now.Add(m.backoff(job.Versuche))
+	}
+
+	return m.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND versuche = ?", job.ID, models.JobLaeuft, job.Versuche).
+		Updates(updates).Error
+}
+
+// backoff verdoppelt die Wartezeit mit jedem Versuch bis zu BackoffMax
+func (m *Manager) backoff(versuche int) time.Duration {
+	wait := m.cfg.BackoffBase
+	for i := 1; i < versuche && wait < m.cfg.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, m.cfg.BackoffMax)
+}
@@ -0,0 +1,188 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AniList nutzt die GraphQL-API von anilist.co für Mangas und Anime
+type AniList struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewAniList(baseURL string, client *http.Client) *AniList {
+	return &AniList{BaseURL: strings.TrimRight(baseURL, "/"), Client: client}
+}
+
+func (p *AniList) Name() string { return "anilist" }
+
+func (p *AniList) Supports(art string) bool {
+	return art == "" || art == "Manga" || art == "Filmserie"
+}
+
+const aniListQuery = `query ($search: String, $type: MediaType, $perPage: Int) {
+  Page(perPage: $perPage) {
+    media(search: $search, type: $type) {
+      id
+      type
+      format
+      title { romaji english native }
+      genres
+      volumes
+      episodes
+      countryOfOrigin
+      staff(perPage: 4) { edges { role node { name { full } } } }
+    }
+  }
+}`
+
+type aniListStaffEdge struct {
+	Role string `json:"role"`
+	Node struct {
+		Name struct {
+			Full string `json:"full"`
+		} `json:"name"`
+	} `json:"node"`
+}
+
+type aniListResponse struct {
+	Data struct {
+		Page struct {
+			Media []struct {
+				ID     int    `json:"id"`
+				Type   string `json:"type"`
+				Format string `json:"format"`
+				Title  struct {
+					Romaji  string `json:"romaji"`
+					English string `json:"english"`
+					Native  string `json:"native"`
+				} `json:"title"`
+				Genres          []string `json:"genres"`
+				Volumes         *int     `json:"volumes"`
+				Episodes        *int     `json:"episodes"`
+				CountryOfOrigin string   `json:"countryOfOrigin"`
+				Staff           struct {
+					Edges []aniListStaffEdge `json:"edges"`
+				} `json:"staff"`
+			} `json:"media"`
+		} `json:"Page"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// aniListCountries bildet das Herkunftsland auf die Originalsprache ab
+var aniListCountries = map[string]string{"JP": "ja", "KR": "ko", "CN": "zh", "TW": "zh"}
+
+func (p *AniList) Lookup(ctx context.Context, query Query) ([]Candidate, error) {
+	if query.Name == "" {
+		// AniList kennt keine ISBNs
+		return nil, nil
+	}
+
+	var types []string
+	switch query.Art {
+	case "Manga":
+		types = []string{"MANGA"}
+	case "Filmserie":
+		types = []string{"ANIME"}
+	default:
+		types = []string{"MANGA", "ANIME"}
+	}
+
+	var candidates []Candidate
+	for _, mediaType := range types {
+		found, err := p.search(ctx, query.Name, mediaType)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, found...)
+	}
+	return candidates, nil
+}
+
+func (p *AniList) search(ctx context.Context, search string, mediaType string) ([]Candidate, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": aniListQuery,
+		"variables": map[string]interface{}{
+			"search":  search,
+			"type":    mediaType,
+			"perPage": maxCandidates,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	var result aniListResponse
+	if err := doJSON(p.Client, req, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("anilist: %s", result.Errors[0].Message)
+	}
+
+	candidates := make([]Candidate, 0, len(result.Data.Page.Media))
+	for _, media := range result.Data.Page.Media {
+		name := media.Title.English
+		if name == "" {
+			name = media.Title.Romaji
+		}
+		candidate := Candidate{
+			Provider:   p.Name(),
+			ExternalID: fmt.Sprintf("%d", media.ID),
+			Name:       name,
+			Genre:      JoinGenres(media.Genres),
+		}
+		if code, ok := aniListCountries[media.CountryOfOrigin]; ok {
+			candidate.Sprache = LanguageName(code)
+		}
+
+		if media.Type == "MANGA" {
+			candidate.Art = "Manga"
+			candidate.Baende = media.Volumes
+			candidate.Mangaka = aniListMangaka(media.Staff.Edges)
+		} else {
+			candidate.Art = "Filmserie"
+			candidate.Episoden = media.Episodes
+			art := "Serie"
+			if media.Format == "MOVIE" {
+				art = "Film"
+			}
+			candidate.FilmArt = &art
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// aniListMangaka wählt die für Story und Zeichnung verantwortlichen Personen aus
+func aniListMangaka(edges []aniListStaffEdge) *string {
+	var names []string
+	seen := map[string]bool{}
+	for _, edge := range edges {
+		role := strings.ToLower(edge.Role)
+		if !strings.Contains(role, "story") && !strings.Contains(role, "art") {
+			continue
+		}
+		name := edge.Node.Name.Full
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return strPtr(strings.Join(names, ", "))
+}
@@ -0,0 +1,136 @@
+// Package metadata sucht Produktdaten (Autor, Genre, Sprache, ...) bei externen Diensten,
+// damit sie beim Anlegen von Produkten nicht von Hand eingetippt werden müssen.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Query beschreibt eine Suche nach Metadaten. Name oder ISBN muss gesetzt sein.
+type Query struct {
+	Art  string // Buch, Manga, Spiel oder Filmserie; leer = alle
+	Name string
+	ISBN string // Normalisierte ISBN-13
+}
+
+// Key liefert einen stabilen Schlüssel für den Cache
+func (q Query) Key() string {
+	return strings.ToLower(q.Art + "|" + strings.TrimSpace(q.Name) + "|" + q.ISBN)
+}
+
+// Candidate ist ein Treffer eines Providers. Die Felder entsprechen den
+// typspezifischen Feldern der Create*-Handler.
+type Candidate struct {
+	Provider   string  `json:"provider"`
+	ExternalID string  `json:"externalId,omitempty"`
+	Art        string  `json:"art"` // Produktart
+	Name       string  `json:"name"`
+	Nummer     *int    `json:"nummer,omitempty"`
+	Autor      *string `json:"autor,omitempty"`
+	Mangaka    *string `json:"mangaka,omitempty"`
+	Konsole    *string `json:"konsole,omitempty"`
+	FilmArt    *string `json:"filmArt,omitempty"` // 'Film' oder 'Serie'
+	Sprache    *string `json:"sprache,omitempty"`
+	Genre      *string `json:"genre,omitempty"`
+	ISBN       *string `json:"isbn,omitempty"`
+	Baende     *int    `json:"baende,omitempty"`   // Anzahl Bände (Manga)
+	Episoden   *int    `json:"episoden,omitempty"` // Anzahl Episoden (Serie)
+}
+
+// Provider ist eine Metadatenquelle
+type Provider interface {
+	// Name identifiziert den Provider in Antworten und Konfiguration
+	Name() string
+	// Supports gibt an, ob der Provider Produkte dieser Art kennt
+	Supports(art string) bool
+	// Lookup sucht Kandidaten; der Context trägt das Timeout
+	Lookup(ctx context.Context, query Query) ([]Candidate, error)
+}
+
+// maxCandidates begrenzt die Treffer je Provider
+const maxCandidates = 5
+
+// getJSON führt eine GET-Anfrage aus und dekodiert die JSON-Antwort
+func getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return doJSON(client, req, target)
+}
+
+func doJSON(client *http.Client, req *http.Request, target interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		// *url.Error enthält die vollständige URL samt Parametern (z.B. API-Schlüssel)
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("request to %s failed: %w", req.URL.Host, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 5<<20)).Decode(target)
+}
+
+func strPtr(s string) *string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return &s
+}
+
+// JoinGenres fasst mehrere Genres zu einem String zusammen, der in Genre (varchar 100) passt
+func JoinGenres(genres []string) *string {
+	var result []string
+	length := 0
+	for _, genre := range genres {
+		genre = strings.TrimSpace(genre)
+		if genre == "" {
+			continue
+		}
+		if length+len(genre)+2 > 100 {
+			break
+		}
+		result = append(result, genre)
+		length += len(genre) + 2
+		if len(result) == 3 {
+			break
+		}
+	}
+	return strPtr(strings.Join(result, ", "))
+}
+
+// languageNames übersetzt gängige Sprachcodes (ISO 639-1/-2) in die im Katalog übliche Schreibweise
+var languageNames = map[string]string{
+	"de": "Deutsch", "ger": "Deutsch", "deu": "Deutsch",
+	"en": "Englisch", "eng": "Englisch",
+	"ja": "Japanisch", "jpn": "Japanisch",
+	"fr": "Französisch", "fre": "Französisch", "fra": "Französisch",
+	"es": "Spanisch", "spa": "Spanisch",
+	"it": "Italienisch", "ita": "Italienisch",
+	"ko": "Koreanisch", "kor": "Koreanisch",
+	"zh": "Chinesisch", "chi": "Chinesisch", "zho": "Chinesisch",
+}
+
+// LanguageName liefert den Sprachnamen zu einem Code; unbekannte Codes werden unverändert übernommen
+func LanguageName(code string) *string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if name, ok := languageNames[code]; ok {
+		return &name
+	}
+	return strPtr(code)
+}
@@ -0,0 +1,194 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenLibraryLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search.json", r.URL.Path)
+		assert.Equal(t, "9780306406157", r.URL.Query().Get("isbn"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"docs": [{
+			"key": "/works/OL1W",
+			"title": "Der Hobbit",
+			"author_name": ["J. R. R. Tolkien"],
+			"language": ["ger", "eng"],
+			"subject": ["Fantasy", "Adventure", "Dragons", "Dwarves"]
+		}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenLibrary(server.URL, server.Client())
+	candidates, err := provider.Lookup(context.Background(), Query{Art: "Buch", ISBN: "9780306406157"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+
+	candidate := candidates[0]
+	assert.Equal(t, "openlibrary", candidate.Provider)
+	assert.Equal(t, "Buch", candidate.Art)
+	assert.Equal(t, "Der Hobbit", candidate.Name)
+	assert.Equal(t, "J. R. R. Tolkien", *candidate.Autor)
+	assert.Equal(t, "Deutsch", *candidate.Sprache)
+	assert.Equal(t, "Fantasy, Adventure, Dragons", *candidate.Genre)
+	assert.Equal(t, "9780306406157", *candidate.ISBN)
+}
+
+func TestAniListLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "MANGA", body.Variables["type"])
+		assert.Equal(t, "Berserk", body.Variables["search"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"Page": {"media": [{
+			"id": 30002, "type": "MANGA", "format": "MANGA",
+			"title": {"romaji": "Berserk", "english": null},
+			"genres": ["Action", "Drama"], "volumes": 41, "countryOfOrigin": "JP",
+			"staff": {"edges": [
+				{"role": "Story & Art", "node": {"name": {"full": "Kentarou Miura"}}},
+				{"role": "Assistant", "node": {"name": {"full": "Someone Else"}}}
+			]}
+		}]}}}`))
+	}))
+	defer server.Close()
+
+	provider := NewAniList(server.URL, server.Client())
+	candidates, err := provider.Lookup(context.Background(), Query{Art: "Manga", Name: "Berserk"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+
+	candidate := candidates[0]
+	assert.Equal(t, "Manga", candidate.Art)
+	assert.Equal(t, "Berserk", candidate.Name)
+	assert.Equal(t, "Kentarou Miura", *candidate.Mangaka)
+	assert.Equal(t, "Japanisch", *candidate.Sprache)
+	assert.Equal(t, 41, *candidate.Baende)
+}
+
+func TestTMDBLookup(t *testing.T) {
+	var genreCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.URL.Query().Get("api_key"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/search/multi":
+			_, _ = w.Write([]byte(`{"results": [
+				{"id": 1, "media_type": "movie", "title": "Alien", "original_language": "en", "genre_ids": [27, 878]},
+				{"id": 2, "media_type": "person", "name": "Sigourney Weaver"},
+				{"id": 3, "media_type": "tv", "name": "Alien: Earth", "original_language": "en", "genre_ids": []}
+			]}`))
+		case "/genre/movie/list":
+			atomic.AddInt32(&genreCalls, 1)
+			_, _ = w.Write([]byte(`{"genres": [{"id": 27, "name": "Horror"}, {"id": 878, "name": "Science Fiction"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewTMDB(server.URL, "secret", server.Client())
+	for i := 0; i < 2; i++ {
+		candidates, err := provider.Lookup(context.Background(), Query{Name: "Alien"})
+		require.NoError(t, err)
+		require.Len(t, candidates, 2)
+		assert.Equal(t, "Film", *candidates[0].FilmArt)
+		assert.Equal(t, "Horror, Science Fiction", *candidates[0].Genre)
+		assert.Equal(t, "Englisch", *candidates[0].Sprache)
+		assert.Equal(t, "Serie", *candidates[1].FilmArt)
+		assert.Nil(t, candidates[1].Genre)
+	}
+	// Genre list is only fetched once
+	assert.Equal(t, int32(1), atomic.LoadInt32(&genreCalls))
+}
+
+func TestTMDBKeyIsNotLeaked(t *testing.T) {
+	// A v4 read access token is sent as bearer header, not in the URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("api_key"))
+		assert.Equal(t, "Bearer eyJhbGciOiJIUzI1NiJ9.e30.sig", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"results": []}`))
+	}))
+	_, err := NewTMDB(server.URL, "eyJhbGciOiJIUzI1NiJ9.e30.sig", server.Client()).Lookup(context.Background(), Query{Name: "Alien"})
+	require.NoError(t, err)
+	server.Close()
+
+	// Transport errors of a v3 key must not contain the URL with the key
+	provider := NewTMDB(server.URL, "secret", server.Client())
+	_, err = provider.Lookup(context.Background(), Query{Name: "Alien"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+
+	result := NewService([]Provider{provider}, time.Second, time.Minute, 10).Lookup(context.Background(), Query{Name: "Alien"})
+	assert.Equal(t, map[string]string{"tmdb": "Lookup failed"}, result.Errors)
+}
+
+// stubProvider zählt Aufrufe und kann künstlich verzögert werden
+type stubProvider struct {
+	name  string
+	art   string
+	delay time.Duration
+	calls int32
+}
+
+func (p *stubProvider) Name() string             { return p.name }
+func (p *stubProvider) Supports(art string) bool { return art == "" || art == p.art }
+func (p *stubProvider) Lookup(ctx context.Context, query Query) ([]Candidate, error) {
+	atomic.AddInt32(&p.calls, 1)
+	select {
+	case <-time.After(p.delay):
+		return []Candidate{{Provider: p.name, Art: p.art, Name: query.Name}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestServiceCachesAndTimesOut(t *testing.T) {
+	fast := &stubProvider{name: "fast", art: "Buch"}
+	slow := &stubProvider{name: "slow", art: "Buch", delay: time.Second}
+	other := &stubProvider{name: "other", art: "Spiel"}
+
+	service := NewService([]Provider{fast, slow, other}, 50*time.Millisecond, time.Minute, 10)
+
+	result := service.Lookup(context.Background(), Query{Art: "Buch", Name: "Momo"})
+	require.Len(t, result.Candidates, 1)
+	assert.Equal(t, "fast", result.Candidates[0].Provider)
+	assert.Contains(t, result.Errors, "slow")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&other.calls), "provider for other type must not be queried")
+
+	// Second lookup is served from the cache for the fast provider, the failed one is retried
+	service.Lookup(context.Background(), Query{Art: "Buch", Name: "momo "})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fast.calls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&slow.calls))
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := newCache(time.Minute, 2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.set("a", []Candidate{{Name: "A"}})
+	now = now.Add(time.Second)
+	c.set("b", []Candidate{{Name: "B"}})
+	c.set("c", []Candidate{{Name: "C"}}) // evicts the oldest entry
+	_, ok := c.get("a")
+	assert.False(t, ok)
+	_, ok = c.get("c")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.get("c")
+	assert.False(t, ok)
+}
@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OpenLibrary nutzt die Such-API von openlibrary.org für Bücher
+type OpenLibrary struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewOpenLibrary(baseURL string, client *http.Client) *OpenLibrary {
+	return &OpenLibrary{BaseURL: strings.TrimRight(baseURL, "/"), Client: client}
+}
+
+func (p *OpenLibrary) Name() string { return "openlibrary" }
+
+func (p *OpenLibrary) Supports(art string) bool {
+	return art == "" || art == "Buch"
+}
+
+type openLibrarySearch struct {
+	Docs []struct {
+		Key        string   `json:"key"`
+		Title      string   `json:"title"`
+		AuthorName []string `json:"author_name"`
+		Language   []string `json:"language"`
+		Subject    []string `json:"subject"`
+		ISBN       []string `json:"isbn"`
+	} `json:"docs"`
+}
+
+func (p *OpenLibrary) Lookup(ctx context.Context, query Query) ([]Candidate, error) {
+	params := url.Values{}
+	if query.ISBN != "" {
+		params.Set("isbn", query.ISBN)
+	} else {
+		params.Set("title", query.Name)
+	}
+	params.Set("limit", strconv.Itoa(maxCandidates))
+	params.Set("fields", "key,title,author_name,language,subject,isbn")
+
+	var result openLibrarySearch
+	if err := getJSON(ctx, p.Client, p.BaseURL+"/search.json?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(result.Docs))
+	for _, doc := range result.Docs {
+		candidate := Candidate{
+			Provider:   p.Name(),
+			ExternalID: doc.Key,
+			Art:        "Buch",
+			Name:       doc.Title,
+			Genre:      JoinGenres(doc.Subject),
+		}
+		if len(doc.AuthorName) > 0 {
+			candidate.Autor = strPtr(strings.Join(doc.AuthorName, ", "))
+		}
+		if len(doc.Language) > 0 {
+			candidate.Sprache = LanguageName(doc.Language[0])
+		}
+		if query.ISBN != "" {
+			candidate.ISBN = strPtr(query.ISBN)
+		} else {
+			for _, isbn := range doc.ISBN {
+				if len(isbn) == 13 {
+					candidate.ISBN = strPtr(isbn)
+					break
+				}
+			}
+		}
+		candidates = append(candidates, candidate)
+		if len(candidates) == maxCandidates {
+			break
+		}
+	}
+	return candidates, nil
+}
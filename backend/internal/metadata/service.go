@@ -0,0 +1,198 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+)
+
+// Result fasst die Kandidaten aller Provider zusammen. Fehler einzelner Provider
+// führen nicht zum Abbruch, sondern werden je Provider gemeldet.
+type Result struct {
+	Candidates []Candidate       `json:"candidates"`
+	Errors     map[string]string `json:"errors,omitempty"`
+}
+
+// Service fragt alle passenden Provider parallel ab und cached die Ergebnisse
+type Service struct {
+	providers []Provider
+	timeout   time.Duration
+	cache     *cache
+}
+
+func NewService(providers []Provider, timeout time.Duration, cacheTTL time.Duration, cacheMaxEntries int) *Service {
+	return &Service{
+		providers: providers,
+		timeout:   timeout,
+		cache:     newCache(cacheTTL, cacheMaxEntries),
+	}
+}
+
+// NewServiceFromConfig erstellt die in der Konfiguration aktivierten Provider
+func NewServiceFromConfig(cfg *config.MetadataConfig) *Service {
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	var providers []Provider
+	for _, name := range cfg.Providers {
+		switch name {
+		case "openlibrary":
+			providers = append(providers, NewOpenLibrary(cfg.OpenLibraryURL, client))
+		case "anilist":
+			providers = append(providers, NewAniList(cfg.AniListURL, client))
+		case "tmdb":
+			if cfg.TMDBAPIKey == "" {
+				log.Println("Metadata provider tmdb disabled: METADATA_TMDB_API_KEY not set")
+				continue
+			}
+			providers = append(providers, NewTMDB(cfg.TMDBURL, cfg.TMDBAPIKey, client))
+		default:
+			log.Printf("Unknown metadata provider %q ignored", name)
+		}
+	}
+
+	return NewService(providers, cfg.Timeout, cfg.CacheTTL, cfg.CacheMaxEntries)
+}
+
+// Providers liefert die Namen der aktiven Provider
+func (s *Service) Providers() []string {
+	names := make([]string, len(s.providers))
+	for i, provider := range s.providers {
+		names[i] = provider.Name()
+	}
+	return names
+}
+
+// Lookup fragt alle Provider ab, die die Produktart unterstützen
+func (s *Service) Lookup(ctx context.Context, query Query) Result {
+	type providerResult struct {
+		candidates []Candidate
+		err        error
+	}
+
+	var relevant []Provider
+	for _, provider := range s.providers {
+		if provider.Supports(query.Art) {
+			relevant = append(relevant, provider)
+		}
+	}
+
+	results := make([]providerResult, len(relevant))
+	var wg sync.WaitGroup
+	for i, provider := range relevant {
+		wg.Add(1)
+		go func(i int, provider Provider) {
+			defer wg.Done()
+			candidates, err := s.lookupProvider(ctx, provider, query)
+			results[i] = providerResult{candidates: candidates, err: err}
+		}(i, provider)
+	}
+	wg.Wait()
+
+	result := Result{Candidates: []Candidate{}}
+	for i, r := range results {
+		if r.err != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			// Fehlertexte können Interna enthalten und gehen nur ins Log
+			log.Printf("ERROR Metadata lookup %s: %v\n", relevant[i].Name(), r.err)
+			result.Errors[relevant[i].Name()] = "Lookup failed"
+			if errors.Is(r.err, context.DeadlineExceeded) {
+				result.Errors[relevant[i].Name()] = "Lookup timed out"
+			}
+			continue
+		}
+		for _, candidate := range r.candidates {
+			if query.Art == "" || candidate.Art == query.Art {
+				result.Candidates = append(result.Candidates, candidate)
+			}
+		}
+	}
+	return result
+}
+
+func (s *Service) lookupProvider(ctx context.Context, provider Provider, query Query) ([]Candidate, error) {
+	key := provider.Name() + "|" + query.Key()
+	if candidates, ok := s.cache.get(key); ok {
+		return candidates, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	candidates, err := provider.Lookup(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.set(key, candidates)
+	return candidates, nil
+}
+
+// cache ist ein einfacher TTL-Cache im Speicher. Fehlerantworten werden nicht gecached.
+type cache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	candidates []Candidate
+	expires    time.Time
+}
+
+func newCache(ttl time.Duration, maxEntries int) *cache {
+	return &cache{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]cacheEntry), now: time.Now}
+}
+
+func (c *cache) get(key string) ([]Candidate, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.candidates, true
+}
+
+func (c *cache) set(key string, candidates []Candidate) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		// Abgelaufene Einträge entfernen; reicht das nicht, den ältesten verwerfen
+		now := c.now()
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.expires.Before(oldest) {
+				oldestKey, oldest = k, entry.expires
+			}
+		}
+		if len(c.entries) >= c.maxEntries && oldestKey != "" {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = cacheEntry{candidates: candidates, expires: c.now().Add(c.ttl)}
+}
@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// TMDB nutzt die Such-API von themoviedb.org (bzw. kompatible Dienste) für Filme und Serien
+type TMDB struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+
+	mu     sync.Mutex
+	genres map[string]map[int]string // media_type -> genre id -> Name
+}
+
+func NewTMDB(baseURL string, apiKey string, client *http.Client) *TMDB {
+	return &TMDB{BaseURL: strings.TrimRight(baseURL, "/"), APIKey: apiKey, Client: client}
+}
+
+func (p *TMDB) Name() string { return "tmdb" }
+
+func (p *TMDB) Supports(art string) bool {
+	return art == "" || art == "Filmserie"
+}
+
+type tmdbSearch struct {
+	Results []struct {
+		ID               int    `json:"id"`
+		MediaType        string `json:"media_type"`
+		Title            string `json:"title"` // Filme
+		Name             string `json:"name"`  // Serien
+		OriginalLanguage string `json:"original_language"`
+		GenreIDs         []int  `json:"genre_ids"`
+	} `json:"results"`
+}
+
+type tmdbGenreList struct {
+	Genres []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"genres"`
+}
+
+// get fragt einen Endpunkt ab. Ein Lesezugriffstoken (v4, JWT) wird als Bearer-Header
+// gesendet und erscheint so in keiner URL; ein klassischer API-Schlüssel (v3) geht nur als
+// Parameter api_key.
+func (p *TMDB) get(ctx context.Context, path string, params url.Values, target interface{}) error {
+	bearer := strings.Contains(p.APIKey, ".")
+	if !bearer {
+		params.Set("api_key", p.APIKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	return doJSON(p.Client, req, target)
+}
+
+func (p *TMDB) Lookup(ctx context.Context, query Query) ([]Candidate, error) {
+	if query.Name == "" {
+		return nil, nil
+	}
+
+	var result tmdbSearch
+	if err := p.get(ctx, "/search/multi", url.Values{"query": {query.Name}}, &result); err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for _, item := range result.Results {
+		var name, art string
+		switch item.MediaType {
+		case "movie":
+			name, art = item.Title, "Film"
+		case "tv":
+			name, art = item.Name, "Serie"
+		default:
+			continue // Personen o.ä.
+		}
+
+		genreNames, err := p.genreNames(ctx, item.MediaType, item.GenreIDs)
+		if err != nil {
+			return nil, err
+		}
+		filmArt := art
+		candidates = append(candidates, Candidate{
+			Provider:   p.Name(),
+			ExternalID: fmt.Sprintf("%s/%d", item.MediaType, item.ID),
+			Art:        "Filmserie",
+			Name:       name,
+			FilmArt:    &filmArt,
+			Sprache:    LanguageName(item.OriginalLanguage),
+			Genre:      JoinGenres(genreNames),
+		})
+		if len(candidates) == maxCandidates {
+			break
+		}
+	}
+	return candidates, nil
+}
+
+// genreNames löst Genre-IDs auf. Die Genre-Listen werden einmalig je Medientyp geladen.
+func (p *TMDB) genreNames(ctx context.Context, mediaType string, ids []int) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.genres == nil {
+		p.genres = make(map[string]map[int]string)
+	}
+	byID, ok := p.genres[mediaType]
+	if !ok {
+		var list tmdbGenreList
+		if err := p.get(ctx, "/genre/"+mediaType+"/list", url.Values{}, &list); err != nil {
+			return nil, err
+		}
+		byID = make(map[int]string, len(list.Genres))
+		for _, genre := range list.Genres {
+			byID[genre.ID] = genre.Name
+		}
+		p.genres[mediaType] = byID
+	}
+
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := byID[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,80 @@
+package models
+
+// Sprachen für Fehlermeldungen und Oberfläche
+const (
+	SpracheDeutsch  = "de"
+	SpracheEnglisch = "en"
+)
+
+// Sprachen enthält alle unterstützten Sprachen
+var Sprachen = []string{SpracheDeutsch, SpracheEnglisch}
+
+// Standardsortierung von Listen (Sammlungen, Einträge einer Sammlung)
+const (
+	SortierungName    = "name"    // Alphabetisch
+	SortierungNeueste = "neueste" // Zuletzt angelegte zuerst
+)
+
+// Sortierungen enthält alle gültigen Sortierungen
+var Sortierungen = []string{SortierungName, SortierungNeueste}
+
+// Datumsformate enthält die Formate, die die Oberfläche anzeigen kann
+var Datumsformate = []string{"DD.MM.YYYY", "YYYY-MM-DD", "MM/DD/YYYY"}
+
+// Benutzereinstellungen sind Profilangaben und Vorlieben eines Benutzers. Fehlt der Eintrag,
+// gelten die Werte aus StandardEinstellungen.
+type Benutzereinstellungen struct {
+	WebuserID          string  `gorm:"column:webuser_id;primaryKey;type:varchar(255)"`
+	Anzeigename        *string `gorm:"type:varchar(255)"`  // Überschreibt den name-Claim
+	AvatarURL          *string `gorm:"type:varchar(1024)"` // http(s)
+	Sprache            *string `gorm:"type:varchar(8)"`    // nil = Accept-Language des Browsers
+	StandardSammlungID *uint   // Wird ignoriert, sobald der Benutzer keinen Zugriff mehr hat
+	Waehrung           string  `gorm:"not null;type:varchar(3)"` // ISO 4217
+	Datumsformat       string  `gorm:"not null;type:varchar(16)"`
+	Sortierung         string  `gorm:"not null;type:varchar(20)"`
+	// Privatsphäre
+	NameSichtbar        bool    `gorm:"not null"` // Name in Mitgliederlisten geteilter Sammlungen
+	EinladungenErlauben bool    `gorm:"not null"` // Andere dürfen in Sammlungen einladen
+	Webuser             Webuser `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Benutzereinstellungen) TableName() string {
+	return "benutzereinstellungen"
+}
+
+// StandardEinstellungen liefert die Einstellungen eines Benutzers, der noch nichts geändert hat
+func StandardEinstellungen(webuserID string) Benutzereinstellungen {
+	return Benutzereinstellungen{
+		WebuserID:           webuserID,
+		Waehrung:            "EUR",
+		Datumsformat:        Datumsformate[0],
+		Sortierung:          SortierungName,
+		NameSichtbar:        true,
+		EinladungenErlauben: true,
+	}
+}
+
+// enthalten prüft, ob value in values vorkommt
+func enthalten(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GueltigeSprache prüft, ob sprache unterstützt wird
+func GueltigeSprache(sprache string) bool {
+	return enthalten(Sprachen, sprache)
+}
+
+// GueltigeSortierung prüft, ob sortierung eine bekannte Sortierung ist
+func GueltigeSortierung(sortierung string) bool {
+	return enthalten(Sortierungen, sortierung)
+}
+
+// GueltigesDatumsformat prüft, ob format ein bekanntes Datumsformat ist
+func GueltigesDatumsformat(format string) bool {
+	return enthalten(Datumsformate, format)
+}
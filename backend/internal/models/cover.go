@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Cover speichert die Metadaten des Titelbildes eines Produkts. Die Bilddaten selbst
+// (Original und Vorschaubilder) liegen im Storage-Backend.
+type Cover struct {
+	ProduktID      uint      `gorm:"column:produkt_id;primaryKey;autoIncrement:false"`
+	ContentType    string    `gorm:"not null;type:varchar(50)"` // Erkannter Typ des Originals
+	Groesse        int64     `gorm:"not null"`                  // Größe des Originals in Bytes
+	Breite         int       `gorm:"not null"`
+	Hoehe          int       `gorm:"not null"`
+	Hash           string    `gorm:"not null;type:varchar(64)"` // SHA-256 des Originals, dient als ETag/Version
+	AktualisiertAm time.Time `gorm:"not null"`
+	Produkt        Produkt   `gorm:"foreignKey:ProduktID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Cover) TableName() string {
+	return "cover"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Edition beschreibt eine konkrete Ausgabe eines Produkts (z.B. Hardcover, Blu-ray, Digital)
+type Edition struct {
+	ID                uint       `gorm:"primaryKey"`
+	ProduktID         uint       `gorm:"column:produkt_id;not null;index"`
+	Format            string     `gorm:"not null;type:varchar(50)"`
+	Verlag            *string    `gorm:"type:varchar(255)"` // Verlag bzw. Publisher/Studio
+	Erscheinungsdatum *time.Time `gorm:"type:date"`
+	Code              *string    `gorm:"type:varchar(20);index"` // EAN bzw. ISBN (normalisiert)
+	Sprache           *string    `gorm:"type:varchar(50)"`
+	Produkt           Produkt    `gorm:"foreignKey:ProduktID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Edition) TableName() string {
+	return "edition"
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// EpisodeGesehen speichert, welche Episoden ein Benutzer bereits gesehen hat
+type EpisodeGesehen struct {
+	WebuserID string    `gorm:"primaryKey;column:webuser_id;type:varchar(255)"`
+	EpisodeID uint      `gorm:"primaryKey"`
+	GesehenAm time.Time `gorm:"not null"`
+	Webuser   Webuser   `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+	Episode   Episode   `gorm:"foreignKey:EpisodeID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (EpisodeGesehen) TableName() string {
+	return "episode_gesehen"
+}
@@ -4,6 +4,7 @@ type Filmserie struct {
 	ProdukteID uint    `gorm:"primaryKey"`
 	Art        *string `gorm:"type:enum_filmserie_art"`
 	Genre      *string `gorm:"type:varchar(100)"`
+	Jahr       *int    // Erscheinungsjahr, unterscheidet z.B. Neuverfilmungen mit gleichem Namen
 	Produkt    Produkt `gorm:"foreignKey:ProdukteID;references:ID;constraint:OnDelete:CASCADE"`
 }
 
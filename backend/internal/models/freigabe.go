@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Freigabe ist ein öffentlicher, nur lesender Link auf eine Sammlung. Wer das Token kennt,
+// kann die Sammlung ohne Anmeldung ansehen.
+type Freigabe struct {
+	ID          uint       `gorm:"primaryKey"`
+	SammlungID  uint       `gorm:"not null;index"`
+	Token       string     `gorm:"not null;type:varchar(64);uniqueIndex"`
+	Bezeichnung *string    `gorm:"type:varchar(255)"` // z.B. "Für Tom"
+	ErstelltAm  time.Time  `gorm:"not null"`
+	GueltigBis  *time.Time // nil = unbegrenzt gültig
+	Sammlung    Sammlung   `gorm:"foreignKey:SammlungID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Freigabe) TableName() string {
+	return "freigabe"
+}
+
+// Gueltig prüft, ob die Freigabe zum Zeitpunkt now noch verwendet werden darf
+func (f Freigabe) Gueltig(now time.Time) bool {
+	return f.GueltigBis == nil || now.Before(*f.GueltigBis)
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Rollen in einem Haushalt
+const (
+	HaushaltVerwalter = "verwalter" // verwaltet Mitglieder und Kinderprofile, Besitzerrechte an allen Haushaltssammlungen
+	HaushaltMitglied  = "mitglied"  // Bearbeiterrechte an allen Haushaltssammlungen
+)
+
+// Haushalt ist ein Mandant, dem Sammlungen und Katalogprodukte gehören können. Produkte eines
+// Haushalts sind nur für dessen Mitglieder sichtbar.
+type Haushalt struct {
+	ID         uint      `gorm:"primaryKey"`
+	Name       string    `gorm:"not null;type:varchar(255)"`
+	Gruppe     *string   `gorm:"type:varchar(255);uniqueIndex"` // Keycloak-Gruppe, deren Mitglieder automatisch beitreten
+	ErstelltAm time.Time `gorm:"not null"`
+}
+
+func (Haushalt) TableName() string {
+	return "haushalt"
+}
+
+// HaushaltMitgliedschaft ordnet einen Benutzer einem Haushalt zu
+type HaushaltMitgliedschaft struct {
+	HaushaltID uint     `gorm:"primaryKey"`
+	WebuserID  string   `gorm:"column:webuser_id;primaryKey;type:varchar(255);index"`
+	Rolle      string   `gorm:"not null;type:varchar(20)"` // HaushaltVerwalter oder HaushaltMitglied
+	AusGruppe  bool     `gorm:"not null;default:false"`    // Über die Keycloak-Gruppe beigetreten; entfällt mit der Gruppe
+	Haushalt   Haushalt `gorm:"foreignKey:HaushaltID;references:ID;constraint:OnDelete:CASCADE"`
+	Webuser    Webuser  `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (HaushaltMitgliedschaft) TableName() string {
+	return "haushalt_mitglied"
+}
+
+// Kinderprofil ist ein Profil ohne eigenes Konto, z.B. um einem Kind eigene Sammlungen zuzuordnen
+type Kinderprofil struct {
+	ID         uint      `gorm:"primaryKey"`
+	HaushaltID uint      `gorm:"not null;index"`
+	Name       string    `gorm:"not null;type:varchar(255)"`
+	ErstelltAm time.Time `gorm:"not null"`
+	Haushalt   Haushalt  `gorm:"foreignKey:HaushaltID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Kinderprofil) TableName() string {
+	return "kinderprofil"
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Status eines Hintergrundjobs
+const (
+	JobWartend        = "wartend" // Wartet auf einen Worker bzw. auf den nächsten Versuch
+	JobLaeuft         = "laeuft"
+	JobErfolgreich    = "erfolgreich"
+	JobFehlgeschlagen = "fehlgeschlagen" // Alle Versuche fehlgeschlagen
+	JobAbgebrochen    = "abgebrochen"
+)
+
+// Job ist ein persistenter Auftrag für die Worker im Serverprozess
+type Job struct {
+	ID                 uint       `gorm:"primaryKey"`
+	WebuserID          string     `gorm:"column:webuser_id;not null;type:varchar(255);index"`
+	Typ                string     `gorm:"not null;type:varchar(50)"`
+	Status             string     `gorm:"not null;type:varchar(20);index:idx_job_faellig,priority:1"`
+	Payload            string     `gorm:"not null;type:text"` // JSON, abhängig vom Typ
+	Ergebnis           *string    `gorm:"type:text"`          // JSON, nur bei Erfolg
+	Fehler             *string    `gorm:"type:text"`          // Letzter Fehler
+	Fortschritt        int        `gorm:"not null;default:0"` // 0 bis 100
+	Versuche           int        `gorm:"not null;default:0"`
+	MaxVersuche        int        `gorm:"not null;default:1"`
+	AbbruchAngefordert bool       `gorm:"not null;default:false"`
+	NaechsterVersuch   time.Time  `gorm:"not null;index:idx_job_faellig,priority:2"`
+	GesperrtBis        *time.Time // Lease des ausführenden Workers
+	ErstelltAm         time.Time  `gorm:"not null"`
+	GestartetAm        *time.Time
+	BeendetAm          *time.Time
+	Webuser            Webuser `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Job) TableName() string {
+	return "job"
+}
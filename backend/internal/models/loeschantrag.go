@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Umgang mit den vom Benutzer angelegten Katalogprodukten bei der Kontolöschung
+const (
+	ProdukteAnonymisieren = "anonymisieren" // Produkte bleiben ohne Ersteller erhalten
+	ProdukteUebertragen   = "uebertragen"   // Produkte gehen an einen anderen Benutzer über
+)
+
+// Loeschantrag ist eine beantragte Kontolöschung. Sie wird erst nach Ablauf der Frist
+// ausgeführt und kann bis dahin zurückgezogen werden.
+type Loeschantrag struct {
+	WebuserID     string    `gorm:"column:webuser_id;primaryKey;type:varchar(255)"`
+	BeantragtAm   time.Time `gorm:"not null"`
+	LoeschenAb    time.Time `gorm:"not null;index"`
+	Produkte      string    `gorm:"not null;type:varchar(20)"` // ProdukteAnonymisieren oder ProdukteUebertragen
+	UebertragenAn *string   `gorm:"column:uebertragen_an;type:varchar(255)"`
+	Webuser       Webuser   `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Loeschantrag) TableName() string {
+	return "loeschantrag"
+}
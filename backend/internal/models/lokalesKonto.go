@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// LokalesKonto enthält die Anmeldedaten eines Benutzers im lokalen Anmeldemodus
+// (AUTH_MODE=local). Name und Sammlungen stehen wie bei OIDC-Benutzern am Webuser.
+type LokalesKonto struct {
+	WebuserID           string    `gorm:"column:webuser_id;primaryKey;type:varchar(255)"`
+	Benutzername        string    `gorm:"not null;type:varchar(64);uniqueIndex"` // Kleingeschrieben
+	PasswortHash        string    `gorm:"not null;type:varchar(255)"`            // argon2id
+	Rollen              string    `gorm:"not null;type:varchar(255);default:''"` // Kommagetrennt, z.B. "admin"
+	ErstelltAm          time.Time `gorm:"not null"`
+	PasswortGeaendertAm time.Time `gorm:"not null"`
+	Webuser             Webuser   `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (LokalesKonto) TableName() string {
+	return "lokales_konto"
+}
+
+// RollenListe liefert die Rollen als Liste
+func (k LokalesKonto) RollenListe() []string {
+	if k.Rollen == "" {
+		return []string{}
+	}
+	return strings.Split(k.Rollen, ",")
+}
+
+// RefreshToken erlaubt im lokalen Modus, neue Access Tokens ohne Passwort zu holen. Jedes
+// Token wird nur einmal verwendet und dabei durch ein neues ersetzt. Gespeichert wird nur
+// der SHA-256-Hash.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey"`
+	WebuserID    string     `gorm:"column:webuser_id;not null;type:varchar(255);index"`
+	Hash         string     `gorm:"not null;type:varchar(64);uniqueIndex"`
+	ErstelltAm   time.Time  `gorm:"not null"`
+	GueltigBis   time.Time  `gorm:"not null"`
+	WiderrufenAm *time.Time // Gesetzt nach Verwendung, Abmeldung oder Passwortänderung
+	Webuser      Webuser    `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// Gueltig prüft, ob das Token zum Zeitpunkt now weder verwendet noch abgelaufen ist
+func (r RefreshToken) Gueltig(now time.Time) bool {
+	return r.WiderrufenAm == nil && now.Before(r.GueltigBis)
+}
+
+// Registrierungseinladung ist ein einmal verwendbarer Code, mit dem sich im Einladungsmodus
+// ein neues lokales Konto registrieren lässt
+type Registrierungseinladung struct {
+	ID           uint      `gorm:"primaryKey"`
+	Hash         string    `gorm:"not null;type:varchar(64);uniqueIndex"`
+	ErstelltVon  string    `gorm:"column:erstellt_von;not null;type:varchar(255);index"`
+	ErstelltAm   time.Time `gorm:"not null"`
+	GueltigBis   time.Time `gorm:"not null"`
+	EingeloestAm *time.Time
+	Ersteller    Webuser `gorm:"foreignKey:ErstelltVon;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Registrierungseinladung) TableName() string {
+	return "registrierungseinladung"
+}
+
+// Gueltig prüft, ob die Einladung zum Zeitpunkt now noch eingelöst werden kann
+func (e Registrierungseinladung) Gueltig(now time.Time) bool {
+	return e.EingeloestAm == nil && now.Before(e.GueltigBis)
+}
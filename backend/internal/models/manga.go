@@ -5,6 +5,7 @@ type Manga struct {
 	Mangaka    *string `gorm:"type:varchar(255)"`
 	Sprache    *string `gorm:"type:varchar(50)"`
 	Genre      *string `gorm:"type:varchar(100)"`
+	Baende     *int    // Anzahl der Bände der Reihe, falls bekannt
 	Produkt    Produkt `gorm:"foreignKey:ProdukteID;references:ID;constraint:OnDelete:CASCADE"`
 }
 
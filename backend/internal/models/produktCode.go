@@ -0,0 +1,17 @@
+package models
+
+// ProduktCode speichert eine normalisierte Kennung (ISBN-13 bzw. EAN-13/EAN-8) eines Produkts.
+// Eindeutig ist ein Code je Katalog: einmal im globalen Katalog und einmal je Haushalt. So kann
+// ein Haushalt ein Produkt mit einem Code anlegen, den ein anderer Haushalt schon privat nutzt.
+type ProduktCode struct {
+	ID         uint    `gorm:"primaryKey"`
+	ProduktID  uint    `gorm:"column:produkt_id;not null;index"`
+	HaushaltID *uint   `gorm:"uniqueIndex:idx_produkt_code_haushalt,priority:1,where:haushalt_id IS NOT NULL"` // Wie Produkt.HaushaltID
+	Typ        string  `gorm:"not null;type:varchar(10)"`                                                      // ISBN, EAN oder UPC
+	Code       string  `gorm:"not null;type:varchar(13);uniqueIndex:idx_produkt_code_global,where:haushalt_id IS NULL;uniqueIndex:idx_produkt_code_haushalt,priority:2,where:haushalt_id IS NOT NULL"`
+	Produkt    Produkt `gorm:"foreignKey:ProduktID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (ProduktCode) TableName() string {
+	return "produkt_code"
+}
@@ -1,11 +1,16 @@
 package models
 
 type Sammlung struct {
-	ID        uint      `gorm:"primaryKey"` // Auto-increment -> uint
-	WebuserID string    `gorm:"column:webuser_id;not null;type:varchar(255)"`
-	Name      *string   `gorm:"type:varchar(255)"`
-	Webuser   Webuser   `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
-	Produkte  []Produkt `gorm:"many2many:sammlung_produkte;"`
+	ID        uint    `gorm:"primaryKey"` // Auto-increment -> uint
+	WebuserID string  `gorm:"column:webuser_id;not null;type:varchar(255)"`
+	Name      *string `gorm:"type:varchar(255)"`
+	// Optional: Sammlung eines Haushalts bzw. eines Kinderprofils darin
+	HaushaltID     *uint         `gorm:"index"`
+	KinderprofilID *uint         `gorm:"index"`
+	Haushalt       *Haushalt     `gorm:"foreignKey:HaushaltID;references:ID;constraint:OnDelete:SET NULL" json:"-"`
+	Kinderprofil   *Kinderprofil `gorm:"foreignKey:KinderprofilID;references:ID;constraint:OnDelete:SET NULL" json:"-"`
+	Webuser        Webuser       `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+	Produkte       []Produkt     `gorm:"many2many:sammlung_produkte;"`
 }
 
 func (Sammlung) TableName() string {
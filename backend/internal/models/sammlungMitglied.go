@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Rollen in einer Sammlung, aufsteigend nach Rechten. Der Besitzer steht in Sammlung.WebuserID,
+// Mitglieder werden in SammlungMitglied geführt.
+const (
+	RolleBetrachter = "betrachter" // darf die Sammlung ansehen und exportieren
+	RolleBearbeiter = "bearbeiter" // darf zusätzlich Einträge hinzufügen, ändern, entfernen und importieren
+	RolleBesitzer   = "besitzer"   // darf zusätzlich Mitglieder verwalten, Links teilen, übertragen und löschen
+)
+
+// SammlungMitglied gibt einem weiteren Benutzer Zugriff auf eine Sammlung. Solange die
+// Einladung nicht angenommen ist (AngenommenAm nil), gewährt sie keinen Zugriff.
+type SammlungMitglied struct {
+	SammlungID   uint      `gorm:"primaryKey"`
+	WebuserID    string    `gorm:"column:webuser_id;primaryKey;type:varchar(255);index"`
+	Rolle        string    `gorm:"not null;type:varchar(20)"` // RolleBetrachter oder RolleBearbeiter
+	EingeladenAm time.Time `gorm:"not null"`
+	AngenommenAm *time.Time
+	Sammlung     Sammlung `gorm:"foreignKey:SammlungID;references:ID;constraint:OnDelete:CASCADE"`
+	Webuser      Webuser  `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (SammlungMitglied) TableName() string {
+	return "sammlung_mitglied"
+}
+
+// RolleErlaubt prüft, ob rolle mindestens die Rechte von mindestens hat
+func RolleErlaubt(rolle string, mindestens string) bool {
+	rang := map[string]int{RolleBetrachter: 1, RolleBearbeiter: 2, RolleBesitzer: 3}
+	return rang[rolle] > 0 && rang[rolle] >= rang[mindestens]
+}
@@ -1,8 +1,28 @@
 package models
 
+import "time"
+
+// Status eines Produkts in einer Sammlung (unabhängig von der Medienart)
+const (
+	StatusGeplant       = "geplant"        // z.B. "to-read", Wunschliste
+	StatusInBearbeitung = "in_bearbeitung" // wird gerade gelesen, gespielt, geschaut
+	StatusAbgeschlossen = "abgeschlossen"
+	StatusPausiert      = "pausiert"
+	StatusAbgebrochen   = "abgebrochen"
+)
+
 type SammlungProdukt struct {
-	SammlungID uint `gorm:"primaryKey"`                   // Teil des zusammengesetzten PK
-	ProduktID  uint `gorm:"primaryKey;column:produkt_id"` // Teil des zusammengesetzten PK, Spaltenname beachten
+	SammlungID uint     `gorm:"primaryKey"`                                                      // Teil des zusammengesetzten PK
+	ProduktID  uint     `gorm:"primaryKey;column:produkt_id"`                                    // Teil des zusammengesetzten PK, Spaltenname beachten
+	EditionID  *uint    `gorm:"column:edition_id"`                                               // Optional: konkrete Ausgabe des Produkts
+	Edition    *Edition `gorm:"foreignKey:EditionID;references:ID;constraint:OnDelete:SET NULL"` // Ausgabe wird bei Löschung entkoppelt
+	// Persönliche Angaben des Sammlungsbesitzers
+	Status    *string `gorm:"type:varchar(20)"` // Einer der Status-Konstanten
+	Bewertung *int    // 1 bis 5
+	// Bewertung der Quelle auf einer Skala von 1 bis 10 (MyAnimeList, Kodi); Bewertung ist
+	// daraus gerundet, damit alle Einträge vergleichbar bleiben
+	BewertungOriginal *int
+	AbgeschlossenAm   *time.Time `gorm:"type:date"`
 	// Optional: Relationen zurück, falls benötigt
 	// Sammlung   Sammlung `gorm:"foreignKey:SammlungID"`
 	// Produkt    Produkt  `gorm:"foreignKey:ProduktID"`
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type Staffel struct {
+	ID          uint      `gorm:"primaryKey"`
+	FilmserieID uint      `gorm:"column:filmserie_id;not null;uniqueIndex:idx_staffel_nummer"` // FK auf filmserie.produkte_id
+	Nummer      int       `gorm:"not null;uniqueIndex:idx_staffel_nummer"`
+	Titel       *string   `gorm:"type:varchar(255)"`
+	Filmserie   Filmserie `gorm:"foreignKey:FilmserieID;references:ProdukteID;constraint:OnDelete:CASCADE"`
+	Episoden    []Episode `gorm:"foreignKey:StaffelID;constraint:OnDelete:CASCADE"`
+}
+
+func (Staffel) TableName() string {
+	return "staffel"
+}
+
+type Episode struct {
+	ID           uint       `gorm:"primaryKey"`
+	StaffelID    uint       `gorm:"not null;uniqueIndex:idx_episode_nummer"`
+	Nummer       int        `gorm:"not null;uniqueIndex:idx_episode_nummer"`
+	Titel        *string    `gorm:"type:varchar(255)"`
+	Laufzeit     *int       // Laufzeit in Minuten
+	Ausstrahlung *time.Time `gorm:"type:date"` // Erstausstrahlung
+}
+
+func (Episode) TableName() string {
+	return "episode"
+}
@@ -1,9 +1,14 @@
 package models
 
+import "time"
+
 type Webuser struct {
-	ID         string     `gorm:"primaryKey;type:varchar(255);not null"`
-	Name       *string    `gorm:"type:varchar(255)"`    // Nullable String -> *string
-	Sammlungen []Sammlung `gorm:"foreignKey:WebuserID"` // Ein User hat viele Sammlungen
+	ID               string     `gorm:"primaryKey;type:varchar(255);not null"`
+	Name             *string    `gorm:"type:varchar(255)"`    // Nullable String -> *string
+	Anzeigename      *string    `gorm:"type:varchar(255)"`    // name-Claim, z.B. "Anna Meier"
+	Email            *string    `gorm:"type:varchar(255)"`    // email-Claim
+	ZuletztGesehenAm *time.Time `gorm:"index"`                // Gesammelt aktualisiert, siehe database.UserSync
+	Sammlungen       []Sammlung `gorm:"foreignKey:WebuserID"` // Ein User hat viele Sammlungen
 }
 
 func (Webuser) TableName() string {
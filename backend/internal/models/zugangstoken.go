@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ZugangstokenPraefix kennzeichnet persönliche Zugangstokens; daran erkennt die AuthMiddleware,
+// dass kein JWT vorliegt
+const ZugangstokenPraefix = "dpat_"
+
+// Berechtigungen eines Zugangstokens. Die Bereiche "sammlungen" (Sammlungen, Einträge,
+// Freigaben, Mitglieder) und "katalog" (Produkte, Editionen, Codes, Cover, Metadaten) erlauben
+// alle Methoden, mit ":lesen" nur lesende Anfragen.
+const (
+	ScopeVoll            = "voll"  // Alles außer der Verwaltung von Zugangstokens
+	ScopeLesen           = "lesen" // Alle lesenden Anfragen
+	ScopeSammlungen      = "sammlungen"
+	ScopeSammlungenLesen = "sammlungen:lesen"
+	ScopeKatalog         = "katalog"
+	ScopeKatalogLesen    = "katalog:lesen"
+)
+
+// Scopes enthält alle gültigen Berechtigungen
+var Scopes = []string{ScopeVoll, ScopeLesen, ScopeSammlungen, ScopeSammlungenLesen, ScopeKatalog, ScopeKatalogLesen}
+
+// Zugangstoken ist ein persönliches Token für Skripte und CLI-Werkzeuge. Gespeichert wird nur
+// der SHA-256-Hash; das Token selbst wird einmalig beim Anlegen angezeigt.
+type Zugangstoken struct {
+	ID                 uint       `gorm:"primaryKey"`
+	WebuserID          string     `gorm:"column:webuser_id;not null;type:varchar(255);index"`
+	Name               string     `gorm:"not null;type:varchar(255)"`
+	Anfang             string     `gorm:"not null;type:varchar(16)"`             // Erste Zeichen zum Wiedererkennen
+	Hash               string     `gorm:"not null;type:varchar(64);uniqueIndex"` // SHA-256 (hex)
+	Scopes             string     `gorm:"not null;type:varchar(255)"`            // Kommagetrennt
+	ErstelltAm         time.Time  `gorm:"not null"`
+	GueltigBis         *time.Time // nil = unbegrenzt gültig
+	ZuletztVerwendetAm *time.Time
+	WiderrufenAm       *time.Time
+	Webuser            Webuser `gorm:"foreignKey:WebuserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (Zugangstoken) TableName() string {
+	return "zugangstoken"
+}
+
+// Gueltig prüft, ob das Token zum Zeitpunkt now weder widerrufen noch abgelaufen ist
+func (z Zugangstoken) Gueltig(now time.Time) bool {
+	return z.WiderrufenAm == nil && (z.GueltigBis == nil || now.Before(*z.GueltigBis))
+}
+
+// ScopeListe liefert die Berechtigungen als Liste
+func (z Zugangstoken) ScopeListe() []string {
+	if z.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(z.Scopes, ",")
+}
+
+// GueltigerScope prüft, ob scope eine bekannte Berechtigung ist
+func GueltigerScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
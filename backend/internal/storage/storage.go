@@ -0,0 +1,81 @@
+// Package storage legt Binärdaten (z.B. Cover-Bilder) unter einem Schlüssel ab.
+// Es gibt ein Backend für das lokale Dateisystem und eines für S3-kompatible Dienste.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Object beschreibt ein gespeichertes Objekt
+type Object struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Store ist die gemeinsame Schnittstelle aller Backends.
+// Schlüssel sind relative Pfade wie "covers/12/small.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get liefert den Inhalt; der Aufrufer muss den Reader schließen
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete ist idempotent, ein fehlendes Objekt ist kein Fehler
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromConfig erstellt das konfigurierte Backend
+func NewFromConfig(cfg *config.StorageConfig) (Store, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required for the s3 storage backend")
+		}
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		}, &http.Client{Timeout: 30 * time.Second}), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// validateKey erlaubt nur einfache relative Pfade, damit kein Schlüssel aus dem
+// Speicherbereich ausbrechen kann
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == '/':
+		default:
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Gründe, aus denen ein Token abgelehnt wird
+var (
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+	ErrTokenIssuer      = errors.New("token issuer mismatch")
+	ErrTokenAudience    = errors.New("token not issued for this client")
+)
+
+// InitAuth richtet das gewählte Anmeldeverfahren ein. Im OIDC-Modus werden die Provider und
+// das Rollenmapping gelesen und die Signaturschlüssel im Hintergrund geladen. Ist ein Provider
+// beim Start nicht erreichbar, läuft der Server trotzdem an; Tokens dieses Providers ergeben
+// bis zum ersten erfolgreichen Laden 503. Im lokalen Modus prüft der Server seine eigenen Tokens.
+func InitAuth(ctx context.Context, authCfg *config.AuthConfig) {
+	var err error
+	Roles, err = roleMappingFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid ROLE_MAPPING: %v", err)
+	}
+
+	switch authCfg.Mode {
+	case config.AuthModeLocal:
+		if err := InitLocalAuth(authCfg); err != nil {
+			log.Fatalf("Invalid local auth configuration: %v", err)
+		}
+		log.Printf("Using local accounts (registration: %s)", authCfg.Registration)
+		return
+	case config.AuthModeOIDC:
+	default:
+		log.Fatalf("Invalid AUTH_MODE %q, expected %q or %q", authCfg.Mode, config.AuthModeOIDC, config.AuthModeLocal)
+	}
+
+	Local = nil
+	configs, err := LoadProviderConfigs()
+	if err != nil {
+		log.Fatalf("Invalid OIDC configuration: %v", err)
+	}
+
+	opts := JWKSOptions{
+		RefreshInterval:  envDuration(envKey("JWKS_REFRESH_INTERVAL"), time.Hour),
+		RefreshRateLimit: envDuration(envKey("JWKS_REFRESH_RATE_LIMIT"), time.Minute),
+		BackoffBase:      time.Second,
+		BackoffMax:       envDuration(envKey("JWKS_BACKOFF_MAX"), time.Minute),
+	}
+	providers = nil
+	for _, cfg := range configs {
+		if cfg.SkipAudience {
+			log.Printf("WARNING: audience check disabled for issuer %s", cfg.Issuer)
+		}
+		p := &provider{cfg: cfg, keys: newJWKSHolder(cfg.Issuer)}
+		providers = append(providers, p)
+		go loadJWKS(ctx, p.keys, cfg, opts)
+	}
+}
+
+// envKey wählt zwischen OIDC_<name> und dem älteren KEYCLOAK_<name>
+func envKey(name string) string {
+	if os.Getenv("OIDC_"+name) == "" && os.Getenv("KEYCLOAK_"+name) != "" {
+		return "KEYCLOAK_" + name
+	}
+	return "OIDC_" + name
+}
+
+// OptionalAuth führt middleware nur aus, wenn der Request einen Authorization-Header hat.
+// Für öffentliche Routen, die angemeldeten Benutzern zusätzlich mehr zeigen.
+func OptionalAuth(middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		middleware(c)
+	}
+}
+
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Authentication
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":      "authorization_header_missing",
+				"message":    "Authorization header is required",
+				"statusCode": http.StatusUnauthorized,
+			})
+			return
+		}
+
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+		// Persönliche Zugangstokens werden in der Datenbank geprüft
+		if strings.HasPrefix(tokenString, models.ZugangstokenPraefix) {
+			db, ok := c.MustGet("db").(*gorm.DB)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error":      "database_error",
+					"message":    "Internal server error",
+					"statusCode": http.StatusInternalServerError,
+				})
+				return
+			}
+			authenticateZugangstoken(c, db, tokenString)
+			return
+		}
+
+		p := tokenProvider(tokenString)
+		if p == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":      "invalid_token",
+				"message":    "Invalid or expired authentication token",
+				"statusCode": http.StatusUnauthorized,
+			})
+			return
+		}
+		keyfunc := p.keyfunc()
+		if keyfunc == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":      "auth_unavailable",
+				"message":    "Authentication keys are not available yet, please retry later",
+				"statusCode": http.StatusServiceUnavailable,
+			})
+			return
+		}
+
+		claims, err := ValidateToken(tokenString, keyfunc, p.cfg, time.Now())
+		if err != nil {
+			log.Printf("Rejected token: %v", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":      "invalid_token",
+				"message":    "Invalid or expired authentication token",
+				"statusCode": http.StatusUnauthorized,
+			})
+			return
+		}
+		mapping := p.cfg.Claims
+
+		// Extract user ID
+		keycloakUserID := p.cfg.UserID(claims)
+		if keycloakUserID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":      "invalid_user_id",
+				"message":    "Token missing valid user identifier",
+				"statusCode": http.StatusUnauthorized,
+			})
+			return
+		}
+
+		// Extract user name
+		email := claimString(lookupClaim(claims, mapping.Email))
+		nameToSync := "Unknown"
+		if name := claimString(lookupClaim(claims, mapping.Username)); name != "" {
+			nameToSync = name
+		} else if email != "" {
+			nameToSync = strings.Split(email, "@")[0]
+		}
+
+		// Get DB from context
+		db, ok := c.MustGet("db").(*gorm.DB)
+		if !ok {
+			log.Printf("Invalid database connection in context for user %s", keycloakUserID)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "database_error",
+				"message":    "Internal server error",
+				"statusCode": http.StatusInternalServerError,
+			})
+			return
+		}
+
+		// Sync user and household memberships; only written when the claims changed
+		profile := database.UserProfile{
+			ID:          keycloakUserID,
+			Name:        nameToSync,
+			Anzeigename: claimString(lookupClaim(claims, mapping.DisplayName)),
+			Email:       email,
+			Gruppen:     stringClaims(lookupClaim(claims, mapping.Groups)),
+		}
+		if err := database.Users.Sync(db, profile); err != nil {
+			log.Printf("User sync failed for %s: %v", keycloakUserID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "user_sync_failed",
+				"message":    "Could not process user information",
+				"statusCode": http.StatusInternalServerError,
+			})
+			return
+		}
+
+		// Set user context
+		realmRoles, clientRoles := mapping.roles(claims, p.cfg.ClientID)
+		c.Set("userId", keycloakUserID)
+		c.Set("userName", nameToSync)
+		c.Set("realmRoles", realmRoles)
+		c.Set("clientRoles", clientRoles)
+		c.Set("roles", Roles.Map(realmRoles, clientRoles))
+		if haushalt := claimString(claims["haushalt"]); haushalt != "" {
+			c.Set("haushaltClaim", haushalt)
+		}
+
+		c.Next()
+	}
+}
+
+// tokenProvider wählt anhand des (noch ungeprüften) iss-Claims den zuständigen Provider
+func tokenProvider(tokenString string) *provider {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil
+	}
+	issuer, _ := claims["iss"].(string)
+	return providerFor(issuer)
+}
+
+// ValidateToken prüft Signatur und Verfahren des Tokens sowie exp (Pflicht), nbf, iat, iss und
+// aud bzw. azp gegen die Konfiguration. Ohne Client-ID wird jedes Token abgelehnt, außer die
+// Prüfung ist mit cfg.SkipAudience ausdrücklich abgeschaltet. Zeitangaben werden mit cfg.ClockSkew Toleranz geprüft.
+func ValidateToken(tokenString string, keyfunc jwt.Keyfunc, cfg ProviderConfig, now time.Time) (jwt.MapClaims, error) {
+	// Die Zeitprüfung von jwt-go kennt keine Toleranz, daher unten selbst
+	parser := jwt.Parser{ValidMethods: cfg.Algorithms, SkipClaimsValidation: true}
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(tokenString, claims, keyfunc); err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyExpiresAt(now.Add(-cfg.ClockSkew).Unix(), true) {
+		return nil, ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now.Add(cfg.ClockSkew).Unix(), false) ||
+		!claims.VerifyIssuedAt(now.Add(cfg.ClockSkew).Unix(), false) {
+		return nil, ErrTokenNotYetValid
+	}
+	if issuer, _ := claims["iss"].(string); issuer == "" || !sameIssuer(issuer, cfg.Issuer) {
+		return nil, ErrTokenIssuer
+	}
+	if cfg.SkipAudience {
+		return claims, nil
+	}
+	if cfg.ClientID == "" {
+		return nil, ErrTokenAudience
+	}
+	if !claims.VerifyAudience(cfg.ClientID, true) {
+		// Keycloak setzt aud für Access Tokens oft nur auf "account", der Client steht dann in azp
+		if azp, _ := claims["azp"].(string); azp != cfg.ClientID {
+			return nil, ErrTokenAudience
+		}
+	}
+	return claims, nil
+}
+
+// envDuration liest Werte wie "30s" oder "1h"; ungültige Werte ergeben den Standardwert
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// stringClaims liest einen Claim, der eine Liste von Strings enthält (z.B. groups)
+func stringClaims(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// claimString liest einen Claim als String; Zahlen werden ohne Nachkommastellen formatiert
+func claimString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MicahParks/keyfunc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testIssuer = "https://auth.example.org/realms/diplodocu"
+
+// jwksBody erzeugt ein JWKS-Dokument mit dem öffentlichen Schlüssel
+func jwksBody(t *testing.T, key *rsa.PrivateKey, kid string) []byte {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	body, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   encode(key.PublicKey.N.Bytes()),
+			"e":   encode(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+// jwksServer stellt den öffentlichen Schlüssel wie Keycloaks certs-Endpunkt bereit
+func jwksServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	body := jwksBody(t, key, kid)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type tokenSigner struct {
+	t   *testing.T
+	key *rsa.PrivateKey
+	kid string
+}
+
+func (s tokenSigner) sign(method jwt.SigningMethod, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = s.kid
+	var key interface{} = s.key
+	if method == jwt.SigningMethodHS256 {
+		// Klassischer Algorithmus-Verwechslungsangriff: öffentlicher Schlüssel als HMAC-Secret
+		key = s.key.PublicKey.N.Bytes()
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(s.t, err)
+	return signed
+}
+
+func setupTestJWKS(t *testing.T) (tokenSigner, jwt.Keyfunc, ProviderConfig) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := jwksServer(t, key, "test-key")
+	set, err := keyfunc.Get(server.URL, keyfunc.Options{})
+	require.NoError(t, err)
+
+	cfg := ProviderConfig{
+		Issuer:     testIssuer,
+		ClientID:   "diplodocu",
+		JwksURI:    server.URL,
+		Algorithms: []string{"RS256"},
+		ClockSkew:  30 * time.Second,
+		Claims:     DefaultClaimMapping,
+	}
+	return tokenSigner{t: t, key: key, kid: "test-key"}, set.Keyfunc, cfg
+}
+
+func validClaims(now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":                "user-1",
+		"iss":                testIssuer,
+		"aud":                "diplodocu",
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nbf":                now.Unix(),
+		"preferred_username": "anna",
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	signer, keyfunc, cfg := setupTestJWKS(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		modify  func(claims jwt.MapClaims)
+		err     error // Erwarteter Ablehnungsgrund
+		invalid bool  // Abgelehnt mit beliebigem Fehler
+	}{
+		{name: "valid token", modify: func(jwt.MapClaims) {}},
+		{name: "audience list", modify: func(c jwt.MapClaims) { c["aud"] = []interface{}{"account", "diplodocu"} }},
+		{name: "authorized party instead of audience", modify: func(c jwt.MapClaims) { c["aud"] = "account"; c["azp"] = "diplodocu" }},
+		{name: "expired within skew", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() }},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, err: ErrTokenExpired},
+		{name: "missing expiry", modify: func(c jwt.MapClaims) { delete(c, "exp") }, err: ErrTokenExpired},
+		{name: "not before within skew", modify: func(c jwt.MapClaims) { c["nbf"] = now.Add(10 * time.Second).Unix() }},
+		{name: "not yet valid", modify: func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() }, err: ErrTokenNotYetValid},
+		{name: "issued in the future", modify: func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() }, err: ErrTokenNotYetValid},
+		{name: "other issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://auth.example.org/realms/other" }, err: ErrTokenIssuer},
+		{name: "missing issuer", modify: func(c jwt.MapClaims) { delete(c, "iss") }, err: ErrTokenIssuer},
+		{name: "other client", modify: func(c jwt.MapClaims) { c["aud"] = "account"; c["azp"] = "other-app" }, err: ErrTokenAudience},
+		{name: "missing audience", modify: func(c jwt.MapClaims) { delete(c, "aud") }, err: ErrTokenAudience},
+		{name: "disallowed algorithm", method: jwt.SigningMethodRS512, modify: func(jwt.MapClaims) {}, invalid: true},
+		{name: "hmac with public key", method: jwt.SigningMethodHS256, modify: func(jwt.MapClaims) {}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(now)
+			tt.modify(claims)
+			method := tt.method
+			if method == nil {
+				method = jwt.SigningMethodRS256
+			}
+
+			result, err := ValidateToken(signer.sign(method, claims), keyfunc, cfg, now)
+			switch {
+			case tt.err != nil:
+				assert.ErrorIs(t, err, tt.err)
+			case tt.invalid:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "user-1", result["sub"])
+			}
+		})
+	}
+
+	t.Run("unsigned token", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(now)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = ValidateToken(token, keyfunc, cfg, now)
+		assert.Error(t, err)
+	})
+
+	t.Run("foreign signing key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		forged := tokenSigner{t: t, key: other, kid: "test-key"}.sign(jwt.SigningMethodRS256, validClaims(now))
+		_, err = ValidateToken(forged, keyfunc, cfg, now)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejected without client id", func(t *testing.T) {
+		open := cfg
+		open.ClientID = ""
+		_, err := ValidateToken(signer.sign(jwt.SigningMethodRS256, validClaims(now)), keyfunc, open, now)
+		assert.ErrorIs(t, err, ErrTokenAudience)
+	})
+
+	t.Run("audience not checked when explicitly skipped", func(t *testing.T) {
+		claims := validClaims(now)
+		claims["aud"] = "account"
+		open := cfg
+		open.ClientID = ""
+		open.SkipAudience = true
+		_, err := ValidateToken(signer.sign(jwt.SigningMethodRS256, claims), keyfunc, open, now)
+		assert.NoError(t, err)
+	})
+}
+
+// useProviders ersetzt die konfigurierten Provider für die Dauer eines Tests
+func useProviders(t *testing.T, configs ...ProviderConfig) []*provider {
+	previousProviders, previousRoles := providers, Roles
+	t.Cleanup(func() { providers, Roles = previousProviders, previousRoles })
+	providers = nil
+	for _, cfg := range configs {
+		providers = append(providers, &provider{cfg: cfg, keys: newJWKSHolder(cfg.Issuer)})
+	}
+	return providers
+}
+
+// useUserSync ersetzt den Benutzer-Cache, damit sich Tests mit eigener Datenbank nicht beeinflussen
+func useUserSync(t *testing.T) {
+	previous := database.Users
+	t.Cleanup(func() { database.Users = previous })
+	database.Users = database.NewUserSync(time.Minute)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	useUserSync(t)
+	signer, _, cfg := setupTestJWKS(t)
+	set, err := keyfunc.Get(cfg.JwksURI, keyfunc.Options{})
+	require.NoError(t, err)
+
+	// Second issuer like Authentik: trailing slash, roles and username from other claims
+	otherSigner, _, otherCfg := setupTestJWKS(t)
+	otherCfg.Issuer = "https://authentik.example.org/application/o/diplodocu/"
+	otherCfg.Claims = ClaimMapping{UserID: "sub", Username: "nickname", RealmRoles: "groups"}
+	otherCfg.UserIDPrefix = UserIDPrefixFor(otherCfg.Issuer)
+	otherSet, err := keyfunc.Get(otherCfg.JwksURI, keyfunc.Options{})
+	require.NoError(t, err)
+
+	configured := useProviders(t, cfg, otherCfg)
+	configured[1].keys.set(otherSet)
+	Roles = RoleMapping{RoleAdmin: {"admin", "diplodocu admins"}}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.GET("/me", AuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetString("userId"), "userName": c.GetString("userName"), "roles": c.GetStringSlice("roles")})
+	})
+	call := func(token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without keys protected routes are unavailable instead of failing hard
+	w := call(signer.sign(jwt.SigningMethodRS256, validClaims(time.Now())))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	configured[0].keys.set(set)
+
+	now := time.Now()
+	claims := validClaims(now)
+	claims["realm_access"] = map[string]interface{}{"roles": []interface{}{"admin"}}
+	claims["name"] = "Anna Meier"
+	claims["email"] = "anna@example.org"
+	w = call(signer.sign(jwt.SigningMethodRS256, claims))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"userId":"user-1","userName":"anna","roles":["admin"]}`, w.Body.String())
+	var anna models.Webuser
+	require.NoError(t, db.Where("id = ?", "user-1").First(&anna).Error)
+	assert.Equal(t, "anna", *anna.Name)
+	assert.Equal(t, "Anna Meier", *anna.Anzeigename)
+	assert.Equal(t, "anna@example.org", *anna.Email)
+
+	claims = validClaims(now)
+	claims["iss"] = otherCfg.Issuer
+	claims["nickname"] = "ben"
+	claims["groups"] = []interface{}{"diplodocu admins"}
+	w = call(otherSigner.sign(jwt.SigningMethodRS256, claims))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	// Same sub as Anna at the first issuer, but a different user
+	benID := otherCfg.UserIDPrefix + "user-1"
+	assert.JSONEq(t, `{"userId":"`+benID+`","userName":"ben","roles":["admin"]}`, w.Body.String())
+	var count int64
+	require.NoError(t, db.Model(&models.Webuser{}).Where("id IN ?", []string{"user-1", benID}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// Keys of one issuer do not validate tokens claiming another
+	claims["iss"] = testIssuer
+	assert.Equal(t, http.StatusUnauthorized, call(otherSigner.sign(jwt.SigningMethodRS256, claims)).Code)
+	claims["iss"] = "https://evil.example.org"
+	assert.Equal(t, http.StatusUnauthorized, call(otherSigner.sign(jwt.SigningMethodRS256, claims)).Code)
+
+	claims = validClaims(now)
+	claims["azp"], claims["aud"] = "other-app", "other-app"
+	assert.Equal(t, http.StatusUnauthorized, call(signer.sign(jwt.SigningMethodRS256, claims)).Code)
+	assert.Equal(t, http.StatusUnauthorized, call("").Code)
+	assert.Equal(t, http.StatusUnauthorized, call("not-a-token").Code)
+}
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", OptionalAuth(func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	call := func(authorization string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(""), "anonymous requests skip the middleware")
+	assert.Equal(t, http.StatusUnauthorized, call("Bearer abc"))
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// Code-Typen für Produktkennungen
+const (
+	CodeTypeISBN = "ISBN"
+	CodeTypeEAN  = "EAN"
+	CodeTypeUPC  = "UPC"
+)
+
+var (
+	ErrInvalidCodeFormat   = errors.New("code must consist of 8, 10, 12 or 13 digits")
+	ErrInvalidCodeChecksum = errors.New("code has an invalid check digit")
+	ErrNotAnISBN           = errors.New("code is not a valid ISBN-10 or ISBN-13")
+)
+
+// cleanCode entfernt Bindestriche und Leerzeichen, wie sie auf Barcodes und in ISBNs üblich sind
+func cleanCode(code string) string {
+	replacer := strings.NewReplacer("-", "", " ", "", "\t", "")
+	return strings.ToUpper(replacer.Replace(strings.TrimSpace(code)))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// gtinCheckDigit berechnet die Prüfziffer für EAN-8, UPC-A und EAN-13 (Gewichtung 3/1 von rechts)
+func gtinCheckDigit(payload string) byte {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		digit := int(payload[i] - '0')
+		if (len(payload)-1-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func validGTIN(code string) bool {
+	return isDigits(code) && gtinCheckDigit(code[:len(code)-1]) == code[len(code)-1]
+}
+
+// isbn10CheckDigit berechnet die Prüfziffer (0-9 oder X) einer ISBN-10
+func isbn10CheckDigit(payload string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(payload[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// NormalizeISBN prüft eine ISBN-10 oder ISBN-13 und gibt sie als ISBN-13 zurück
+func NormalizeISBN(code string) (string, error) {
+	cleaned := cleanCode(code)
+	switch len(cleaned) {
+	case 10:
+		payload := cleaned[:9]
+		if !isDigits(payload) {
+			return "", ErrNotAnISBN
+		}
+		if isbn10CheckDigit(payload) != cleaned[9] {
+			return "", ErrInvalidCodeChecksum
+		}
+		isbn13 := "978" + payload
+		return isbn13 + string(gtinCheckDigit(isbn13)), nil
+	case 13:
+		if !isDigits(cleaned) || !(strings.HasPrefix(cleaned, "978") || strings.HasPrefix(cleaned, "979")) {
+			return "", ErrNotAnISBN
+		}
+		if !validGTIN(cleaned) {
+			return "", ErrInvalidCodeChecksum
+		}
+		return cleaned, nil
+	default:
+		return "", ErrNotAnISBN
+	}
+}
+
+// ISBN13To10 wandelt eine ISBN-13 mit Präfix 978 in eine ISBN-10 um.
+// ISBNs mit Präfix 979 haben keine ISBN-10-Entsprechung.
+func ISBN13To10(isbn13 string) (string, bool) {
+	normalized, err := NormalizeISBN(isbn13)
+	if err != nil || !strings.HasPrefix(normalized, "978") {
+		return "", false
+	}
+	payload := normalized[3:12]
+	return payload + string(isbn10CheckDigit(payload)), true
+}
+
+// NormalizeEAN prüft einen EAN-8, UPC-A (12 Stellen) oder EAN-13 Code.
+// UPC-A wird mit führender Null auf EAN-13 gebracht, damit beide Schreibweisen
+// auf denselben gespeicherten Wert abgebildet werden. Zurückgegeben wird auch der erkannte Typ.
+func NormalizeEAN(code string) (string, string, error) {
+	cleaned := cleanCode(code)
+	if !isDigits(cleaned) {
+		return "", "", ErrInvalidCodeFormat
+	}
+	switch len(cleaned) {
+	case 8, 13:
+		if !validGTIN(cleaned) {
+			return "", "", ErrInvalidCodeChecksum
+		}
+		return cleaned, CodeTypeEAN, nil
+	case 12:
+		if !validGTIN(cleaned) {
+			return "", "", ErrInvalidCodeChecksum
+		}
+		return "0" + cleaned, CodeTypeUPC, nil
+	default:
+		return "", "", ErrInvalidCodeFormat
+	}
+}
+
+// NormalizeCode erkennt den Typ eines beliebigen gescannten Codes und normalisiert ihn.
+// EAN-13 mit Präfix 978/979 (Bookland) gelten als ISBN.
+func NormalizeCode(code string) (string, string, error) {
+	cleaned := cleanCode(code)
+	if len(cleaned) == 10 {
+		normalized, err := NormalizeISBN(cleaned)
+		return normalized, CodeTypeISBN, err
+	}
+	normalized, codeType, err := NormalizeEAN(cleaned)
+	if err != nil {
+		return "", "", err
+	}
+	if codeType == CodeTypeEAN && (strings.HasPrefix(normalized, "978") || strings.HasPrefix(normalized, "979")) && len(normalized) == 13 {
+		codeType = CodeTypeISBN
+	}
+	return normalized, codeType, nil
+}
+
+// NormalizeCodeForArt normalisiert einen Code passend zur Produktart:
+// Bücher und Mangas erwarten eine ISBN, Spiele und Filme/Serien einen EAN/UPC.
+func NormalizeCodeForArt(art string, code string) (string, string, error) {
+	switch art {
+	case "Buch", "Manga":
+		normalized, err := NormalizeISBN(code)
+		return normalized, CodeTypeISBN, err
+	default:
+		return NormalizeEAN(code)
+	}
+}
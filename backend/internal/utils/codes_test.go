@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "isbn-10 is converted to isbn-13", input: "0-306-40615-2", expected: "9780306406157"},
+		{name: "isbn-10 with X check digit", input: "0-8044-2957-x", expected: "9780804429573"},
+		{name: "isbn-13 with hyphens", input: "978-0-306-40615-7", expected: "9780306406157"},
+		{name: "isbn-13 with 979 prefix", input: "979-10-90636-07-1", expected: "9791090636071"},
+		{name: "wrong isbn-10 check digit", input: "0306406153", err: ErrInvalidCodeChecksum},
+		{name: "wrong isbn-13 check digit", input: "9780306406158", err: ErrInvalidCodeChecksum},
+		{name: "ean without bookland prefix", input: "4006381333931", err: ErrNotAnISBN},
+		{name: "wrong length", input: "12345", err: ErrNotAnISBN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := NormalizeISBN(tt.input)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+
+func TestISBN13To10(t *testing.T) {
+	isbn10, ok := ISBN13To10("9780306406157")
+	assert.True(t, ok)
+	assert.Equal(t, "0306406152", isbn10)
+
+	isbn10, ok = ISBN13To10("9780804429573")
+	assert.True(t, ok)
+	assert.Equal(t, "080442957X", isbn10)
+
+	_, ok = ISBN13To10("9791090636071")
+	assert.False(t, ok)
+}
+
+func TestNormalizeEAN(t *testing.T) {
+	normalized, codeType, err := NormalizeEAN("4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, "4006381333931", normalized)
+	assert.Equal(t, CodeTypeEAN, codeType)
+
+	// UPC-A maps onto the same EAN-13 value
+	normalized, codeType, err = NormalizeEAN("0 36000 29145 2")
+	require.NoError(t, err)
+	assert.Equal(t, "0036000291452", normalized)
+	assert.Equal(t, CodeTypeUPC, codeType)
+
+	normalized, _, err = NormalizeEAN("96385074")
+	require.NoError(t, err)
+	assert.Equal(t, "96385074", normalized)
+
+	_, _, err = NormalizeEAN("4006381333932")
+	assert.ErrorIs(t, err, ErrInvalidCodeChecksum)
+
+	_, _, err = NormalizeEAN("ABC")
+	assert.ErrorIs(t, err, ErrInvalidCodeFormat)
+}
+
+func TestNormalizeCode(t *testing.T) {
+	normalized, codeType, err := NormalizeCode("0306406152")
+	require.NoError(t, err)
+	assert.Equal(t, "9780306406157", normalized)
+	assert.Equal(t, CodeTypeISBN, codeType)
+
+	_, codeType, err = NormalizeCode("9780306406157")
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeISBN, codeType)
+
+	_, codeType, err = NormalizeCode("036000291452")
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeUPC, codeType)
+}
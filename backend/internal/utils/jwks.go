@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc"
+)
+
+// JWKSStatus beschreibt den Zustand der Signaturschlüssel eines Providers für Health-Checks
+type JWKSStatus struct {
+	Issuer        string     `json:"issuer"`
+	Ready         bool       `json:"ready"`
+	URI           string     `json:"uri,omitempty"` // Leer, solange die Discovery aussteht
+	Attempts      int        `json:"attempts"`      // Fehlgeschlagene Ladeversuche seit dem letzten Erfolg
+	LastRefreshAt *time.Time `json:"lastRefreshAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorAt   *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// jwksHolder hält die aktuell geladenen Schlüssel eines Providers. Bis zum ersten
+// erfolgreichen Laden ist jwks nil und Tokens dieses Providers ergeben 503.
+type jwksHolder struct {
+	mu     sync.RWMutex
+	jwks   *keyfunc.JWKS
+	status JWKSStatus
+}
+
+func newJWKSHolder(issuer string) *jwksHolder {
+	return &jwksHolder{status: JWKSStatus{Issuer: issuer}}
+}
+
+// current liefert die geladenen Schlüssel oder nil
+func (h *jwksHolder) current() *keyfunc.JWKS {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.jwks
+}
+
+func (h *jwksHolder) set(jwks *keyfunc.JWKS) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.jwks = jwks
+	h.status.Ready = jwks != nil
+}
+
+func (h *jwksHolder) setURI(uri string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.URI = uri
+}
+
+func (h *jwksHolder) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.status.LastRefreshAt = &now
+	h.status.Attempts = 0
+}
+
+func (h *jwksHolder) recordError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.status.LastError = err.Error()
+	h.status.LastErrorAt = &now
+	h.status.Attempts++
+}
+
+// state liefert eine Kopie des aktuellen Zustands
+func (h *jwksHolder) state() JWKSStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.status
+}
+
+// JWKSStates liefert den Schlüsselzustand aller Provider
+func JWKSStates() []JWKSStatus {
+	states := make([]JWKSStatus, 0, len(providers))
+	for _, p := range providers {
+		if p.keys != nil {
+			states = append(states, p.keys.state())
+		}
+	}
+	return states
+}
+
+// JWKSOptions steuert Laden und Aktualisieren der Schlüssel
+type JWKSOptions struct {
+	RefreshInterval  time.Duration // Regelmäßige Aktualisierung, z.B. nach Schlüsselrotation
+	RefreshRateLimit time.Duration // Mindestabstand von Aktualisierungen wegen unbekannter kid
+	BackoffBase      time.Duration // Wartezeit nach dem ersten Fehlschlag beim Start, verdoppelt sich
+	BackoffMax       time.Duration
+}
+
+// loadJWKS ermittelt die JWKS-URL (falls nötig per Discovery) und lädt die Schlüssel im
+// Hintergrund, bis es gelingt oder ctx endet. Danach aktualisiert keyfunc sie regelmäßig und
+// sofort (höchstens alle RefreshRateLimit), wenn ein Token eine unbekannte kid trägt.
+func loadJWKS(ctx context.Context, holder *jwksHolder, cfg ProviderConfig, opts JWKSOptions) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	uri := cfg.JwksURI
+	if uri != "" {
+		holder.setURI(uri)
+	}
+
+	wait := opts.BackoffBase
+	for {
+		var err error
+		if uri == "" {
+			uri, err = discoverJWKSURI(ctx, client, cfg.Issuer)
+			if err == nil {
+				holder.setURI(uri)
+			}
+		}
+		if err == nil {
+			var jwks *keyfunc.JWKS
+			jwks, err = keyfunc.Get(uri, holder.options(ctx, uri, opts))
+			if err == nil {
+				holder.set(jwks)
+				log.Printf("JWKS for %s loaded from %s", cfg.Issuer, uri)
+				go func() {
+					<-ctx.Done()
+					jwks.EndBackground()
+				}()
+				return
+			}
+		}
+		holder.recordError(err)
+		log.Printf("ERROR loading JWKS for %s (retrying in %s): %v\n", cfg.Issuer, wait, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > opts.BackoffMax {
+			wait = opts.BackoffMax
+		}
+	}
+}
+
+func (h *jwksHolder) options(ctx context.Context, uri string, opts JWKSOptions) keyfunc.Options {
+	return keyfunc.Options{
+		Ctx:               ctx,
+		RefreshInterval:   opts.RefreshInterval,
+		RefreshRateLimit:  opts.RefreshRateLimit,
+		RefreshTimeout:    10 * time.Second,
+		RefreshUnknownKID: true,
+		RefreshErrorHandler: func(err error) {
+			log.Printf("ERROR refreshing JWKS from %s: %v\n", uri, err)
+			h.recordError(err)
+		},
+		// Erfolgreiche Abrufe für den Health-Check festhalten
+		ResponseExtractor: func(ctx context.Context, resp *http.Response) (json.RawMessage, error) {
+			raw, err := keyfunc.ResponseExtractorStatusOK(ctx, resp)
+			if err == nil {
+				h.recordSuccess()
+			}
+			return raw, err
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatingJWKS liefert zunächst Fehler, danach den jeweils aktuellen Schlüssel
+type rotatingJWKS struct {
+	mu       sync.Mutex
+	failures int32
+	requests atomic.Int32
+	body     []byte
+}
+
+func (r *rotatingJWKS) rotate(t *testing.T, key *rsa.PrivateKey, kid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.body = jwksBody(t, key, kid)
+}
+
+func (r *rotatingJWKS) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.requests.Add(1) <= r.failures {
+		http.Error(w, "keycloak starting", http.StatusServiceUnavailable)
+		return
+	}
+	r.mu.Lock()
+	body := r.body
+	r.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func TestLoadJWKSRetriesAndRefreshesUnknownKID(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	upstream := &rotatingJWKS{failures: 2}
+	upstream.rotate(t, first, "key-1")
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	holder := newJWKSHolder(testIssuer)
+	go loadJWKS(ctx, holder, ProviderConfig{Issuer: testIssuer, JwksURI: server.URL}, JWKSOptions{
+		RefreshInterval:  time.Hour,
+		RefreshRateLimit: 10 * time.Millisecond,
+		BackoffBase:      10 * time.Millisecond,
+		BackoffMax:       20 * time.Millisecond,
+	})
+
+	require.Eventually(t, func() bool { return holder.current() != nil }, 5*time.Second, 5*time.Millisecond)
+	status := holder.state()
+	assert.True(t, status.Ready)
+	assert.Equal(t, server.URL, status.URI)
+	assert.Equal(t, 0, status.Attempts)
+	assert.NotNil(t, status.LastRefreshAt)
+	assert.NotEmpty(t, status.LastError, "failed attempts before the first success are reported")
+	assert.Equal(t, int32(3), upstream.requests.Load())
+
+	cfg := ProviderConfig{Issuer: testIssuer, ClientID: "diplodocu", Algorithms: []string{"RS256"}, ClockSkew: time.Second}
+	token := tokenSigner{t: t, key: first, kid: "key-1"}.sign(jwt.SigningMethodRS256, validClaims(time.Now()))
+	_, err = ValidateToken(token, holder.current().Keyfunc, cfg, time.Now())
+	require.NoError(t, err)
+
+	// After a key rotation an unknown kid triggers a refresh
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	upstream.rotate(t, second, "key-2")
+	time.Sleep(20 * time.Millisecond) // Rate limit of the previous refresh
+	token = tokenSigner{t: t, key: second, kid: "key-2"}.sign(jwt.SigningMethodRS256, validClaims(time.Now()))
+	_, err = ValidateToken(token, holder.current().Keyfunc, cfg, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), upstream.requests.Load())
+}
+
+func TestLoadJWKSRetriesDiscoveryUntilCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	holder := newJWKSHolder(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		loadJWKS(ctx, holder, ProviderConfig{Issuer: server.URL}, JWKSOptions{BackoffBase: 10 * time.Millisecond, BackoffMax: 10 * time.Millisecond})
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return holder.state().Attempts >= 2 }, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("loadJWKS did not stop after cancel")
+	}
+	status := holder.state()
+	assert.False(t, status.Ready)
+	assert.NotEmpty(t, status.LastError)
+	assert.Nil(t, holder.current())
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+)
+
+// localAudience ist aud der lokal ausgestellten Access Tokens
+const localAudience = "diplodocu"
+
+// localSecretMinLength ist die Mindestlänge des HMAC-Schlüssels (256 Bit)
+const localSecretMinLength = 32
+
+// Local ist die Konfiguration des lokalen Anmeldemodus; nil im OIDC-Modus
+var Local *config.AuthConfig
+
+// LocalMode meldet, ob der Server seine Benutzer selbst verwaltet
+func LocalMode() bool {
+	return Local != nil
+}
+
+// InitLocalAuth richtet den lokalen Modus ein: der Server ist einziger vertrauenswürdiger
+// Issuer und prüft seine mit HS256 signierten Tokens selbst
+func InitLocalAuth(cfg *config.AuthConfig) error {
+	if len(cfg.LocalSecret) < localSecretMinLength {
+		return fmt.Errorf("LOCAL_AUTH_SECRET must be at least %d characters", localSecretMinLength)
+	}
+	switch cfg.Registration {
+	case config.RegistrationOpen, config.RegistrationInvite, config.RegistrationClosed:
+	default:
+		return fmt.Errorf("invalid LOCAL_AUTH_REGISTRATION %q", cfg.Registration)
+	}
+
+	Local = cfg
+	providers = []*provider{{
+		cfg: ProviderConfig{
+			Issuer:     cfg.LocalIssuer,
+			ClientID:   localAudience,
+			Algorithms: []string{jwt.SigningMethodHS256.Alg()},
+			ClockSkew:  5 * time.Second,
+			Claims:     ClaimMapping{UserID: "sub", Username: "preferred_username", DisplayName: "name", RealmRoles: "roles"},
+		},
+		secret: []byte(cfg.LocalSecret),
+	}}
+	return nil
+}
+
+// IssueAccessToken stellt im lokalen Modus ein Access Token aus. Die Rollen landen im Claim
+// roles und werden wie Realm-Rollen über ROLE_MAPPING abgebildet.
+func IssueAccessToken(webuserID, username, displayName string, roles []string, now time.Time) (string, time.Time, error) {
+	if Local == nil {
+		return "", time.Time{}, fmt.Errorf("local auth is not enabled")
+	}
+	expiresAt := now.Add(Local.AccessTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":                Local.LocalIssuer,
+		"aud":                localAudience,
+		"sub":                webuserID,
+		"preferred_username": username,
+		"name":               displayName,
+		"roles":              roles,
+		"iat":                now.Unix(),
+		"exp":                expiresAt.Unix(),
+	})
+	signed, err := token.SignedString([]byte(Local.LocalSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
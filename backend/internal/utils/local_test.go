@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/config"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testLocalSecret = "0123456789abcdef0123456789abcdef"
+
+func testLocalConfig() *config.AuthConfig {
+	return &config.AuthConfig{
+		Mode:              config.AuthModeLocal,
+		LocalSecret:       testLocalSecret,
+		LocalIssuer:       "diplodocu-test",
+		AccessTokenTTL:    15 * time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		Registration:      config.RegistrationInvite,
+		PasswordMinLength: 10,
+	}
+}
+
+// useLocalAuth aktiviert den lokalen Modus für die Dauer eines Tests
+func useLocalAuth(t *testing.T, cfg *config.AuthConfig) {
+	previousProviders, previousLocal, previousRoles := providers, Local, Roles
+	t.Cleanup(func() { providers, Local, Roles = previousProviders, previousLocal, previousRoles })
+	require.NoError(t, InitLocalAuth(cfg))
+	Roles = RoleMapping{RoleAdmin: {RoleAdmin}}
+}
+
+func TestInitLocalAuthValidation(t *testing.T) {
+	previousProviders, previousLocal := providers, Local
+	t.Cleanup(func() { providers, Local = previousProviders, previousLocal })
+
+	cfg := testLocalConfig()
+	cfg.LocalSecret = "too-short"
+	assert.Error(t, InitLocalAuth(cfg))
+
+	cfg = testLocalConfig()
+	cfg.Registration = "sometimes"
+	assert.Error(t, InitLocalAuth(cfg))
+	assert.False(t, LocalMode())
+}
+
+func TestAuthMiddlewareLocalTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useUserSync(t)
+	useLocalAuth(t, testLocalConfig())
+	assert.True(t, LocalMode())
+	assert.Empty(t, JWKSStates())
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Haushalt{}, &models.HaushaltMitgliedschaft{}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.GET("/me", AuthMiddleware(), RequireRole(RoleAdmin), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetString("userId"), "userName": c.GetString("userName")})
+	})
+	do := func(token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now()
+	token, expiresAt, err := IssueAccessToken("local-user", "alice", "Alice", []string{RoleAdmin}, now)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(15*time.Minute), expiresAt, time.Second)
+	w := do(token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"userId":"local-user","userName":"alice"}`, w.Body.String())
+
+	// Without the admin role
+	token, _, err = IssueAccessToken("local-user", "alice", "Alice", []string{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, do(token).Code)
+
+	// Expired, or signed with another secret
+	token, _, err = IssueAccessToken("local-user", "alice", "Alice", []string{RoleAdmin}, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, do(token).Code)
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": "diplodocu-test", "aud": "diplodocu", "sub": "local-user", "roles": []string{RoleAdmin},
+		"exp": now.Add(time.Minute).Unix(),
+	}).SignedString([]byte(strings.Repeat("x", 32)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, do(forged).Code)
+}
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClaimMapping legt fest, aus welchen Claims Benutzerdaten und Rollen gelesen werden. Pfade
+// verschachtelter Claims werden mit Punkten angegeben; {client_id} wird durch die Client-ID
+// des Providers ersetzt. Leere Pfade werden nicht ausgewertet.
+type ClaimMapping struct {
+	UserID      string // Standard: sub
+	Username    string // Standard: preferred_username
+	DisplayName string // Standard: name
+	Email       string // Standard: email (Fallback für den Namen)
+	Groups      string // Standard: groups (Haushalte)
+	RealmRoles  string // Standard: realm_access.roles
+	ClientRoles string // Standard: resource_access.{client_id}.roles
+}
+
+// DefaultClaimMapping entspricht den Access Tokens von Keycloak
+var DefaultClaimMapping = ClaimMapping{
+	UserID:      "sub",
+	Username:    "preferred_username",
+	DisplayName: "name",
+	Email:       "email",
+	Groups:      "groups",
+	RealmRoles:  "realm_access.roles",
+	ClientRoles: "resource_access.{client_id}.roles",
+}
+
+// ProviderConfig beschreibt einen vertrauenswürdigen OIDC-Provider (Keycloak, Authentik, Dex, ...)
+type ProviderConfig struct {
+	Issuer       string
+	ClientID     string        // Muss in aud stehen oder als azp gesetzt sein
+	SkipAudience bool          // Keine Prüfung von aud und azp; nur per OIDC_SKIP_AUDIENCE_CHECK
+	UserIDPrefix string        // Wird vor die Benutzer-ID gesetzt, siehe UserIDPrefixFor
+	JwksURI      string        // Leer = per Discovery ermitteln
+	Algorithms   []string      // Erlaubte Signaturverfahren, z.B. RS256
+	ClockSkew    time.Duration // Toleranz bei exp, nbf und iat für abweichende Uhren
+	Claims       ClaimMapping
+}
+
+// UserID liefert die Benutzer-ID aus dem Token, mit dem Präfix des Issuers
+func (cfg ProviderConfig) UserID(claims jwt.MapClaims) string {
+	id := claimString(lookupClaim(claims, cfg.Claims.UserID))
+	if id == "" {
+		return ""
+	}
+	return cfg.UserIDPrefix + id
+}
+
+// UserIDPrefixFor bildet das Präfix für die Benutzer-IDs eines weiteren Issuers. sub ist nur
+// je Issuer eindeutig; ohne Präfix könnten zwei Provider denselben Benutzer liefern. Der erste
+// Issuer behält die IDs ohne Präfix, damit bestehende Konten erhalten bleiben.
+func UserIDPrefixFor(issuer string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(issuer, "/")))
+	return hex.EncodeToString(sum[:6]) + ":"
+}
+
+// provider ist ein konfigurierter Provider mit seinen geladenen Schlüsseln. Lokal ausgestellte
+// Tokens werden statt mit JWKS mit secret geprüft.
+type provider struct {
+	cfg    ProviderConfig
+	keys   *jwksHolder
+	secret []byte
+}
+
+// keyfunc liefert die Schlüssel des Providers oder nil, solange sie nicht geladen sind
+func (p *provider) keyfunc() jwt.Keyfunc {
+	if p.secret != nil {
+		return func(*jwt.Token) (interface{}, error) { return p.secret, nil }
+	}
+	if jwks := p.keys.current(); jwks != nil {
+		return jwks.Keyfunc
+	}
+	return nil
+}
+
+// providers sind alle vertrauenswürdigen Issuer; gesetzt durch InitAuth
+var providers []*provider
+
+// sameIssuer vergleicht Issuer ohne abschließenden Schrägstrich, den z.B. Authentik anhängt
+func sameIssuer(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// providerFor sucht den Provider zum Issuer eines Tokens
+func providerFor(issuer string) *provider {
+	for _, p := range providers {
+		if sameIssuer(p.cfg.Issuer, issuer) {
+			return p
+		}
+	}
+	return nil
+}
+
+// LoadProviderConfigs liest die Provider aus der Umgebung. OIDC_ISSUERS enthält eine
+// kommagetrennte Liste von Issuern, optional mit eigener Client-ID ("issuer|client").
+// Ohne OIDC_ISSUERS gelten weiterhin KEYCLOAK_ISSUER und KEYCLOAK_CLIENT_ID. Alle übrigen
+// OIDC_*-Variablen fallen ebenso auf ihr KEYCLOAK_*-Gegenstück zurück. Ein Issuer ohne
+// Client-ID ist nur mit OIDC_SKIP_AUDIENCE_CHECK=true erlaubt. Die Benutzer-IDs aller Issuer
+// nach dem ersten erhalten ein Präfix, die Reihenfolge der Liste muss daher gleich bleiben.
+func LoadProviderConfigs() ([]ProviderConfig, error) {
+	issuers := envFirst("OIDC_ISSUERS", "KEYCLOAK_ISSUER")
+	if issuers == "" {
+		return nil, fmt.Errorf("OIDC_ISSUERS or KEYCLOAK_ISSUER must be set")
+	}
+	defaultClientID := envFirst("OIDC_CLIENT_ID", "KEYCLOAK_CLIENT_ID")
+	skipAudience := false
+	if value := envFirst("OIDC_SKIP_AUDIENCE_CHECK", "KEYCLOAK_SKIP_AUDIENCE_CHECK"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("OIDC_SKIP_AUDIENCE_CHECK must be true or false")
+		}
+		skipAudience = parsed
+	}
+
+	algorithms := []string{"RS256"}
+	if value := envFirst("OIDC_ALGORITHMS", "KEYCLOAK_ALGORITHMS"); value != "" {
+		algorithms = nil
+		for _, alg := range strings.Split(value, ",") {
+			if alg = strings.TrimSpace(alg); alg != "" {
+				algorithms = append(algorithms, alg)
+			}
+		}
+	}
+	clockSkew, err := time.ParseDuration(envFirst("OIDC_CLOCK_SKEW", "KEYCLOAK_CLOCK_SKEW"))
+	if err != nil {
+		clockSkew = 30 * time.Second
+	}
+
+	claims := DefaultClaimMapping
+	for key, target := range map[string]*string{
+		"OIDC_USER_ID_CLAIM":      &claims.UserID,
+		"OIDC_USERNAME_CLAIM":     &claims.Username,
+		"OIDC_DISPLAY_NAME_CLAIM": &claims.DisplayName,
+		"OIDC_EMAIL_CLAIM":        &claims.Email,
+		"OIDC_GROUPS_CLAIM":       &claims.Groups,
+		"OIDC_ROLES_CLAIM":        &claims.RealmRoles,
+		"OIDC_CLIENT_ROLES_CLAIM": &claims.ClientRoles,
+	} {
+		// "-" schaltet einen Claim ab, z.B. Client-Rollen bei Providern ohne resource_access
+		if value, ok := os.LookupEnv(key); ok && strings.TrimSpace(value) != "" {
+			*target = strings.TrimSpace(value)
+			if *target == "-" {
+				*target = ""
+			}
+		}
+	}
+	if claims.UserID == "" {
+		return nil, fmt.Errorf("OIDC_USER_ID_CLAIM must not be disabled")
+	}
+
+	var configs []ProviderConfig
+	for _, entry := range strings.Split(issuers, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		issuer, clientID, found := strings.Cut(entry, "|")
+		if !found {
+			clientID = defaultClientID
+		}
+		if !strings.HasPrefix(issuer, "http://") && !strings.HasPrefix(issuer, "https://") {
+			return nil, fmt.Errorf("issuer %q must include http:// or https:// protocol", issuer)
+		}
+		clientID = strings.TrimSpace(clientID)
+		if clientID == "" && !skipAudience {
+			return nil, fmt.Errorf("issuer %q has no client ID; set OIDC_CLIENT_ID or OIDC_SKIP_AUDIENCE_CHECK=true", issuer)
+		}
+		prefix := ""
+		if len(configs) > 0 {
+			prefix = UserIDPrefixFor(issuer)
+		}
+		for _, other := range configs {
+			if sameIssuer(other.Issuer, issuer) {
+				return nil, fmt.Errorf("issuer %q is listed more than once", issuer)
+			}
+		}
+		configs = append(configs, ProviderConfig{
+			Issuer:       issuer,
+			ClientID:     clientID,
+			SkipAudience: skipAudience,
+			UserIDPrefix: prefix,
+			JwksURI:      os.Getenv("OIDC_JWKS_URI"), // Nur sinnvoll bei einem einzelnen Issuer
+			Algorithms:   algorithms,
+			ClockSkew:    clockSkew,
+			Claims:       claims,
+		})
+	}
+	if len(configs) > 1 && os.Getenv("OIDC_JWKS_URI") != "" {
+		return nil, fmt.Errorf("OIDC_JWKS_URI can only be used with a single issuer")
+	}
+	return configs, nil
+}
+
+// discoveryDocument enthält die benötigten Felder aus .well-known/openid-configuration
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JwksURI string `json:"jwks_uri"`
+}
+
+// discoverJWKSURI ermittelt die JWKS-URL eines Issuers per OIDC Discovery. Der Issuer im
+// Dokument muss dem konfigurierten entsprechen.
+func discoverJWKSURI(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("discovery: unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("discovery: %w", err)
+	}
+	if !sameIssuer(doc.Issuer, issuer) {
+		return "", fmt.Errorf("discovery: issuer %q does not match configured issuer %q", doc.Issuer, issuer)
+	}
+	if doc.JwksURI == "" {
+		return "", fmt.Errorf("discovery: no jwks_uri for %s", issuer)
+	}
+	return doc.JwksURI, nil
+}
+
+// lookupClaim liest einen Claim über einen Punkt-Pfad wie "realm_access.roles"
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// roles liest Realm- und Client-Rollen gemäß Mapping aus den Claims
+func (m ClaimMapping) roles(claims map[string]interface{}, clientID string) (realmRoles, clientRoles []string) {
+	realmRoles = stringClaims(lookupClaim(claims, m.RealmRoles))
+	if clientID != "" && m.ClientRoles != "" {
+		clientRoles = stringClaims(lookupClaim(claims, strings.ReplaceAll(m.ClientRoles, "{client_id}", clientID)))
+	}
+	return realmRoles, clientRoles
+}
+
+// envFirst liefert den ersten gesetzten Wert der Umgebungsvariablen
+func envFirst(keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
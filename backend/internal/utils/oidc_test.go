@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearOIDCEnv setzt alle Auth-Variablen für den Test zurück
+func clearOIDCEnv(t *testing.T) {
+	for _, key := range []string{
+		"OIDC_ISSUERS", "OIDC_CLIENT_ID", "OIDC_ALGORITHMS", "OIDC_CLOCK_SKEW", "OIDC_JWKS_URI",
+		"OIDC_USER_ID_CLAIM", "OIDC_USERNAME_CLAIM", "OIDC_DISPLAY_NAME_CLAIM", "OIDC_EMAIL_CLAIM", "OIDC_GROUPS_CLAIM",
+		"OIDC_ROLES_CLAIM", "OIDC_CLIENT_ROLES_CLAIM",
+		"OIDC_SKIP_AUDIENCE_CHECK",
+		"KEYCLOAK_ISSUER", "KEYCLOAK_CLIENT_ID", "KEYCLOAK_ALGORITHMS", "KEYCLOAK_CLOCK_SKEW", "KEYCLOAK_SKIP_AUDIENCE_CHECK",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoadProviderConfigsKeycloakVariables(t *testing.T) {
+	clearOIDCEnv(t)
+	t.Setenv("KEYCLOAK_ISSUER", "https://keycloak.example.org/realms/diplodocu")
+	t.Setenv("KEYCLOAK_CLIENT_ID", "diplodocu")
+	t.Setenv("KEYCLOAK_CLOCK_SKEW", "1m")
+
+	configs, err := LoadProviderConfigs()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, ProviderConfig{
+		Issuer:     "https://keycloak.example.org/realms/diplodocu",
+		ClientID:   "diplodocu",
+		Algorithms: []string{"RS256"},
+		ClockSkew:  time.Minute,
+		Claims:     DefaultClaimMapping,
+	}, configs[0])
+}
+
+func TestLoadProviderConfigsMultipleIssuers(t *testing.T) {
+	clearOIDCEnv(t)
+	t.Setenv("OIDC_ISSUERS", "https://keycloak.example.org/realms/diplodocu, https://authentik.example.org/application/o/diplodocu/|authentik-client")
+	t.Setenv("OIDC_CLIENT_ID", "diplodocu")
+	t.Setenv("OIDC_ALGORITHMS", "RS256, ES256")
+	t.Setenv("OIDC_USERNAME_CLAIM", "nickname")
+	t.Setenv("OIDC_ROLES_CLAIM", "groups")
+	t.Setenv("OIDC_CLIENT_ROLES_CLAIM", "-")
+
+	configs, err := LoadProviderConfigs()
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	assert.Equal(t, "diplodocu", configs[0].ClientID)
+	assert.Empty(t, configs[0].UserIDPrefix)
+	assert.Equal(t, UserIDPrefixFor("https://authentik.example.org/application/o/diplodocu"), configs[1].UserIDPrefix)
+	assert.NotEmpty(t, configs[1].UserIDPrefix)
+	assert.Equal(t, "https://authentik.example.org/application/o/diplodocu/", configs[1].Issuer)
+	assert.Equal(t, "authentik-client", configs[1].ClientID)
+	assert.Equal(t, []string{"RS256", "ES256"}, configs[1].Algorithms)
+	assert.Equal(t, 30*time.Second, configs[1].ClockSkew)
+	assert.Equal(t, ClaimMapping{
+		UserID:      "sub",
+		Username:    "nickname",
+		DisplayName: "name",
+		Email:       "email",
+		Groups:      "groups",
+		RealmRoles:  "groups",
+	}, configs[1].Claims)
+}
+
+func TestLoadProviderConfigsSkipAudience(t *testing.T) {
+	clearOIDCEnv(t)
+	t.Setenv("OIDC_ISSUERS", "https://dex.example.org")
+	t.Setenv("OIDC_SKIP_AUDIENCE_CHECK", "true")
+
+	configs, err := LoadProviderConfigs()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Empty(t, configs[0].ClientID)
+	assert.True(t, configs[0].SkipAudience)
+}
+
+func TestLoadProviderConfigsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "no issuer", env: map[string]string{}},
+		{name: "issuer without protocol", env: map[string]string{"OIDC_ISSUERS": "auth.example.org", "OIDC_CLIENT_ID": "diplodocu"}},
+		{name: "user id claim disabled", env: map[string]string{
+			"OIDC_ISSUERS": "https://a.example.org", "OIDC_CLIENT_ID": "diplodocu", "OIDC_USER_ID_CLAIM": "-",
+		}},
+		{name: "jwks uri with several issuers", env: map[string]string{
+			"OIDC_ISSUERS":   "https://a.example.org,https://b.example.org",
+			"OIDC_CLIENT_ID": "diplodocu",
+			"OIDC_JWKS_URI":  "https://a.example.org/keys",
+		}},
+		{name: "duplicate issuer", env: map[string]string{
+			"OIDC_ISSUERS":   "https://a.example.org,https://a.example.org/",
+			"OIDC_CLIENT_ID": "diplodocu",
+		}},
+		{name: "no client id", env: map[string]string{"OIDC_ISSUERS": "https://a.example.org"}},
+		{name: "empty client id for one issuer", env: map[string]string{
+			"OIDC_ISSUERS":   "https://a.example.org,https://b.example.org|",
+			"OIDC_CLIENT_ID": "diplodocu",
+		}},
+		{name: "invalid audience opt-out", env: map[string]string{"OIDC_ISSUERS": "https://a.example.org", "OIDC_SKIP_AUDIENCE_CHECK": "maybe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearOIDCEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, err := LoadProviderConfigs()
+			assert.Error(t, err)
+		})
+	}
+}
+
+// discoveryServer stellt Discovery-Dokument und JWKS wie ein OIDC-Provider bereit
+func discoveryServer(t *testing.T, key *rsa.PrivateKey, issuer func(base string) string) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer(server.URL),
+			"jwks_uri": server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(jwksBody(t, key, "discovered"))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscovery(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := discoveryServer(t, key, func(base string) string { return base + "/" })
+
+	// Trailing slashes in issuers are tolerated
+	uri, err := discoverJWKSURI(context.Background(), http.DefaultClient, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/keys", uri)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	holder := newJWKSHolder(server.URL)
+	cfg := ProviderConfig{Issuer: server.URL, ClientID: "diplodocu", Algorithms: []string{"RS256"}, Claims: DefaultClaimMapping}
+	go loadJWKS(ctx, holder, cfg, JWKSOptions{BackoffBase: 10 * time.Millisecond, BackoffMax: 10 * time.Millisecond})
+	require.Eventually(t, func() bool { return holder.current() != nil }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, server.URL+"/keys", holder.state().URI)
+
+	claims := validClaims(time.Now())
+	claims["iss"] = server.URL + "/"
+	token := tokenSigner{t: t, key: key, kid: "discovered"}.sign(jwt.SigningMethodRS256, claims)
+	_, err = ValidateToken(token, holder.current().Keyfunc, cfg, time.Now())
+	assert.NoError(t, err)
+
+	// A discovery document for another issuer is rejected
+	foreign := discoveryServer(t, key, func(string) string { return "https://evil.example.org" })
+	_, err = discoverJWKSURI(context.Background(), http.DefaultClient, foreign.URL)
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestClaimMappingRoles(t *testing.T) {
+	claims := map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []interface{}{"offline_access", "admin"}},
+		"resource_access": map[string]interface{}{
+			"diplodocu": map[string]interface{}{"roles": []interface{}{"catalog-admin"}},
+			"account":   map[string]interface{}{"roles": []interface{}{"manage-account"}},
+		},
+		"groups": []interface{}{"admins"},
+	}
+
+	realm, client := DefaultClaimMapping.roles(claims, "diplodocu")
+	assert.Equal(t, []string{"offline_access", "admin"}, realm)
+	assert.Equal(t, []string{"catalog-admin"}, client)
+
+	_, client = DefaultClaimMapping.roles(claims, "")
+	assert.Empty(t, client)
+	realm, client = DefaultClaimMapping.roles(map[string]interface{}{}, "diplodocu")
+	assert.Empty(t, realm)
+	assert.Empty(t, client)
+
+	realm, client = ClaimMapping{RealmRoles: "groups"}.roles(claims, "diplodocu")
+	assert.Equal(t, []string{"admins"}, realm)
+	assert.Empty(t, client)
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastPasswordParams senkt den Aufwand von argon2id für die Dauer eines Tests
+func fastPasswordParams(t *testing.T) {
+	previous := PasswordParams
+	t.Cleanup(func() { PasswordParams = previous })
+	PasswordParams.Memory = 1024
+	PasswordParams.Iterations = 1
+	PasswordParams.Parallelism = 1
+}
+
+func TestHashPassword(t *testing.T) {
+	fastPasswordParams(t)
+
+	hash, err := HashPassword("korrekt pferd batterie")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	ok, err := VerifyPassword("korrekt pferd batterie", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = VerifyPassword("korrekt pferd", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Same password, different salt
+	other, err := HashPassword("korrekt pferd batterie")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+
+	// Hashes keep their own parameters after the defaults change
+	PasswordParams.Iterations = 2
+	ok, err = VerifyPassword("korrekt pferd batterie", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$2a$10$abcdefghijklmnopqrstuv",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	} {
+		_, err := VerifyPassword("secret", encoded)
+		assert.Error(t, err, encoded)
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EditionFormate legt fest, welche Formate je Produktart erlaubt sind
+var EditionFormate = map[string][]string{
+	"Buch":      {"Hardcover", "Paperback", "E-Book"},
+	"Manga":     {"Hardcover", "Paperback", "E-Book"},
+	"Filmserie": {"DVD", "Blu-ray", "4K"},
+	"Spiel":     {"Physical", "Digital"},
+}
+
+// FilmArten sind die Werte des ENUMs enum_filmserie_art
+var FilmArten = []string{"Film", "Serie"}
+
+// ValidateEditionFormat prüft das Format gegen die Produktart und liefert die kanonische Schreibweise
+func ValidateEditionFormat(art string, format string) (string, error) {
+	erlaubt := EditionFormate[art]
+	for _, f := range erlaubt {
+		if strings.EqualFold(f, strings.TrimSpace(format)) {
+			return f, nil
+		}
+	}
+	if len(erlaubt) == 0 {
+		return "", fmt.Errorf("editions are not supported for product type '%s'", art)
+	}
+	return "", fmt.Errorf("format for '%s' must be one of: %s", art, strings.Join(erlaubt, ", "))
+}
+
+// ValidFilmArt prüft, ob art ein gültiger Wert für Filmserie.Art ist
+func ValidFilmArt(art string) bool {
+	for _, f := range FilmArten {
+		if f == art {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEditionFormat(t *testing.T) {
+	format, err := ValidateEditionFormat("Filmserie", " blu-RAY ")
+	require.NoError(t, err)
+	assert.Equal(t, "Blu-ray", format)
+
+	_, err = ValidateEditionFormat("Buch", "DVD")
+	assert.EqualError(t, err, "format for 'Buch' must be one of: Hardcover, Paperback, E-Book")
+	_, err = ValidateEditionFormat("Hörspiel", "CD")
+	assert.EqualError(t, err, "editions are not supported for product type 'Hörspiel'")
+}
+
+func TestValidFilmArt(t *testing.T) {
+	assert.True(t, ValidFilmArt("Film"))
+	assert.True(t, ValidFilmArt("Serie"))
+	assert.False(t, ValidFilmArt("film"))
+	assert.False(t, ValidFilmArt("Kinofilm"))
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rollen der Anwendung
+const (
+	RoleAdmin = "admin" // Katalogpflege, z.B. Produkte löschen
+)
+
+// Standard ohne ROLE_MAPPING: die gleichnamige Realm- oder Client-Rolle
+const defaultRoleMapping = "admin=admin"
+
+// RoleMapping ordnet jeder Anwendungsrolle die Keycloak-Rollen zu, die sie gewähren.
+// Einträge der Form "realm:name" bzw. "client:name" passen nur auf Realm- bzw. Client-Rollen
+// (siehe ClaimMapping), ein Name ohne Präfix auf beide.
+type RoleMapping map[string][]string
+
+// Roles ist das beim Start aus ROLE_MAPPING geladene Mapping
+var Roles RoleMapping
+
+// ParseRoleMapping liest ein Mapping der Form "admin=realm:diplodocu-admin|client:catalog-admin,editor=editor"
+func ParseRoleMapping(value string) (RoleMapping, error) {
+	mapping := RoleMapping{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		role, sources, found := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !found || role == "" {
+			return nil, fmt.Errorf("invalid role mapping entry %q", entry)
+		}
+		for _, source := range strings.Split(sources, "|") {
+			source = strings.TrimSpace(source)
+			if prefix, name, ok := strings.Cut(source, ":"); ok && (prefix != "realm" && prefix != "client" || name == "") {
+				return nil, fmt.Errorf("invalid keycloak role %q for %s", source, role)
+			}
+			if source != "" {
+				mapping[role] = append(mapping[role], source)
+			}
+		}
+		if len(mapping[role]) == 0 {
+			return nil, fmt.Errorf("role %s has no keycloak roles", role)
+		}
+	}
+	return mapping, nil
+}
+
+// roleMappingFromEnv lädt ROLE_MAPPING bzw. das Standardmapping
+func roleMappingFromEnv() (RoleMapping, error) {
+	value := os.Getenv("ROLE_MAPPING")
+	if strings.TrimSpace(value) == "" {
+		value = defaultRoleMapping
+	}
+	return ParseRoleMapping(value)
+}
+
+// Map liefert die Anwendungsrollen zu den Realm- und Client-Rollen aus dem Token
+func (m RoleMapping) Map(realmRoles, clientRoles []string) []string {
+	has := func(list []string, name string) bool {
+		for _, item := range list {
+			if item == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var roles []string
+	for role, sources := range m {
+		for _, source := range sources {
+			prefix, name, prefixed := strings.Cut(source, ":")
+			matched := false
+			switch {
+			case !prefixed:
+				matched = has(realmRoles, source) || has(clientRoles, source)
+			case prefix == "realm":
+				matched = has(realmRoles, name)
+			case prefix == "client":
+				matched = has(clientRoles, name)
+			}
+			if matched {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// HasRole prüft, ob die AuthMiddleware dem Benutzer die Anwendungsrolle zugewiesen hat
+func HasRole(c *gin.Context, role string) bool {
+	roles, _ := c.Get("roles")
+	list, _ := roles.([]string)
+	for _, item := range list {
+		if item == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole lässt nur Benutzer mit mindestens einer der Rollen durch. Muss nach der
+// AuthMiddleware laufen.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, role := range roles {
+			if HasRole(c, role) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":      "insufficient_role",
+			"message":    "This action requires one of the roles: " + strings.Join(roles, ", "),
+			"statusCode": http.StatusForbidden,
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping, err := ParseRoleMapping(" admin = realm:diplodocu-admin | client:catalog-admin , editor=editor,")
+	require.NoError(t, err)
+	assert.Equal(t, RoleMapping{
+		"admin":  {"realm:diplodocu-admin", "client:catalog-admin"},
+		"editor": {"editor"},
+	}, mapping)
+
+	for _, invalid := range []string{"admin", "=admin", "admin=", "admin=group:x", "admin=realm:"} {
+		_, err := ParseRoleMapping(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRoleMappingMap(t *testing.T) {
+	mapping := RoleMapping{
+		"admin":  {"realm:diplodocu-admin", "client:catalog-admin"},
+		"editor": {"editor"},
+	}
+
+	assert.Equal(t, []string{"admin"}, mapping.Map([]string{"diplodocu-admin"}, nil))
+	assert.Equal(t, []string{"admin", "editor"}, mapping.Map([]string{"offline_access"}, []string{"catalog-admin", "editor"}))
+	// Prefixed entries only match their own source
+	assert.Empty(t, mapping.Map([]string{"catalog-admin"}, []string{"diplodocu-admin"}))
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(roles []string) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if roles != nil {
+				c.Set("roles", roles)
+			}
+			c.Next()
+		})
+		router.DELETE("/books/:id", RequireRole(RoleAdmin, "editor"), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name  string
+		roles []string
+		code  int
+	}{
+		{name: "admin", roles: []string{RoleAdmin}, code: http.StatusNoContent},
+		{name: "any of the roles", roles: []string{"viewer", "editor"}, code: http.StatusNoContent},
+		{name: "other roles", roles: []string{"viewer"}, code: http.StatusForbidden},
+		{name: "no roles in context", code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodDelete, "/books/1", nil)
+			w := httptest.NewRecorder()
+			newRouter(tt.roles).ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// scopeBereiche ordnet die erste Pfadkomponente unter /api dem Bereich eines Scopes zu
+var scopeBereiche = map[string]string{
+	"sammlungen":  models.ScopeSammlungen,
+	"sammlung":    models.ScopeSammlungen,
+	"einladungen": models.ScopeSammlungen,
+	"books":       models.ScopeKatalog,
+	"mangas":      models.ScopeKatalog,
+	"spiel":       models.ScopeKatalog,
+	"filmserie":   models.ScopeKatalog,
+	"episoden":    models.ScopeKatalog,
+	"editionen":   models.ScopeKatalog,
+	"produkte":    models.ScopeKatalog,
+	"metadata":    models.ScopeKatalog,
+}
+
+// ScopeErlaubt prüft, ob die Scopes eines Zugangstokens eine Anfrage auf die Route path
+// (z.B. "/api/sammlungen/:id") erlauben. Zugangstokens und das Passwort lassen sich nie mit
+// einem Zugangstoken ändern.
+func ScopeErlaubt(scopes []string, method, path string) bool {
+	segmente := strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, "/"), "api/"), "/")
+	if len(segmente) > 1 && segmente[0] == "me" && (segmente[1] == "tokens" || segmente[1] == "passwort") {
+		return false
+	}
+	bereich := scopeBereiche[segmente[0]]
+	lesend := method == http.MethodGet || method == http.MethodHead
+
+	for _, scope := range scopes {
+		switch scope {
+		case models.ScopeVoll:
+			return true
+		case models.ScopeLesen:
+			if lesend {
+				return true
+			}
+		default:
+			name, suffix, _ := strings.Cut(scope, ":")
+			if bereich != "" && name == bereich && (suffix == "" || lesend) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authenticateZugangstoken meldet eine Anfrage mit einem persönlichen Zugangstoken an. Solche
+// Anfragen erhalten keine Rollen; Aktionen, die etwa Admin erfordern, brauchen weiterhin ein
+// Token des Identity Providers.
+func authenticateZugangstoken(c *gin.Context, db *gorm.DB, token string) {
+	zugangstoken, err := database.FindZugangstoken(db, token, time.Now().UTC())
+	if err != nil {
+		if !errors.Is(err, database.ErrZugangstokenUngueltig) {
+			log.Printf("ERROR access token lookup: %v\n", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "database_error",
+				"message":    "Internal server error",
+				"statusCode": http.StatusInternalServerError,
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":      "invalid_token",
+			"message":    "Invalid or expired authentication token",
+			"statusCode": http.StatusUnauthorized,
+		})
+		return
+	}
+
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	scopes := zugangstoken.ScopeListe()
+	if !ScopeErlaubt(scopes, c.Request.Method, path) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":      "insufficient_scope",
+			"message":    "The access token does not permit this request",
+			"statusCode": http.StatusForbidden,
+		})
+		return
+	}
+
+	var webuser models.Webuser
+	if err := db.Where("id = ?", zugangstoken.WebuserID).First(&webuser).Error; err != nil {
+		log.Printf("ERROR access token %d - Find user %s: %v\n", zugangstoken.ID, zugangstoken.WebuserID, err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":      "invalid_token",
+			"message":    "Invalid or expired authentication token",
+			"statusCode": http.StatusUnauthorized,
+		})
+		return
+	}
+	name := "Unknown"
+	if webuser.Name != nil {
+		name = *webuser.Name
+	}
+
+	c.Set("userId", webuser.ID)
+	c.Set("userName", name)
+	c.Set("roles", []string{})
+	c.Set("zugangstokenId", zugangstoken.ID)
+	c.Set("scopes", scopes)
+
+	database.Users.Touch(webuser.ID)
+	c.Next()
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/database"
+	"github.com/kitzune-no-aki/diplodocu/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestScopeErlaubt(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{[]string{models.ScopeVoll}, http.MethodDelete, "/api/sammlungen/:id", true},
+		{[]string{models.ScopeVoll}, http.MethodPost, "/api/me/tokens", false},
+		{[]string{models.ScopeVoll}, http.MethodGet, "/api/me/tokens", false},
+		{[]string{models.ScopeVoll}, http.MethodPut, "/api/me/passwort", false},
+		{[]string{models.ScopeLesen}, http.MethodGet, "/api/backup", true},
+		{[]string{models.ScopeLesen}, http.MethodPost, "/api/books", false},
+		{[]string{models.ScopeSammlungen}, http.MethodPost, "/api/sammlung/:sammlungId/produkte", true},
+		{[]string{models.ScopeSammlungen}, http.MethodGet, "/api/books", false},
+		{[]string{models.ScopeSammlungenLesen}, http.MethodGet, "/api/sammlungen", true},
+		{[]string{models.ScopeSammlungenLesen}, http.MethodDelete, "/api/sammlungen/:id", false},
+		{[]string{models.ScopeSammlungenLesen, models.ScopeKatalog}, http.MethodPut, "/api/books/:id", true},
+		{[]string{models.ScopeKatalogLesen}, http.MethodGet, "/api/produkte/by-code/:code", true},
+		{[]string{models.ScopeKatalog}, http.MethodGet, "/api/haushalte", false},
+		{[]string{}, http.MethodGet, "/api/sammlungen", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ScopeErlaubt(tt.scopes, tt.method, tt.path), "%v %s %s", tt.scopes, tt.method, tt.path)
+	}
+}
+
+func TestAuthMiddlewareZugangstoken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useUserSync(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Webuser{}, &models.Zugangstoken{}))
+	name := "Alice"
+	require.NoError(t, db.Create(&models.Webuser{ID: "alice", Name: &name}).Error)
+
+	now := time.Now().UTC()
+	create := func(token, scopes string, gueltigBis, widerrufenAm *time.Time) models.Zugangstoken {
+		zugangstoken := models.Zugangstoken{WebuserID: "alice", Name: token, Anfang: token[:8], Hash: database.HashToken(token),
+			Scopes: scopes, ErstelltAm: now, GueltigBis: gueltigBis, WiderrufenAm: widerrufenAm}
+		require.NoError(t, db.Omit("Webuser").Create(&zugangstoken).Error)
+		return zugangstoken
+	}
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	lesen := create("dpat_lesen", models.ScopeSammlungenLesen, &future, nil)
+	create("dpat_abgelaufen", models.ScopeVoll, &past, nil)
+	create("dpat_widerrufen", models.ScopeVoll, nil, &past)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	api := router.Group("/api", AuthMiddleware())
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetString("userId"), "userName": c.GetString("userName"), "roles": c.GetStringSlice("roles")})
+	}
+	api.GET("/sammlungen", handler)
+	api.DELETE("/sammlungen/:id", handler)
+	api.DELETE("/books/:id", RequireRole(RoleAdmin), handler)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/sammlungen", "dpat_lesen")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"userId":"alice","userName":"Alice","roles":[]}`, w.Body.String())
+	var stored models.Zugangstoken
+	require.NoError(t, db.First(&stored, lesen.ID).Error)
+	require.NotNil(t, stored.ZuletztVerwendetAm)
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/sammlungen/1", "dpat_lesen").Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/books/1", "dpat_lesen").Code)
+	for _, token := range []string{"dpat_abgelaufen", "dpat_widerrufen", "dpat_unbekannt"} {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/sammlungen", token).Code, token)
+	}
+}